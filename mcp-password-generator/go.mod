@@ -2,8 +2,9 @@ module github.com/ss49919201/ai-playground/go/mcp-password-generator
 
 go 1.18
 
+require gopkg.in/yaml.v2 v2.4.0
+
 require (
 	github.com/gorilla/mux v1.8.1 // indirect
 	github.com/gorilla/rpc v1.2.1 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
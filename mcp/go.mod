@@ -2,8 +2,9 @@ module github.com/ss49919201/ai-playground/go/mcp
 
 go 1.24.0
 
+require github.com/mark3labs/mcp-go v0.17.0
+
 require (
 	github.com/google/uuid v1.6.0 // indirect
-	github.com/mark3labs/mcp-go v0.17.0 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 )
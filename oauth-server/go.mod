@@ -3,7 +3,7 @@ module github.com/ss49919201/ai-playground/go/oauth-server
 go 1.24.0
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/google/uuid v1.6.0
+	golang.org/x/crypto v0.37.0
+	gopkg.in/yaml.v2 v2.4.0
 )
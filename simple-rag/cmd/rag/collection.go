@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"simple-rag/internal/config"
+	"simple-rag/internal/vector"
+)
+
+// runCollection runs "collection create|list|drop [name]"
+func runCollection(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: -cmd collection create|list|drop [name]")
+	}
+	collections := vector.NewCollections(cfg.VectorDB.StoragePath)
+
+	switch args[0] {
+	case "list":
+		infos, err := collections.List()
+		if err != nil {
+			return err
+		}
+		printCollections(infos, cfg.VectorDB.Collection)
+		return nil
+
+	case "create", "drop":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: -cmd collection %s <name>", args[0])
+		}
+		name := args[1]
+		if args[0] == "create" {
+			if err := collections.Create(name, databaseOptions(cfg)); err != nil {
+				return err
+			}
+			fmt.Printf("Collection created: %s (%s)\n", name, embeddingModelName(cfg))
+			return nil
+		}
+		if err := collections.Drop(name); err != nil {
+			return err
+		}
+		fmt.Printf("Collection dropped: %s\n", name)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown collection command: %s", args[0])
+	}
+}
+
+// runExport writes the configured collection to an archive file
+func runExport(cfg *config.Config, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	info, err := vector.NewCollections(cfg.VectorDB.StoragePath).Export(cfg.VectorDB.Collection, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	fmt.Printf("Exported collection %s (%d documents, %d chunks) to %s\n", info.Name, info.Documents, info.Chunks, path)
+	return nil
+}
+
+// runImport creates a collection from an archive file. The collection is
+// named by -collection, or by the archive when the flag is not set.
+func runImport(cfg *config.Config, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	info, err := vector.NewCollections(cfg.VectorDB.StoragePath).Import(file, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported collection %s (%d documents, %d chunks, %s, %s quantization)\n",
+		info.Name, info.Documents, info.Chunks, info.EmbeddingModel, info.Quantization)
+	if model := embeddingModelName(cfg); info.EmbeddingModel != "" && info.EmbeddingModel != model {
+		fmt.Printf("Note: the collection was embedded with %s, but the configuration uses %s\n", info.EmbeddingModel, model)
+	}
+	return nil
+}
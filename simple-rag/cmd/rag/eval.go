@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"simple-rag/internal/config"
+	"simple-rag/internal/eval"
+	"simple-rag/pkg/types"
+)
+
+// EvalOptions controls the eval command
+type EvalOptions struct {
+	// Dataset is the YAML or JSONL file of golden questions
+	Dataset string
+	// CorpusDir, when set, is ingested into a temporary store per config so
+	// chunking settings can be compared. Otherwise the configured store is used.
+	CorpusDir string
+	// K overrides rerank.top_n as the retrieval cutoff when positive
+	K int
+	// RetrievalOnly skips answer generation
+	RetrievalOnly bool
+	// ReportPath, when set, receives the reports as JSON
+	ReportPath string
+}
+
+// evalPipeline adapts RAGSystem to eval.Pipeline
+type evalPipeline struct {
+	system *RAGSystem
+}
+
+func (p evalPipeline) Retrieve(question string) ([]*types.SearchResult, error) {
+	return p.system.retrieve(question, nil, &types.QueryTrace{Query: question})
+}
+
+func (p evalPipeline) Generate(question string, results []*types.SearchResult) (string, error) {
+	if len(results) == 0 {
+		return noResultsResponse(question, results).Answer, nil
+	}
+
+	response, err := p.system.llmClient.GenerateWithContext(question, results)
+	if err != nil {
+		return "", err
+	}
+	return response.Answer, nil
+}
+
+// EvalTarget is a configuration under evaluation, named after its file
+type EvalTarget struct {
+	Name   string
+	Config *config.Config
+}
+
+// RunEval evaluates the dataset against each configuration and returns one
+// report per target, in order
+func RunEval(targets []EvalTarget, opts EvalOptions) ([]*eval.Report, error) {
+	dataset, err := eval.LoadDataset(opts.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*eval.Report
+	for _, target := range targets {
+		report, err := evalConfig(target.Name, target.Config, dataset, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Name, err)
+		}
+		reports = append(reports, report)
+	}
+
+	if opts.ReportPath != "" {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		if err := os.WriteFile(opts.ReportPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	return reports, nil
+}
+
+// evalConfig builds a system for one configuration and runs the dataset
+func evalConfig(name string, base *config.Config, dataset *eval.Dataset, opts EvalOptions) (*eval.Report, error) {
+	cfg := *base
+	if opts.K > 0 {
+		cfg.Rerank.TopN = opts.K
+	}
+
+	if opts.CorpusDir != "" {
+		storage, err := os.MkdirTemp("", "rag-eval-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary store: %w", err)
+		}
+		defer os.RemoveAll(storage)
+		cfg.VectorDB.StoragePath = storage
+	}
+
+	system, err := NewRAGSystem(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer system.Close()
+
+	if opts.CorpusDir != "" {
+		summary, err := system.SyncDirectory(opts.CorpusDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to ingest corpus: %w", err)
+		}
+		for path, err := range summary.Failed {
+			fmt.Fprintf(os.Stderr, "%s: failed to ingest %s: %v\n", name, path, err)
+		}
+	}
+
+	return eval.Run(name, evalPipeline{system: system}, dataset, eval.Options{
+		K:              system.topN(),
+		SkipGeneration: opts.RetrievalOnly,
+	}), nil
+}
@@ -6,21 +6,39 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"simple-rag/internal/config"
-	"simple-rag/internal/document"
-	"simple-rag/internal/llm"
+	"simple-rag/internal/conversation"
 	"simple-rag/internal/vector"
+	"simple-rag/pkg/types"
 )
 
 func main() {
 	var (
 		configPath = flag.String("config", "config.yaml", "Path to configuration file")
-		command    = flag.String("cmd", "interactive", "Command to run: interactive, add, query, list")
-		filePath   = flag.String("file", "", "File path for add command")
+		command    = flag.String("cmd", "interactive", "Command to run: interactive, add, ingest, query, list, eval, collection, export, import, mcp")
+		filePath   = flag.String("file", "", "File path for add command, or archive for export and import commands")
+		dirPath    = flag.String("dir", "", "Directory path for ingest command, or corpus to ingest for eval command")
+		watch      = flag.Bool("watch", false, "Keep re-syncing the ingest directory until interrupted")
+		interval   = flag.Duration("interval", 5*time.Second, "Polling interval for ingest -watch")
 		query      = flag.String("query", "", "Query for query command")
+		session    = flag.String("session", "", "Conversation session name or file to continue for query command")
+		dataset    = flag.String("dataset", "", "Golden question file (.yaml or .jsonl) for eval command")
+		compare    = flag.String("compare", "", "Second config file to evaluate side by side for eval command")
+		evalK      = flag.Int("k", 0, "Retrieval cutoff for eval command (default: rerank.top_n)")
+		retrieval  = flag.Bool("retrieval-only", false, "Skip answer generation in eval command")
+		reportPath = flag.String("report", "", "Write eval results as JSON to this file")
+		verbose    = flag.Bool("verbose", false, "Print per-stage timings and retrieval statistics for queries")
+		traceFile  = flag.String("trace-file", "", "Append query traces as JSON lines to this file (overrides logging.trace_file)")
+		promptFile = flag.String("prompt", "", "Prompt template file (overrides llm.prompt_file)")
+		collection = flag.String("collection", "", "Collection to use (overrides vector_db.collection)")
+		filters    filterFlags
 	)
+	flag.Var(&filters, "filter", "Restrict query to matching documents, e.g. type=md, path=docs/api/, created>=2024-01-01 (repeatable)")
 	flag.Parse()
 
 	// Load configuration
@@ -29,25 +47,51 @@ func main() {
 		log.Printf("Failed to load config, using defaults: %v", err)
 		cfg = config.GetDefaultConfig()
 	}
+	if *traceFile != "" {
+		cfg.Logging.TraceFile = *traceFile
+	}
+	if *promptFile != "" {
+		cfg.LLM.PromptFile = *promptFile
+	}
+	if *collection != "" {
+		cfg.VectorDB.Collection = *collection
+	}
+
+	// Collection commands work on the stores directly
+	switch *command {
+	case "collection":
+		if err := runCollection(cfg, flag.Args()); err != nil {
+			log.Fatalf("Failed to manage collections: %v", err)
+		}
+		return
+	case "export":
+		if *filePath == "" {
+			log.Fatal("Archive path is required for export command")
+		}
+		if err := runExport(cfg, *filePath); err != nil {
+			log.Fatalf("Failed to export collection: %v", err)
+		}
+		return
+	case "import":
+		if *filePath == "" {
+			log.Fatal("Archive path is required for import command")
+		}
+		if err := runImport(cfg, *filePath, *collection); err != nil {
+			log.Fatalf("Failed to import collection: %v", err)
+		}
+		return
+	}
 
-	// Initialize components
-	db := vector.NewDatabase(cfg.VectorDB.StoragePath)
-	if err := db.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// In MCP mode stdout carries the protocol, so logs go to stderr
+	if *command == "mcp" && (cfg.Logging.Output == "" || cfg.Logging.Output == "stdout") {
+		cfg.Logging.Output = "stderr"
 	}
 
-	embeddingClient := vector.NewEmbeddingClient(cfg.Embedding.URL, cfg.Embedding.Model)
-	llmClient := llm.NewClient(cfg.LLM.URL, cfg.LLM.Model, cfg.LLM.Temperature, cfg.LLM.MaxTokens)
-	docReader := document.NewReader(cfg.Document.ChunkSize, cfg.Document.ChunkOverlap)
-
-	// Create RAG system
-	ragSystem := &RAGSystem{
-		db:              db,
-		embeddingClient: embeddingClient,
-		llmClient:       llmClient,
-		docReader:       docReader,
-		config:          cfg,
+	ragSystem, err := NewRAGSystem(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
 	}
+	defer ragSystem.Close()
 
 	// Execute command
 	switch *command {
@@ -60,38 +104,99 @@ func main() {
 		}
 		fmt.Printf("Document added successfully: %s\n", *filePath)
 
+	case "ingest":
+		if *dirPath == "" {
+			log.Fatal("Directory path is required for ingest command")
+		}
+		summary, err := ragSystem.SyncDirectory(*dirPath)
+		if err != nil {
+			log.Fatalf("Failed to ingest directory: %v", err)
+		}
+		printSyncSummary(summary)
+
+		if *watch {
+			runWatch(ragSystem, *dirPath, *interval)
+		}
+
 	case "query":
 		if *query == "" {
 			log.Fatal("Query is required for query command")
 		}
-		response, err := ragSystem.Query(*query)
+		filter, err := vector.ParseFilter(filters)
+		if err != nil {
+			log.Fatalf("Invalid filter: %v", err)
+		}
+		if *session != "" {
+			response, err := runSessionQuery(ragSystem, *session, *query, filter)
+			if err != nil {
+				log.Fatalf("Failed to query: %v", err)
+			}
+			printResponse(response)
+			if *verbose {
+				printTrace(response.Trace)
+			}
+			break
+		}
+		response, err := ragSystem.Query(*query, filter)
 		if err != nil {
 			log.Fatalf("Failed to query: %v", err)
 		}
 		printResponse(response)
+		if *verbose {
+			printTrace(response.Trace)
+		}
 
 	case "list":
 		documents := ragSystem.ListDocuments()
 		printDocuments(documents)
 
+	case "eval":
+		if *dataset == "" {
+			log.Fatal("Dataset is required for eval command")
+		}
+		targets := []EvalTarget{{Name: filepath.Base(*configPath), Config: cfg}}
+		if *compare != "" {
+			other, err := config.LoadConfig(*compare)
+			if err != nil {
+				log.Fatalf("Failed to load compare config: %v", err)
+			}
+			targets = append(targets, EvalTarget{Name: filepath.Base(*compare), Config: other})
+		}
+		reports, err := RunEval(targets, EvalOptions{
+			Dataset:       *dataset,
+			CorpusDir:     *dirPath,
+			K:             *evalK,
+			RetrievalOnly: *retrieval,
+			ReportPath:    *reportPath,
+		})
+		if err != nil {
+			log.Fatalf("Failed to evaluate: %v", err)
+		}
+		printEvalReports(reports)
+
 	case "interactive":
-		runInteractive(ragSystem)
+		runInteractive(ragSystem, *verbose)
+
+	case "mcp":
+		if err := runMCP(ragSystem, os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("MCP server failed: %v", err)
+		}
 
 	default:
 		log.Fatalf("Unknown command: %s", *command)
 	}
 }
 
-func runInteractive(ragSystem *RAGSystem) {
+func runInteractive(ragSystem *RAGSystem, verbose bool) {
 	fmt.Println("Simple RAG System - Interactive Mode")
-	fmt.Println("Commands:")
-	fmt.Println("  add <file_path>  - Add a document")
-	fmt.Println("  query <question> - Ask a question")
-	fmt.Println("  list             - List all documents")
-	fmt.Println("  help             - Show this help")
-	fmt.Println("  exit             - Exit the program")
+	printHelp()
 	fmt.Println()
 
+	// Follow-up questions are answered in the context of this session
+	session := conversation.NewSession()
+	sessionDir := ragSystem.sessionDir()
+	var filter *vector.Filter
+
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
 		fmt.Print("> ")
@@ -120,30 +225,91 @@ func runInteractive(ragSystem *RAGSystem) {
 				fmt.Printf("Document added successfully: %s\n", filePath)
 			}
 
+		case "ingest":
+			if len(parts) < 2 {
+				fmt.Println("Usage: ingest <dir>")
+				continue
+			}
+			summary, err := ragSystem.SyncDirectory(parts[1])
+			if err != nil {
+				fmt.Printf("Error ingesting directory: %v\n", err)
+			} else {
+				printSyncSummary(summary)
+			}
+
 		case "query":
 			if len(parts) < 2 {
 				fmt.Println("Usage: query <question>")
 				continue
 			}
 			question := parts[1]
-			response, err := ragSystem.Query(question)
+			response, err := ragSystem.Chat(session, question, filter)
 			if err != nil {
 				fmt.Printf("Error querying: %v\n", err)
 			} else {
 				printResponse(response)
+				if verbose {
+					printTrace(response.Trace)
+				}
+			}
+
+		case "filter":
+			if len(parts) < 2 {
+				filter = nil
+				fmt.Println("Filter cleared.")
+				continue
+			}
+			parsed, err := vector.ParseFilter(strings.Fields(parts[1]))
+			if err != nil {
+				fmt.Printf("Error parsing filter: %v\n", err)
+			} else {
+				filter = parsed
+				fmt.Printf("Filter set: %s\n", parts[1])
+			}
+
+		case "new":
+			session = conversation.NewSession()
+			fmt.Println("Started a new conversation.")
+
+		case "history":
+			printHistory(session.Recent(0))
+
+		case "save":
+			if len(parts) < 2 {
+				fmt.Println("Usage: save <name>")
+				continue
+			}
+			path := conversation.SessionPath(sessionDir, parts[1])
+			if err := session.Save(path); err != nil {
+				fmt.Printf("Error saving session: %v\n", err)
+			} else {
+				fmt.Printf("Session saved: %s\n", path)
+			}
+
+		case "load":
+			if len(parts) < 2 {
+				fmt.Println("Usage: load <name>")
+				continue
+			}
+			path := conversation.SessionPath(sessionDir, parts[1])
+			loaded, err := conversation.Load(path)
+			if err != nil {
+				fmt.Printf("Error loading session: %v\n", err)
+			} else {
+				session = loaded
+				fmt.Printf("Session loaded: %s (%d messages)\n", path, session.Len())
 			}
 
 		case "list":
 			documents := ragSystem.ListDocuments()
 			printDocuments(documents)
 
+		case "verbose":
+			verbose = !verbose
+			fmt.Printf("Verbose traces: %v\n", verbose)
+
 		case "help":
-			fmt.Println("Commands:")
-			fmt.Println("  add <file_path>  - Add a document")
-			fmt.Println("  query <question> - Ask a question")
-			fmt.Println("  list             - List all documents")
-			fmt.Println("  help             - Show this help")
-			fmt.Println("  exit             - Exit the program")
+			printHelp()
 
 		case "exit":
 			fmt.Println("Goodbye!")
@@ -153,4 +319,79 @@ func runInteractive(ragSystem *RAGSystem) {
 			fmt.Printf("Unknown command: %s. Type 'help' for available commands.\n", command)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// runSessionQuery answers a question within a saved session, creating it if needed
+func runSessionQuery(ragSystem *RAGSystem, name, question string, filter *vector.Filter) (*types.RAGResponse, error) {
+	path := conversation.SessionPath(ragSystem.sessionDir(), name)
+
+	session := conversation.NewSession()
+	if _, err := os.Stat(path); err == nil {
+		if session, err = conversation.Load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := ragSystem.Chat(session, question, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.Save(path); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// printHelp prints the interactive mode commands
+func printHelp() {
+	fmt.Println("Commands:")
+	fmt.Println("  add <file_path>  - Add a document")
+	fmt.Println("  ingest <dir>     - Sync a directory of documents")
+	fmt.Println("  query <question> - Ask a question (follow-ups use the conversation)")
+	fmt.Println("  filter [expr...] - Restrict queries, e.g. filter type=md path=docs/api/ (no args clears)")
+	fmt.Println("  new              - Start a new conversation")
+	fmt.Println("  history          - Show the conversation")
+	fmt.Println("  save <name>      - Save the conversation")
+	fmt.Println("  load <name>      - Resume a saved conversation")
+	fmt.Println("  list             - List all documents")
+	fmt.Println("  verbose          - Toggle per-query timing traces")
+	fmt.Println("  help             - Show this help")
+	fmt.Println("  exit             - Exit the program")
+}
+
+// filterFlags collects repeated -filter flags
+type filterFlags []string
+
+func (f *filterFlags) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *filterFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runWatch re-syncs a directory periodically until the process is interrupted
+func runWatch(ragSystem *RAGSystem, dir string, interval time.Duration) {
+	fmt.Printf("Watching %s every %v (Ctrl+C to stop)\n", dir, interval)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	stop := make(chan struct{})
+	go func() {
+		<-interrupt
+		close(stop)
+	}()
+
+	ragSystem.WatchDirectory(dir, interval, stop, func(summary *SyncSummary, err error) {
+		if err != nil {
+			fmt.Printf("Error syncing directory: %v\n", err)
+			return
+		}
+		if summary.HasChanges() {
+			printSyncSummary(summary)
+		}
+	})
+	fmt.Println("Stopped watching.")
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"simple-rag/internal/mcp"
+	"simple-rag/internal/vector"
+	"simple-rag/pkg/types"
+)
+
+// mcpServerVersion is reported to MCP clients during the handshake
+const mcpServerVersion = "0.1.0"
+
+// filtersSchema describes the optional filter expressions of search tools
+const filtersSchema = `"filters": {
+      "type": "array",
+      "items": {"type": "string"},
+      "description": "Restrict the search to matching documents, e.g. \"type=md\", \"path=docs/api/\", \"created>=2024-01-01\""
+    }`
+
+// runMCP serves the RAG system as MCP tools over the given streams until
+// the client closes its input
+func runMCP(ragSystem *RAGSystem, in io.Reader, out io.Writer) error {
+	server := mcp.NewServer("simple-rag", mcpServerVersion, ragSystem.logger)
+
+	server.AddTool(mcp.Tool{
+		Name:        "search_documents",
+		Description: "Search the document index and return the most relevant chunks with their similarity scores, without generating an answer.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "query": {"type": "string", "description": "What to search for"},
+    "limit": {"type": "integer", "minimum": 1, "description": "Maximum number of chunks to return (at most rerank.top_n)"},
+    ` + filtersSchema + `
+  },
+  "required": ["query"]
+}`),
+		Handler: ragSystem.mcpSearchDocuments,
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "ask",
+		Description: "Answer a question from the indexed documents. The answer cites its sources as [1], [2], ... referring to the returned sources.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "question": {"type": "string", "description": "The question to answer"},
+    ` + filtersSchema + `
+  },
+  "required": ["question"]
+}`),
+		Handler: ragSystem.mcpAsk,
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "list_documents",
+		Description: "List the documents in the index.",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`),
+		Handler:     ragSystem.mcpListDocuments,
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "add_document",
+		Description: "Read, chunk, embed and index a file from the server's file system.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "path": {"type": "string", "description": "Path of the file to add, relative to the server's working directory"}
+  },
+  "required": ["path"]
+}`),
+		Handler: ragSystem.mcpAddDocument,
+	})
+
+	return server.Serve(in, out)
+}
+
+// mcpSource is a retrieved chunk as returned to MCP clients
+type mcpSource struct {
+	Index       int     `json:"index"`
+	DocumentID  string  `json:"document_id"`
+	Title       string  `json:"title,omitempty"`
+	FilePath    string  `json:"file_path,omitempty"`
+	ChunkID     string  `json:"chunk_id"`
+	Content     string  `json:"content"`
+	StartPos    int     `json:"start_pos"`
+	EndPos      int     `json:"end_pos"`
+	Similarity  float64 `json:"similarity"`
+	RerankScore float64 `json:"rerank_score,omitempty"`
+}
+
+// mcpSources numbers search results as they are cited in answers
+func mcpSources(results []*types.SearchResult) []mcpSource {
+	sources := make([]mcpSource, len(results))
+	for i, result := range results {
+		sources[i] = mcpSource{
+			Index:       i + 1,
+			DocumentID:  result.Chunk.DocumentID,
+			ChunkID:     result.Chunk.ID,
+			Content:     result.Chunk.Content,
+			StartPos:    result.Chunk.StartPos,
+			EndPos:      result.Chunk.EndPos,
+			Similarity:  result.Similarity,
+			RerankScore: result.RerankScore,
+		}
+		if result.Document != nil {
+			sources[i].Title = result.Document.Title
+			sources[i].FilePath = result.Document.FilePath
+		}
+	}
+	return sources
+}
+
+func (r *RAGSystem) mcpSearchDocuments(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Query   string   `json:"query"`
+		Limit   int      `json:"limit"`
+		Filters []string `json:"filters"`
+	}
+	if err := mcp.DecodeArguments(arguments, &args); err != nil {
+		return nil, err
+	}
+	if args.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	filter, err := vector.ParseFilter(args.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := r.Search(args.Query, filter)
+	if err != nil {
+		return nil, err
+	}
+	if args.Limit > 0 && len(results) > args.Limit {
+		results = results[:args.Limit]
+	}
+	return map[string]any{"query": args.Query, "results": mcpSources(results)}, nil
+}
+
+func (r *RAGSystem) mcpAsk(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Question string   `json:"question"`
+		Filters  []string `json:"filters"`
+	}
+	if err := mcp.DecodeArguments(arguments, &args); err != nil {
+		return nil, err
+	}
+	if args.Question == "" {
+		return nil, fmt.Errorf("question is required")
+	}
+	filter, err := vector.ParseFilter(args.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.Query(args.Question, filter)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"question":     response.Query,
+		"answer":       response.Answer,
+		"sources":      mcpSources(response.Sources),
+		"claims":       response.Claims,
+		"process_time": response.ProcessTime.Round(time.Millisecond).String(),
+	}, nil
+}
+
+func (r *RAGSystem) mcpListDocuments(arguments json.RawMessage) (any, error) {
+	type document struct {
+		ID        string    `json:"id"`
+		Title     string    `json:"title"`
+		FilePath  string    `json:"file_path"`
+		FileType  string    `json:"file_type"`
+		FileSize  int64     `json:"file_size"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	documents := r.ListDocuments()
+	listed := make([]document, len(documents))
+	for i, doc := range documents {
+		listed[i] = document{
+			ID:        doc.ID,
+			Title:     doc.Title,
+			FilePath:  doc.FilePath,
+			FileType:  doc.FileType,
+			FileSize:  doc.FileSize,
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+	return map[string]any{"documents": listed}, nil
+}
+
+func (r *RAGSystem) mcpAddDocument(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := mcp.DecodeArguments(arguments, &args); err != nil {
+		return nil, err
+	}
+	if args.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	if err := r.AddDocument(args.Path); err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("Document added successfully: %s", args.Path), nil
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"time"
 
 	"simple-rag/internal/config"
+	"simple-rag/internal/conversation"
 	"simple-rag/internal/document"
 	"simple-rag/internal/llm"
+	"simple-rag/internal/logging"
+	"simple-rag/internal/rerank"
 	"simple-rag/internal/vector"
 	"simple-rag/pkg/types"
 )
@@ -14,16 +20,198 @@ import (
 // RAGSystem combines all components for the RAG functionality
 type RAGSystem struct {
 	db              *vector.Database
-	embeddingClient *vector.EmbeddingClient
+	embeddingClient vector.EmbeddingProvider
 	llmClient       *llm.Client
 	docReader       *document.Reader
+	reranker        rerank.Reranker
 	config          *config.Config
+	logger          *slog.Logger
+	logCloser       io.Closer
+	// traces receives a JSON line per query when logging.trace_file is set
+	traces *logging.TraceWriter
+}
+
+const (
+	// defaultTopN is the number of sources passed to the LLM when not configured
+	defaultTopN = 5
+	// defaultSessionDir is where conversations are saved when not configured
+	defaultSessionDir = "./data/sessions"
+	// defaultBatchSize is the number of chunks per embedding request when not configured
+	defaultBatchSize = 32
+	// defaultWorkers is the number of concurrent embedding requests when not configured
+	defaultWorkers = 4
+	// defaultMaxRetries and defaultRetryBackoff apply to transient embedding errors
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+	// maxRetryBackoff caps the exponential backoff between retries
+	maxRetryBackoff = 10 * time.Second
+	// defaultContextWindow is the model's context length in tokens when not configured
+	defaultContextWindow = 4096
+)
+
+// NewRAGSystem creates the components described by a configuration
+func NewRAGSystem(cfg *config.Config) (*RAGSystem, error) {
+	logger, logCloser, err := logging.New(cfg.Logging.Level, cfg.Logging.Output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+
+	db, err := vector.NewCollections(cfg.VectorDB.StoragePath).Open(cfg.VectorDB.Collection, databaseOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	embeddingClient, err := newEmbeddingProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	llmClient, err := llm.NewProviderClient(cfg.LLM.Provider, cfg.LLM.URL, cfg.LLM.Model, cfg.LLM.APIKey, cfg.LLM.Temperature, cfg.LLM.MaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM configuration: %w", err)
+	}
+	prompt, err := llm.LoadPromptTemplate(cfg.LLM.PromptFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM configuration: %w", err)
+	}
+	llmClient.SetPromptTemplate(prompt, cfg.LLM.Language)
+	budget, err := promptBudget(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM configuration: %w", err)
+	}
+	llmClient.SetPromptBudget(budget)
+
+	docReader := document.NewReader(cfg.Document.ChunkSize, cfg.Document.ChunkOverlap)
+	sizer, err := document.NewSizer(cfg.Document.ChunkSizer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document configuration: %w", err)
+	}
+	chunker, err := document.NewChunker(cfg.Document.ChunkStrategy, sizer, cfg.Document.ChunkSize, cfg.Document.ChunkOverlap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document configuration: %w", err)
+	}
+	docReader.SetChunker(chunker)
+	if len(cfg.Document.SupportedFormats) > 0 {
+		if err := docReader.SetSupportedFormats(cfg.Document.SupportedFormats); err != nil {
+			return nil, fmt.Errorf("invalid document configuration: %w", err)
+		}
+	}
+
+	reranker, err := rerank.New(cfg.Rerank.Provider, cfg.Rerank.URL, cfg.Rerank.Model, llmClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rerank configuration: %w", err)
+	}
+
+	var traces *logging.TraceWriter
+	if cfg.Logging.TraceFile != "" {
+		if traces, err = logging.NewTraceWriter(cfg.Logging.TraceFile); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Debug("initialized RAG system",
+		"storage", cfg.VectorDB.StoragePath,
+		"collection", cfg.VectorDB.Collection,
+		"quantization", db.Quantization(),
+		"embedding_provider", cfg.Embedding.Provider,
+		"llm_provider", cfg.LLM.Provider)
+
+	return &RAGSystem{
+		db:              db,
+		embeddingClient: embeddingClient,
+		llmClient:       llmClient,
+		docReader:       docReader,
+		reranker:        reranker,
+		config:          cfg,
+		logger:          logger,
+		logCloser:       logCloser,
+		traces:          traces,
+	}, nil
+}
+
+// databaseOptions returns the store options of a configuration
+func databaseOptions(cfg *config.Config) vector.Options {
+	return vector.Options{
+		Quantization:   cfg.VectorDB.Quantization,
+		PQSubvectors:   cfg.VectorDB.PQSubvectors,
+		RescoreFactor:  cfg.VectorDB.RescoreFactor,
+		EmbeddingModel: embeddingModelName(cfg),
+	}
+}
+
+// embeddingModelName identifies the configured embedding model, e.g.
+// "ollama/nomic-embed-text", so a collection is never searched with
+// embeddings from another model
+func embeddingModelName(cfg *config.Config) string {
+	provider := cfg.Embedding.Provider
+	if provider == "" {
+		provider = "custom"
+	}
+	if cfg.Embedding.Model == "" {
+		return provider
+	}
+	return provider + "/" + cfg.Embedding.Model
+}
+
+// promptBudget returns the tokens left for the prompt once the answer's
+// max_tokens are reserved from the model's context window
+func promptBudget(cfg *config.Config) (int, error) {
+	window := cfg.LLM.ContextWindow
+	if window <= 0 {
+		window = defaultContextWindow
+	}
+	budget := window - cfg.LLM.MaxTokens
+	if budget <= 0 {
+		return 0, fmt.Errorf("max_tokens %d leaves no room for the prompt in a context window of %d", cfg.LLM.MaxTokens, window)
+	}
+	return budget, nil
+}
+
+// Close releases the log, trace and vector files
+func (r *RAGSystem) Close() error {
+	var errs []error
+	if r.traces != nil {
+		errs = append(errs, r.traces.Close())
+	}
+	errs = append(errs, r.db.Close(), r.logCloser.Close())
+	return errors.Join(errs...)
+}
+
+// newEmbeddingProvider builds the configured provider with retries and,
+// when a cache directory is set, an on-disk embedding cache
+func newEmbeddingProvider(cfg *config.Config) (vector.EmbeddingProvider, error) {
+	provider, err := vector.NewEmbeddingProvider(cfg.Embedding.Provider, cfg.Embedding.URL, cfg.Embedding.Model, cfg.Embedding.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedding configuration: %w", err)
+	}
+
+	policy := vector.RetryPolicy{
+		MaxRetries:     cfg.Embedding.MaxRetries,
+		InitialBackoff: cfg.Embedding.RetryBackoff,
+		MaxBackoff:     maxRetryBackoff,
+	}
+	if policy.MaxRetries <= 0 {
+		policy.MaxRetries = defaultMaxRetries
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultRetryBackoff
+	}
+	provider = vector.NewRetryingProvider(provider, policy)
+
+	if cfg.Embedding.CacheDir != "" {
+		cache, err := vector.NewEmbeddingCache(cfg.Embedding.CacheDir, cfg.Embedding.Provider+"|"+cfg.Embedding.Model)
+		if err != nil {
+			return nil, err
+		}
+		provider = vector.NewCachedProvider(provider, cache)
+	}
+
+	return provider, nil
 }
 
 // AddDocument processes and adds a document to the system
 func (r *RAGSystem) AddDocument(filePath string) error {
 	// Check if file type is supported
-	if !document.IsSupported(filePath) {
+	if !r.docReader.IsSupported(filePath) {
 		return fmt.Errorf("file type not supported: %s", filePath)
 	}
 
@@ -38,10 +226,14 @@ func (r *RAGSystem) AddDocument(filePath string) error {
 		return fmt.Errorf("document already exists: %s", doc.Title)
 	}
 
-	// Store document
-	if err := r.db.StoreDocument(doc); err != nil {
-		return fmt.Errorf("failed to store document: %w", err)
-	}
+	return r.storeDocument(doc, "")
+}
+
+// storeDocument embeds a document's chunks and stores them with the
+// document, replacing the document replaceID if set. Nothing is written
+// unless every chunk was embedded, so a failure leaves the previous state.
+func (r *RAGSystem) storeDocument(doc *types.Document, replaceID string) error {
+	startTime := time.Now()
 
 	// Chunk document
 	chunks, err := r.docReader.ChunkDocument(doc)
@@ -49,61 +241,208 @@ func (r *RAGSystem) AddDocument(filePath string) error {
 		return fmt.Errorf("failed to chunk document: %w", err)
 	}
 
-	// Process chunks in batches to get embeddings
-	batchSize := r.config.Embedding.BatchSize
-	for i := 0; i < len(chunks); i += batchSize {
-		end := i + batchSize
-		if end > len(chunks) {
-			end = len(chunks)
-		}
+	// Embed batches concurrently
+	if err := vector.EmbedChunks(r.embeddingClient, chunks, r.batchSize(), r.workers()); err != nil {
+		return err
+	}
 
-		batch := chunks[i:end]
-		if err := r.embeddingClient.ProcessChunks(batch); err != nil {
-			return fmt.Errorf("failed to process embeddings for batch: %w", err)
-		}
+	if err := r.db.ReplaceDocument(replaceID, doc, chunks); err != nil {
+		return err
+	}
+
+	r.logger.Debug("stored document",
+		"path", doc.FilePath,
+		"chunks", len(chunks),
+		"replaced", replaceID != "",
+		"duration", time.Since(startTime))
+	return nil
+}
+
+// Query performs a RAG query and returns the response.
+// A nil filter searches all documents.
+func (r *RAGSystem) Query(query string, filter *vector.Filter) (*types.RAGResponse, error) {
+	startTime := time.Now()
+	trace := &types.QueryTrace{Query: query}
+
+	searchResults, err := r.retrieve(query, filter, trace)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(searchResults) == 0 {
+		return r.finish(noResultsResponse(query, searchResults), trace, startTime), nil
+	}
+
+	// Generate response using LLM
+	response, err := r.llmClient.GenerateWithContext(query, searchResults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate response: %w", err)
+	}
 
-		// Store chunks with embeddings
-		for _, chunk := range batch {
-			if err := r.db.StoreChunk(chunk); err != nil {
-				return fmt.Errorf("failed to store chunk: %w", err)
-			}
+	return r.finish(response, trace, startTime), nil
+}
+
+// Chat answers a question in the context of a conversation.
+// The follow-up is condensed with the history into a standalone search query,
+// the answer is generated with the trimmed history, and both turns are
+// appended to the session.
+func (r *RAGSystem) Chat(session *conversation.Session, question string, filter *vector.Filter) (*types.RAGResponse, error) {
+	startTime := time.Now()
+	trace := &types.QueryTrace{Query: question}
+	history := session.Recent(r.config.Conversation.MaxHistoryTurns)
+
+	searchQuery, err := r.llmClient.CondenseQuestion(history, question)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 {
+		trace.CondenseLatency = time.Since(startTime)
+	}
+	if searchQuery != question {
+		trace.SearchQuery = searchQuery
+	}
+
+	searchResults, err := r.retrieve(searchQuery, filter, trace)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *types.RAGResponse
+	if len(searchResults) == 0 {
+		response = noResultsResponse(question, searchResults)
+	} else {
+		response, err = r.llmClient.GenerateWithHistory(question, history, searchResults)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate response: %w", err)
 		}
 	}
+	if searchQuery != question {
+		response.SearchQuery = searchQuery
+	}
 
-	return nil
+	session.AddTurn(types.RoleUser, question)
+	session.AddTurn(types.RoleAssistant, response.Answer)
+
+	return r.finish(response, trace, startTime), nil
 }
 
-// Query performs a RAG query and returns the response
-func (r *RAGSystem) Query(query string) (*types.RAGResponse, error) {
+// Search returns the chunks that would be passed to the LLM for a query,
+// without generating an answer. A nil filter searches all documents.
+func (r *RAGSystem) Search(query string, filter *vector.Filter) ([]*types.SearchResult, error) {
+	return r.retrieve(query, filter, &types.QueryTrace{Query: query})
+}
+
+// finish completes the trace of a query with the generation stage recorded
+// by the LLM client, sets the end-to-end process time, and logs the trace
+func (r *RAGSystem) finish(response *types.RAGResponse, trace *types.QueryTrace, startTime time.Time) *types.RAGResponse {
+	if generation := response.Trace; generation != nil {
+		trace.GenerationLatency = generation.GenerationLatency
+		trace.PromptChars = generation.PromptChars
+		trace.PromptTokens = generation.PromptTokens
+		trace.DroppedContexts = generation.DroppedContexts
+		trace.AnswerChars = generation.AnswerChars
+	}
+	trace.TotalLatency = time.Since(startTime)
+	trace.CreatedAt = time.Now()
+
+	response.ProcessTime = trace.TotalLatency
+	response.Trace = trace
+
+	r.logger.Debug("query trace", logging.TraceAttrs(trace)...)
+	if r.traces != nil {
+		if err := r.traces.Write(trace); err != nil {
+			r.logger.Warn("failed to write trace", "error", err)
+		}
+	}
+	return response
+}
+
+// retrieve embeds a search query and returns the best matching chunks,
+// recording stage latencies and candidate statistics in the trace
+func (r *RAGSystem) retrieve(searchQuery string, filter *vector.Filter, trace *types.QueryTrace) ([]*types.SearchResult, error) {
 	// Get query embedding
-	queryEmbedding, err := r.embeddingClient.GetSingleEmbedding(query)
+	stageStart := time.Now()
+	queryEmbedding, err := r.embeddingClient.GetSingleEmbedding(searchQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get query embedding: %w", err)
 	}
+	trace.EmbeddingLatency = time.Since(stageStart)
 
 	// Search for similar chunks
-	searchResults, err := r.db.Search(queryEmbedding, 5, r.config.VectorDB.SimilarityThreshold)
+	stageStart = time.Now()
+	searchResults, err := r.db.SearchWithFilter(queryEmbedding, r.candidateCount(), r.config.VectorDB.SimilarityThreshold, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search: %w", err)
 	}
+	trace.SearchLatency = time.Since(stageStart)
+	trace.Candidates = len(searchResults)
+	trace.Similarity = logging.SimilarityStats(searchResults)
 
-	if len(searchResults) == 0 {
-		return &types.RAGResponse{
-			Query:       query,
-			Answer:      "I don't have enough information to answer this question based on the available documents.",
-			Sources:     searchResults,
-			ProcessTime: 0,
-			CreatedAt:   time.Now(),
-		}, nil
+	// Rescore candidates and keep the best
+	if r.reranker != nil && len(searchResults) > 0 {
+		stageStart = time.Now()
+		searchResults, err = r.reranker.Rerank(searchQuery, searchResults)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rerank: %w", err)
+		}
+		trace.RerankLatency = time.Since(stageStart)
 	}
+	if topN := r.topN(); len(searchResults) > topN {
+		searchResults = searchResults[:topN]
+	}
+	trace.Results = len(searchResults)
 
-	// Generate response using LLM
-	response, err := r.llmClient.GenerateWithContext(query, searchResults)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate response: %w", err)
+	return searchResults, nil
+}
+
+// noResultsResponse is returned when retrieval finds nothing above the threshold
+func noResultsResponse(query string, searchResults []*types.SearchResult) *types.RAGResponse {
+	return &types.RAGResponse{
+		Query:     query,
+		Answer:    "I don't have enough information to answer this question based on the available documents.",
+		Sources:   searchResults,
+		CreatedAt: time.Now(),
+	}
+}
+
+// topN returns how many sources are passed to the LLM
+func (r *RAGSystem) topN() int {
+	if r.config.Rerank.TopN > 0 {
+		return r.config.Rerank.TopN
+	}
+	return defaultTopN
+}
+
+// candidateCount returns how many chunks to retrieve before reranking
+func (r *RAGSystem) candidateCount() int {
+	if r.reranker == nil || r.config.Rerank.Candidates < r.topN() {
+		return r.topN()
+	}
+	return r.config.Rerank.Candidates
+}
+
+// batchSize returns how many chunks are embedded per request
+func (r *RAGSystem) batchSize() int {
+	if r.config.Embedding.BatchSize > 0 {
+		return r.config.Embedding.BatchSize
+	}
+	return defaultBatchSize
+}
+
+// workers returns how many embedding requests run concurrently
+func (r *RAGSystem) workers() int {
+	if r.config.Embedding.Workers > 0 {
+		return r.config.Embedding.Workers
 	}
+	return defaultWorkers
+}
 
-	return response, nil
+// sessionDir returns the directory conversations are saved in
+func (r *RAGSystem) sessionDir() string {
+	if r.config.Conversation.SessionDir != "" {
+		return r.config.Conversation.SessionDir
+	}
+	return defaultSessionDir
 }
 
 // ListDocuments returns all documents in the system
@@ -129,4 +468,4 @@ func (r *RAGSystem) Health() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
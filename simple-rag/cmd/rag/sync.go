@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// SyncSummary reports what a directory sync changed
+type SyncSummary struct {
+	Directory string
+	Added     []string
+	Updated   []string
+	Unchanged []string
+	Removed   []string
+	Failed    map[string]error
+	Duration  time.Duration
+}
+
+// HasChanges reports whether the sync added, updated or removed anything
+func (s *SyncSummary) HasChanges() bool {
+	return len(s.Added) > 0 || len(s.Updated) > 0 || len(s.Removed) > 0 || len(s.Failed) > 0
+}
+
+// SyncDirectory walks a directory tree and brings the stored documents in line with it.
+// New files are added, modified files are re-chunked and re-embedded, unchanged files
+// are skipped, and documents whose files disappeared from the tree are removed.
+func (r *RAGSystem) SyncDirectory(dir string) (*SyncSummary, error) {
+	startTime := time.Now()
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+
+	summary := &SyncSummary{
+		Directory: dir,
+		Failed:    make(map[string]error),
+	}
+	seen := make(map[string]bool)
+	// Paths the walk could not read; documents under them are kept because
+	// their files may still exist
+	var unreadable []string
+
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			summary.Failed[path] = err
+			if absPath, err := filepath.Abs(path); err == nil {
+				unreadable = append(unreadable, absPath)
+			}
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !r.docReader.IsSupported(path) {
+			return nil
+		}
+
+		if absPath, err := filepath.Abs(path); err == nil {
+			seen[absPath] = true
+		}
+
+		if err := r.syncFile(path, summary); err != nil {
+			r.logger.Warn("failed to sync file", "path", path, "error", err)
+			summary.Failed[path] = err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	// Remove documents whose files are gone from the tree
+	for _, doc := range r.documentsUnder(dir) {
+		absPath, err := filepath.Abs(doc.FilePath)
+		if err != nil || seen[absPath] || withinAny(absPath, unreadable) {
+			continue
+		}
+		if err := r.db.DeleteDocument(doc.ID); err != nil {
+			summary.Failed[doc.FilePath] = fmt.Errorf("failed to remove document: %w", err)
+			continue
+		}
+		summary.Removed = append(summary.Removed, doc.FilePath)
+	}
+
+	summary.Duration = time.Since(startTime)
+	r.logger.Debug("synced directory",
+		"dir", dir,
+		"added", len(summary.Added),
+		"updated", len(summary.Updated),
+		"unchanged", len(summary.Unchanged),
+		"removed", len(summary.Removed),
+		"failed", len(summary.Failed),
+		"duration", summary.Duration)
+	return summary, nil
+}
+
+// syncFile adds or refreshes a single file and records the outcome in the summary
+func (r *RAGSystem) syncFile(path string, summary *SyncSummary) error {
+	existing := r.db.FindDocumentByPath(path)
+
+	// Size and modification time unchanged means the content is unchanged
+	if existing != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat file: %w", err)
+		}
+		if existing.FileSize == info.Size() && existing.CreatedAt.Equal(info.ModTime()) {
+			summary.Unchanged = append(summary.Unchanged, path)
+			return nil
+		}
+	}
+
+	doc, err := r.docReader.ReadDocument(path)
+	if err != nil {
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+
+	if existing != nil && existing.Hash == doc.Hash {
+		// Record the new modification time so later syncs skip the file without reading it
+		refreshed := *existing
+		refreshed.FileSize = doc.FileSize
+		refreshed.CreatedAt = doc.CreatedAt
+		if err := r.db.StoreDocument(&refreshed); err != nil {
+			return fmt.Errorf("failed to update document: %w", err)
+		}
+		summary.Unchanged = append(summary.Unchanged, path)
+		return nil
+	}
+
+	// The stale document is swapped out only once the new one is embedded
+	replaceID := ""
+	if existing != nil {
+		replaceID = existing.ID
+	}
+	if err := r.storeDocument(doc, replaceID); err != nil {
+		return err
+	}
+
+	if existing != nil {
+		summary.Updated = append(summary.Updated, path)
+	} else {
+		summary.Added = append(summary.Added, path)
+	}
+	return nil
+}
+
+// documentsUnder returns the stored documents whose files live inside dir
+func (r *RAGSystem) documentsUnder(dir string) []*types.Document {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+
+	var documents []*types.Document
+	for _, doc := range r.db.ListDocuments() {
+		absPath, err := filepath.Abs(doc.FilePath)
+		if err != nil {
+			continue
+		}
+		if within(absPath, absDir) {
+			documents = append(documents, doc)
+		}
+	}
+
+	return documents
+}
+
+// within reports whether an absolute path is dir itself or lies inside it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// withinAny reports whether an absolute path lies inside any of dirs
+func withinAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchDirectory re-syncs a directory every interval until stop is closed.
+// Each sync only re-embeds files whose size, modification time or hash changed,
+// so polling a mostly static tree is cheap.
+func (r *RAGSystem) WatchDirectory(dir string, interval time.Duration, stop <-chan struct{}, onSync func(*SyncSummary, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			onSync(r.SyncDirectory(dir))
+		}
+	}
+}
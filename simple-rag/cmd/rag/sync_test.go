@@ -0,0 +1,271 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"simple-rag/internal/config"
+)
+
+// newSyncTestSystem builds a RAG system with offline stub providers
+func newSyncTestSystem(t *testing.T) *RAGSystem {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Embedding.Provider = "stub"
+	cfg.LLM.Provider = "stub"
+	cfg.Document.ChunkSize = 100
+	cfg.Document.ChunkOverlap = 10
+	cfg.Document.ChunkStrategy = "fixed"
+	cfg.Document.ChunkSizer = "runes"
+	cfg.VectorDB.StoragePath = t.TempDir()
+	cfg.Rerank.Provider = "none"
+	cfg.Logging.Level = "error"
+
+	system, err := NewRAGSystem(cfg)
+	if err != nil {
+		t.Fatalf("NewRAGSystem failed: %v", err)
+	}
+	t.Cleanup(func() { system.Close() })
+	return system
+}
+
+// writeSyncFile writes a file under dir, creating parent directories
+func writeSyncFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+// relativePaths returns paths relative to dir in sorted order
+func relativePaths(t *testing.T, dir string, paths []string) []string {
+	t.Helper()
+
+	var rel []string
+	for _, path := range paths {
+		r, err := filepath.Rel(dir, path)
+		if err != nil {
+			t.Fatalf("Rel failed: %v", err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	sort.Strings(rel)
+	return rel
+}
+
+func TestSyncDirectory(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(t *testing.T, dir string)
+		added     []string
+		updated   []string
+		unchanged []string
+		removed   []string
+		failed    []string
+	}{
+		{
+			name:      "Unchanged",
+			change:    func(t *testing.T, dir string) {},
+			unchanged: []string{"a.txt", "sub/b.txt"},
+		},
+		{
+			name: "Added",
+			change: func(t *testing.T, dir string) {
+				writeSyncFile(t, dir, "c.md", "# New\n\nA new document.")
+			},
+			added:     []string{"c.md"},
+			unchanged: []string{"a.txt", "sub/b.txt"},
+		},
+		{
+			name: "Updated",
+			change: func(t *testing.T, dir string) {
+				writeSyncFile(t, dir, "a.txt", "The first document was rewritten.")
+			},
+			updated:   []string{"a.txt"},
+			unchanged: []string{"sub/b.txt"},
+		},
+		{
+			name: "Touched",
+			change: func(t *testing.T, dir string) {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(filepath.Join(dir, "a.txt"), later, later); err != nil {
+					t.Fatalf("Chtimes failed: %v", err)
+				}
+			},
+			unchanged: []string{"a.txt", "sub/b.txt"},
+		},
+		{
+			name: "Removed",
+			change: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, "sub", "b.txt")); err != nil {
+					t.Fatalf("Remove failed: %v", err)
+				}
+			},
+			unchanged: []string{"a.txt"},
+			removed:   []string{"sub/b.txt"},
+		},
+		{
+			name: "UnreadableSubdirectory",
+			change: func(t *testing.T, dir string) {
+				if os.Geteuid() == 0 {
+					t.Skip("directory permissions are not enforced for root")
+				}
+				sub := filepath.Join(dir, "sub")
+				if err := os.Chmod(sub, 0); err != nil {
+					t.Fatalf("Chmod failed: %v", err)
+				}
+				t.Cleanup(func() { os.Chmod(sub, 0755) })
+			},
+			unchanged: []string{"a.txt"},
+			failed:    []string{"sub"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			system := newSyncTestSystem(t)
+			dir := t.TempDir()
+			writeSyncFile(t, dir, "a.txt", "The first document.")
+			writeSyncFile(t, dir, "sub/b.txt", "The second document.")
+
+			summary, err := system.SyncDirectory(dir)
+			if err != nil {
+				t.Fatalf("SyncDirectory failed: %v", err)
+			}
+			if got := relativePaths(t, dir, summary.Added); !reflect.DeepEqual(got, []string{"a.txt", "sub/b.txt"}) {
+				t.Fatalf("Expected both files added by the first sync, got %v", got)
+			}
+
+			test.change(t, dir)
+			summary, err = system.SyncDirectory(dir)
+			if err != nil {
+				t.Fatalf("SyncDirectory failed: %v", err)
+			}
+
+			var failed []string
+			for path := range summary.Failed {
+				failed = append(failed, path)
+			}
+			for _, check := range []struct {
+				kind     string
+				got      []string
+				expected []string
+			}{
+				{"added", summary.Added, test.added},
+				{"updated", summary.Updated, test.updated},
+				{"unchanged", summary.Unchanged, test.unchanged},
+				{"removed", summary.Removed, test.removed},
+				{"failed", failed, test.failed},
+			} {
+				if got := relativePaths(t, dir, check.got); !reflect.DeepEqual(got, check.expected) {
+					t.Errorf("Expected %s %v, got %v", check.kind, check.expected, got)
+				}
+			}
+
+			// Documents under an unreadable directory are kept
+			if test.failed != nil && system.db.FindDocumentByPath(filepath.Join(dir, "sub", "b.txt")) == nil {
+				t.Error("Expected sub/b.txt to stay stored")
+			}
+		})
+	}
+}
+
+func TestSyncFileRecordsModificationTime(t *testing.T) {
+	system := newSyncTestSystem(t)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "a.txt", "The first document.")
+	path := filepath.Join(dir, "a.txt")
+
+	if err := system.syncFile(path, &SyncSummary{}); err != nil {
+		t.Fatalf("syncFile failed: %v", err)
+	}
+
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	summary := &SyncSummary{}
+	if err := system.syncFile(path, summary); err != nil {
+		t.Fatalf("syncFile failed: %v", err)
+	}
+	if len(summary.Unchanged) != 1 {
+		t.Fatalf("Expected the touched file to be unchanged, got %+v", summary)
+	}
+
+	doc := system.db.FindDocumentByPath(path)
+	if doc == nil {
+		t.Fatal("Expected the document to be stored")
+	}
+	if !doc.CreatedAt.Equal(later) {
+		t.Errorf("Expected stored modification time %v, got %v", later, doc.CreatedAt)
+	}
+}
+
+func TestSyncDirectoryMatchesRelativePaths(t *testing.T) {
+	system := newSyncTestSystem(t)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "docs/a.txt", "The first document.")
+	t.Chdir(dir)
+
+	if err := system.AddDocument("docs/a.txt"); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	summary, err := system.SyncDirectory(filepath.Join(dir, "docs"))
+	if err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+	if len(summary.Added) != 0 || len(summary.Unchanged) != 1 {
+		t.Errorf("Expected the relatively added file to be unchanged, got %+v", summary)
+	}
+	if count := len(system.db.ListDocuments()); count != 1 {
+		t.Errorf("Expected 1 document, got %d", count)
+	}
+}
+
+func TestWatchDirectory(t *testing.T) {
+	system := newSyncTestSystem(t)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "a.txt", "The first document.")
+
+	summaries := make(chan *SyncSummary, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		system.WatchDirectory(dir, 10*time.Millisecond, stop, func(summary *SyncSummary, err error) {
+			if err != nil {
+				t.Errorf("SyncDirectory failed: %v", err)
+				return
+			}
+			summaries <- summary
+		})
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for added := false; !added; {
+		select {
+		case summary := <-summaries:
+			added = len(summary.Added) == 1
+		case <-deadline:
+			t.Fatal("Timed out waiting for the file to be added")
+		}
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchDirectory did not return after stop was closed")
+	}
+}
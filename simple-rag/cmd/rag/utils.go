@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"simple-rag/internal/eval"
+	"simple-rag/internal/vector"
 	"simple-rag/pkg/types"
 )
 
@@ -11,6 +14,9 @@ import (
 func printResponse(response *types.RAGResponse) {
 	fmt.Println(repeatString("=", 60))
 	fmt.Printf("Query: %s\n", response.Query)
+	if response.SearchQuery != "" {
+		fmt.Printf("Search Query: %s\n", response.SearchQuery)
+	}
 	fmt.Println(repeatString("-", 60))
 	fmt.Printf("Answer: %s\n", response.Answer)
 	fmt.Println(repeatString("-", 60))
@@ -18,12 +24,22 @@ func printResponse(response *types.RAGResponse) {
 	if len(response.Sources) > 0 {
 		fmt.Println("Sources:")
 		for i, source := range response.Sources {
-			fmt.Printf("\n%d. Document: %s (Similarity: %.3f)\n", 
-				i+1, source.Document.Title, source.Similarity)
+			if source.RerankScore != 0 {
+				fmt.Printf("\n%d. Document: %s (Similarity: %.3f, Rerank: %.3f)\n",
+					i+1, source.Document.Title, source.Similarity, source.RerankScore)
+			} else {
+				fmt.Printf("\n%d. Document: %s (Similarity: %.3f)\n",
+					i+1, source.Document.Title, source.Similarity)
+			}
 			fmt.Printf("   Content: %s...\n", truncateString(source.Chunk.Content, 100))
 			fmt.Printf("   File: %s\n", source.Document.FilePath)
 		}
 	}
+
+	if len(response.Claims) > 0 {
+		fmt.Println(repeatString("-", 60))
+		printCitations(response)
+	}
 	
 	fmt.Println(repeatString("-", 60))
 	fmt.Printf("Process Time: %v\n", response.ProcessTime)
@@ -31,6 +47,67 @@ func printResponse(response *types.RAGResponse) {
 	fmt.Println(repeatString("=", 60))
 }
 
+// printTrace prints the per-stage timings and retrieval statistics of a query
+func printTrace(trace *types.QueryTrace) {
+	if trace == nil {
+		return
+	}
+
+	fmt.Println("Trace:")
+	if trace.CondenseLatency > 0 {
+		fmt.Printf("  Condense:    %v\n", trace.CondenseLatency)
+	}
+	fmt.Printf("  Embedding:   %v\n", trace.EmbeddingLatency)
+	fmt.Printf("  Search:      %v (%d candidates)\n", trace.SearchLatency, trace.Candidates)
+	if trace.RerankLatency > 0 {
+		fmt.Printf("  Rerank:      %v\n", trace.RerankLatency)
+	}
+	fmt.Printf("  Generation:  %v (prompt %d chars / ~%d tokens, answer %d chars)\n",
+		trace.GenerationLatency, trace.PromptChars, trace.PromptTokens, trace.AnswerChars)
+	if trace.DroppedContexts > 0 {
+		fmt.Printf("  Dropped:     %d contexts over the prompt budget\n", trace.DroppedContexts)
+	}
+	fmt.Printf("  Total:       %v\n", trace.TotalLatency)
+	if trace.Candidates > 0 {
+		fmt.Printf("  Similarity:  min %.3f / median %.3f / mean %.3f / max %.3f\n",
+			trace.Similarity.Min, trace.Similarity.Median, trace.Similarity.Mean, trace.Similarity.Max)
+	}
+	fmt.Printf("  Sources:     %d\n", trace.Results)
+	fmt.Println(repeatString("=", 60))
+}
+
+// printCitations prints each cited claim with the quoted source spans.
+// Citations of sources that were not retrieved are flagged.
+func printCitations(response *types.RAGResponse) {
+	fmt.Println("Citations:")
+	for i, claim := range response.Claims {
+		fmt.Printf("\n%d. %s\n", i+1, claim.Text)
+		for _, citation := range claim.Citations {
+			if !citation.Retrieved {
+				fmt.Printf("   [%d] WARNING: not among the retrieved sources\n", citation.Source)
+				continue
+			}
+
+			source := response.Sources[citation.Source-1]
+			fmt.Printf("   [%d] %s (span %d-%d):\n", citation.Source,
+				source.Document.FilePath, citation.StartPos, citation.EndPos)
+			for _, line := range strings.Split(citedSpan(source, citation), "\n") {
+				fmt.Printf("       > %s\n", line)
+			}
+		}
+	}
+}
+
+// citedSpan returns the document text between StartPos and EndPos,
+// falling back to the chunk content when the span is out of range
+func citedSpan(source *types.SearchResult, citation types.Citation) string {
+	if source.Document != nil && citation.StartPos >= 0 &&
+		citation.StartPos < citation.EndPos && citation.EndPos <= len(source.Document.Content) {
+		return source.Document.Content[citation.StartPos:citation.EndPos]
+	}
+	return source.Chunk.Content
+}
+
 // printDocuments prints a list of documents
 func printDocuments(documents []*types.Document) {
 	if len(documents) == 0 {
@@ -56,6 +133,148 @@ func printDocuments(documents []*types.Document) {
 	fmt.Println(repeatString("-", 80))
 }
 
+// printCollections prints the collections, marking the one in use
+func printCollections(infos []vector.CollectionInfo, current string) {
+	if len(infos) == 0 {
+		fmt.Println("No collections found.")
+		return
+	}
+	if current == "" {
+		current = vector.DefaultCollection
+	}
+
+	fmt.Printf("  %-20s %-32s %6s %-6s %6s %8s\n", "NAME", "EMBEDDING MODEL", "DIMS", "QUANT", "DOCS", "CHUNKS")
+	for _, info := range infos {
+		marker := " "
+		if info.Name == current {
+			marker = "*"
+		}
+		model := info.EmbeddingModel
+		if model == "" {
+			model = "-"
+		}
+		fmt.Printf("%s %-20s %-32s %6d %-6s %6d %8d\n", marker, info.Name, truncateString(model, 32), info.Dimensions, info.Quantization, info.Documents, info.Chunks)
+	}
+}
+
+// printHistory prints the turns of a conversation
+func printHistory(turns []types.ChatTurn) {
+	if len(turns) == 0 {
+		fmt.Println("No messages in this conversation.")
+		return
+	}
+
+	for _, turn := range turns {
+		speaker := "You"
+		if turn.Role == types.RoleAssistant {
+			speaker = "Assistant"
+		}
+		fmt.Printf("[%s] %s: %s\n", turn.CreatedAt.Format("15:04:05"), speaker, turn.Content)
+	}
+}
+
+// printSyncSummary prints the result of a directory sync
+func printSyncSummary(summary *SyncSummary) {
+	fmt.Println(repeatString("=", 60))
+	fmt.Printf("Sync: %s\n", summary.Directory)
+	fmt.Println(repeatString("-", 60))
+	fmt.Printf("Added:     %d\n", len(summary.Added))
+	fmt.Printf("Updated:   %d\n", len(summary.Updated))
+	fmt.Printf("Unchanged: %d\n", len(summary.Unchanged))
+	fmt.Printf("Removed:   %d\n", len(summary.Removed))
+	fmt.Printf("Failed:    %d\n", len(summary.Failed))
+
+	printPaths := func(label string, paths []string) {
+		for _, path := range paths {
+			fmt.Printf("  %s %s\n", label, path)
+		}
+	}
+	if summary.HasChanges() {
+		fmt.Println(repeatString("-", 60))
+		printPaths("+", summary.Added)
+		printPaths("~", summary.Updated)
+		printPaths("-", summary.Removed)
+		for path, err := range summary.Failed {
+			fmt.Printf("  ! %s: %v\n", path, err)
+		}
+	}
+
+	fmt.Println(repeatString("-", 60))
+	fmt.Printf("Process Time: %v\n", summary.Duration)
+	fmt.Println(repeatString("=", 60))
+}
+
+// printEvalReports prints evaluation reports. With two reports the
+// metrics are shown side by side with the difference.
+func printEvalReports(reports []*eval.Report) {
+	if len(reports) == 0 {
+		return
+	}
+
+	fmt.Println(repeatString("=", 72))
+	fmt.Printf("Per question (k=%d):\n", reports[0].K)
+	fmt.Println(repeatString("-", 72))
+	for i, result := range reports[0].Results {
+		fmt.Printf("%-16s", truncateString(result.ID, 13))
+		for _, report := range reports {
+			r := report.Results[i]
+			column := "error"
+			if r.Error == "" {
+				column = fmt.Sprintf("R=%.2f RR=%.2f nDCG=%.2f", r.Recall, r.ReciprocalRank, r.NDCG)
+				if r.HasReference && r.Answer != "" {
+					column += fmt.Sprintf(" F1=%.2f", r.AnswerOverlap)
+				}
+			}
+			fmt.Printf("  %-35s", column)
+		}
+		fmt.Println()
+	}
+	for _, report := range reports {
+		for _, r := range report.Results {
+			if r.Error != "" {
+				fmt.Printf("! %s [%s]: %s\n", r.ID, report.Name, r.Error)
+			}
+		}
+	}
+
+	fmt.Println(repeatString("-", 72))
+	fmt.Printf("%-16s", "Metric")
+	for _, report := range reports {
+		fmt.Printf("%16s", truncateString(report.Name, 16))
+	}
+	if len(reports) == 2 {
+		fmt.Printf("%12s", "Delta")
+	}
+	fmt.Println()
+
+	row := func(label string, value func(*eval.Report) float64) {
+		fmt.Printf("%-16s", label)
+		for _, report := range reports {
+			fmt.Printf("%16.3f", value(report))
+		}
+		if len(reports) == 2 {
+			fmt.Printf("%+12.3f", value(reports[1])-value(reports[0]))
+		}
+		fmt.Println()
+	}
+	row(fmt.Sprintf("Recall@%d", reports[0].K), func(r *eval.Report) float64 { return r.Recall })
+	row("MRR", func(r *eval.Report) float64 { return r.MRR })
+	row(fmt.Sprintf("nDCG@%d", reports[0].K), func(r *eval.Report) float64 { return r.NDCG })
+	row("Answer F1", func(r *eval.Report) float64 { return r.AnswerOverlap })
+
+	fmt.Printf("%-16s", "Evaluated")
+	for _, report := range reports {
+		fmt.Printf("%16s", fmt.Sprintf("%d (%d failed)", report.Evaluated, report.Failed))
+	}
+	fmt.Println()
+	fmt.Printf("%-16s", "Process Time")
+	for _, report := range reports {
+		fmt.Printf("%16v", report.Duration.Round(time.Microsecond))
+	}
+	fmt.Println()
+	fmt.Println(repeatString("=", 72))
+}
+
 // truncateString truncates a string to a maximum length
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -15,32 +16,63 @@ type Config struct {
 	} `yaml:"server"`
 
 	Embedding struct {
-		URL       string `yaml:"url"`
-		Model     string `yaml:"model"`
-		BatchSize int    `yaml:"batch_size"`
+		Provider     string        `yaml:"provider"`
+		URL          string        `yaml:"url"`
+		Model        string        `yaml:"model"`
+		APIKey       string        `yaml:"api_key"`
+		BatchSize    int           `yaml:"batch_size"`
+		Workers      int           `yaml:"workers"`
+		MaxRetries   int           `yaml:"max_retries"`
+		RetryBackoff time.Duration `yaml:"retry_backoff"`
+		CacheDir     string        `yaml:"cache_dir"`
 	} `yaml:"embedding"`
 
 	LLM struct {
-		URL         string  `yaml:"url"`
-		Model       string  `yaml:"model"`
-		Temperature float64 `yaml:"temperature"`
-		MaxTokens   int     `yaml:"max_tokens"`
+		Provider      string  `yaml:"provider"`
+		URL           string  `yaml:"url"`
+		Model         string  `yaml:"model"`
+		APIKey        string  `yaml:"api_key"`
+		Temperature   float64 `yaml:"temperature"`
+		MaxTokens     int     `yaml:"max_tokens"`
+		PromptFile    string  `yaml:"prompt_file"`
+		Language      string  `yaml:"language"`
+		ContextWindow int     `yaml:"context_window"`
 	} `yaml:"llm"`
 
 	Document struct {
 		ChunkSize        int      `yaml:"chunk_size"`
 		ChunkOverlap     int      `yaml:"chunk_overlap"`
+		ChunkStrategy    string   `yaml:"chunk_strategy"`
+		ChunkSizer       string   `yaml:"chunk_sizer"`
 		SupportedFormats []string `yaml:"supported_formats"`
 	} `yaml:"document"`
 
 	VectorDB struct {
 		StoragePath         string  `yaml:"storage_path"`
 		SimilarityThreshold float64 `yaml:"similarity_threshold"`
+		Quantization        string  `yaml:"quantization"`
+		PQSubvectors        int     `yaml:"pq_subvectors"`
+		RescoreFactor       int     `yaml:"rescore_factor"`
+		Collection          string  `yaml:"collection"`
 	} `yaml:"vector_db"`
 
+	Rerank struct {
+		Provider   string `yaml:"provider"`
+		URL        string `yaml:"url"`
+		Model      string `yaml:"model"`
+		Candidates int    `yaml:"candidates"`
+		TopN       int    `yaml:"top_n"`
+	} `yaml:"rerank"`
+
+	Conversation struct {
+		MaxHistoryTurns int    `yaml:"max_history_turns"`
+		SessionDir      string `yaml:"session_dir"`
+	} `yaml:"conversation"`
+
 	Logging struct {
-		Level  string `yaml:"level"`
-		Output string `yaml:"output"`
+		Level     string `yaml:"level"`
+		Output    string `yaml:"output"`
+		TraceFile string `yaml:"trace_file"`
 	} `yaml:"logging"`
 }
 
@@ -70,44 +102,94 @@ func GetDefaultConfig() *Config {
 			Host: "localhost",
 		},
 		Embedding: struct {
-			URL       string `yaml:"url"`
-			Model     string `yaml:"model"`
-			BatchSize int    `yaml:"batch_size"`
+			Provider     string        `yaml:"provider"`
+			URL          string        `yaml:"url"`
+			Model        string        `yaml:"model"`
+			APIKey       string        `yaml:"api_key"`
+			BatchSize    int           `yaml:"batch_size"`
+			Workers      int           `yaml:"workers"`
+			MaxRetries   int           `yaml:"max_retries"`
+			RetryBackoff time.Duration `yaml:"retry_backoff"`
+			CacheDir     string        `yaml:"cache_dir"`
 		}{
-			URL:       "http://localhost:8000",
-			Model:     "all-MiniLM-L6-v2",
-			BatchSize: 32,
+			Provider:     "custom",
+			URL:          "http://localhost:8000",
+			Model:        "all-MiniLM-L6-v2",
+			BatchSize:    32,
+			Workers:      4,
+			MaxRetries:   3,
+			RetryBackoff: 500 * time.Millisecond,
+			CacheDir:     "./data/embedding_cache",
 		},
 		LLM: struct {
-			URL         string  `yaml:"url"`
-			Model       string  `yaml:"model"`
-			Temperature float64 `yaml:"temperature"`
-			MaxTokens   int     `yaml:"max_tokens"`
+			Provider      string  `yaml:"provider"`
+			URL           string  `yaml:"url"`
+			Model         string  `yaml:"model"`
+			APIKey        string  `yaml:"api_key"`
+			Temperature   float64 `yaml:"temperature"`
+			MaxTokens     int     `yaml:"max_tokens"`
+			PromptFile    string  `yaml:"prompt_file"`
+			Language      string  `yaml:"language"`
+			ContextWindow int     `yaml:"context_window"`
 		}{
-			URL:         "http://localhost:11434",
-			Model:       "llama2",
-			Temperature: 0.7,
-			MaxTokens:   512,
+			Provider:      "ollama",
+			URL:           "http://localhost:11434",
+			Model:         "llama2",
+			Temperature:   0.7,
+			MaxTokens:     512,
+			ContextWindow: 4096,
 		},
 		Document: struct {
 			ChunkSize        int      `yaml:"chunk_size"`
 			ChunkOverlap     int      `yaml:"chunk_overlap"`
+			ChunkStrategy    string   `yaml:"chunk_strategy"`
+			ChunkSizer       string   `yaml:"chunk_sizer"`
 			SupportedFormats []string `yaml:"supported_formats"`
 		}{
 			ChunkSize:        512,
 			ChunkOverlap:     50,
-			SupportedFormats: []string{"txt", "pdf", "docx"},
+			ChunkStrategy:    "fixed",
+			ChunkSizer:       "runes",
+			SupportedFormats: []string{"txt", "md", "text", "html", "pdf", "docx"},
 		},
 		VectorDB: struct {
 			StoragePath         string  `yaml:"storage_path"`
 			SimilarityThreshold float64 `yaml:"similarity_threshold"`
+			Quantization        string  `yaml:"quantization"`
+			PQSubvectors        int     `yaml:"pq_subvectors"`
+			RescoreFactor       int     `yaml:"rescore_factor"`
+			Collection          string  `yaml:"collection"`
 		}{
 			StoragePath:         "./data/vectors",
 			SimilarityThreshold: 0.7,
+			Quantization:        "none",
+			PQSubvectors:        8,
+			RescoreFactor:       10,
+		},
+		Rerank: struct {
+			Provider   string `yaml:"provider"`
+			URL        string `yaml:"url"`
+			Model      string `yaml:"model"`
+			Candidates int    `yaml:"candidates"`
+			TopN       int    `yaml:"top_n"`
+		}{
+			Provider:   "none",
+			URL:        "http://localhost:8001",
+			Model:      "bge-reranker-base",
+			Candidates: 20,
+			TopN:       5,
+		},
+		Conversation: struct {
+			MaxHistoryTurns int    `yaml:"max_history_turns"`
+			SessionDir      string `yaml:"session_dir"`
+		}{
+			MaxHistoryTurns: 6,
+			SessionDir:      "./data/sessions",
 		},
 		Logging: struct {
-			Level  string `yaml:"level"`
-			Output string `yaml:"output"`
+			Level     string `yaml:"level"`
+			Output    string `yaml:"output"`
+			TraceFile string `yaml:"trace_file"`
 		}{
 			Level:  "info",
 			Output: "stdout",
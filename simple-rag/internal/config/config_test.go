@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGetDefaultConfig(t *testing.T) {
@@ -22,6 +23,9 @@ func TestGetDefaultConfig(t *testing.T) {
 	}
 
 	// Test embedding defaults
+	if config.Embedding.Provider != "custom" {
+		t.Errorf("Expected embedding provider 'custom', got %s", config.Embedding.Provider)
+	}
 	if config.Embedding.URL != "http://localhost:8000" {
 		t.Errorf("Expected embedding URL 'http://localhost:8000', got %s", config.Embedding.URL)
 	}
@@ -31,8 +35,20 @@ func TestGetDefaultConfig(t *testing.T) {
 	if config.Embedding.BatchSize != 32 {
 		t.Errorf("Expected embedding batch size 32, got %d", config.Embedding.BatchSize)
 	}
+	if config.Embedding.Workers != 4 || config.Embedding.MaxRetries != 3 {
+		t.Errorf("Expected 4 embedding workers and 3 retries, got %d and %d", config.Embedding.Workers, config.Embedding.MaxRetries)
+	}
+	if config.Embedding.RetryBackoff != 500*time.Millisecond {
+		t.Errorf("Expected retry backoff 500ms, got %v", config.Embedding.RetryBackoff)
+	}
+	if config.Embedding.CacheDir != "./data/embedding_cache" {
+		t.Errorf("Expected embedding cache dir './data/embedding_cache', got %s", config.Embedding.CacheDir)
+	}
 
 	// Test LLM defaults
+	if config.LLM.Provider != "ollama" {
+		t.Errorf("Expected LLM provider 'ollama', got %s", config.LLM.Provider)
+	}
 	if config.LLM.URL != "http://localhost:11434" {
 		t.Errorf("Expected LLM URL 'http://localhost:11434', got %s", config.LLM.URL)
 	}
@@ -45,6 +61,12 @@ func TestGetDefaultConfig(t *testing.T) {
 	if config.LLM.MaxTokens != 512 {
 		t.Errorf("Expected LLM max tokens 512, got %d", config.LLM.MaxTokens)
 	}
+	if config.LLM.PromptFile != "" {
+		t.Errorf("Expected no LLM prompt file, got %s", config.LLM.PromptFile)
+	}
+	if config.LLM.ContextWindow != 4096 {
+		t.Errorf("Expected LLM context window 4096, got %d", config.LLM.ContextWindow)
+	}
 
 	// Test document defaults
 	if config.Document.ChunkSize != 512 {
@@ -53,7 +75,13 @@ func TestGetDefaultConfig(t *testing.T) {
 	if config.Document.ChunkOverlap != 50 {
 		t.Errorf("Expected document chunk overlap 50, got %d", config.Document.ChunkOverlap)
 	}
-	expectedFormats := []string{"txt", "pdf", "docx"}
+	if config.Document.ChunkStrategy != "fixed" {
+		t.Errorf("Expected document chunk strategy 'fixed', got %s", config.Document.ChunkStrategy)
+	}
+	if config.Document.ChunkSizer != "runes" {
+		t.Errorf("Expected document chunk sizer 'runes', got %s", config.Document.ChunkSizer)
+	}
+	expectedFormats := []string{"txt", "md", "text", "html", "pdf", "docx"}
 	if len(config.Document.SupportedFormats) != len(expectedFormats) {
 		t.Errorf("Expected %d supported formats, got %d", len(expectedFormats), len(config.Document.SupportedFormats))
 	}
@@ -70,6 +98,34 @@ func TestGetDefaultConfig(t *testing.T) {
 	if config.VectorDB.SimilarityThreshold != 0.7 {
 		t.Errorf("Expected vector DB similarity threshold 0.7, got %f", config.VectorDB.SimilarityThreshold)
 	}
+	if config.VectorDB.Quantization != "none" {
+		t.Errorf("Expected vector DB quantization 'none', got %s", config.VectorDB.Quantization)
+	}
+	if config.VectorDB.PQSubvectors != 8 || config.VectorDB.RescoreFactor != 10 {
+		t.Errorf("Expected pq_subvectors 8 and rescore_factor 10, got %d and %d", config.VectorDB.PQSubvectors, config.VectorDB.RescoreFactor)
+	}
+	if config.VectorDB.Collection != "" {
+		t.Errorf("Expected the default collection, got %s", config.VectorDB.Collection)
+	}
+
+	// Test rerank defaults
+	if config.Rerank.Provider != "none" {
+		t.Errorf("Expected rerank provider 'none', got %s", config.Rerank.Provider)
+	}
+	if config.Rerank.Candidates != 20 {
+		t.Errorf("Expected rerank candidates 20, got %d", config.Rerank.Candidates)
+	}
+	if config.Rerank.TopN != 5 {
+		t.Errorf("Expected rerank top_n 5, got %d", config.Rerank.TopN)
+	}
+
+	// Test conversation defaults
+	if config.Conversation.MaxHistoryTurns != 6 {
+		t.Errorf("Expected conversation max history turns 6, got %d", config.Conversation.MaxHistoryTurns)
+	}
+	if config.Conversation.SessionDir != "./data/sessions" {
+		t.Errorf("Expected conversation session dir './data/sessions', got %s", config.Conversation.SessionDir)
+	}
 
 	// Test logging defaults
 	if config.Logging.Level != "info" {
@@ -99,8 +155,11 @@ embedding:
   url: "http://test:8000"
   model: "test-model"
   batch_size: 16
+  retry_backoff: 2s
 llm:
+  provider: "openai"
   url: "http://test:11434"
+  api_key: "sk-test"
   model: "test-llm"
   temperature: 0.5
   max_tokens: 256
@@ -111,9 +170,14 @@ document:
 vector_db:
   storage_path: "/tmp/vectors"
   similarity_threshold: 0.8
+rerank:
+  provider: "llm"
+  candidates: 30
+  top_n: 3
 logging:
   level: "debug"
   output: "file"
+  trace_file: "traces.jsonl"
 `
 
 		err := os.WriteFile(configPath, []byte(configContent), 0644)
@@ -135,12 +199,27 @@ logging:
 		if config.Embedding.URL != "http://test:8000" {
 			t.Errorf("Expected embedding URL 'http://test:8000', got %s", config.Embedding.URL)
 		}
+		if config.Embedding.RetryBackoff != 2*time.Second {
+			t.Errorf("Expected retry backoff 2s, got %v", config.Embedding.RetryBackoff)
+		}
+		if config.LLM.Provider != "openai" || config.LLM.APIKey != "sk-test" {
+			t.Errorf("Expected LLM provider 'openai' with api key, got %s and %s", config.LLM.Provider, config.LLM.APIKey)
+		}
 		if config.Document.ChunkSize != 256 {
 			t.Errorf("Expected document chunk size 256, got %d", config.Document.ChunkSize)
 		}
 		if config.VectorDB.SimilarityThreshold != 0.8 {
 			t.Errorf("Expected vector DB similarity threshold 0.8, got %f", config.VectorDB.SimilarityThreshold)
 		}
+		if config.Logging.TraceFile != "traces.jsonl" {
+			t.Errorf("Expected trace file 'traces.jsonl', got %s", config.Logging.TraceFile)
+		}
+		if config.Rerank.Provider != "llm" {
+			t.Errorf("Expected rerank provider 'llm', got %s", config.Rerank.Provider)
+		}
+		if config.Rerank.Candidates != 30 || config.Rerank.TopN != 3 {
+			t.Errorf("Expected rerank candidates 30 and top_n 3, got %d and %d", config.Rerank.Candidates, config.Rerank.TopN)
+		}
 	})
 
 	// Test loading non-existent file
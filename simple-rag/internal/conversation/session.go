@@ -0,0 +1,136 @@
+package conversation
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// Session keeps the chat history of one conversation
+type Session struct {
+	ID        string           `json:"id"`
+	Turns     []types.ChatTurn `json:"turns"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+
+	mu sync.RWMutex
+}
+
+// NewSession creates an empty session with a random ID
+func NewSession() *Session {
+	now := time.Now()
+	return &Session{
+		ID:        generateSessionID(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// AddTurn appends a message to the history
+func (s *Session) AddTurn(role, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.Turns = append(s.Turns, types.ChatTurn{
+		Role:      role,
+		Content:   content,
+		CreatedAt: now,
+	})
+	s.UpdatedAt = now
+}
+
+// Recent returns up to maxTurns of the latest turns, oldest first.
+// A non-positive maxTurns returns the whole history.
+func (s *Session) Recent(maxTurns int) []types.ChatTurn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	turns := s.Turns
+	if maxTurns > 0 && len(turns) > maxTurns {
+		turns = turns[len(turns)-maxTurns:]
+	}
+
+	recent := make([]types.ChatTurn, len(turns))
+	copy(recent, turns)
+	return recent
+}
+
+// Len returns the number of turns in the history
+func (s *Session) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.Turns)
+}
+
+// Clear drops the history but keeps the session ID
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Turns = nil
+	s.UpdatedAt = time.Now()
+}
+
+// Save writes the session to a JSON file, creating parent directories
+func (s *Session) Save(path string) error {
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+
+	return nil
+}
+
+// Load reads a session saved with Save
+func Load(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+	if session.ID == "" {
+		session.ID = generateSessionID()
+	}
+
+	return &session, nil
+}
+
+// SessionPath resolves a session name to a file in dir.
+// Names that already look like paths are returned unchanged.
+func SessionPath(dir, name string) string {
+	if filepath.Ext(name) == ".json" || filepath.Base(name) != name {
+		return name
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// generateSessionID generates a random session ID
+func generateSessionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("session_%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("session_%x", b)
+}
@@ -0,0 +1,139 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestNewSession(t *testing.T) {
+	session := NewSession()
+	if session.ID == "" {
+		t.Error("Expected non-empty session ID")
+	}
+	if session.Len() != 0 {
+		t.Errorf("Expected empty history, got %d turns", session.Len())
+	}
+	if session.CreatedAt.IsZero() {
+		t.Error("Expected non-zero CreatedAt")
+	}
+
+	other := NewSession()
+	if other.ID == session.ID {
+		t.Error("Expected unique session IDs")
+	}
+}
+
+func TestRecent(t *testing.T) {
+	session := NewSession()
+	session.AddTurn(types.RoleUser, "q1")
+	session.AddTurn(types.RoleAssistant, "a1")
+	session.AddTurn(types.RoleUser, "q2")
+	session.AddTurn(types.RoleAssistant, "a2")
+
+	t.Run("Trimmed", func(t *testing.T) {
+		recent := session.Recent(2)
+		if len(recent) != 2 {
+			t.Fatalf("Expected 2 turns, got %d", len(recent))
+		}
+		if recent[0].Content != "q2" || recent[1].Content != "a2" {
+			t.Errorf("Expected latest turns q2, a2, got %s, %s", recent[0].Content, recent[1].Content)
+		}
+		if recent[1].Role != types.RoleAssistant {
+			t.Errorf("Expected assistant role, got %s", recent[1].Role)
+		}
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		if len(session.Recent(0)) != 4 {
+			t.Errorf("Expected all 4 turns, got %d", len(session.Recent(0)))
+		}
+	})
+
+	t.Run("ReturnsCopy", func(t *testing.T) {
+		recent := session.Recent(0)
+		recent[0].Content = "changed"
+		if session.Recent(0)[0].Content != "q1" {
+			t.Error("Modifying Recent result should not change the session")
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		id := session.ID
+		session.Clear()
+		if session.Len() != 0 {
+			t.Errorf("Expected empty history after Clear, got %d", session.Len())
+		}
+		if session.ID != id {
+			t.Error("Clear should keep the session ID")
+		}
+	})
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "conversation_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	session := NewSession()
+	session.AddTurn(types.RoleUser, "Goの特徴は？")
+	session.AddTurn(types.RoleAssistant, "シンプルで高速です。")
+
+	path := filepath.Join(tempDir, "nested", "chat.json")
+	if err := session.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if loaded.ID != session.ID {
+		t.Errorf("Expected ID %s, got %s", session.ID, loaded.ID)
+	}
+	if loaded.Len() != 2 {
+		t.Fatalf("Expected 2 turns, got %d", loaded.Len())
+	}
+	if loaded.Turns[0].Content != "Goの特徴は？" {
+		t.Errorf("Expected first turn content preserved, got %s", loaded.Turns[0].Content)
+	}
+	if !loaded.CreatedAt.Equal(session.CreatedAt) {
+		t.Errorf("Expected CreatedAt %v, got %v", session.CreatedAt, loaded.CreatedAt)
+	}
+
+	t.Run("MissingFile", func(t *testing.T) {
+		if _, err := Load(filepath.Join(tempDir, "missing.json")); err == nil {
+			t.Error("Expected error for missing file, got nil")
+		}
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		invalid := filepath.Join(tempDir, "invalid.json")
+		os.WriteFile(invalid, []byte("{not json"), 0644)
+		if _, err := Load(invalid); err == nil {
+			t.Error("Expected error for invalid JSON, got nil")
+		}
+	})
+}
+
+func TestSessionPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"work", filepath.Join("sessions", "work.json")},
+		{"work.json", "work.json"},
+		{filepath.Join("other", "chat"), filepath.Join("other", "chat")},
+	}
+
+	for _, test := range tests {
+		if got := SessionPath("sessions", test.name); got != test.expected {
+			t.Errorf("SessionPath(%q): expected %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
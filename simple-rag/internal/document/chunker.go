@@ -0,0 +1,574 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"simple-rag/pkg/types"
+)
+
+// Sizer measures text in the unit that chunk sizes and overlaps are expressed in
+type Sizer interface {
+	Size(text string) int
+}
+
+// ByteSizer counts UTF-8 bytes
+type ByteSizer struct{}
+
+// Size returns the number of bytes in text
+func (ByteSizer) Size(text string) int {
+	return len(text)
+}
+
+// RuneSizer counts characters
+type RuneSizer struct{}
+
+// Size returns the number of runes in text
+func (RuneSizer) Size(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// TokenSizer approximates the token count of the subword tokenizers used by
+// embedding models. Every CJK character, punctuation mark and symbol counts as
+// one token, and each run of letters or digits counts one token per four characters.
+type TokenSizer struct{}
+
+// Size returns the approximate number of tokens in text
+func (TokenSizer) Size(text string) int {
+	tokens := 0
+	word := 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// NewSizer returns the sizer for a name: "bytes", "runes" (the default) or "tokens"
+func NewSizer(name string) (Sizer, error) {
+	switch strings.ToLower(name) {
+	case "bytes":
+		return ByteSizer{}, nil
+	case "", "runes", "chars", "characters":
+		return RuneSizer{}, nil
+	case "tokens":
+		return TokenSizer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunk sizer: %s", name)
+	}
+}
+
+// Chunker splits a document into chunks.
+// Chunk positions are byte offsets into doc.Content and always fall on rune
+// boundaries, so doc.Content[chunk.StartPos:chunk.EndPos] == chunk.Content.
+type Chunker interface {
+	Chunk(doc *types.Document) ([]*types.DocumentChunk, error)
+}
+
+// NewChunker returns the chunker for a strategy: "fixed" (the default), "recursive" or "markdown"
+func NewChunker(strategy string, sizer Sizer, chunkSize, chunkOverlap int) (Chunker, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive: %d", chunkSize)
+	}
+	if chunkOverlap < 0 || chunkOverlap >= chunkSize {
+		return nil, fmt.Errorf("chunk overlap must be between 0 and chunk size: %d", chunkOverlap)
+	}
+
+	switch strings.ToLower(strategy) {
+	case "", "fixed":
+		return &FixedSizeChunker{ChunkSize: chunkSize, ChunkOverlap: chunkOverlap, Sizer: sizer}, nil
+	case "recursive":
+		return &RecursiveChunker{ChunkSize: chunkSize, ChunkOverlap: chunkOverlap, Sizer: sizer}, nil
+	case "markdown":
+		return &MarkdownChunker{ChunkSize: chunkSize, ChunkOverlap: chunkOverlap, Sizer: sizer}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunk strategy: %s", strategy)
+	}
+}
+
+// span is a byte range of document content
+type span struct {
+	start int
+	end   int
+}
+
+// FixedSizeChunker cuts windows of at most ChunkSize units, preferring to break
+// at whitespace or sentence punctuation and never inside a multi-byte character
+type FixedSizeChunker struct {
+	ChunkSize    int
+	ChunkOverlap int
+	Sizer        Sizer
+}
+
+// Chunk splits a document into fixed-size overlapping chunks
+func (c *FixedSizeChunker) Chunk(doc *types.Document) ([]*types.DocumentChunk, error) {
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("document content is empty")
+	}
+
+	sizer := sizerOrDefault(c.Sizer)
+	return buildChunks(doc, fixedSpans(doc.Content, span{0, len(doc.Content)}, sizer, c.ChunkSize, c.ChunkOverlap), nil), nil
+}
+
+// fixedSpans cuts a region into windows of at most limit units
+func fixedSpans(content string, region span, sizer Sizer, limit, overlap int) []span {
+	var spans []span
+
+	start := region.start
+	for start < region.end {
+		end := start + fitWindow(content[start:region.end], sizer, limit)
+		if end == start {
+			// A single rune larger than the limit still has to go somewhere
+			_, width := utf8.DecodeRuneInString(content[start:])
+			end = start + width
+		}
+
+		// Try to break at a word or sentence boundary
+		if end < region.end {
+			if boundary := lastBoundary(content[start:end]); boundary > 0 {
+				end = start + boundary
+			}
+		}
+
+		spans = append(spans, span{start, end})
+		if end >= region.end {
+			break
+		}
+
+		// Move to next chunk with overlap
+		next := end
+		if overlap > 0 {
+			next = end - fitSuffix(content[start:end], sizer, overlap)
+		}
+		if next <= start {
+			_, width := utf8.DecodeRuneInString(content[start:])
+			next = start + width
+		}
+		start = next
+	}
+
+	return spans
+}
+
+// RecursiveChunker splits text at the coarsest boundary that yields pieces
+// within ChunkSize (paragraphs, then lines, then sentences, then words, then
+// characters) and merges neighbouring pieces back up to ChunkSize
+type RecursiveChunker struct {
+	ChunkSize    int
+	ChunkOverlap int
+	Sizer        Sizer
+}
+
+// Chunk splits a document along its natural boundaries
+func (c *RecursiveChunker) Chunk(doc *types.Document) ([]*types.DocumentChunk, error) {
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("document content is empty")
+	}
+
+	spans := c.spans(doc.Content, span{0, len(doc.Content)})
+	return buildChunks(doc, spans, nil), nil
+}
+
+// spans splits and merges one region of content
+func (c *RecursiveChunker) spans(content string, region span) []span {
+	sizer := sizerOrDefault(c.Sizer)
+	pieces := splitRecursive(content, region, sizer, c.ChunkSize, 0)
+	return mergeSpans(content, pieces, sizer, c.ChunkSize, c.ChunkOverlap)
+}
+
+// separators are the split functions tried from coarsest to finest
+var separators = []func(text string) []int{
+	paragraphCuts,
+	lineCuts,
+	sentenceCuts,
+	wordCuts,
+}
+
+// splitRecursive returns pieces of a region that each fit within limit
+func splitRecursive(content string, region span, sizer Sizer, limit, level int) []span {
+	if sizer.Size(content[region.start:region.end]) <= limit {
+		return []span{region}
+	}
+	if level >= len(separators) {
+		return fixedSpans(content, region, sizer, limit, 0)
+	}
+
+	var pieces []span
+	start := region.start
+	cuts := append(separators[level](content[region.start:region.end]), region.end-region.start)
+	for _, cut := range cuts {
+		end := region.start + cut
+		if end <= start {
+			continue
+		}
+		pieces = append(pieces, splitRecursive(content, span{start, end}, sizer, limit, level+1)...)
+		start = end
+	}
+
+	return pieces
+}
+
+// mergeSpans joins adjacent pieces into chunks of at most limit units,
+// repeating trailing pieces up to overlap units at the start of the next chunk
+func mergeSpans(content string, pieces []span, sizer Sizer, limit, overlap int) []span {
+	var (
+		merged  []span
+		current []span
+	)
+
+	for _, piece := range pieces {
+		if len(current) > 0 && sizer.Size(content[current[0].start:piece.end]) > limit {
+			last := current[len(current)-1]
+			merged = append(merged, span{current[0].start, last.end})
+
+			// Keep trailing pieces that fit in the overlap and still leave room for this piece
+			keep := len(current)
+			for keep > 0 && sizer.Size(content[current[keep-1].start:last.end]) <= overlap {
+				keep--
+			}
+			current = current[keep:]
+			for len(current) > 0 && sizer.Size(content[current[0].start:piece.end]) > limit {
+				current = current[1:]
+			}
+		}
+		current = append(current, piece)
+	}
+	if len(current) > 0 {
+		merged = append(merged, span{current[0].start, current[len(current)-1].end})
+	}
+
+	return merged
+}
+
+// MarkdownChunker splits a Markdown document into heading sections, chunks each
+// section recursively and records the heading path in chunk metadata
+type MarkdownChunker struct {
+	ChunkSize    int
+	ChunkOverlap int
+	Sizer        Sizer
+}
+
+// Chunk splits a Markdown document by heading sections
+func (c *MarkdownChunker) Chunk(doc *types.Document) ([]*types.DocumentChunk, error) {
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("document content is empty")
+	}
+
+	recursive := &RecursiveChunker{ChunkSize: c.ChunkSize, ChunkOverlap: c.ChunkOverlap, Sizer: c.Sizer}
+
+	var (
+		spans []span
+		paths []string
+	)
+	for _, section := range markdownSections(doc.Content) {
+		body := strings.TrimSpace(doc.Content[section.bodyStart:section.end])
+		if body == "" {
+			continue
+		}
+		for _, s := range recursive.spans(doc.Content, span{section.start, section.end}) {
+			spans = append(spans, s)
+			paths = append(paths, strings.Join(section.path, " > "))
+		}
+	}
+
+	// Whitespace trimming can drop spans, so map metadata by start position
+	metadata := make(map[int]map[string]string, len(spans))
+	for i, s := range spans {
+		if paths[i] == "" {
+			continue
+		}
+		headings := strings.Split(paths[i], " > ")
+		metadata[s.start] = map[string]string{
+			"heading_path": paths[i],
+			"heading":      headings[len(headings)-1],
+		}
+	}
+
+	return buildChunks(doc, spans, func(s span) map[string]string {
+		return metadata[s.start]
+	}), nil
+}
+
+// markdownSection is the content under one heading
+type markdownSection struct {
+	start     int
+	bodyStart int
+	end       int
+	path      []string
+}
+
+// markdownSections splits Markdown content at ATX headings outside code fences
+func markdownSections(content string) []markdownSection {
+	var (
+		sections []markdownSection
+		stack    []string
+		levels   []int
+		inFence  bool
+	)
+	current := markdownSection{}
+
+	offset := 0
+	for offset < len(content) {
+		lineEnd := strings.IndexByte(content[offset:], '\n')
+		next := len(content)
+		if lineEnd >= 0 {
+			next = offset + lineEnd + 1
+		}
+		line := strings.TrimSpace(content[offset:next])
+
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			if level, heading := ParseHeading(line); level > 0 {
+				current.end = offset
+				if current.end > current.start {
+					sections = append(sections, current)
+				}
+
+				for len(levels) > 0 && levels[len(levels)-1] >= level {
+					levels = levels[:len(levels)-1]
+					stack = stack[:len(stack)-1]
+				}
+				levels = append(levels, level)
+				stack = append(stack, heading)
+
+				current = markdownSection{
+					start:     offset,
+					bodyStart: next,
+					path:      append([]string(nil), stack...),
+				}
+			}
+		}
+		offset = next
+	}
+
+	current.end = len(content)
+	if current.end > current.start {
+		sections = append(sections, current)
+	}
+
+	return sections
+}
+
+// buildChunks turns spans into document chunks, trimming surrounding whitespace
+func buildChunks(doc *types.Document, spans []span, metadata func(span) map[string]string) []*types.DocumentChunk {
+	var chunks []*types.DocumentChunk
+
+	for _, s := range spans {
+		raw := doc.Content[s.start:s.end]
+		start := s.start + (len(raw) - len(strings.TrimLeftFunc(raw, unicode.IsSpace)))
+		end := s.start + len(strings.TrimRightFunc(raw, unicode.IsSpace))
+		if end <= start {
+			continue
+		}
+
+		chunk := &types.DocumentChunk{
+			ID:         generateChunkID(doc.ID, len(chunks)),
+			DocumentID: doc.ID,
+			ChunkIndex: len(chunks),
+			Content:    doc.Content[start:end],
+			StartPos:   start,
+			EndPos:     end,
+			Embedding:  nil, // Will be set later
+			CreatedAt:  time.Now(),
+		}
+		if metadata != nil {
+			chunk.Metadata = metadata(s)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// fitWindow is fitPrefix for text that may be much longer than limit. It
+// measures a window that grows until it no longer fits, so each chunk costs
+// time proportional to its own size rather than to the rest of the document.
+func fitWindow(text string, sizer Sizer, limit int) int {
+	window := max(limit, 1) * utf8.UTFMax
+	for {
+		if window >= len(text) {
+			return fitPrefix(text, sizer, limit)
+		}
+		end := window
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		if n := fitPrefix(text[:end], sizer, limit); n < end {
+			return n
+		}
+		window *= 2
+	}
+}
+
+// fitPrefix returns the byte length of the longest rune-aligned prefix within limit
+func fitPrefix(text string, sizer Sizer, limit int) int {
+	if sizer.Size(text) <= limit {
+		return len(text)
+	}
+	boundaries := runeBoundaries(text)
+	i := sort.Search(len(boundaries), func(i int) bool {
+		return sizer.Size(text[:boundaries[i]]) > limit
+	})
+	if i == 0 {
+		return 0
+	}
+	return boundaries[i-1]
+}
+
+// fitSuffix returns the byte length of the longest rune-aligned suffix within limit
+func fitSuffix(text string, sizer Sizer, limit int) int {
+	if sizer.Size(text) <= limit {
+		return len(text)
+	}
+	boundaries := runeBoundaries(text)
+	// Suffixes get shorter as the start boundary moves right
+	i := sort.Search(len(boundaries), func(i int) bool {
+		return sizer.Size(text[boundaries[i]:]) <= limit
+	})
+	if i == len(boundaries) {
+		return 0
+	}
+	return len(text) - boundaries[i]
+}
+
+// runeBoundaries returns the byte offsets after each rune of text
+func runeBoundaries(text string) []int {
+	boundaries := make([]int, 0, len(text))
+	for i := range text {
+		if i > 0 {
+			boundaries = append(boundaries, i)
+		}
+	}
+	return append(boundaries, len(text))
+}
+
+// lastBoundary returns the offset after the last whitespace or sentence
+// punctuation in text, or 0 if there is none
+func lastBoundary(text string) int {
+	for i := len(text); i > 0; {
+		r, width := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.IsSpace(r) {
+			if i-width > 0 {
+				return i - width
+			}
+			return 0
+		}
+		if isSentenceEnd(r) || r == '、' || r == '，' {
+			return i
+		}
+		i -= width
+	}
+	return 0
+}
+
+// paragraphCuts returns offsets just after each blank-line run
+func paragraphCuts(text string) []int {
+	var cuts []int
+	for i := 0; i < len(text); {
+		idx := strings.Index(text[i:], "\n\n")
+		if idx < 0 {
+			break
+		}
+		end := i + idx
+		for end < len(text) && (text[end] == '\n' || text[end] == '\r') {
+			end++
+		}
+		cuts = append(cuts, end)
+		i = end
+	}
+	return cuts
+}
+
+// lineCuts returns offsets just after each newline
+func lineCuts(text string) []int {
+	var cuts []int
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			cuts = append(cuts, i+1)
+		}
+	}
+	return cuts
+}
+
+// sentenceCuts returns offsets after sentence-ending punctuation.
+// Western terminators need following whitespace; CJK terminators do not.
+func sentenceCuts(text string) []int {
+	var cuts []int
+	for i, r := range text {
+		if !isSentenceEnd(r) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		if r > unicode.MaxASCII {
+			cuts = append(cuts, end)
+			continue
+		}
+		next, width := utf8.DecodeRuneInString(text[end:])
+		if width > 0 && unicode.IsSpace(next) {
+			cuts = append(cuts, end+width)
+		}
+	}
+	return cuts
+}
+
+// wordCuts returns offsets after each whitespace run
+func wordCuts(text string) []int {
+	var cuts []int
+	inSpace := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			inSpace = true
+			continue
+		}
+		if inSpace {
+			cuts = append(cuts, i)
+			inSpace = false
+		}
+	}
+	return cuts
+}
+
+// isSentenceEnd reports whether r ends a sentence
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// sizerOrDefault returns the sizer, falling back to counting runes
+func sizerOrDefault(sizer Sizer) Sizer {
+	if sizer == nil {
+		return RuneSizer{}
+	}
+	return sizer
+}
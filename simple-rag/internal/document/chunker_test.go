@@ -0,0 +1,345 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"simple-rag/pkg/types"
+)
+
+func TestSizers(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizer    Sizer
+		text     string
+		expected int
+	}{
+		{"BytesASCII", ByteSizer{}, "hello", 5},
+		{"BytesJapanese", ByteSizer{}, "日本語", 9},
+		{"RunesJapanese", RuneSizer{}, "日本語", 3},
+		{"TokensWords", TokenSizer{}, "the quick brown fox", 6},
+		{"TokensPunctuation", TokenSizer{}, "Hi, there!", 5},
+		{"TokensJapanese", TokenSizer{}, "日本語です。", 6},
+		{"TokensEmpty", TokenSizer{}, "", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.sizer.Size(test.text); got != test.expected {
+				t.Errorf("Size(%q): expected %d, got %d", test.text, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewSizer(t *testing.T) {
+	for _, name := range []string{"", "bytes", "runes", "tokens", "TOKENS"} {
+		if _, err := NewSizer(name); err != nil {
+			t.Errorf("NewSizer(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := NewSizer("words"); err == nil {
+		t.Error("Expected error for unknown sizer")
+	}
+}
+
+func TestNewChunker(t *testing.T) {
+	for _, strategy := range []string{"", "fixed", "recursive", "markdown"} {
+		if _, err := NewChunker(strategy, RuneSizer{}, 100, 10); err != nil {
+			t.Errorf("NewChunker(%q) failed: %v", strategy, err)
+		}
+	}
+
+	errorCases := []struct {
+		name     string
+		strategy string
+		size     int
+		overlap  int
+	}{
+		{"UnknownStrategy", "semantic", 100, 10},
+		{"ZeroSize", "fixed", 0, 0},
+		{"NegativeOverlap", "fixed", 100, -1},
+		{"OverlapTooLarge", "fixed", 100, 100},
+	}
+	for _, test := range errorCases {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewChunker(test.strategy, RuneSizer{}, test.size, test.overlap); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFixedSizeChunker(t *testing.T) {
+	t.Run("JapaneseIsRuneSafe", func(t *testing.T) {
+		doc := &types.Document{
+			ID:      "ja_doc",
+			Content: strings.Repeat("機械学習は人工知能の一分野です。", 10),
+		}
+
+		chunker := &FixedSizeChunker{ChunkSize: 20, ChunkOverlap: 5, Sizer: RuneSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		if len(chunks) < 2 {
+			t.Fatalf("Expected multiple chunks, got %d", len(chunks))
+		}
+
+		assertChunkSpans(t, doc, chunks)
+		for i, chunk := range chunks {
+			if n := utf8.RuneCountInString(chunk.Content); n > 20 {
+				t.Errorf("Chunk %d has %d runes, limit 20", i, n)
+			}
+			if !strings.HasSuffix(chunk.Content, "。") && i < len(chunks)-1 {
+				t.Errorf("Chunk %d should break after sentence punctuation, got %q", i, chunk.Content)
+			}
+		}
+	})
+
+	t.Run("ByteSizerStillRuneSafe", func(t *testing.T) {
+		doc := &types.Document{ID: "bytes_doc", Content: "日本語のテキスト"}
+
+		chunker := &FixedSizeChunker{ChunkSize: 4, Sizer: ByteSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		assertChunkSpans(t, doc, chunks)
+		if len(chunks) != 8 {
+			t.Errorf("Expected one chunk per 3-byte rune (8), got %d", len(chunks))
+		}
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		doc := &types.Document{ID: "overlap_doc", Content: "alpha beta gamma delta epsilon zeta"}
+
+		chunker := &FixedSizeChunker{ChunkSize: 12, ChunkOverlap: 6, Sizer: RuneSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		assertChunkSpans(t, doc, chunks)
+		for i := 1; i < len(chunks); i++ {
+			if chunks[i].StartPos >= chunks[i-1].EndPos {
+				t.Errorf("Chunk %d should overlap the previous chunk", i)
+			}
+		}
+	})
+}
+
+// BenchmarkFixedSizeChunker guards against chunking cost growing with the
+// square of the document size
+func BenchmarkFixedSizeChunker(b *testing.B) {
+	for _, size := range []int{1 << 17, 1 << 19, 1 << 21} {
+		doc := &types.Document{
+			ID:      "bench_doc",
+			Content: strings.Repeat("機械学習は人工知能の一分野です。 ", size/49),
+		}
+		for _, sizer := range []Sizer{ByteSizer{}, RuneSizer{}, TokenSizer{}} {
+			chunker := &FixedSizeChunker{ChunkSize: 512, ChunkOverlap: 64, Sizer: sizer}
+			b.Run(fmt.Sprintf("%T/%dKB", sizer, size>>10), func(b *testing.B) {
+				for b.Loop() {
+					if _, err := chunker.Chunk(doc); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRecursiveChunker(t *testing.T) {
+	t.Run("KeepsParagraphsTogether", func(t *testing.T) {
+		paragraphs := []string{
+			"First paragraph has one sentence.",
+			"Second paragraph is here. It has two sentences.",
+			"Third paragraph closes the document.",
+		}
+		doc := &types.Document{ID: "para_doc", Content: strings.Join(paragraphs, "\n\n")}
+
+		chunker := &RecursiveChunker{ChunkSize: 50, Sizer: RuneSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		assertChunkSpans(t, doc, chunks)
+
+		if len(chunks) != 3 {
+			t.Fatalf("Expected 3 chunks, got %d: %v", len(chunks), chunkContents(chunks))
+		}
+		for i, paragraph := range paragraphs {
+			if chunks[i].Content != paragraph {
+				t.Errorf("Chunk %d: expected %q, got %q", i, paragraph, chunks[i].Content)
+			}
+		}
+	})
+
+	t.Run("FallsBackToSentencesAndWords", func(t *testing.T) {
+		doc := &types.Document{
+			ID:      "long_doc",
+			Content: "One two three four five. Six seven eight nine ten. Eleven twelve thirteen fourteen fifteen sixteen seventeen.",
+		}
+
+		chunker := &RecursiveChunker{ChunkSize: 30, Sizer: RuneSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		assertChunkSpans(t, doc, chunks)
+
+		if chunks[0].Content != "One two three four five." {
+			t.Errorf("Expected first chunk to be the first sentence, got %q", chunks[0].Content)
+		}
+		for i, chunk := range chunks {
+			if n := utf8.RuneCountInString(chunk.Content); n > 30 {
+				t.Errorf("Chunk %d has %d runes, limit 30", i, n)
+			}
+			if strings.HasPrefix(chunk.Content, "n ") || strings.HasSuffix(chunk.Content, " e") {
+				t.Errorf("Chunk %d splits a word: %q", i, chunk.Content)
+			}
+		}
+	})
+
+	t.Run("MergesSmallPiecesWithOverlap", func(t *testing.T) {
+		doc := &types.Document{ID: "lines_doc", Content: "a1\nb2\nc3\nd4\ne5\nf6"}
+
+		chunker := &RecursiveChunker{ChunkSize: 8, ChunkOverlap: 3, Sizer: RuneSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		assertChunkSpans(t, doc, chunks)
+
+		if len(chunks) < 2 {
+			t.Fatalf("Expected multiple chunks, got %d", len(chunks))
+		}
+		for i := 1; i < len(chunks); i++ {
+			if chunks[i].StartPos >= chunks[i-1].EndPos {
+				t.Errorf("Chunk %d should repeat the last line of the previous chunk", i)
+			}
+		}
+	})
+
+	t.Run("TokenSizer", func(t *testing.T) {
+		doc := &types.Document{ID: "token_doc", Content: strings.Repeat("Goは並行処理が得意です。", 20)}
+
+		chunker := &RecursiveChunker{ChunkSize: 40, Sizer: TokenSizer{}}
+		chunks, err := chunker.Chunk(doc)
+		if err != nil {
+			t.Fatalf("Chunk failed: %v", err)
+		}
+		assertChunkSpans(t, doc, chunks)
+		for i, chunk := range chunks {
+			if n := (TokenSizer{}).Size(chunk.Content); n > 40 {
+				t.Errorf("Chunk %d has %d tokens, limit 40", i, n)
+			}
+		}
+	})
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	content := `Preamble text.
+
+# Guide
+
+Intro to the guide.
+
+## Install
+
+Run the installer.
+
+` + "```sh\n# not a heading\nmake install\n```" + `
+
+### Linux
+
+Use the package manager.
+
+## Usage
+
+Call the binary.
+`
+	doc := &types.Document{ID: "md_doc", Content: content}
+
+	chunker := &MarkdownChunker{ChunkSize: 200, Sizer: RuneSizer{}}
+	chunks, err := chunker.Chunk(doc)
+	if err != nil {
+		t.Fatalf("Chunk failed: %v", err)
+	}
+	assertChunkSpans(t, doc, chunks)
+
+	expected := []struct {
+		path    string
+		content string
+	}{
+		{"", "Preamble text."},
+		{"Guide", "Intro to the guide."},
+		{"Guide > Install", "make install"},
+		{"Guide > Install > Linux", "Use the package manager."},
+		{"Guide > Usage", "Call the binary."},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %v", len(expected), len(chunks), chunkContents(chunks))
+	}
+
+	for i, want := range expected {
+		if got := chunks[i].Metadata["heading_path"]; got != want.path {
+			t.Errorf("Chunk %d: expected heading_path %q, got %q", i, want.path, got)
+		}
+		if !strings.Contains(chunks[i].Content, want.content) {
+			t.Errorf("Chunk %d: expected content containing %q, got %q", i, want.content, chunks[i].Content)
+		}
+	}
+	if chunks[3].Metadata["heading"] != "Linux" {
+		t.Errorf("Expected heading 'Linux', got %q", chunks[3].Metadata["heading"])
+	}
+	if chunks[0].Metadata != nil {
+		t.Errorf("Expected no metadata for preamble, got %v", chunks[0].Metadata)
+	}
+}
+
+func TestChunkersRejectEmptyDocument(t *testing.T) {
+	doc := &types.Document{ID: "empty_doc"}
+	chunkers := []Chunker{
+		&FixedSizeChunker{ChunkSize: 10},
+		&RecursiveChunker{ChunkSize: 10},
+		&MarkdownChunker{ChunkSize: 10},
+	}
+
+	for _, chunker := range chunkers {
+		if _, err := chunker.Chunk(doc); err == nil {
+			t.Errorf("%T: expected error for empty document", chunker)
+		}
+	}
+}
+
+// assertChunkSpans checks indexes and that every chunk matches its byte span
+func assertChunkSpans(t *testing.T, doc *types.Document, chunks []*types.DocumentChunk) {
+	t.Helper()
+
+	for i, chunk := range chunks {
+		if chunk.ChunkIndex != i {
+			t.Errorf("Chunk %d has wrong index %d", i, chunk.ChunkIndex)
+		}
+		if !utf8.ValidString(chunk.Content) {
+			t.Errorf("Chunk %d is not valid UTF-8: %q", i, chunk.Content)
+		}
+		if chunk.StartPos < 0 || chunk.EndPos > len(doc.Content) || chunk.StartPos >= chunk.EndPos {
+			t.Fatalf("Chunk %d has invalid span [%d, %d)", i, chunk.StartPos, chunk.EndPos)
+		}
+		if doc.Content[chunk.StartPos:chunk.EndPos] != chunk.Content {
+			t.Errorf("Chunk %d content does not match its span", i)
+		}
+	}
+}
+
+// chunkContents returns chunk contents for error messages
+func chunkContents(chunks []*types.DocumentChunk) []string {
+	contents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		contents[i] = chunk.Content
+	}
+	return contents
+}
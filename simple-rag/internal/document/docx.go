@@ -0,0 +1,153 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// extractDOCX reads the paragraphs of a Word document.
+// Paragraphs styled "Heading N" are written as Markdown headings, and the
+// title comes from the core properties or, failing that, a "Title" paragraph.
+func extractDOCX(data []byte) (*Extraction, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx archive: %w", err)
+	}
+
+	body, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	extraction, err := parseDOCXBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if core, err := readZipFile(archive, "docProps/core.xml"); err == nil {
+		if title := parseDOCXTitle(core); title != "" {
+			extraction.Title = title
+		}
+	}
+
+	return extraction, nil
+}
+
+// readZipFile returns the content of a named file inside a zip archive
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
+
+// parseDOCXBody walks word/document.xml and collects paragraph text
+func parseDOCXBody(body []byte) (*Extraction, error) {
+	extraction := &Extraction{}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var (
+		paragraphs []string
+		paragraph  strings.Builder
+		style      string
+		inText     bool
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse document.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				style = ""
+			case "pStyle":
+				style = xmlAttr(t, "val")
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				if text == "" {
+					continue
+				}
+				if level := docxHeadingLevel(style); level > 0 {
+					extraction.Headings = append(extraction.Headings, text)
+					text = headingLine(level, text)
+				} else if strings.EqualFold(style, "Title") && extraction.Title == "" {
+					extraction.Title = text
+				}
+				paragraphs = append(paragraphs, text)
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		}
+	}
+
+	extraction.Text = strings.Join(paragraphs, "\n\n")
+	return extraction, nil
+}
+
+// parseDOCXTitle returns dc:title from docProps/core.xml
+func parseDOCXTitle(core []byte) string {
+	var props struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.Unmarshal(core, &props); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(props.Title)
+}
+
+// docxHeadingLevel converts a paragraph style such as "Heading2" to a heading level
+func docxHeadingLevel(style string) int {
+	lower := strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if !strings.HasPrefix(lower, "heading") {
+		return 0
+	}
+	level, err := strconv.Atoi(strings.TrimPrefix(lower, "heading"))
+	if err != nil || level < 1 {
+		return 0
+	}
+	return level
+}
+
+// xmlAttr returns the value of an attribute by local name
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
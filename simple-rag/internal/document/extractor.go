@@ -0,0 +1,128 @@
+package document
+
+import (
+	"bufio"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Extraction holds the plain text and structure pulled out of a source file
+type Extraction struct {
+	Text     string
+	Title    string
+	Headings []string
+}
+
+// Extractor converts the raw bytes of a file into plain text.
+// Extractors that recover document structure write headings into Text as
+// Markdown "#" lines so that later stages can treat every format alike.
+type Extractor interface {
+	Extract(data []byte) (*Extraction, error)
+}
+
+// ExtractorFunc adapts an ordinary function to the Extractor interface
+type ExtractorFunc func(data []byte) (*Extraction, error)
+
+// Extract calls f(data)
+func (f ExtractorFunc) Extract(data []byte) (*Extraction, error) {
+	return f(data)
+}
+
+// defaultExtractors returns the built-in extractors keyed by file type
+func defaultExtractors() map[string]Extractor {
+	return map[string]Extractor{
+		"txt":      ExtractorFunc(extractPlainText),
+		"text":     ExtractorFunc(extractPlainText),
+		"md":       ExtractorFunc(extractMarkdown),
+		"markdown": ExtractorFunc(extractMarkdown),
+		"html":     ExtractorFunc(extractHTML),
+		"htm":      ExtractorFunc(extractHTML),
+		"pdf":      ExtractorFunc(extractPDF),
+		"docx":     ExtractorFunc(extractDOCX),
+	}
+}
+
+// BuiltinFormats returns the file types that have a built-in extractor
+func BuiltinFormats() []string {
+	extractors := defaultExtractors()
+	formats := make([]string, 0, len(extractors))
+	for format := range extractors {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// extractPlainText returns the file content as text. Files in other
+// encodings (e.g. Shift_JIS) are not rejected: invalid UTF-8 sequences are
+// replaced with U+FFFD so the readable parts can still be indexed.
+func extractPlainText(data []byte) (*Extraction, error) {
+	return &Extraction{Text: strings.ToValidUTF8(string(data), string(utf8.RuneError))}, nil
+}
+
+// extractMarkdown keeps the Markdown source and collects its ATX headings.
+// The first level-1 heading becomes the title.
+func extractMarkdown(data []byte) (*Extraction, error) {
+	extraction, err := extractPlainText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	inFence := false
+	scanner := bufio.NewScanner(strings.NewReader(extraction.Text))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		level, heading := ParseHeading(line)
+		if level == 0 {
+			continue
+		}
+		extraction.Headings = append(extraction.Headings, heading)
+		if level == 1 && extraction.Title == "" {
+			extraction.Title = heading
+		}
+	}
+
+	return extraction, nil
+}
+
+// ParseHeading parses a Markdown ATX heading line such as "## Usage".
+// It returns the heading level and text, or level 0 if the line is not a heading.
+func ParseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0, ""
+	}
+
+	heading := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	if heading == "" {
+		return 0, ""
+	}
+	return level, heading
+}
+
+// headingLine formats a heading as a Markdown ATX line
+func headingLine(level int, text string) string {
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	return strings.Repeat("#", level) + " " + text
+}
@@ -0,0 +1,387 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseHeading(t *testing.T) {
+	tests := []struct {
+		line          string
+		expectedLevel int
+		expectedText  string
+	}{
+		{"# Title", 1, "Title"},
+		{"### Deep heading ###", 3, "Deep heading"},
+		{"#NoSpace", 0, ""},
+		{"####### Too deep", 0, ""},
+		{"#", 0, ""},
+		{"plain text", 0, ""},
+	}
+
+	for _, test := range tests {
+		level, text := ParseHeading(test.line)
+		if level != test.expectedLevel || text != test.expectedText {
+			t.Errorf("ParseHeading(%q): expected (%d, %q), got (%d, %q)",
+				test.line, test.expectedLevel, test.expectedText, level, text)
+		}
+	}
+}
+
+func TestExtractPlainText(t *testing.T) {
+	t.Run("UTF8", func(t *testing.T) {
+		source := "日本語のテキスト\n"
+		extraction, err := extractPlainText([]byte(source))
+		if err != nil {
+			t.Fatalf("extractPlainText failed: %v", err)
+		}
+		if extraction.Text != source {
+			t.Errorf("Expected text to be kept as-is, got %q", extraction.Text)
+		}
+	})
+
+	t.Run("InvalidUTF8IsReplaced", func(t *testing.T) {
+		// "Hello " followed by 日本 in Shift_JIS
+		source := []byte("Hello \x93\xfa\x96\x7b")
+		extraction, err := extractPlainText(source)
+		if err != nil {
+			t.Fatalf("Expected non-UTF-8 text to be accepted, got %v", err)
+		}
+		if !utf8.ValidString(extraction.Text) {
+			t.Errorf("Expected valid UTF-8, got %q", extraction.Text)
+		}
+		if !strings.HasPrefix(extraction.Text, "Hello ") || !strings.ContainsRune(extraction.Text, utf8.RuneError) {
+			t.Errorf("Expected readable text with U+FFFD replacements, got %q", extraction.Text)
+		}
+	})
+}
+
+func TestExtractMarkdown(t *testing.T) {
+	source := "# Guide\n\nIntro text.\n\n## Install\n\n```sh\n# not a heading\n```\n\n## Usage\n"
+
+	extraction, err := extractMarkdown([]byte(source))
+	if err != nil {
+		t.Fatalf("extractMarkdown failed: %v", err)
+	}
+
+	if extraction.Text != source {
+		t.Error("Expected Markdown source to be kept as-is")
+	}
+	if extraction.Title != "Guide" {
+		t.Errorf("Expected title 'Guide', got '%s'", extraction.Title)
+	}
+	expected := []string{"Guide", "Install", "Usage"}
+	if strings.Join(extraction.Headings, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected headings %v, got %v", expected, extraction.Headings)
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	source := `<!DOCTYPE html>
+<html>
+<head><title>Go &amp; RAG</title><style>body { color: red; }</style></head>
+<body>
+<h1>Overview</h1>
+<p>Go is   a <b>compiled</b> language.</p>
+<script>var x = "<p>hidden</p>";</script>
+<!-- comment -->
+<h2>Concurrency</h2>
+<ul><li>goroutines</li><li>channels</li></ul>
+</body>
+</html>`
+
+	extraction, err := extractHTML([]byte(source))
+	if err != nil {
+		t.Fatalf("extractHTML failed: %v", err)
+	}
+
+	if extraction.Title != "Go & RAG" {
+		t.Errorf("Expected title 'Go & RAG', got '%s'", extraction.Title)
+	}
+	if strings.Join(extraction.Headings, ",") != "Overview,Concurrency" {
+		t.Errorf("Expected headings [Overview Concurrency], got %v", extraction.Headings)
+	}
+
+	for _, want := range []string{"# Overview", "Go is a compiled language.", "## Concurrency", "goroutines\nchannels"} {
+		if !strings.Contains(extraction.Text, want) {
+			t.Errorf("Expected text to contain %q, got:\n%s", want, extraction.Text)
+		}
+	}
+	for _, unwanted := range []string{"hidden", "color: red", "comment", "<"} {
+		if strings.Contains(extraction.Text, unwanted) {
+			t.Errorf("Expected text not to contain %q, got:\n%s", unwanted, extraction.Text)
+		}
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	t.Run("ValidDocument", func(t *testing.T) {
+		data := buildDOCX(t, map[string]string{
+			"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Style Title</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Introduction</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Hello </w:t></w:r><w:r><w:t>world.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Details</w:t></w:r></w:p>
+<w:p><w:r><w:t>Line one</w:t><w:br/><w:t>Line two</w:t></w:r></w:p>
+</w:body>
+</w:document>`,
+			"docProps/core.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:title>Core Title</dc:title>
+</cp:coreProperties>`,
+		})
+
+		extraction, err := extractDOCX(data)
+		if err != nil {
+			t.Fatalf("extractDOCX failed: %v", err)
+		}
+
+		if extraction.Title != "Core Title" {
+			t.Errorf("Expected title 'Core Title', got '%s'", extraction.Title)
+		}
+		if strings.Join(extraction.Headings, ",") != "Introduction,Details" {
+			t.Errorf("Expected headings [Introduction Details], got %v", extraction.Headings)
+		}
+
+		expected := "Style Title\n\n# Introduction\n\nHello world.\n\n## Details\n\nLine one\nLine two"
+		if extraction.Text != expected {
+			t.Errorf("Expected text:\n%s\ngot:\n%s", expected, extraction.Text)
+		}
+	})
+
+	t.Run("TitleStyleFallback", func(t *testing.T) {
+		data := buildDOCX(t, map[string]string{
+			"word/document.xml": `<w:document xmlns:w="w"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Style Title</w:t></w:r></w:p>
+</w:body></w:document>`,
+		})
+
+		extraction, err := extractDOCX(data)
+		if err != nil {
+			t.Fatalf("extractDOCX failed: %v", err)
+		}
+		if extraction.Title != "Style Title" {
+			t.Errorf("Expected title 'Style Title', got '%s'", extraction.Title)
+		}
+	})
+
+	t.Run("NotAnArchive", func(t *testing.T) {
+		if _, err := extractDOCX([]byte("plain text")); err == nil {
+			t.Error("Expected error for non-zip data")
+		}
+	})
+
+	t.Run("MissingBody", func(t *testing.T) {
+		data := buildDOCX(t, map[string]string{"other.xml": "<x/>"})
+		if _, err := extractDOCX(data); err == nil {
+			t.Error("Expected error for archive without word/document.xml")
+		}
+	})
+}
+
+func TestExtractPDF(t *testing.T) {
+	t.Run("FlateContent", func(t *testing.T) {
+		content := "BT /F1 12 Tf 72 720 Td (Hello PDF) Tj 0 -14 Td [(Second) -300 (line)] TJ T* (Escaped \\(paren\\)) Tj ET"
+		data := buildPDF(t, content, true, "(Sample Title)")
+
+		extraction, err := extractPDF(data)
+		if err != nil {
+			t.Fatalf("extractPDF failed: %v", err)
+		}
+
+		if extraction.Title != "Sample Title" {
+			t.Errorf("Expected title 'Sample Title', got '%s'", extraction.Title)
+		}
+		expected := "Hello PDF\nSecond line\nEscaped (paren)"
+		if extraction.Text != expected {
+			t.Errorf("Expected text:\n%s\ngot:\n%s", expected, extraction.Text)
+		}
+	})
+
+	t.Run("UncompressedHexAndUTF16Title", func(t *testing.T) {
+		content := "BT <48656C6C6F> Tj ( world) Tj ET"
+		data := buildPDF(t, content, false, "<FEFF00540069>")
+
+		extraction, err := extractPDF(data)
+		if err != nil {
+			t.Fatalf("extractPDF failed: %v", err)
+		}
+
+		if extraction.Title != "Ti" {
+			t.Errorf("Expected title 'Ti', got '%s'", extraction.Title)
+		}
+		if extraction.Text != "Hello world" {
+			t.Errorf("Expected text 'Hello world', got '%s'", extraction.Text)
+		}
+	})
+
+	t.Run("NotAPDF", func(t *testing.T) {
+		if _, err := extractPDF([]byte("hello")); err == nil {
+			t.Error("Expected error for non-PDF data")
+		}
+	})
+
+	t.Run("NoText", func(t *testing.T) {
+		data := buildPDF(t, "0 0 m 10 10 l S", false, "")
+		if _, err := extractPDF(data); err == nil {
+			t.Error("Expected error for PDF without text")
+		}
+	})
+}
+
+func TestReaderFormats(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "extractor_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Run("SetSupportedFormats", func(t *testing.T) {
+		reader := NewReader(512, 50)
+		if err := reader.SetSupportedFormats([]string{"txt", ".MD"}); err != nil {
+			t.Fatalf("SetSupportedFormats failed: %v", err)
+		}
+
+		if !reader.IsSupported("notes.md") || !reader.IsSupported("notes.txt") {
+			t.Error("Expected txt and md to be supported")
+		}
+		if reader.IsSupported("notes.pdf") {
+			t.Error("Expected pdf not to be supported")
+		}
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		reader := NewReader(512, 50)
+		if err := reader.SetSupportedFormats([]string{"txt", "xlsx"}); err == nil {
+			t.Error("Expected error for format without extractor")
+		}
+		if !reader.IsSupported("report.pdf") {
+			t.Error("Failed SetSupportedFormats should keep previous formats")
+		}
+	})
+
+	t.Run("RegisterExtractor", func(t *testing.T) {
+		reader := NewReader(512, 50)
+		reader.RegisterExtractor(".csv", ExtractorFunc(func(data []byte) (*Extraction, error) {
+			return &Extraction{
+				Text:  strings.ReplaceAll(string(data), ",", " "),
+				Title: "Table",
+			}, nil
+		}))
+
+		path := filepath.Join(tempDir, "table.csv")
+		if err := os.WriteFile(path, []byte("a,b,c"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		if !reader.IsSupported(path) {
+			t.Fatal("Expected csv to be supported after RegisterExtractor")
+		}
+		doc, err := reader.ReadDocument(path)
+		if err != nil {
+			t.Fatalf("ReadDocument failed: %v", err)
+		}
+		if doc.Content != "a b c" {
+			t.Errorf("Expected content 'a b c', got '%s'", doc.Content)
+		}
+		if doc.Metadata["title"] != "Table" {
+			t.Errorf("Expected metadata title 'Table', got '%s'", doc.Metadata["title"])
+		}
+	})
+
+	t.Run("HTMLMetadata", func(t *testing.T) {
+		reader := NewReader(512, 50)
+		path := filepath.Join(tempDir, "page.html")
+		html := "<html><head><title>Page</title></head><body><h1>One</h1><p>x</p><h2>Two</h2></body></html>"
+		if err := os.WriteFile(path, []byte(html), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		doc, err := reader.ReadDocument(path)
+		if err != nil {
+			t.Fatalf("ReadDocument failed: %v", err)
+		}
+		if doc.Metadata["title"] != "Page" {
+			t.Errorf("Expected metadata title 'Page', got '%s'", doc.Metadata["title"])
+		}
+		if doc.Metadata["headings"] != "One\nTwo" {
+			t.Errorf("Expected metadata headings 'One\\nTwo', got '%s'", doc.Metadata["headings"])
+		}
+		if strings.Contains(doc.Content, "<") {
+			t.Errorf("Expected tags to be stripped, got '%s'", doc.Content)
+		}
+	})
+
+	t.Run("ExtractionError", func(t *testing.T) {
+		reader := NewReader(512, 50)
+		path := filepath.Join(tempDir, "broken.pdf")
+		if err := os.WriteFile(path, []byte("not a pdf"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if _, err := reader.ReadDocument(path); err == nil {
+			t.Error("Expected error for broken PDF")
+		}
+	})
+}
+
+// buildDOCX creates an in-memory zip archive with the given files
+func buildDOCX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF creates a minimal single-page PDF with one content stream
+func buildPDF(t *testing.T, content string, compress bool, title string) []byte {
+	t.Helper()
+
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(stream); err != nil {
+			t.Fatalf("Failed to compress stream: %v", err)
+		}
+		w.Close()
+		stream = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter)
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n")
+	if title != "" {
+		fmt.Fprintf(&pdf, "5 0 obj\n<< /Title %s >>\nendobj\n", title)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Info 5 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
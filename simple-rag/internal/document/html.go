@@ -0,0 +1,179 @@
+package document
+
+import (
+	"html"
+	"strings"
+)
+
+// blockTags are HTML elements that start a new line of text
+var blockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "td": true, "th": true,
+	"ul": true, "ol": true, "table": true, "section": true, "article": true,
+	"header": true, "footer": true, "nav": true, "aside": true, "main": true,
+	"blockquote": true, "pre": true, "hr": true, "dt": true, "dd": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// skippedTags are HTML elements whose content is never shown as text
+var skippedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+// extractHTML strips tags from an HTML page and keeps its headings.
+// Headings are written as Markdown "#" lines and <title> becomes the title.
+func extractHTML(data []byte) (*Extraction, error) {
+	extraction := &Extraction{}
+	src := string(data)
+
+	var (
+		text         strings.Builder
+		heading      strings.Builder
+		title        strings.Builder
+		headingLevel int
+		inTitle      bool
+		inPre        bool
+	)
+
+	newline := func() {
+		s := text.String()
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			end := strings.IndexByte(src[i:], '<')
+			if end < 0 {
+				end = len(src) - i
+			}
+			chunk := html.UnescapeString(src[i : i+end])
+			i += end
+
+			switch {
+			case inTitle:
+				title.WriteString(chunk)
+			case headingLevel > 0:
+				heading.WriteString(chunk)
+			case inPre:
+				text.WriteString(chunk)
+			default:
+				writeCollapsed(&text, chunk)
+			}
+			continue
+		}
+
+		// Comments and doctype
+		if strings.HasPrefix(src[i:], "<!--") {
+			end := strings.Index(src[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		end := strings.IndexByte(src[i:], '>')
+		if end < 0 {
+			break
+		}
+		name, closing := tagName(src[i+1 : i+end])
+		i += end + 1
+
+		if skippedTags[name] && !closing {
+			closeTag := strings.Index(strings.ToLower(src[i:]), "</"+name)
+			if closeTag < 0 {
+				break
+			}
+			i += closeTag
+			continue
+		}
+
+		switch {
+		case name == "title":
+			inTitle = !closing
+		case name == "pre":
+			inPre = !closing
+			newline()
+		case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+			level := int(name[1] - '0')
+			if !closing {
+				headingLevel = level
+				heading.Reset()
+				newline()
+				continue
+			}
+			if headingLevel == 0 {
+				continue
+			}
+			headingText := strings.Join(strings.Fields(heading.String()), " ")
+			if headingText != "" {
+				extraction.Headings = append(extraction.Headings, headingText)
+				text.WriteString(headingLine(headingLevel, headingText))
+				text.WriteString("\n")
+			}
+			headingLevel = 0
+		case blockTags[name]:
+			newline()
+		}
+	}
+
+	extraction.Title = strings.Join(strings.Fields(title.String()), " ")
+	if extraction.Title == "" && len(extraction.Headings) > 0 {
+		extraction.Title = extraction.Headings[0]
+	}
+	extraction.Text = cleanLines(text.String())
+
+	return extraction, nil
+}
+
+// tagName returns the lower-cased element name of a tag body and whether it is a closing tag
+func tagName(tag string) (string, bool) {
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+	end := strings.IndexAny(tag, " \t\r\n/")
+	if end >= 0 {
+		tag = tag[:end]
+	}
+	return strings.ToLower(tag), closing
+}
+
+// writeCollapsed writes text with runs of whitespace collapsed to a single space
+func writeCollapsed(b *strings.Builder, s string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" && b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") && !strings.HasSuffix(b.String(), " ") {
+			b.WriteString(" ")
+		}
+		return
+	}
+
+	current := b.String()
+	if len(current) > 0 && !strings.HasSuffix(current, "\n") && !strings.HasSuffix(current, " ") &&
+		strings.TrimLeft(s, " \t\r\n") != s {
+		b.WriteString(" ")
+	}
+	b.WriteString(strings.Join(fields, " "))
+	if strings.TrimRight(s, " \t\r\n") != s {
+		b.WriteString(" ")
+	}
+}
+
+// cleanLines trims every line and drops repeated blank lines
+func cleanLines(s string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.TrimSpace(line) == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,471 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// extractPDF pulls the text out of a PDF file.
+// It decodes uncompressed and Flate-compressed content streams and reads the
+// text-showing operators (Tj, TJ, ' and "). Strings are decoded as Latin-1 or
+// UTF-16BE, so text drawn with CID fonts that need a ToUnicode map comes out
+// garbled; such files should be converted to text before ingesting.
+func extractPDF(data []byte) (*Extraction, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+
+	extraction := &Extraction{Title: pdfTitle(data)}
+
+	var pages []string
+	for _, stream := range pdfStreams(data) {
+		if extraction.Title == "" {
+			extraction.Title = pdfTitle(stream)
+		}
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+		if text := strings.TrimSpace(pdfContentText(stream)); text != "" {
+			pages = append(pages, text)
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no extractable text found in PDF")
+	}
+
+	extraction.Text = cleanLines(strings.Join(pages, "\n\n"))
+	return extraction, nil
+}
+
+// pdfStreams returns the decoded content of every stream object that may hold text
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+
+	offset := 0
+	for {
+		idx := bytes.Index(data[offset:], []byte("stream"))
+		if idx < 0 {
+			break
+		}
+		start := offset + idx
+		offset = start + len("stream")
+
+		// Skip "endstream" and anything that is not the stream keyword
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		bodyStart := offset
+		if bodyStart < len(data) && data[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart >= len(data) || data[bodyStart] != '\n' {
+			continue
+		}
+		bodyStart++
+
+		end := bytes.Index(data[bodyStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		offset = bodyStart + end + len("endstream")
+
+		dict := pdfStreamDict(data[:start])
+		if pdfSkipStream(dict) {
+			continue
+		}
+
+		// Prefer the declared length; binary data may itself end in EOL bytes
+		var body []byte
+		if length, ok := pdfStreamLength(dict); ok && length <= end {
+			body = data[bodyStart : bodyStart+length]
+		} else {
+			body = trimEOL(data[bodyStart : bodyStart+end])
+		}
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			decoded, err := inflate(body)
+			if err != nil {
+				continue
+			}
+			body = decoded
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Other filters (DCT, LZW, ...) never carry extractable text here
+			continue
+		}
+
+		streams = append(streams, body)
+	}
+
+	return streams
+}
+
+// pdfStreamDict returns the dictionary of the object that precedes a stream keyword
+func pdfStreamDict(before []byte) []byte {
+	idx := bytes.LastIndex(before, []byte("obj"))
+	if idx < 0 {
+		return nil
+	}
+	return before[idx:]
+}
+
+// pdfStreamLength returns a direct /Length value from a stream dictionary.
+// Indirect lengths ("12 0 R") are reported as missing.
+func pdfStreamLength(dict []byte) (int, bool) {
+	idx := bytes.Index(dict, []byte("/Length"))
+	if idx < 0 {
+		return 0, false
+	}
+	fields := pdfDictFields(dict[idx+len("/Length"):])
+	if len(fields) == 0 {
+		return 0, false
+	}
+	if len(fields) >= 3 && fields[2] == "R" {
+		return 0, false
+	}
+	length, err := strconv.Atoi(fields[0])
+	if err != nil || length < 0 {
+		return 0, false
+	}
+	return length, true
+}
+
+// trimEOL removes a single trailing end-of-line marker
+func trimEOL(data []byte) []byte {
+	if bytes.HasSuffix(data, []byte("\r\n")) {
+		return data[:len(data)-2]
+	}
+	if bytes.HasSuffix(data, []byte("\n")) || bytes.HasSuffix(data, []byte("\r")) {
+		return data[:len(data)-1]
+	}
+	return data
+}
+
+// pdfSkipStream reports whether a stream holds binary data such as images or fonts
+func pdfSkipStream(dict []byte) bool {
+	fields := pdfDictFields(dict)
+	for i, field := range fields {
+		switch field {
+		case "/Length1", "/Length2", "/Length3":
+			return true
+		case "/Subtype", "/Type":
+			if i+1 < len(fields) {
+				switch fields[i+1] {
+				case "/Image", "/XRef", "/Type1C", "/CIDFontType0C", "/OpenType":
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pdfDictFields splits dictionary text into names, numbers and brackets
+func pdfDictFields(dict []byte) []string {
+	return strings.Fields(strings.NewReplacer("/", " /", "<<", " << ", ">>", " >> ", "[", " [ ", "]", " ] ").Replace(string(dict)))
+}
+
+// inflate decompresses a zlib stream, keeping whatever was read before a corrupt tail
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil && len(decoded) == 0 {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// pdfTitle returns the /Title entry of an Info dictionary, if present
+func pdfTitle(data []byte) string {
+	idx := bytes.Index(data, []byte("/Title"))
+	for idx >= 0 {
+		rest := bytes.TrimLeft(data[idx+len("/Title"):], " \r\n\t")
+		if len(rest) > 0 && (rest[0] == '(' || rest[0] == '<') && !bytes.HasPrefix(rest, []byte("<<")) {
+			var value []byte
+			if rest[0] == '(' {
+				value, _ = pdfLiteralString(rest)
+			} else {
+				value, _ = pdfHexString(rest)
+			}
+			if title := strings.TrimSpace(pdfDecodeText(value)); title != "" {
+				return title
+			}
+		}
+		next := bytes.Index(data[idx+1:], []byte("/Title"))
+		if next < 0 {
+			break
+		}
+		idx += 1 + next
+	}
+	return ""
+}
+
+// pdfContentText interprets the text operators of a content stream
+func pdfContentText(content []byte) string {
+	var (
+		text     strings.Builder
+		operands []interface{}
+	)
+
+	newline := func() {
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			value, n := pdfLiteralString(content[i:])
+			operands = append(operands, value)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			// Inline dictionaries (marked content properties) carry no text
+			end := bytes.Index(content[i:], []byte(">>"))
+			if end < 0 {
+				return text.String()
+			}
+			i += end + 2
+		case c == '<':
+			value, n := pdfHexString(content[i:])
+			operands = append(operands, value)
+			i += n
+		case c == '[':
+			operands = append(operands, '[')
+			i++
+		case c == ']':
+			// Collapse everything since the matching '[' into one array operand
+			var array []interface{}
+			for j := len(operands) - 1; j >= 0; j-- {
+				if marker, ok := operands[j].(rune); ok && marker == '[' {
+					array = append(array, operands[j+1:]...)
+					operands = append(operands[:j], array)
+					break
+				}
+			}
+			i++
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			token := string(content[start:i])
+			if isPDFOperand(token) {
+				operands = append(operands, token)
+				continue
+			}
+
+			switch token {
+			case "Tj":
+				writePDFStrings(&text, operands)
+			case "'", "\"":
+				newline()
+				writePDFStrings(&text, operands)
+			case "TJ":
+				writePDFStrings(&text, operands)
+			case "T*", "ET":
+				newline()
+			case "Td", "TD":
+				if len(operands) >= 2 {
+					if ty, ok := operands[len(operands)-1].(string); ok && ty != "0" && ty != "-0" {
+						newline()
+					} else if text.Len() > 0 && !strings.HasSuffix(text.String(), " ") && !strings.HasSuffix(text.String(), "\n") {
+						text.WriteString(" ")
+					}
+				}
+			case "BI":
+				// Skip inline image data
+				end := bytes.Index(content[i:], []byte("EI"))
+				if end < 0 {
+					return text.String()
+				}
+				i += end + 2
+			}
+			operands = operands[:0]
+		}
+	}
+
+	return text.String()
+}
+
+// writePDFStrings writes the string operands of a text-showing operator.
+// Large negative kerning inside a TJ array is treated as a word gap.
+func writePDFStrings(text *strings.Builder, operands []interface{}) {
+	for _, operand := range operands {
+		switch v := operand.(type) {
+		case []byte:
+			text.WriteString(pdfDecodeText(v))
+		case []interface{}:
+			for _, item := range v {
+				switch iv := item.(type) {
+				case []byte:
+					text.WriteString(pdfDecodeText(iv))
+				case string:
+					if strings.HasPrefix(iv, "-") && len(iv) > 3 {
+						text.WriteString(" ")
+					}
+				}
+			}
+		}
+	}
+}
+
+// pdfLiteralString parses a (...) string and returns its bytes and the length consumed
+func pdfLiteralString(data []byte) ([]byte, int) {
+	var out []byte
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, i + 1
+			}
+			out = append(out, c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				return out, i
+			}
+			switch e := data[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					j := 0
+					for ; j < 3 && i+j < len(data) && data[i+j] >= '0' && data[i+j] <= '7'; j++ {
+						value = value*8 + int(data[i+j]-'0')
+					}
+					out = append(out, byte(value))
+					i += j - 1
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, len(data)
+}
+
+// pdfHexString parses a <...> string and returns its bytes and the length consumed
+func pdfHexString(data []byte) ([]byte, int) {
+	end := bytes.IndexByte(data, '>')
+	if end < 0 {
+		return nil, len(data)
+	}
+
+	var digits []byte
+	for _, c := range data[1:end] {
+		if isHexDigit(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		out[i] = hexValue(digits[2*i])<<4 | hexValue(digits[2*i+1])
+	}
+	return out, end + 1
+}
+
+// pdfDecodeText decodes a PDF string as UTF-16BE (with BOM) or Latin-1
+func pdfDecodeText(value []byte) string {
+	if len(value) >= 2 && value[0] == 0xFE && value[1] == 0xFF {
+		units := make([]uint16, 0, (len(value)-2)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			units = append(units, uint16(value[i])<<8|uint16(value[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, 0, len(value))
+	for _, b := range value {
+		if b < 0x20 && b != '\n' && b != '\t' {
+			continue
+		}
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// isPDFOperand reports whether a bare token is a number, name or keyword operand
+func isPDFOperand(token string) bool {
+	if token == "true" || token == "false" || token == "null" {
+		return true
+	}
+	for _, c := range token {
+		if (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
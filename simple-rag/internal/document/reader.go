@@ -15,14 +15,61 @@ import (
 type Reader struct {
 	chunkSize    int
 	chunkOverlap int
+	extractors   map[string]Extractor
+	formats      map[string]bool
+	chunker      Chunker
 }
 
-// NewReader creates a new document reader
+// NewReader creates a new document reader that accepts every built-in format
 func NewReader(chunkSize, chunkOverlap int) *Reader {
-	return &Reader{
+	r := &Reader{
 		chunkSize:    chunkSize,
 		chunkOverlap: chunkOverlap,
+		extractors:   defaultExtractors(),
+		formats:      make(map[string]bool),
+		chunker: &FixedSizeChunker{
+			ChunkSize:    chunkSize,
+			ChunkOverlap: chunkOverlap,
+			Sizer:        RuneSizer{},
+		},
 	}
+	for format := range r.extractors {
+		r.formats[format] = true
+	}
+	return r
+}
+
+// SetChunker replaces the strategy used by ChunkDocument
+func (r *Reader) SetChunker(chunker Chunker) {
+	r.chunker = chunker
+}
+
+// RegisterExtractor sets the extractor used for a file type and accepts that type
+func (r *Reader) RegisterExtractor(fileType string, extractor Extractor) {
+	fileType = normalizeFormat(fileType)
+	r.extractors[fileType] = extractor
+	r.formats[fileType] = true
+}
+
+// SetSupportedFormats limits the accepted file types to the given list.
+// Every format must have an extractor registered for it.
+func (r *Reader) SetSupportedFormats(formats []string) error {
+	accepted := make(map[string]bool, len(formats))
+	for _, format := range formats {
+		format = normalizeFormat(format)
+		if _, ok := r.extractors[format]; !ok {
+			return fmt.Errorf("no extractor for file type: %s", format)
+		}
+		accepted[format] = true
+	}
+
+	r.formats = accepted
+	return nil
+}
+
+// IsSupported checks if the file type is accepted by this reader
+func (r *Reader) IsSupported(filePath string) bool {
+	return r.formats[fileType(filePath)]
 }
 
 // ReadDocument reads a document from file path and returns a Document
@@ -44,16 +91,22 @@ func (r *Reader) ReadDocument(filePath string) (*types.Document, error) {
 
 	// Extract file info
 	fileName := filepath.Base(filePath)
-	fileExt := strings.ToLower(filepath.Ext(filePath))
-	if fileExt != "" {
-		fileExt = fileExt[1:] // Remove the dot
+	fileExt := fileType(filePath)
+
+	// Extract text, falling back to the raw content for unknown types
+	extraction := &Extraction{Text: string(content)}
+	if extractor, ok := r.extractors[fileExt]; ok {
+		extraction, err = extractor.Extract(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s text: %w", fileExt, err)
+		}
 	}
 
 	// Create document
 	doc := &types.Document{
 		ID:          generateDocumentID(filePath, hash),
 		Title:       fileName,
-		Content:     string(content),
+		Content:     extraction.Text,
 		FilePath:    filePath,
 		FileType:    fileExt,
 		FileSize:    fileInfo.Size(),
@@ -67,64 +120,19 @@ func (r *Reader) ReadDocument(filePath string) (*types.Document, error) {
 	doc.Metadata["original_name"] = fileName
 	doc.Metadata["file_extension"] = fileExt
 	doc.Metadata["content_length"] = fmt.Sprintf("%d", len(doc.Content))
+	if extraction.Title != "" {
+		doc.Metadata["title"] = extraction.Title
+	}
+	if len(extraction.Headings) > 0 {
+		doc.Metadata["headings"] = strings.Join(extraction.Headings, "\n")
+	}
 
 	return doc, nil
 }
 
-// ChunkDocument splits a document into chunks
+// ChunkDocument splits a document into chunks using the configured strategy
 func (r *Reader) ChunkDocument(doc *types.Document) ([]*types.DocumentChunk, error) {
-	content := doc.Content
-	if len(content) == 0 {
-		return nil, fmt.Errorf("document content is empty")
-	}
-
-	var chunks []*types.DocumentChunk
-	chunkIndex := 0
-	startPos := 0
-
-	for startPos < len(content) {
-		endPos := startPos + r.chunkSize
-		if endPos > len(content) {
-			endPos = len(content)
-		}
-
-		// Try to break at word boundary
-		if endPos < len(content) {
-			for i := endPos; i > startPos; i-- {
-				if content[i] == ' ' || content[i] == '\n' || content[i] == '\t' {
-					endPos = i
-					break
-				}
-			}
-		}
-
-		chunkContent := content[startPos:endPos]
-		chunkContent = strings.TrimSpace(chunkContent)
-
-		if len(chunkContent) > 0 {
-			chunk := &types.DocumentChunk{
-				ID:         generateChunkID(doc.ID, chunkIndex),
-				DocumentID: doc.ID,
-				ChunkIndex: chunkIndex,
-				Content:    chunkContent,
-				StartPos:   startPos,
-				EndPos:     endPos,
-				Embedding:  nil, // Will be set later
-				CreatedAt:  time.Now(),
-			}
-			chunks = append(chunks, chunk)
-			chunkIndex++
-		}
-
-		// Move to next chunk with overlap
-		nextStart := endPos - r.chunkOverlap
-		if nextStart <= startPos {
-			nextStart = startPos + 1
-		}
-		startPos = nextStart
-	}
-
-	return chunks, nil
+	return r.chunker.Chunk(doc)
 }
 
 // generateDocumentID generates a unique ID for a document
@@ -137,13 +145,18 @@ func generateChunkID(documentID string, chunkIndex int) string {
 	return fmt.Sprintf("%s_chunk_%d", documentID, chunkIndex)
 }
 
-// IsSupported checks if the file type is supported
+// IsSupported checks if the file type has a built-in extractor
 func IsSupported(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	supportedExts := map[string]bool{
-		".txt":  true,
-		".md":   true,
-		".text": true,
-	}
-	return supportedExts[ext]
+	_, ok := defaultExtractors()[fileType(filePath)]
+	return ok
+}
+
+// fileType returns the lower-cased extension of a path without the dot
+func fileType(filePath string) string {
+	return normalizeFormat(filepath.Ext(filePath))
+}
+
+// normalizeFormat turns ".MD" or "md" into "md"
+func normalizeFormat(format string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(format)), ".")
 }
@@ -322,9 +322,11 @@ func TestIsSupported(t *testing.T) {
 		{"test.text", true},
 		{"test.TXT", true}, // Case insensitive
 		{"test.MD", true},
-		{"test.pdf", false},
-		{"test.docx", false},
-		{"test.html", false},
+		{"test.pdf", true},
+		{"test.docx", true},
+		{"test.html", true},
+		{"test.xlsx", false},
+		{"test.png", false},
 		{"test", false}, // No extension
 		{"", false},     // Empty path
 	}
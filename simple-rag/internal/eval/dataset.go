@@ -0,0 +1,125 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Question is one golden question.
+// ExpectedDocuments may hold document IDs or file paths; a path matches a
+// stored document whose path equals it or ends with it. ExpectedChunks holds
+// chunk IDs. ReferenceAnswer is optional and enables answer metrics.
+type Question struct {
+	ID                string   `yaml:"id" json:"id"`
+	Question          string   `yaml:"question" json:"question"`
+	ExpectedDocuments []string `yaml:"expected_documents" json:"expected_documents"`
+	ExpectedChunks    []string `yaml:"expected_chunks" json:"expected_chunks"`
+	ReferenceAnswer   string   `yaml:"reference_answer" json:"reference_answer"`
+}
+
+// Dataset is a set of golden questions
+type Dataset struct {
+	Questions []Question `yaml:"questions" json:"questions"`
+}
+
+// LoadDataset reads questions from a YAML file (.yaml/.yml) or a JSON
+// lines file (.jsonl) with one question per line
+func LoadDataset(path string) (*Dataset, error) {
+	var (
+		dataset *Dataset
+		err     error
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dataset, err = loadYAML(path)
+	case ".jsonl":
+		dataset, err = loadJSONL(path)
+	default:
+		return nil, fmt.Errorf("unsupported dataset format: %s (use .yaml, .yml or .jsonl)", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataset.validate(); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// loadYAML accepts either a questions: list or a bare list
+func loadYAML(path string) (*Dataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var dataset Dataset
+	if err := yaml.Unmarshal(data, &dataset); err != nil {
+		var questions []Question
+		if listErr := yaml.Unmarshal(data, &questions); listErr != nil {
+			return nil, fmt.Errorf("failed to parse dataset: %w", err)
+		}
+		dataset.Questions = questions
+	}
+
+	return &dataset, nil
+}
+
+// loadJSONL reads one JSON question per non-empty line
+func loadJSONL(path string) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	defer file.Close()
+
+	dataset := &Dataset{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var question Question
+		if err := json.Unmarshal([]byte(line), &question); err != nil {
+			return nil, fmt.Errorf("failed to parse dataset line %d: %w", lineNo, err)
+		}
+		dataset.Questions = append(dataset.Questions, question)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	return dataset, nil
+}
+
+// validate checks every question has text and expectations, and fills in IDs
+func (d *Dataset) validate() error {
+	if len(d.Questions) == 0 {
+		return fmt.Errorf("dataset has no questions")
+	}
+
+	for i := range d.Questions {
+		q := &d.Questions[i]
+		if q.ID == "" {
+			q.ID = fmt.Sprintf("q%d", i+1)
+		}
+		if strings.TrimSpace(q.Question) == "" {
+			return fmt.Errorf("question %s: empty question", q.ID)
+		}
+		if len(q.ExpectedDocuments) == 0 && len(q.ExpectedChunks) == 0 {
+			return fmt.Errorf("question %s: no expected_documents or expected_chunks", q.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDataset(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "eval_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	t.Run("YAML", func(t *testing.T) {
+		path := write("golden.yaml", `
+questions:
+  - id: q-auth
+    question: When do tokens expire?
+    expected_documents: [docs/api/auth.md]
+    reference_answer: After one hour.
+  - question: What is a chunk?
+    expected_chunks: [doc_1_chunk_0, doc_1_chunk_1]
+`)
+		dataset, err := LoadDataset(path)
+		if err != nil {
+			t.Fatalf("LoadDataset failed: %v", err)
+		}
+		if len(dataset.Questions) != 2 {
+			t.Fatalf("Expected 2 questions, got %d", len(dataset.Questions))
+		}
+		if dataset.Questions[0].ReferenceAnswer != "After one hour." {
+			t.Errorf("Expected reference answer, got %q", dataset.Questions[0].ReferenceAnswer)
+		}
+		if dataset.Questions[1].ID != "q2" {
+			t.Errorf("Expected generated ID q2, got %s", dataset.Questions[1].ID)
+		}
+		if len(dataset.Questions[1].ExpectedChunks) != 2 {
+			t.Errorf("Expected 2 expected chunks, got %d", len(dataset.Questions[1].ExpectedChunks))
+		}
+	})
+
+	t.Run("YAMLList", func(t *testing.T) {
+		path := write("list.yml", `
+- question: What is Go?
+  expected_documents: [go.md]
+`)
+		dataset, err := LoadDataset(path)
+		if err != nil {
+			t.Fatalf("LoadDataset failed: %v", err)
+		}
+		if len(dataset.Questions) != 1 {
+			t.Errorf("Expected 1 question, got %d", len(dataset.Questions))
+		}
+	})
+
+	t.Run("JSONL", func(t *testing.T) {
+		path := write("golden.jsonl", `{"id": "a", "question": "Goとは？", "expected_documents": ["go.md"]}
+
+{"id": "b", "question": "MLとは？", "expected_documents": ["ml.md"], "reference_answer": "機械学習"}
+`)
+		dataset, err := LoadDataset(path)
+		if err != nil {
+			t.Fatalf("LoadDataset failed: %v", err)
+		}
+		if len(dataset.Questions) != 2 {
+			t.Fatalf("Expected 2 questions, got %d", len(dataset.Questions))
+		}
+		if dataset.Questions[1].ReferenceAnswer != "機械学習" {
+			t.Errorf("Expected reference answer, got %q", dataset.Questions[1].ReferenceAnswer)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := map[string]string{
+			"bad.jsonl":       `{"question": `,
+			"empty.yaml":      `questions: []`,
+			"noexpect.yaml":   "questions:\n  - question: What?\n",
+			"noquestion.yaml": "questions:\n  - expected_documents: [a.md]\n",
+			"golden.txt":      `question`,
+		}
+		for name, content := range invalid {
+			if _, err := LoadDataset(write(name, content)); err == nil {
+				t.Errorf("%s: expected error, got nil", name)
+			}
+		}
+		if _, err := LoadDataset(filepath.Join(tempDir, "missing.yaml")); err == nil {
+			t.Error("Expected error for missing file, got nil")
+		}
+	})
+}
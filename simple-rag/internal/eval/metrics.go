@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"simple-rag/pkg/types"
+)
+
+// citationMarker matches bracketed citations, which are ignored for answer overlap
+var citationMarker = regexp.MustCompile(`\[\s*\d+(?:\s*[,、]\s*\d+)*\s*\]`)
+
+// relevantItems returns the expected documents and chunks of a question as
+// a list of matchers, one per item
+func relevantItems(q Question) []func(*types.SearchResult) bool {
+	var items []func(*types.SearchResult) bool
+	for _, chunkID := range q.ExpectedChunks {
+		chunkID := chunkID
+		items = append(items, func(result *types.SearchResult) bool {
+			return result.Chunk != nil && result.Chunk.ID == chunkID
+		})
+	}
+	for _, expected := range q.ExpectedDocuments {
+		expected := expected
+		items = append(items, func(result *types.SearchResult) bool {
+			return matchesDocument(result.Document, expected)
+		})
+	}
+	return items
+}
+
+// matchesDocument reports whether a document has the expected ID or path
+func matchesDocument(doc *types.Document, expected string) bool {
+	if doc == nil {
+		return false
+	}
+	if doc.ID == expected {
+		return true
+	}
+
+	path := filepath.ToSlash(filepath.Clean(doc.FilePath))
+	want := filepath.ToSlash(filepath.Clean(expected))
+	return path == want || strings.HasSuffix(path, "/"+want)
+}
+
+// hits returns, for each of the first k results, the index of the relevant
+// item it newly covers or -1. Each item counts only for its first hit, so
+// several chunks of the same expected document are not double counted.
+func hits(items []func(*types.SearchResult) bool, results []*types.SearchResult, k int) []int {
+	if k > len(results) {
+		k = len(results)
+	}
+
+	covered := make([]bool, len(items))
+	ranks := make([]int, k)
+	for i := 0; i < k; i++ {
+		ranks[i] = -1
+		for j, match := range items {
+			if !covered[j] && match(results[i]) {
+				covered[j] = true
+				ranks[i] = j
+				break
+			}
+		}
+	}
+	return ranks
+}
+
+// RecallAtK is the fraction of relevant items found in the top k results
+func RecallAtK(q Question, results []*types.SearchResult, k int) float64 {
+	items := relevantItems(q)
+	if len(items) == 0 {
+		return 0
+	}
+
+	found := 0
+	for _, item := range hits(items, results, k) {
+		if item >= 0 {
+			found++
+		}
+	}
+	return float64(found) / float64(len(items))
+}
+
+// ReciprocalRank is 1/rank of the first relevant result in the top k, or 0
+func ReciprocalRank(q Question, results []*types.SearchResult, k int) float64 {
+	for i, item := range hits(relevantItems(q), results, k) {
+		if item >= 0 {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// NDCGAtK is the normalized discounted cumulative gain of the top k results
+// with binary relevance
+func NDCGAtK(q Question, results []*types.SearchResult, k int) float64 {
+	items := relevantItems(q)
+
+	var dcg float64
+	for i, item := range hits(items, results, k) {
+		if item >= 0 {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+
+	var ideal float64
+	for i := 0; i < len(items) && i < k; i++ {
+		ideal += 1 / math.Log2(float64(i+2))
+	}
+	if ideal == 0 {
+		return 0
+	}
+	return dcg / ideal
+}
+
+// AnswerOverlap is the token-level F1 score between an answer and the
+// reference answer. CJK characters count as one token each so Japanese
+// answers are comparable without a word segmenter.
+func AnswerOverlap(reference, answer string) float64 {
+	refTokens := tokenize(reference)
+	ansTokens := tokenize(citationMarker.ReplaceAllString(answer, " "))
+	if len(refTokens) == 0 || len(ansTokens) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int)
+	for _, token := range refTokens {
+		counts[token]++
+	}
+	common := 0
+	for _, token := range ansTokens {
+		if counts[token] > 0 {
+			counts[token]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+
+	precision := float64(common) / float64(len(ansTokens))
+	recall := float64(common) / float64(len(refTokens))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// tokenize lowercases text and splits it into words and CJK characters
+func tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// isCJK reports whether r is a Han, Hiragana, Katakana or Hangul character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,119 @@
+package eval
+
+import (
+	"math"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+// result builds a search result for a chunk of a document at a path
+func result(chunkID, docID, path string) *types.SearchResult {
+	return &types.SearchResult{
+		Chunk:    &types.DocumentChunk{ID: chunkID, DocumentID: docID},
+		Document: &types.Document{ID: docID, FilePath: path},
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRetrievalMetrics(t *testing.T) {
+	results := []*types.SearchResult{
+		result("c1", "guide", "docs/guide/intro.md"),
+		result("c2", "api", "docs/api/auth.md"),
+		result("c3", "api", "docs/api/auth.md"),
+		result("c4", "faq", "docs/faq.md"),
+	}
+
+	tests := []struct {
+		name   string
+		q      Question
+		k      int
+		recall float64
+		rr     float64
+		ndcg   float64
+	}{
+		{
+			name:   "DocumentByPathSuffix",
+			q:      Question{ExpectedDocuments: []string{"api/auth.md"}},
+			k:      4,
+			recall: 1, rr: 0.5, ndcg: (1 / math.Log2(3)) / 1,
+		},
+		{
+			name:   "DocumentByID",
+			q:      Question{ExpectedDocuments: []string{"guide"}},
+			k:      4,
+			recall: 1, rr: 1, ndcg: 1,
+		},
+		{
+			name:   "CutoffExcludesHit",
+			q:      Question{ExpectedDocuments: []string{"docs/faq.md"}},
+			k:      3,
+			recall: 0, rr: 0, ndcg: 0,
+		},
+		{
+			name:   "PartialRecall",
+			q:      Question{ExpectedDocuments: []string{"docs/api/auth.md", "missing.md"}},
+			k:      4,
+			recall: 0.5, rr: 0.5,
+			ndcg: (1 / math.Log2(3)) / (1 + 1/math.Log2(3)),
+		},
+		{
+			name:   "Chunks",
+			q:      Question{ExpectedChunks: []string{"c3", "c4"}},
+			k:      4,
+			recall: 1, rr: 1.0 / 3,
+			ndcg: (1/math.Log2(4) + 1/math.Log2(5)) / (1 + 1/math.Log2(3)),
+		},
+		{
+			name:   "PathPrefixIsNotSuffix",
+			q:      Question{ExpectedDocuments: []string{"auth.md/extra"}},
+			k:      4,
+			recall: 0, rr: 0, ndcg: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RecallAtK(test.q, results, test.k); !almostEqual(got, test.recall) {
+				t.Errorf("Expected recall %.3f, got %.3f", test.recall, got)
+			}
+			if got := ReciprocalRank(test.q, results, test.k); !almostEqual(got, test.rr) {
+				t.Errorf("Expected reciprocal rank %.3f, got %.3f", test.rr, got)
+			}
+			if got := NDCGAtK(test.q, results, test.k); !almostEqual(got, test.ndcg) {
+				t.Errorf("Expected nDCG %.3f, got %.3f", test.ndcg, got)
+			}
+		})
+	}
+
+	t.Run("NoResults", func(t *testing.T) {
+		q := Question{ExpectedDocuments: []string{"a.md"}}
+		if RecallAtK(q, nil, 5) != 0 || ReciprocalRank(q, nil, 5) != 0 || NDCGAtK(q, nil, 5) != 0 {
+			t.Error("Expected zero metrics without results")
+		}
+	})
+}
+
+func TestAnswerOverlap(t *testing.T) {
+	tests := []struct {
+		reference string
+		answer    string
+		expected  float64
+	}{
+		{"Tokens expire after one hour.", "Tokens expire after one hour [1].", 1},
+		{"tokens expire after one hour", "TOKENS EXPIRE", 2 * 1 * 0.4 / 1.4},
+		{"one hour", "thirty days", 0},
+		{"", "anything", 0},
+		{"Goは速い", "Goは速い", 1},
+		{"ゴルーチン", "関数", 0},
+	}
+
+	for _, test := range tests {
+		if got := AnswerOverlap(test.reference, test.answer); !almostEqual(got, test.expected) {
+			t.Errorf("AnswerOverlap(%q, %q): expected %.3f, got %.3f", test.reference, test.answer, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package eval
+
+import (
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// Pipeline is the retrieval and generation system under evaluation
+type Pipeline interface {
+	Retrieve(question string) ([]*types.SearchResult, error)
+	Generate(question string, results []*types.SearchResult) (string, error)
+}
+
+// QuestionResult holds the metrics of one question.
+// Answer metrics are only set when the question has a reference answer.
+type QuestionResult struct {
+	ID             string        `json:"id"`
+	Question       string        `json:"question"`
+	Retrieved      []string      `json:"retrieved"`
+	Recall         float64       `json:"recall"`
+	ReciprocalRank float64       `json:"reciprocal_rank"`
+	NDCG           float64       `json:"ndcg"`
+	Answer         string        `json:"answer,omitempty"`
+	AnswerOverlap  float64       `json:"answer_overlap,omitempty"`
+	HasReference   bool          `json:"has_reference"`
+	Duration       time.Duration `json:"duration"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// Report aggregates the results of a dataset run.
+// Retrieval metrics are averaged over the questions that ran without error,
+// AnswerOverlap over those that also had a reference answer.
+type Report struct {
+	Name          string           `json:"name"`
+	K             int              `json:"k"`
+	Results       []QuestionResult `json:"results"`
+	Recall        float64          `json:"recall"`
+	MRR           float64          `json:"mrr"`
+	NDCG          float64          `json:"ndcg"`
+	AnswerOverlap float64          `json:"answer_overlap"`
+	Evaluated     int              `json:"evaluated"`
+	Answered      int              `json:"answered"`
+	Failed        int              `json:"failed"`
+	Duration      time.Duration    `json:"duration"`
+}
+
+// Options controls a run
+type Options struct {
+	// K is the cutoff for recall, MRR and nDCG
+	K int
+	// SkipGeneration evaluates retrieval only
+	SkipGeneration bool
+}
+
+// Run evaluates every question of the dataset against the pipeline.
+// A failing question is recorded in its result and does not stop the run.
+func Run(name string, pipeline Pipeline, dataset *Dataset, opts Options) *Report {
+	startTime := time.Now()
+	report := &Report{Name: name, K: opts.K}
+
+	for _, q := range dataset.Questions {
+		result := evaluate(pipeline, q, opts)
+		report.Results = append(report.Results, result)
+
+		if result.Error != "" {
+			report.Failed++
+			continue
+		}
+		report.Evaluated++
+		report.Recall += result.Recall
+		report.MRR += result.ReciprocalRank
+		report.NDCG += result.NDCG
+		if result.HasReference && !opts.SkipGeneration {
+			report.Answered++
+			report.AnswerOverlap += result.AnswerOverlap
+		}
+	}
+
+	if report.Evaluated > 0 {
+		n := float64(report.Evaluated)
+		report.Recall /= n
+		report.MRR /= n
+		report.NDCG /= n
+	}
+	if report.Answered > 0 {
+		report.AnswerOverlap /= float64(report.Answered)
+	}
+	report.Duration = time.Since(startTime)
+
+	return report
+}
+
+// evaluate runs a single question
+func evaluate(pipeline Pipeline, q Question, opts Options) QuestionResult {
+	startTime := time.Now()
+	result := QuestionResult{
+		ID:           q.ID,
+		Question:     q.Question,
+		HasReference: q.ReferenceAnswer != "",
+	}
+
+	results, err := pipeline.Retrieve(q.Question)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	k := opts.K
+	if k <= 0 {
+		k = len(results)
+	}
+	for i, r := range results {
+		if i >= k {
+			break
+		}
+		if r.Chunk != nil {
+			result.Retrieved = append(result.Retrieved, r.Chunk.ID)
+		}
+	}
+	result.Recall = RecallAtK(q, results, k)
+	result.ReciprocalRank = ReciprocalRank(q, results, k)
+	result.NDCG = NDCGAtK(q, results, k)
+
+	if result.HasReference && !opts.SkipGeneration {
+		answer, err := pipeline.Generate(q.Question, results)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Answer = answer
+			result.AnswerOverlap = AnswerOverlap(q.ReferenceAnswer, answer)
+		}
+	}
+	result.Duration = time.Since(startTime)
+
+	return result
+}
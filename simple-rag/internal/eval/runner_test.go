@@ -0,0 +1,77 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+// fakePipeline returns canned results keyed by question
+type fakePipeline struct {
+	results   map[string][]*types.SearchResult
+	answers   map[string]string
+	failing   string
+	generated []string
+}
+
+func (p *fakePipeline) Retrieve(question string) ([]*types.SearchResult, error) {
+	if question == p.failing {
+		return nil, fmt.Errorf("embedding service returned status 500")
+	}
+	return p.results[question], nil
+}
+
+func (p *fakePipeline) Generate(question string, results []*types.SearchResult) (string, error) {
+	p.generated = append(p.generated, question)
+	return p.answers[question], nil
+}
+
+func TestRun(t *testing.T) {
+	pipeline := &fakePipeline{
+		results: map[string][]*types.SearchResult{
+			"hit":  {result("c1", "a", "a.md"), result("c2", "b", "b.md")},
+			"miss": {result("c3", "c", "c.md")},
+		},
+		answers: map[string]string{"hit": "Answer text [1]."},
+		failing: "broken",
+	}
+	dataset := &Dataset{Questions: []Question{
+		{ID: "1", Question: "hit", ExpectedDocuments: []string{"b.md"}, ReferenceAnswer: "Answer text."},
+		{ID: "2", Question: "miss", ExpectedDocuments: []string{"a.md"}},
+		{ID: "3", Question: "broken", ExpectedDocuments: []string{"a.md"}},
+	}}
+
+	report := Run("base", pipeline, dataset, Options{K: 2})
+
+	if report.Evaluated != 2 || report.Failed != 1 {
+		t.Fatalf("Expected 2 evaluated and 1 failed, got %d and %d", report.Evaluated, report.Failed)
+	}
+	if !almostEqual(report.Recall, 0.5) {
+		t.Errorf("Expected mean recall 0.5, got %.3f", report.Recall)
+	}
+	if !almostEqual(report.MRR, 0.25) {
+		t.Errorf("Expected MRR 0.25, got %.3f", report.MRR)
+	}
+	if report.Answered != 1 || !almostEqual(report.AnswerOverlap, 1) {
+		t.Errorf("Expected 1 answer with overlap 1, got %d and %.3f", report.Answered, report.AnswerOverlap)
+	}
+	if len(pipeline.generated) != 1 {
+		t.Errorf("Expected generation only for questions with a reference, got %v", pipeline.generated)
+	}
+	if got := strings.Join(report.Results[0].Retrieved, ","); got != "c1,c2" {
+		t.Errorf("Expected retrieved c1,c2, got %s", got)
+	}
+	if !strings.Contains(report.Results[2].Error, "500") {
+		t.Errorf("Expected retrieval error recorded, got %q", report.Results[2].Error)
+	}
+
+	t.Run("RetrievalOnly", func(t *testing.T) {
+		pipeline.generated = nil
+		report := Run("base", pipeline, dataset, Options{K: 2, SkipGeneration: true})
+		if len(pipeline.generated) != 0 || report.Answered != 0 {
+			t.Errorf("Expected no generation, got %v", pipeline.generated)
+		}
+	})
+}
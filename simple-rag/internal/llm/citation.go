@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"simple-rag/pkg/types"
+)
+
+// citationPattern matches bracketed source numbers such as [1], [1, 3] or [1][2]
+var citationPattern = regexp.MustCompile(`\[\s*\d+(?:\s*[,、]\s*\d+)*\s*\]`)
+
+// markerPattern matches a citation with its leading whitespace, for removal
+var markerPattern = regexp.MustCompile(`[ \t]*` + citationPattern.String())
+
+// ParseCitations splits an answer into sentences and returns the ones that
+// cite sources, resolving each [n] against the 1-based search results.
+// Citations that point outside the results are kept with Retrieved false.
+func ParseCitations(answer string, sources []*types.SearchResult) []types.Claim {
+	var claims []types.Claim
+	for _, sentence := range splitSentences(answer) {
+		var citations []types.Citation
+		seen := make(map[int]bool)
+		for _, marker := range citationPattern.FindAllString(sentence, -1) {
+			for _, n := range citationNumbers(marker) {
+				if seen[n] {
+					continue
+				}
+				seen[n] = true
+				citations = append(citations, resolveCitation(n, sources))
+			}
+		}
+		if len(citations) == 0 {
+			continue
+		}
+
+		text := strings.TrimSpace(markerPattern.ReplaceAllString(sentence, ""))
+		claims = append(claims, types.Claim{Text: text, Citations: citations})
+	}
+
+	return claims
+}
+
+// resolveCitation maps a source number to the chunk it refers to
+func resolveCitation(n int, sources []*types.SearchResult) types.Citation {
+	citation := types.Citation{Source: n}
+	if n < 1 || n > len(sources) || sources[n-1] == nil || sources[n-1].Chunk == nil {
+		return citation
+	}
+
+	chunk := sources[n-1].Chunk
+	citation.Retrieved = true
+	citation.ChunkID = chunk.ID
+	citation.DocumentID = chunk.DocumentID
+	citation.StartPos = chunk.StartPos
+	citation.EndPos = chunk.EndPos
+	return citation
+}
+
+// citationNumbers extracts the numbers of a marker like "[1, 3]"
+func citationNumbers(marker string) []int {
+	fields := strings.FieldsFunc(strings.Trim(marker, "[]"), func(r rune) bool {
+		return r == ',' || r == '、' || unicode.IsSpace(r)
+	})
+
+	numbers := make([]int, 0, len(fields))
+	for _, field := range fields {
+		if n, err := strconv.Atoi(field); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+// splitSentences splits text after sentence-ending punctuation and newlines.
+// Citation markers right after the punctuation ("Go is fast. [1]") stay with
+// the sentence they follow.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !endsSentence(r, text[i:]) {
+			continue
+		}
+
+		// Absorb trailing citation markers
+		for {
+			rest := strings.TrimLeft(text[i:], " \t")
+			loc := citationPattern.FindStringIndex(rest)
+			if loc == nil || loc[0] != 0 {
+				break
+			}
+			i = len(text) - len(rest) + loc[1]
+		}
+
+		if sentence := strings.TrimSpace(text[start:i]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = i
+	}
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+
+	return sentences
+}
+
+// endsSentence reports whether r ends a sentence given the text after it.
+// A period only counts when followed by whitespace, a citation or the end,
+// so decimals and abbreviations like "v1.2" are not split.
+func endsSentence(r rune, rest string) bool {
+	switch r {
+	case '\n', '。', '！', '？':
+		return true
+	case '.', '!', '?':
+		if rest == "" {
+			return true
+		}
+		next, _ := utf8.DecodeRuneInString(rest)
+		return unicode.IsSpace(next) || next == '['
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestParseCitations(t *testing.T) {
+	sources := []*types.SearchResult{
+		{Chunk: &types.DocumentChunk{ID: "c1", DocumentID: "d1", StartPos: 0, EndPos: 40}},
+		{Chunk: &types.DocumentChunk{ID: "c2", DocumentID: "d2", StartPos: 100, EndPos: 180}},
+	}
+
+	answer := "Go has goroutines [1]. It compiles to a single binary. [2] Version 1.22 added range over ints [1, 2].\nIt was designed at Google [5]."
+	claims := ParseCitations(answer, sources)
+
+	if len(claims) != 4 {
+		t.Fatalf("Expected 4 claims, got %d: %+v", len(claims), claims)
+	}
+
+	expected := []struct {
+		text    string
+		sources []int
+	}{
+		{"Go has goroutines.", []int{1}},
+		{"It compiles to a single binary.", []int{2}},
+		{"Version 1.22 added range over ints.", []int{1, 2}},
+		{"It was designed at Google.", []int{5}},
+	}
+	for i, want := range expected {
+		claim := claims[i]
+		if claim.Text != want.text {
+			t.Errorf("Claim %d: expected text %q, got %q", i, want.text, claim.Text)
+		}
+		if len(claim.Citations) != len(want.sources) {
+			t.Errorf("Claim %d: expected %d citations, got %d", i, len(want.sources), len(claim.Citations))
+			continue
+		}
+		for j, source := range want.sources {
+			if claim.Citations[j].Source != source {
+				t.Errorf("Claim %d: expected source %d, got %d", i, source, claim.Citations[j].Source)
+			}
+		}
+	}
+
+	t.Run("ResolvesSpans", func(t *testing.T) {
+		citation := claims[1].Citations[0]
+		if !citation.Retrieved || citation.ChunkID != "c2" || citation.DocumentID != "d2" {
+			t.Errorf("Expected retrieved citation of c2/d2, got %+v", citation)
+		}
+		if citation.StartPos != 100 || citation.EndPos != 180 {
+			t.Errorf("Expected span 100-180, got %d-%d", citation.StartPos, citation.EndPos)
+		}
+	})
+
+	t.Run("FlagsUnretrieved", func(t *testing.T) {
+		citation := claims[3].Citations[0]
+		if citation.Retrieved {
+			t.Error("Expected citation [5] to be flagged as not retrieved")
+		}
+		if citation.ChunkID != "" {
+			t.Errorf("Expected no chunk for unretrieved citation, got %s", citation.ChunkID)
+		}
+	})
+}
+
+func TestParseCitationsJapanese(t *testing.T) {
+	sources := []*types.SearchResult{
+		{Chunk: &types.DocumentChunk{ID: "c1"}},
+		{Chunk: &types.DocumentChunk{ID: "c2"}},
+	}
+
+	claims := ParseCitations("Goはシンプルです[1]。並行処理が得意です[1][2]。", sources)
+	if len(claims) != 2 {
+		t.Fatalf("Expected 2 claims, got %d: %+v", len(claims), claims)
+	}
+	if claims[0].Text != "Goはシンプルです。" {
+		t.Errorf("Expected first claim text without marker, got %q", claims[0].Text)
+	}
+	if len(claims[1].Citations) != 2 {
+		t.Errorf("Expected 2 citations in second claim, got %d", len(claims[1].Citations))
+	}
+}
+
+func TestParseCitationsWithoutMarkers(t *testing.T) {
+	if claims := ParseCitations("No citations here. None at all.", nil); len(claims) != 0 {
+		t.Errorf("Expected no claims, got %+v", claims)
+	}
+
+	// Duplicate markers in one sentence are reported once
+	claims := ParseCitations("Repeated [1] and again [1].", []*types.SearchResult{{Chunk: &types.DocumentChunk{ID: "c1"}}})
+	if len(claims) != 1 || len(claims[0].Citations) != 1 {
+		t.Errorf("Expected a single citation, got %+v", claims)
+	}
+}
@@ -1,113 +1,94 @@
 package llm
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"simple-rag/pkg/types"
 )
 
-// Client handles communication with Ollama LLM service
+// Backend sends prompts to a model server; implementations speak one protocol each
+type Backend interface {
+	Complete(prompt string) (string, error)
+	Health() error
+	ListModels() ([]string, error)
+}
+
+// Client builds RAG prompts and generates answers through a backend
 type Client struct {
-	baseURL     string
-	model       string
-	temperature float64
-	maxTokens   int
-	httpClient  *http.Client
+	backend  Backend
+	prompt   *PromptTemplate
+	language string
+	// promptBudget is the most tokens a RAG prompt may use, or 0 for no limit
+	promptBudget int
 }
 
-// NewClient creates a new LLM client
+// NewClient creates a new LLM client for an Ollama server
 func NewClient(baseURL, model string, temperature float64, maxTokens int) *Client {
-	return &Client{
-		baseURL:     baseURL,
-		model:       model,
-		temperature: temperature,
-		maxTokens:   maxTokens,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-	}
+	return NewClientWithBackend(NewOllamaBackend(baseURL, model, temperature, maxTokens))
 }
 
-// OllamaRequest represents an Ollama API request
-type OllamaRequest struct {
-	Model       string                 `json:"model"`
-	Prompt      string                 `json:"prompt"`
-	Stream      bool                   `json:"stream"`
-	Options     map[string]interface{} `json:"options,omitempty"`
+// NewClientWithBackend creates a new LLM client using the given backend
+func NewClientWithBackend(backend Backend) *Client {
+	return &Client{backend: backend, prompt: defaultPrompt}
 }
 
-// OllamaResponse represents an Ollama API response
-type OllamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+// SetPromptTemplate replaces the RAG prompt template. Language is passed to
+// the template as {{.Language}}; empty leaves the answer language to the model.
+func (c *Client) SetPromptTemplate(prompt *PromptTemplate, language string) {
+	c.prompt = prompt
+	c.language = language
 }
 
-// Generate generates a response using the LLM
-func (c *Client) Generate(prompt string) (string, error) {
-	// Prepare request
-	req := OllamaRequest{
-		Model:  c.model,
-		Prompt: prompt,
-		Stream: false,
-		Options: map[string]interface{}{
-			"temperature": c.temperature,
-			"num_predict": c.maxTokens,
-		},
-	}
-
-	// Serialize request
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Make HTTP request
-	url := fmt.Sprintf("%s/api/generate", c.baseURL)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("LLM service returned status %d", resp.StatusCode)
-	}
+// SetPromptBudget limits RAG prompts to a number of tokens. Contexts that do
+// not fit are dropped, lowest-scored first. Zero disables the limit.
+func (c *Client) SetPromptBudget(tokens int) {
+	c.promptBudget = tokens
+}
 
-	// Parse response
-	var ollamaResp OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+// NewProviderClient creates a new LLM client for a provider name: "ollama",
+// "openai" or "stub" (offline answers for tests and CI)
+func NewProviderClient(provider, baseURL, model, apiKey string, temperature float64, maxTokens int) (*Client, error) {
+	switch provider {
+	case "", "ollama":
+		return NewClient(baseURL, model, temperature, maxTokens), nil
+	case "openai":
+		return NewClientWithBackend(NewOpenAIBackend(baseURL, model, apiKey, temperature, maxTokens)), nil
+	case "stub":
+		return NewClientWithBackend(NewStubBackend()), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", provider)
 	}
+}
 
-	return strings.TrimSpace(ollamaResp.Response), nil
+// Generate generates a response using the LLM
+func (c *Client) Generate(prompt string) (string, error) {
+	return c.backend.Complete(prompt)
 }
 
 // GenerateWithContext generates a response using retrieved context
 func (c *Client) GenerateWithContext(query string, searchResults []*types.SearchResult) (*types.RAGResponse, error) {
-	startTime := time.Now()
+	return c.GenerateWithHistory(query, nil, searchResults)
+}
 
-	// Build context from search results
-	var contextParts []string
-	for i, result := range searchResults {
-		contextParts = append(contextParts, fmt.Sprintf("Context %d (similarity: %.3f):\n%s", 
-			i+1, result.Similarity, result.Chunk.Content))
-	}
-	context := strings.Join(contextParts, "\n\n")
+// GenerateWithHistory generates a response using retrieved context and the
+// preceding conversation. Contexts that do not fit the prompt budget are
+// left out of the prompt and of the response sources. The response trace
+// records the prompt size and generation latency.
+func (c *Client) GenerateWithHistory(query string, history []types.ChatTurn, searchResults []*types.SearchResult) (*types.RAGResponse, error) {
+	startTime := time.Now()
 
 	// Build prompt
-	prompt := c.buildRAGPrompt(query, context)
+	prompt, kept, tokens, err := packContexts(c.prompt, PromptData{
+		Query:    query,
+		History:  formatHistory(history),
+		Language: c.language,
+	}, searchResults, c.promptBudget)
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate response
 	answer, err := c.Generate(prompt)
@@ -119,72 +100,88 @@ func (c *Client) GenerateWithContext(query string, searchResults []*types.Search
 	response := &types.RAGResponse{
 		Query:       query,
 		Answer:      answer,
-		Sources:     searchResults,
+		Sources:     kept,
+		Claims:      ParseCitations(answer, kept),
 		ProcessTime: time.Since(startTime),
-		CreatedAt:   time.Now(),
+		Trace: &types.QueryTrace{
+			GenerationLatency: time.Since(startTime),
+			PromptChars:       utf8.RuneCountInString(prompt),
+			PromptTokens:      tokens,
+			DroppedContexts:   len(searchResults) - len(kept),
+			AnswerChars:       utf8.RuneCountInString(answer),
+		},
+		CreatedAt: time.Now(),
 	}
 
 	return response, nil
 }
 
-// buildRAGPrompt builds a prompt for RAG using retrieved context
-func (c *Client) buildRAGPrompt(query, context string) string {
-	template := `You are a helpful assistant that answers questions based on the provided context. 
-Use only the information from the context to answer the question. If the context doesn't contain enough information to answer the question, say so.
+// CondenseQuestion rewrites a follow-up question into a standalone search
+// query using the conversation history. Without history the question is
+// returned unchanged.
+func (c *Client) CondenseQuestion(history []types.ChatTurn, question string) (string, error) {
+	if len(history) == 0 {
+		return question, nil
+	}
 
-Context:
-%s
+	rewritten, err := c.Generate(buildCondensePrompt(formatHistory(history), question))
+	if err != nil {
+		return "", fmt.Errorf("failed to condense question: %w", err)
+	}
 
-Question: %s
+	if standalone := cleanCondensed(rewritten); standalone != "" {
+		return standalone, nil
+	}
+	return question, nil
+}
 
-Answer:`
+// buildCondensePrompt builds the prompt that turns a follow-up into a standalone question
+func buildCondensePrompt(history, question string) string {
+	template := `Given the conversation below and a follow-up question, rewrite the follow-up as a standalone question that can be understood without the conversation.
+Resolve pronouns and references such as "it" or "the second one" using the conversation. Keep the language of the follow-up question.
+Respond with only the standalone question.
 
-	return fmt.Sprintf(template, context, query)
-}
+Conversation:
+%s
 
-// Health checks if the LLM service is available
-func (c *Client) Health() error {
-	url := fmt.Sprintf("%s/api/tags", c.baseURL)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return fmt.Errorf("LLM service not reachable: %w", err)
-	}
-	defer resp.Body.Close()
+Follow-up question: %s
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("LLM service health check failed: status %d", resp.StatusCode)
-	}
+Standalone question:`
 
-	return nil
+	return fmt.Sprintf(template, history, question)
 }
 
-// ListModels lists available models
-func (c *Client) ListModels() ([]string, error) {
-	url := fmt.Sprintf("%s/api/tags", c.baseURL)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list models: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list models: status %d", resp.StatusCode)
+// cleanCondensed strips labels and quotes that models like to add around a rewritten question
+func cleanCondensed(rewritten string) string {
+	line := strings.TrimSpace(rewritten)
+	if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+		line = strings.TrimSpace(line[:idx])
 	}
-
-	var response struct {
-		Models []struct {
-			Name string `json:"name"`
-		} `json:"models"`
+	for _, prefix := range []string{"Standalone question:", "standalone question:"} {
+		line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
 	}
+	return strings.Trim(line, "\"'「」 ")
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode models response: %w", err)
-	}
+// formatHistory renders conversation turns as "User:"/"Assistant:" lines
+func formatHistory(history []types.ChatTurn) string {
+	lines := make([]string, 0, len(history))
+	for _, turn := range history {
+		speaker := "User"
+		if turn.Role == types.RoleAssistant {
+			speaker = "Assistant"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", speaker, turn.Content))
+	}
+	return strings.Join(lines, "\n")
+}
 
-	models := make([]string, len(response.Models))
-	for i, model := range response.Models {
-		models[i] = model.Name
-	}
+// Health checks if the LLM service is available
+func (c *Client) Health() error {
+	return c.backend.Health()
+}
 
-	return models, nil
-}
\ No newline at end of file
+// ListModels lists available models
+func (c *Client) ListModels() ([]string, error) {
+	return c.backend.ListModels()
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -16,23 +17,58 @@ func TestNewClient(t *testing.T) {
 	if client == nil {
 		t.Fatal("NewClient returned nil")
 	}
-	if client.baseURL != "http://test:11434" {
-		t.Errorf("Expected baseURL 'http://test:11434', got %s", client.baseURL)
+	backend, ok := client.backend.(*OllamaBackend)
+	if !ok {
+		t.Fatalf("Expected *OllamaBackend, got %T", client.backend)
 	}
-	if client.model != "test-model" {
-		t.Errorf("Expected model 'test-model', got %s", client.model)
+	if backend.baseURL != "http://test:11434" {
+		t.Errorf("Expected baseURL 'http://test:11434', got %s", backend.baseURL)
 	}
-	if client.temperature != 0.7 {
-		t.Errorf("Expected temperature 0.7, got %f", client.temperature)
+	if backend.model != "test-model" {
+		t.Errorf("Expected model 'test-model', got %s", backend.model)
 	}
-	if client.maxTokens != 512 {
-		t.Errorf("Expected maxTokens 512, got %d", client.maxTokens)
+	if backend.temperature != 0.7 {
+		t.Errorf("Expected temperature 0.7, got %f", backend.temperature)
 	}
-	if client.httpClient == nil {
+	if backend.maxTokens != 512 {
+		t.Errorf("Expected maxTokens 512, got %d", backend.maxTokens)
+	}
+	if backend.httpClient == nil {
 		t.Error("httpClient should be initialized")
 	}
-	if client.httpClient.Timeout != 60*time.Second {
-		t.Errorf("Expected timeout 60s, got %v", client.httpClient.Timeout)
+	if backend.httpClient.Timeout != 60*time.Second {
+		t.Errorf("Expected timeout 60s, got %v", backend.httpClient.Timeout)
+	}
+}
+
+func TestNewProviderClient(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected string
+		wantErr  bool
+	}{
+		{"", "*llm.OllamaBackend", false},
+		{"ollama", "*llm.OllamaBackend", false},
+		{"openai", "*llm.OpenAIBackend", false},
+		{"stub", "*llm.StubBackend", false},
+		{"unknown", "", true},
+	}
+
+	for _, test := range tests {
+		client, err := NewProviderClient(test.provider, "http://test", "m", "", 0.1, 100)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("Provider %q: expected error, got nil", test.provider)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Provider %q: unexpected error: %v", test.provider, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", client.backend); got != test.expected {
+			t.Errorf("Provider %q: expected %s, got %s", test.provider, test.expected, got)
+		}
 	}
 }
 
@@ -263,6 +299,19 @@ func TestGenerateWithContext(t *testing.T) {
 			t.Error("ProcessTime should be positive")
 		}
 
+		if response.Trace == nil {
+			t.Fatal("Trace should not be nil")
+		}
+		if response.Trace.GenerationLatency <= 0 {
+			t.Error("GenerationLatency should be positive")
+		}
+		if response.Trace.PromptChars < len("Go has strong concurrency support") {
+			t.Errorf("Expected prompt size to include the context, got %d", response.Trace.PromptChars)
+		}
+		if response.Trace.AnswerChars != len(response.Answer) {
+			t.Errorf("Expected answer size %d, got %d", len(response.Answer), response.Trace.AnswerChars)
+		}
+
 		if response.CreatedAt.IsZero() {
 			t.Error("CreatedAt should not be zero")
 		}
@@ -290,6 +339,49 @@ func TestGenerateWithContext(t *testing.T) {
 		}
 	})
 
+	t.Run("CitedAnswer", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req OllamaRequest
+			json.NewDecoder(r.Body).Decode(&req)
+
+			if !strings.Contains(req.Prompt, "[1] (similarity: 0.900)") {
+				t.Error("Prompt should number contexts as [1]")
+			}
+			if !strings.Contains(req.Prompt, "square brackets") {
+				t.Error("Prompt should ask for bracketed citations")
+			}
+
+			resp := OllamaResponse{
+				Response: "Go was designed at Google [1]. It is fast [3].",
+				Done:     true,
+			}
+			json.NewEncoder(w).Encode(resp)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL, "test-model", 0.7, 512)
+		results := []*types.SearchResult{
+			{
+				Chunk:      &types.DocumentChunk{ID: "c1", Content: "Go was designed at Google.", StartPos: 10, EndPos: 36},
+				Similarity: 0.9,
+			},
+		}
+
+		response, err := client.GenerateWithContext("Who made Go?", results)
+		if err != nil {
+			t.Fatalf("GenerateWithContext failed: %v", err)
+		}
+		if len(response.Claims) != 2 {
+			t.Fatalf("Expected 2 claims, got %d", len(response.Claims))
+		}
+		if citation := response.Claims[0].Citations[0]; !citation.Retrieved || citation.ChunkID != "c1" || citation.EndPos != 36 {
+			t.Errorf("Expected citation of c1 ending at 36, got %+v", citation)
+		}
+		if response.Claims[1].Citations[0].Retrieved {
+			t.Error("Expected citation [3] to be flagged as not retrieved")
+		}
+	})
+
 	t.Run("LLMError", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -314,13 +406,17 @@ func TestGenerateWithContext(t *testing.T) {
 	})
 }
 
-func TestBuildRAGPrompt(t *testing.T) {
-	client := NewClient("http://test:11434", "test-model", 0.7, 512)
-
+func TestDefaultPromptTemplate(t *testing.T) {
 	query := "What is machine learning?"
-	context := "Context 1: Machine learning is a subset of AI.\nContext 2: It involves training algorithms on data."
+	contexts := []PromptContext{
+		{Index: 1, Content: "Machine learning is a subset of AI.", Similarity: 0.9},
+		{Index: 2, Content: "It involves training algorithms on data.", Similarity: 0.8},
+	}
 
-	prompt := client.buildRAGPrompt(query, context)
+	prompt, err := defaultPrompt.Render(PromptData{Query: query, Contexts: contexts})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
 
 	if !strings.Contains(prompt, "Context:") {
 		t.Error("Prompt should contain 'Context:'")
@@ -334,12 +430,148 @@ func TestBuildRAGPrompt(t *testing.T) {
 	if !strings.Contains(prompt, query) {
 		t.Error("Prompt should contain the query")
 	}
-	if !strings.Contains(prompt, context) {
-		t.Error("Prompt should contain the context")
+	if !strings.Contains(prompt, "[1] (similarity: 0.900):\nMachine learning is a subset of AI.\n\n[2] (similarity: 0.800):") {
+		t.Errorf("Prompt should contain the numbered contexts, got %s", prompt)
 	}
 	if !strings.Contains(prompt, "helpful assistant") {
 		t.Error("Prompt should contain instruction about being a helpful assistant")
 	}
+	if strings.Contains(prompt, "Conversation so far:") {
+		t.Error("Prompt should not contain a conversation section without history")
+	}
+	if strings.Contains(prompt, "Answer in") {
+		t.Error("Prompt should not name a language unless configured")
+	}
+
+	t.Run("WithHistory", func(t *testing.T) {
+		history := "User: What is Go?\nAssistant: A programming language."
+		prompt, err := defaultPrompt.Render(PromptData{Query: "Who made it?", Contexts: contexts, History: history})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+
+		if !strings.Contains(prompt, "Conversation so far:\n"+history) {
+			t.Error("Prompt should contain the conversation history")
+		}
+		if strings.Index(prompt, history) > strings.Index(prompt, "Question: Who made it?") {
+			t.Error("Conversation history should come before the question")
+		}
+	})
+
+	t.Run("WithLanguage", func(t *testing.T) {
+		prompt, err := defaultPrompt.Render(PromptData{Query: query, Contexts: contexts, Language: "Japanese"})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if !strings.Contains(prompt, "Answer in Japanese.") {
+			t.Error("Prompt should ask for the configured language")
+		}
+	})
+}
+
+func TestGenerateWithHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if !strings.Contains(req.Prompt, "User: What is Go?\nAssistant: A language by Google.") {
+			t.Errorf("Prompt should contain formatted history, got %s", req.Prompt)
+		}
+
+		resp := OllamaResponse{Response: "Robert Griesemer, Rob Pike and Ken Thompson.", Done: true}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-model", 0.7, 512)
+	history := []types.ChatTurn{
+		{Role: types.RoleUser, Content: "What is Go?"},
+		{Role: types.RoleAssistant, Content: "A language by Google."},
+	}
+	results := []*types.SearchResult{
+		{Chunk: &types.DocumentChunk{Content: "Go was designed at Google."}, Similarity: 0.9},
+	}
+
+	response, err := client.GenerateWithHistory("Who designed it?", history, results)
+	if err != nil {
+		t.Fatalf("GenerateWithHistory failed: %v", err)
+	}
+	if response.Query != "Who designed it?" {
+		t.Errorf("Expected query 'Who designed it?', got %s", response.Query)
+	}
+}
+
+func TestCondenseQuestion(t *testing.T) {
+	history := []types.ChatTurn{
+		{Role: types.RoleUser, Content: "List Go's concurrency primitives"},
+		{Role: types.RoleAssistant, Content: "Goroutines and channels."},
+	}
+
+	t.Run("RewritesFollowUp", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req OllamaRequest
+			json.NewDecoder(r.Body).Decode(&req)
+
+			if !strings.Contains(req.Prompt, "Follow-up question: what about the second one?") {
+				t.Errorf("Prompt should contain the follow-up, got %s", req.Prompt)
+			}
+			if !strings.Contains(req.Prompt, "Assistant: Goroutines and channels.") {
+				t.Errorf("Prompt should contain the history, got %s", req.Prompt)
+			}
+
+			resp := OllamaResponse{Response: "Standalone question: \"How do channels work in Go?\"\nExtra text", Done: true}
+			json.NewEncoder(w).Encode(resp)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL, "test-model", 0.7, 512)
+		standalone, err := client.CondenseQuestion(history, "what about the second one?")
+		if err != nil {
+			t.Fatalf("CondenseQuestion failed: %v", err)
+		}
+		if standalone != "How do channels work in Go?" {
+			t.Errorf("Expected cleaned standalone question, got %q", standalone)
+		}
+	})
+
+	t.Run("NoHistorySkipsLLM", func(t *testing.T) {
+		client := NewClient("http://invalid-host:99999", "test-model", 0.7, 512)
+		standalone, err := client.CondenseQuestion(nil, "What is Go?")
+		if err != nil {
+			t.Fatalf("CondenseQuestion failed: %v", err)
+		}
+		if standalone != "What is Go?" {
+			t.Errorf("Expected question unchanged, got %q", standalone)
+		}
+	})
+
+	t.Run("EmptyReplyFallsBack", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(OllamaResponse{Response: "  ", Done: true})
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL, "test-model", 0.7, 512)
+		standalone, err := client.CondenseQuestion(history, "and channels?")
+		if err != nil {
+			t.Fatalf("CondenseQuestion failed: %v", err)
+		}
+		if standalone != "and channels?" {
+			t.Errorf("Expected fallback to the original question, got %q", standalone)
+		}
+	})
+
+	t.Run("LLMError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL, "test-model", 0.7, 512)
+		if _, err := client.CondenseQuestion(history, "and channels?"); err == nil {
+			t.Error("Expected error for LLM failure, got nil")
+		}
+	})
 }
 
 func TestHealth(t *testing.T) {
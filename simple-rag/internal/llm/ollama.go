@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaBackend handles communication with Ollama LLM service
+type OllamaBackend struct {
+	baseURL     string
+	model       string
+	temperature float64
+	maxTokens   int
+	httpClient  *http.Client
+}
+
+// NewOllamaBackend creates a new Ollama backend
+func NewOllamaBackend(baseURL, model string, temperature float64, maxTokens int) *OllamaBackend {
+	return &OllamaBackend{
+		baseURL:     baseURL,
+		model:       model,
+		temperature: temperature,
+		maxTokens:   maxTokens,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// OllamaRequest represents an Ollama API request
+type OllamaRequest struct {
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	Stream  bool                   `json:"stream"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// OllamaResponse represents an Ollama API response
+type OllamaResponse struct {
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+}
+
+// Complete generates a completion with /api/generate
+func (b *OllamaBackend) Complete(prompt string) (string, error) {
+	// Prepare request
+	req := OllamaRequest{
+		Model:  b.model,
+		Prompt: prompt,
+		Stream: false,
+		Options: map[string]interface{}{
+			"temperature": b.temperature,
+			"num_predict": b.maxTokens,
+		},
+	}
+
+	// Serialize request
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make HTTP request
+	url := fmt.Sprintf("%s/api/generate", b.baseURL)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM service returned status %d", resp.StatusCode)
+	}
+
+	// Parse response
+	var ollamaResp OllamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return strings.TrimSpace(ollamaResp.Response), nil
+}
+
+// Health checks if the LLM service is available
+func (b *OllamaBackend) Health() error {
+	url := fmt.Sprintf("%s/api/tags", b.baseURL)
+	resp, err := b.httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("LLM service not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("LLM service health check failed: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ListModels lists available models
+func (b *OllamaBackend) ListModels() ([]string, error) {
+	url := fmt.Sprintf("%s/api/tags", b.baseURL)
+	resp, err := b.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list models: status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+
+	models := make([]string, len(response.Models))
+	for i, model := range response.Models {
+		models[i] = model.Name
+	}
+
+	return models, nil
+}
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIBackend speaks the OpenAI-compatible /v1/chat/completions API
+// served by llama.cpp server, vLLM, LocalAI and OpenAI itself
+type OpenAIBackend struct {
+	baseURL     string
+	model       string
+	apiKey      string
+	temperature float64
+	maxTokens   int
+	httpClient  *http.Client
+}
+
+// NewOpenAIBackend creates a new OpenAI-compatible backend.
+// baseURL may be given with or without the trailing /v1.
+func NewOpenAIBackend(baseURL, model, apiKey string, temperature float64, maxTokens int) *OpenAIBackend {
+	return &OpenAIBackend{
+		baseURL:     strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1"),
+		model:       model,
+		apiKey:      apiKey,
+		temperature: temperature,
+		maxTokens:   maxTokens,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// ChatMessage is one message of a chat completion request or response
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatCompletionRequest represents a /v1/chat/completions request
+type ChatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream"`
+}
+
+// ChatCompletionResponse represents a /v1/chat/completions response
+type ChatCompletionResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Complete sends the prompt as a single user message
+func (b *OpenAIBackend) Complete(prompt string) (string, error) {
+	// Prepare request
+	req := ChatCompletionRequest{
+		Model:       b.model,
+		Messages:    []ChatMessage{{Role: "user", Content: prompt}},
+		Temperature: b.temperature,
+		MaxTokens:   b.maxTokens,
+		Stream:      false,
+	}
+
+	// Serialize request
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make HTTP request
+	url := fmt.Sprintf("%s/v1/chat/completions", b.baseURL)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	b.authorize(httpReq)
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM service returned status %d", resp.StatusCode)
+	}
+
+	// Parse response
+	var chatResp ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("LLM service returned no choices")
+	}
+
+	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
+}
+
+// Health checks if the LLM service is available
+func (b *OpenAIBackend) Health() error {
+	resp, err := b.getModels()
+	if err != nil {
+		return fmt.Errorf("LLM service not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("LLM service health check failed: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ListModels lists available models
+func (b *OpenAIBackend) ListModels() ([]string, error) {
+	resp, err := b.getModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list models: status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
+	}
+
+	models := make([]string, len(response.Data))
+	for i, model := range response.Data {
+		models[i] = model.ID
+	}
+
+	return models, nil
+}
+
+// getModels requests /v1/models
+func (b *OpenAIBackend) getModels() (*http.Response, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/models", b.baseURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	b.authorize(req)
+	return b.httpClient.Do(req)
+}
+
+// authorize adds the bearer token when an API key is configured
+func (b *OpenAIBackend) authorize(req *http.Request) {
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIBackendComplete(t *testing.T) {
+	t.Run("SuccessfulRequest", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/chat/completions" {
+				t.Errorf("Expected path '/v1/chat/completions', got %s", r.URL.Path)
+			}
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+			}
+
+			var req ChatCompletionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Failed to decode request: %v", err)
+			}
+			if req.Model != "gpt-test" {
+				t.Errorf("Expected model 'gpt-test', got %s", req.Model)
+			}
+			if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "Hello" {
+				t.Errorf("Unexpected messages: %+v", req.Messages)
+			}
+			if req.MaxTokens != 128 {
+				t.Errorf("Expected max_tokens 128, got %d", req.MaxTokens)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"  Hi there  "}}]}`))
+		}))
+		defer server.Close()
+
+		// The /v1 suffix is accepted and not doubled
+		client := NewClientWithBackend(NewOpenAIBackend(server.URL+"/v1", "gpt-test", "secret", 0.2, 128))
+		response, err := client.Generate("Hello")
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if response != "Hi there" {
+			t.Errorf("Expected 'Hi there', got %q", response)
+		}
+	})
+
+	t.Run("NoChoices", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices":[]}`))
+		}))
+		defer server.Close()
+
+		backend := NewOpenAIBackend(server.URL, "m", "", 0, 0)
+		if _, err := backend.Complete("Hello"); err == nil {
+			t.Error("Expected error for empty choices, got nil")
+		}
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		backend := NewOpenAIBackend(server.URL, "m", "", 0, 0)
+		_, err := backend.Complete("Hello")
+		if err == nil || !strings.Contains(err.Error(), "status 401") {
+			t.Errorf("Expected status 401 error, got %v", err)
+		}
+	})
+}
+
+func TestOpenAIBackendModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("Expected path '/v1/models', got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data":[{"id":"model-a"},{"id":"model-b"}]}`))
+	}))
+	defer server.Close()
+
+	backend := NewOpenAIBackend(server.URL, "m", "", 0, 0)
+	if err := backend.Health(); err != nil {
+		t.Errorf("Health failed: %v", err)
+	}
+
+	models, err := backend.ListModels()
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[0] != "model-a" || models[1] != "model-b" {
+		t.Errorf("Unexpected models: %v", models)
+	}
+}
@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"simple-rag/internal/document"
+	"simple-rag/pkg/types"
+)
+
+// DefaultPromptTemplate is the RAG prompt used when no prompt file is configured
+const DefaultPromptTemplate = `You are a helpful assistant that answers questions based on the provided context.
+Use only the information from the context to answer the question. If the context doesn't contain enough information to answer the question, say so.
+Cite the context that supports each sentence with its number in square brackets, e.g. [1] or [1][3]. Only cite the numbered contexts given below.
+{{- if .Language}}
+Answer in {{.Language}}.
+{{- end}}
+
+Context:
+{{range $i, $c := .Contexts}}{{if $i}}
+
+{{end}}[{{$c.Index}}] (similarity: {{printf "%.3f" $c.Similarity}}):
+{{$c.Content}}{{end}}
+
+{{if .History}}Conversation so far:
+{{.History}}
+
+{{end}}Question: {{.Query}}
+
+Answer:`
+
+// defaultPrompt is the parsed DefaultPromptTemplate
+var defaultPrompt = &PromptTemplate{
+	tmpl: template.Must(template.New("default").Option("missingkey=error").Parse(DefaultPromptTemplate)),
+}
+
+// PromptData holds the variables available to a prompt template
+type PromptData struct {
+	// Query is the user's question
+	Query string
+	// Contexts are the retrieved chunks, numbered from 1 for citations
+	Contexts []PromptContext
+	// History is the preceding conversation as "User:"/"Assistant:" lines,
+	// empty for a single question
+	History string
+	// Language is the configured answer language, e.g. "Japanese"
+	Language string
+}
+
+// PromptContext is one retrieved chunk as seen by a prompt template
+type PromptContext struct {
+	Index      int
+	Content    string
+	Similarity float64
+	Title      string
+	Source     string
+}
+
+// PromptTemplate renders RAG prompts from a text/template
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+// ParsePromptTemplate parses and validates a prompt template. The template
+// is rendered once with sample data and must include the query and the
+// contexts, so mistakes are caught at startup rather than on the first query.
+func ParsePromptTemplate(name, text string) (*PromptTemplate, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	p := &PromptTemplate{tmpl: tmpl}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// LoadPromptTemplate reads a prompt template file. An empty path returns
+// the default template.
+func LoadPromptTemplate(path string) (*PromptTemplate, error) {
+	if path == "" {
+		return defaultPrompt, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return ParsePromptTemplate(path, string(data))
+}
+
+// validate renders the template with sample data
+func (p *PromptTemplate) validate() error {
+	const (
+		sampleQuery   = "sample-query-7f3a"
+		sampleContext = "sample-context-9c1e"
+	)
+
+	prompt, err := p.Render(PromptData{
+		Query:    sampleQuery,
+		Contexts: []PromptContext{{Index: 1, Content: sampleContext, Similarity: 0.9, Title: "sample", Source: "sample.md"}},
+		History:  "User: earlier question\nAssistant: earlier answer",
+		Language: "English",
+	})
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(prompt, sampleQuery) {
+		return fmt.Errorf("template does not include {{.Query}}")
+	}
+	if !strings.Contains(prompt, sampleContext) {
+		return fmt.Errorf("template does not include the contents of {{.Contexts}}")
+	}
+	return nil
+}
+
+// Render executes the template
+func (p *PromptTemplate) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return b.String(), nil
+}
+
+// promptContexts numbers search results for a prompt
+func promptContexts(results []*types.SearchResult) []PromptContext {
+	contexts := make([]PromptContext, len(results))
+	for i, result := range results {
+		contexts[i] = PromptContext{
+			Index:      i + 1,
+			Content:    result.Chunk.Content,
+			Similarity: result.Similarity,
+		}
+		if result.Document != nil {
+			contexts[i].Title = result.Document.Title
+			contexts[i].Source = result.Document.FilePath
+		}
+	}
+	return contexts
+}
+
+// countTokens approximates the number of tokens in a prompt
+func countTokens(text string) int {
+	return document.TokenSizer{}.Size(text)
+}
+
+// packContexts renders the prompt with as many results as fit in budget
+// tokens. While the prompt is too long the lowest-scored result is dropped,
+// by rerank score when results were reranked and similarity otherwise; the
+// rest keep their order. A budget of zero or less means no limit.
+// It returns the prompt, the results it includes and its token count.
+func packContexts(p *PromptTemplate, data PromptData, results []*types.SearchResult, budget int) (string, []*types.SearchResult, int, error) {
+	kept := results
+	for {
+		data.Contexts = promptContexts(kept)
+		prompt, err := p.Render(data)
+		if err != nil {
+			return "", nil, 0, err
+		}
+
+		tokens := countTokens(prompt)
+		if budget <= 0 || tokens <= budget {
+			return prompt, kept, tokens, nil
+		}
+		if len(kept) == 0 {
+			return "", nil, 0, fmt.Errorf("prompt needs %d tokens without any context, over the budget of %d", tokens, budget)
+		}
+		kept = dropLowestScored(kept)
+	}
+}
+
+// dropLowestScored returns results without the one with the lowest score
+func dropLowestScored(results []*types.SearchResult) []*types.SearchResult {
+	reranked := false
+	for _, result := range results {
+		if result.RerankScore != 0 {
+			reranked = true
+			break
+		}
+	}
+	score := func(result *types.SearchResult) float64 {
+		if reranked {
+			return result.RerankScore
+		}
+		return result.Similarity
+	}
+
+	lowest := 0
+	for i, result := range results {
+		if score(result) < score(results[lowest]) {
+			lowest = i
+		}
+	}
+
+	kept := make([]*types.SearchResult, 0, len(results)-1)
+	kept = append(kept, results[:lowest]...)
+	return append(kept, results[lowest+1:]...)
+}
@@ -0,0 +1,186 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestParsePromptTemplate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		text := `以下の資料に基づいて{{.Language}}で丁寧に答えてください。
+{{range .Contexts}}[{{.Index}}] {{.Title}}
+{{.Content}}
+{{end}}{{with .History}}これまでの会話:
+{{.}}
+{{end}}質問: {{.Query}}
+回答:`
+		prompt, err := ParsePromptTemplate("ja", text)
+		if err != nil {
+			t.Fatalf("ParsePromptTemplate failed: %v", err)
+		}
+
+		rendered, err := prompt.Render(PromptData{
+			Query:    "Goの特徴は？",
+			Contexts: []PromptContext{{Index: 1, Title: "go.md", Content: "Goはシンプルです。"}},
+			Language: "日本語",
+		})
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if !strings.Contains(rendered, "日本語で丁寧に") || !strings.Contains(rendered, "[1] go.md\nGoはシンプルです。") {
+			t.Errorf("Unexpected prompt: %s", rendered)
+		}
+		if strings.Contains(rendered, "これまでの会話") {
+			t.Error("Expected no history section without history")
+		}
+	})
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"SyntaxError", "{{.Query", "failed to parse"},
+		{"UnknownField", "{{.Question}} {{range .Contexts}}{{.Content}}{{end}}", "Question"},
+		{"MissingQuery", "{{range .Contexts}}{{.Content}}{{end}}", "{{.Query}}"},
+		{"MissingContexts", "Question: {{.Query}}", "{{.Contexts}}"},
+		{"ContextIndexOnly", "{{.Query}} {{range .Contexts}}[{{.Index}}]{{end}}", "{{.Contexts}}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParsePromptTemplate(test.name, test.text)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadPromptTemplate(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		prompt, err := LoadPromptTemplate("")
+		if err != nil {
+			t.Fatalf("LoadPromptTemplate failed: %v", err)
+		}
+		if prompt != defaultPrompt {
+			t.Error("Expected the default template for an empty path")
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prompt.tmpl")
+		if err := os.WriteFile(path, []byte("{{range .Contexts}}{{.Content}}{{end}}\nQ: {{.Query}}"), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		if _, err := LoadPromptTemplate(path); err != nil {
+			t.Errorf("LoadPromptTemplate failed: %v", err)
+		}
+	})
+
+	t.Run("Sample", func(t *testing.T) {
+		if _, err := LoadPromptTemplate("../../data/prompts/ja.tmpl"); err != nil {
+			t.Errorf("Sample template is invalid: %v", err)
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		if _, err := LoadPromptTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+			t.Error("Expected error for missing file, got nil")
+		}
+	})
+}
+
+func TestPackContexts(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	results := []*types.SearchResult{
+		{Chunk: &types.DocumentChunk{ID: "a", Content: "alpha " + long}, Similarity: 0.9},
+		{Chunk: &types.DocumentChunk{ID: "b", Content: "beta " + long}, Similarity: 0.6},
+		{Chunk: &types.DocumentChunk{ID: "c", Content: "gamma " + long}, Similarity: 0.8},
+	}
+	data := PromptData{Query: "What?"}
+
+	_, all, fullTokens, err := packContexts(defaultPrompt, data, results, 0)
+	if err != nil {
+		t.Fatalf("packContexts failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected all results without a budget, got %d", len(all))
+	}
+
+	t.Run("DropsLowestSimilarity", func(t *testing.T) {
+		prompt, kept, tokens, err := packContexts(defaultPrompt, data, results, fullTokens-1)
+		if err != nil {
+			t.Fatalf("packContexts failed: %v", err)
+		}
+		if len(kept) != 2 || kept[0].Chunk.ID != "a" || kept[1].Chunk.ID != "c" {
+			t.Errorf("Expected a and c in order, got %v", chunkIDs(kept))
+		}
+		if tokens >= fullTokens {
+			t.Errorf("Expected fewer tokens than %d, got %d", fullTokens, tokens)
+		}
+		if !strings.Contains(prompt, "[2] (similarity: 0.800):\ngamma") {
+			t.Error("Expected remaining contexts to be renumbered")
+		}
+	})
+
+	t.Run("DropsLowestRerankScore", func(t *testing.T) {
+		reranked := []*types.SearchResult{
+			{Chunk: results[0].Chunk, Similarity: 0.9, RerankScore: 0.2},
+			{Chunk: results[1].Chunk, Similarity: 0.6, RerankScore: 0.9},
+			{Chunk: results[2].Chunk, Similarity: 0.8, RerankScore: 0.5},
+		}
+		_, kept, _, err := packContexts(defaultPrompt, data, reranked, fullTokens-1)
+		if err != nil {
+			t.Fatalf("packContexts failed: %v", err)
+		}
+		if len(kept) != 2 || kept[0].Chunk.ID != "b" || kept[1].Chunk.ID != "c" {
+			t.Errorf("Expected b and c, got %v", chunkIDs(kept))
+		}
+	})
+
+	t.Run("BudgetTooSmall", func(t *testing.T) {
+		if _, _, _, err := packContexts(defaultPrompt, data, results, 5); err == nil {
+			t.Error("Expected error when the prompt cannot fit, got nil")
+		}
+	})
+}
+
+func TestGenerateWithPromptBudget(t *testing.T) {
+	long := strings.Repeat("word ", 200)
+	results := []*types.SearchResult{
+		{Chunk: &types.DocumentChunk{ID: "low", Content: "low " + long}, Similarity: 0.5},
+		{Chunk: &types.DocumentChunk{ID: "high", Content: "high " + long}, Similarity: 0.9},
+	}
+
+	client := NewClientWithBackend(NewStubBackend())
+	client.SetPromptBudget(350)
+
+	response, err := client.GenerateWithContext("What?", results)
+	if err != nil {
+		t.Fatalf("GenerateWithContext failed: %v", err)
+	}
+	if len(response.Sources) != 1 || response.Sources[0].Chunk.ID != "high" {
+		t.Errorf("Expected only the high-scored source, got %v", chunkIDs(response.Sources))
+	}
+	if response.Trace.DroppedContexts != 1 {
+		t.Errorf("Expected 1 dropped context, got %d", response.Trace.DroppedContexts)
+	}
+	if response.Trace.PromptTokens > 350 {
+		t.Errorf("Expected prompt within budget, got %d tokens", response.Trace.PromptTokens)
+	}
+	if !strings.HasPrefix(response.Answer, "high") {
+		t.Errorf("Expected the kept context as [1], got %q", response.Answer)
+	}
+}
+
+func chunkIDs(results []*types.SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Chunk.ID
+	}
+	return ids
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// stubFirstContext captures the first numbered context of a RAG prompt
+var stubFirstContext = regexp.MustCompile(`(?s)\[1\] \([^)\n]*\):\n(.*?)\n\n(?:\[2\] \(|Conversation so far:|Question:)`)
+
+// stubFollowUp captures the question of a condense prompt
+var stubFollowUp = regexp.MustCompile(`Follow-up question: (.*)`)
+
+// StubBackend answers without a model server so evaluations and tests can
+// run offline. It answers RAG prompts with the first context, citing it as
+// [1], and leaves follow-up questions unchanged when condensing.
+type StubBackend struct{}
+
+// NewStubBackend creates a stub backend
+func NewStubBackend() *StubBackend {
+	return &StubBackend{}
+}
+
+// Complete returns a deterministic answer derived from the prompt
+func (b *StubBackend) Complete(prompt string) (string, error) {
+	if m := stubFollowUp.FindStringSubmatch(prompt); m != nil {
+		return strings.TrimSpace(m[1]), nil
+	}
+	if m := stubFirstContext.FindStringSubmatch(prompt); m != nil {
+		return strings.Join(strings.Fields(m[1]), " ") + " [1]", nil
+	}
+	return "stub response", nil
+}
+
+// Health always succeeds
+func (b *StubBackend) Health() error {
+	return nil
+}
+
+// ListModels returns the single stub model
+func (b *StubBackend) ListModels() ([]string, error) {
+	return []string{"stub"}, nil
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestStubBackend(t *testing.T) {
+	client, err := NewProviderClient("stub", "", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("NewProviderClient failed: %v", err)
+	}
+
+	results := []*types.SearchResult{
+		{Chunk: &types.DocumentChunk{ID: "c1", Content: "Tokens expire\nafter one hour."}, Similarity: 0.9},
+		{Chunk: &types.DocumentChunk{ID: "c2", Content: "Other text."}, Similarity: 0.5},
+	}
+	response, err := client.GenerateWithContext("When do tokens expire?", results)
+	if err != nil {
+		t.Fatalf("GenerateWithContext failed: %v", err)
+	}
+	if response.Answer != "Tokens expire after one hour. [1]" {
+		t.Errorf("Expected first context as answer, got %q", response.Answer)
+	}
+	if len(response.Claims) != 1 || response.Claims[0].Citations[0].ChunkID != "c1" {
+		t.Errorf("Expected a claim citing c1, got %+v", response.Claims)
+	}
+
+	history := []types.ChatTurn{{Role: types.RoleUser, Content: "What is Go?"}}
+	condensed, err := client.CondenseQuestion(history, "Who made it?")
+	if err != nil {
+		t.Fatalf("CondenseQuestion failed: %v", err)
+	}
+	if condensed != "Who made it?" {
+		t.Errorf("Expected follow-up unchanged, got %q", condensed)
+	}
+
+	if err := client.Health(); err != nil {
+		t.Errorf("Health failed: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// New creates a logger for the logging configuration. Output is "stdout",
+// "stderr" or a file path, which is appended to. The returned closer
+// releases the file and is a no-op for the standard streams.
+func New(level, output string) (*slog.Logger, io.Closer, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var w io.Writer
+	var closer io.Closer = nopCloser{}
+	switch output {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w, closer = file, file
+	}
+
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: lvl}))
+	return logger, closer, nil
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error". An empty level is info.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level: %s", level)
+	}
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"", slog.LevelInfo},
+		{"INFO", slog.LevelInfo},
+		{"warning", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+
+	for _, test := range tests {
+		level, err := ParseLevel(test.level)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) failed: %v", test.level, err)
+			continue
+		}
+		if level != test.expected {
+			t.Errorf("ParseLevel(%q): expected %v, got %v", test.level, test.expected, level)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level, got nil")
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("FileOutput", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rag.log")
+
+		logger, closer, err := New("warn", path)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		logger.Info("hidden message")
+		logger.Warn("visible message", "path", "a.md")
+		if err := closer.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read log: %v", err)
+		}
+		if strings.Contains(string(data), "hidden message") {
+			t.Error("Expected info message to be filtered at warn level")
+		}
+		if !strings.Contains(string(data), "visible message") || !strings.Contains(string(data), "path=a.md") {
+			t.Errorf("Expected warn message with attributes, got %q", data)
+		}
+	})
+
+	t.Run("StandardStreams", func(t *testing.T) {
+		for _, output := range []string{"", "stdout", "stderr"} {
+			_, closer, err := New("info", output)
+			if err != nil {
+				t.Fatalf("New(%q) failed: %v", output, err)
+			}
+			if err := closer.Close(); err != nil {
+				t.Errorf("Close for %q failed: %v", output, err)
+			}
+		}
+	})
+
+	t.Run("InvalidLevel", func(t *testing.T) {
+		if _, _, err := New("loud", "stdout"); err == nil {
+			t.Error("Expected error for invalid level, got nil")
+		}
+	})
+}
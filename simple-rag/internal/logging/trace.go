@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+
+	"simple-rag/pkg/types"
+)
+
+// SimilarityStats summarizes the similarity scores of search results
+func SimilarityStats(results []*types.SearchResult) types.SimilarityStats {
+	if len(results) == 0 {
+		return types.SimilarityStats{}
+	}
+
+	scores := make([]float64, len(results))
+	var sum float64
+	for i, result := range results {
+		scores[i] = result.Similarity
+		sum += result.Similarity
+	}
+	sort.Float64s(scores)
+
+	median := scores[len(scores)/2]
+	if len(scores)%2 == 0 {
+		median = (scores[len(scores)/2-1] + median) / 2
+	}
+
+	return types.SimilarityStats{
+		Min:    scores[0],
+		Max:    scores[len(scores)-1],
+		Mean:   sum / float64(len(scores)),
+		Median: median,
+	}
+}
+
+// TraceAttrs returns the fields of a trace as log attributes
+func TraceAttrs(trace *types.QueryTrace) []any {
+	return []any{
+		slog.String("query", trace.Query),
+		slog.Duration("embedding", trace.EmbeddingLatency),
+		slog.Duration("search", trace.SearchLatency),
+		slog.Duration("rerank", trace.RerankLatency),
+		slog.Duration("generation", trace.GenerationLatency),
+		slog.Duration("total", trace.TotalLatency),
+		slog.Int("candidates", trace.Candidates),
+		slog.Int("results", trace.Results),
+		slog.Float64("similarity_max", trace.Similarity.Max),
+		slog.Int("prompt_chars", trace.PromptChars),
+		slog.Int("prompt_tokens", trace.PromptTokens),
+		slog.Int("dropped_contexts", trace.DroppedContexts),
+	}
+}
+
+// TraceWriter appends query traces to a file as JSON lines, one trace per
+// line, for offline analysis. It is safe for concurrent use.
+type TraceWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewTraceWriter opens a JSON lines file for appending
+func NewTraceWriter(path string) (*TraceWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &TraceWriter{file: file}, nil
+}
+
+// Write appends a trace as one line
+func (w *TraceWriter) Write(trace *types.QueryTrace) error {
+	data, err := json.Marshal(trace)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+// Close closes the trace file
+func (w *TraceWriter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+func TestSimilarityStats(t *testing.T) {
+	results := []*types.SearchResult{
+		{Similarity: 0.9},
+		{Similarity: 0.5},
+		{Similarity: 0.7},
+		{Similarity: 0.6},
+	}
+
+	stats := SimilarityStats(results)
+	expected := types.SimilarityStats{Min: 0.5, Max: 0.9, Mean: 0.675, Median: 0.65}
+	for name, pair := range map[string][2]float64{
+		"min":    {stats.Min, expected.Min},
+		"max":    {stats.Max, expected.Max},
+		"mean":   {stats.Mean, expected.Mean},
+		"median": {stats.Median, expected.Median},
+	} {
+		if math.Abs(pair[0]-pair[1]) > 1e-9 {
+			t.Errorf("Expected %s %f, got %f", name, pair[1], pair[0])
+		}
+	}
+
+	if odd := SimilarityStats(results[:3]); odd.Median != 0.7 {
+		t.Errorf("Expected median 0.7 for odd count, got %f", odd.Median)
+	}
+	if empty := SimilarityStats(nil); empty != (types.SimilarityStats{}) {
+		t.Errorf("Expected zero stats without results, got %+v", empty)
+	}
+}
+
+func TestTraceWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	for i := 0; i < 2; i++ {
+		// Reopening appends rather than truncates
+		writer, err := NewTraceWriter(path)
+		if err != nil {
+			t.Fatalf("NewTraceWriter failed: %v", err)
+		}
+		trace := &types.QueryTrace{
+			Query:            "What is Go?",
+			EmbeddingLatency: 5 * time.Millisecond,
+			Candidates:       3 + i,
+			Similarity:       types.SimilarityStats{Max: 0.9},
+		}
+		if err := writer.Write(trace); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open traces: %v", err)
+	}
+	defer file.Close()
+
+	var traces []types.QueryTrace
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var trace types.QueryTrace
+		if err := json.Unmarshal(scanner.Bytes(), &trace); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		traces = append(traces, trace)
+	}
+
+	if len(traces) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(traces))
+	}
+	if traces[1].Candidates != 4 || traces[0].EmbeddingLatency != 5*time.Millisecond {
+		t.Errorf("Unexpected traces: %+v", traces)
+	}
+}
@@ -0,0 +1,234 @@
+// Package mcp implements the tools side of the Model Context Protocol over
+// stdio: newline-delimited JSON-RPC 2.0 messages carrying the initialize
+// handshake, tools/list and tools/call.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// ProtocolVersion is the MCP revision the server implements. Clients that
+// ask for another revision are answered with this one.
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize bounds a single JSON-RPC message
+const maxMessageSize = 16 << 20
+
+// ToolHandler runs a tool with its JSON arguments. The result is returned
+// to the client as JSON text; an error is reported as a failed tool call.
+type ToolHandler func(arguments json.RawMessage) (any, error)
+
+// Tool is a tool offered to clients. InputSchema is the JSON schema of the
+// arguments object.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Handler     ToolHandler     `json:"-"`
+}
+
+// Server answers MCP requests for a set of tools
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+	byName  map[string]Tool
+	logger  *slog.Logger
+}
+
+// NewServer creates a server that introduces itself with a name and version
+func NewServer(name, version string, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Server{
+		name:    name,
+		version: version,
+		byName:  make(map[string]Tool),
+		logger:  logger,
+	}
+}
+
+// AddTool registers a tool
+func (s *Server) AddTool(tool Tool) {
+	s.tools = append(s.tools, tool)
+	s.byName[tool.Name] = tool
+}
+
+// request is a JSON-RPC request or notification; notifications have no ID
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// content is a block of a tool result
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// toolResult is the result of tools/call
+type toolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Serve reads requests from r and writes responses to w, one JSON message
+// per line, until r is exhausted. Requests are handled in order.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		resp := s.handle(line)
+		if resp == nil {
+			continue
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	return nil
+}
+
+// handle answers one message. It returns nil for notifications.
+func (s *Server) handle(message []byte) *response {
+	var req request
+	if err := json.Unmarshal(message, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	// Notifications, such as notifications/initialized, need no answer
+	if req.ID == nil {
+		s.logger.Debug("mcp notification", "method", req.Method)
+		return nil
+	}
+
+	s.logger.Debug("mcp request", "method", req.Method)
+	result, rpcErr := s.dispatch(req.Method, req.Params)
+	if rpcErr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch runs a request method
+func (s *Server) dispatch(method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		tools := s.tools
+		if tools == nil {
+			tools = []Tool{}
+		}
+		return map[string]any{"tools": tools}, nil
+
+	case "tools/call":
+		var call struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &call); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+		tool, ok := s.byName[call.Name]
+		if !ok {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + call.Name}
+		}
+		return s.callTool(tool, call.Arguments), nil
+
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// callTool runs a tool. Failures are reported in the result, as MCP asks,
+// so the model can see and react to them.
+func (s *Server) callTool(tool Tool, arguments json.RawMessage) *toolResult {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+
+	result, err := tool.Handler(arguments)
+	if err != nil {
+		s.logger.Warn("mcp tool failed", "tool", tool.Name, "error", err)
+		return &toolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+
+	text, ok := result.(string)
+	if !ok {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return &toolResult{Content: []content{{Type: "text", Text: "failed to encode result: " + err.Error()}}, IsError: true}
+		}
+		text = string(data)
+	}
+	return &toolResult{Content: []content{{Type: "text", Text: text}}}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// DecodeArguments unmarshals tool arguments, rejecting unknown fields so
+// typos in argument names are reported instead of ignored
+func DecodeArguments(arguments json.RawMessage, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(arguments))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// serve runs the messages through a server and returns the decoded responses
+func serve(t *testing.T, server *Server, messages ...string) []map[string]any {
+	t.Helper()
+
+	var out strings.Builder
+	if err := server.Serve(strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var responses []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var response map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid response line %q: %v", scanner.Text(), err)
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+func newEchoServer() *Server {
+	server := NewServer("test", "1.0", nil)
+	server.AddTool(Tool{
+		Name:        "echo",
+		Description: "Echo the text argument",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}`),
+		Handler: func(arguments json.RawMessage) (any, error) {
+			var args struct {
+				Text string `json:"text"`
+			}
+			if err := DecodeArguments(arguments, &args); err != nil {
+				return nil, err
+			}
+			if args.Text == "" {
+				return nil, errors.New("text is required")
+			}
+			return map[string]string{"echo": args.Text}, nil
+		},
+	})
+	return server
+}
+
+func TestServerHandshake(t *testing.T) {
+	responses := serve(t, newEchoServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"client","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"ping"}`,
+	)
+
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses (no answer to the notification), got %d", len(responses))
+	}
+
+	result := responses[0]["result"].(map[string]any)
+	if result["protocolVersion"] != ProtocolVersion {
+		t.Errorf("Expected protocol version %s, got %v", ProtocolVersion, result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]any)["tools"]; !ok {
+		t.Error("Expected the tools capability")
+	}
+	if info := result["serverInfo"].(map[string]any); info["name"] != "test" || info["version"] != "1.0" {
+		t.Errorf("Unexpected server info: %v", info)
+	}
+
+	if responses[1]["id"] != "two" {
+		t.Errorf("Expected the string ID to be echoed, got %v", responses[1]["id"])
+	}
+}
+
+func TestServerTools(t *testing.T) {
+	responses := serve(t, newEchoServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{"txt":"typo"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`,
+	)
+	if len(responses) != 5 {
+		t.Fatalf("Expected 5 responses, got %d", len(responses))
+	}
+
+	t.Run("List", func(t *testing.T) {
+		tools := responses[0]["result"].(map[string]any)["tools"].([]any)
+		if len(tools) != 1 {
+			t.Fatalf("Expected 1 tool, got %d", len(tools))
+		}
+		tool := tools[0].(map[string]any)
+		if tool["name"] != "echo" || tool["inputSchema"].(map[string]any)["type"] != "object" {
+			t.Errorf("Unexpected tool: %v", tool)
+		}
+		if _, ok := tool["Handler"]; ok {
+			t.Error("Expected the handler to stay out of the tool listing")
+		}
+	})
+
+	t.Run("Call", func(t *testing.T) {
+		result := responses[1]["result"].(map[string]any)
+		if result["isError"] == true {
+			t.Fatalf("Unexpected error result: %v", result)
+		}
+		text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+		var decoded map[string]string
+		if err := json.Unmarshal([]byte(text), &decoded); err != nil || decoded["echo"] != "hello" {
+			t.Errorf("Expected JSON text with the echo, got %q", text)
+		}
+	})
+
+	t.Run("ToolErrors", func(t *testing.T) {
+		for _, response := range responses[2:4] {
+			result := response["result"].(map[string]any)
+			if result["isError"] != true {
+				t.Errorf("Expected a failed tool call result, got %v", result)
+			}
+		}
+	})
+
+	t.Run("UnknownTool", func(t *testing.T) {
+		rpcErr := responses[4]["error"].(map[string]any)
+		if rpcErr["code"] != float64(codeInvalidParams) {
+			t.Errorf("Expected invalid params error, got %v", rpcErr)
+		}
+	})
+}
+
+func TestServerErrors(t *testing.T) {
+	responses := serve(t, newEchoServer(),
+		`{not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"1.0","id":2,"method":"ping"}`,
+	)
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+
+	expected := []int{codeParseError, codeMethodNotFound, codeInvalidRequest}
+	for i, code := range expected {
+		rpcErr, ok := responses[i]["error"].(map[string]any)
+		if !ok || rpcErr["code"] != float64(code) {
+			t.Errorf("Response %d: expected error code %d, got %v", i, code, responses[i])
+		}
+	}
+	if responses[0]["id"] != nil {
+		t.Errorf("Expected a null ID for a parse error, got %v", responses[0]["id"])
+	}
+}
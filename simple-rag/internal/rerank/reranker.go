@@ -0,0 +1,202 @@
+package rerank
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// Reranker rescores retrieved candidates against the query.
+// Implementations set SearchResult.RerankScore and return the results
+// sorted by that score, highest first.
+type Reranker interface {
+	Rerank(query string, results []*types.SearchResult) ([]*types.SearchResult, error)
+}
+
+// Generator produces a completion for a prompt; llm.Client satisfies it
+type Generator interface {
+	Generate(prompt string) (string, error)
+}
+
+// HTTPReranker calls a cross-encoder rerank endpoint that speaks the
+// Cohere/Jina "/rerank" protocol (llama.cpp server, Infinity, LocalAI, ...)
+type HTTPReranker struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewHTTPReranker creates a new rerank endpoint client
+func NewHTTPReranker(baseURL, model string) *HTTPReranker {
+	return &HTTPReranker{
+		baseURL: baseURL,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// RerankRequest represents a request to the rerank endpoint
+type RerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+// RerankResponse represents a response from the rerank endpoint
+type RerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Rerank scores every candidate with the cross-encoder
+func (r *HTTPReranker) Rerank(query string, results []*types.SearchResult) ([]*types.SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// Prepare request
+	req := RerankRequest{
+		Model:     r.model,
+		Query:     query,
+		Documents: make([]string, len(results)),
+	}
+	for i, result := range results {
+		req.Documents[i] = result.Chunk.Content
+	}
+
+	// Serialize request
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make HTTP request
+	url := fmt.Sprintf("%s/rerank", r.baseURL)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank service returned status %d", resp.StatusCode)
+	}
+
+	// Parse response
+	var rerankResp RerankResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rerankResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(rerankResp.Results) != len(results) {
+		return nil, fmt.Errorf("rerank count mismatch: expected %d, got %d",
+			len(results), len(rerankResp.Results))
+	}
+
+	for _, scored := range rerankResp.Results {
+		if scored.Index < 0 || scored.Index >= len(results) {
+			return nil, fmt.Errorf("rerank index out of range: %d", scored.Index)
+		}
+		results[scored.Index].RerankScore = scored.RelevanceScore
+	}
+
+	return sortByRerankScore(results), nil
+}
+
+// LLMReranker asks the LLM to rate each candidate's relevance from 0 to 10
+type LLMReranker struct {
+	generator Generator
+}
+
+// NewLLMReranker creates a reranker backed by an LLM
+func NewLLMReranker(generator Generator) *LLMReranker {
+	return &LLMReranker{generator: generator}
+}
+
+// scorePattern matches the first number in an LLM reply
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Rerank scores candidates one prompt at a time
+func (r *LLMReranker) Rerank(query string, results []*types.SearchResult) ([]*types.SearchResult, error) {
+	for _, result := range results {
+		reply, err := r.generator.Generate(buildScorePrompt(query, result.Chunk.Content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to score candidate %s: %w", result.Chunk.ID, err)
+		}
+		result.RerankScore = parseScore(reply)
+	}
+
+	return sortByRerankScore(results), nil
+}
+
+// buildScorePrompt builds the relevance rating prompt for one passage
+func buildScorePrompt(query, passage string) string {
+	template := `Rate how relevant the passage is to the question on a scale from 0 (unrelated) to 10 (fully answers it).
+Respond with only the number.
+
+Question: %s
+
+Passage:
+%s
+
+Score:`
+
+	return fmt.Sprintf(template, query, passage)
+}
+
+// parseScore extracts a 0-10 score from an LLM reply, treating unparseable replies as 0
+func parseScore(reply string) float64 {
+	match := scorePattern.FindString(reply)
+	if match == "" {
+		return 0
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0
+	}
+	if score > 10 {
+		return 10
+	}
+	return score
+}
+
+// sortByRerankScore orders results by rerank score, keeping retrieval order on ties
+func sortByRerankScore(results []*types.SearchResult) []*types.SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RerankScore > results[j].RerankScore
+	})
+	return results
+}
+
+// New returns the reranker for a provider name: "http" or "llm".
+// It returns nil for "none" or an empty name, meaning no rerank stage.
+func New(provider, baseURL, model string, generator Generator) (Reranker, error) {
+	switch provider {
+	case "", "none":
+		return nil, nil
+	case "http":
+		return NewHTTPReranker(baseURL, model), nil
+	case "llm":
+		return NewLLMReranker(generator), nil
+	default:
+		return nil, fmt.Errorf("unknown rerank provider: %s", provider)
+	}
+}
@@ -0,0 +1,234 @@
+package rerank
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+// fakeGenerator returns canned replies keyed by a substring of the prompt
+type fakeGenerator struct {
+	replies map[string]string
+	err     error
+	prompts []string
+}
+
+func (g *fakeGenerator) Generate(prompt string) (string, error) {
+	g.prompts = append(g.prompts, prompt)
+	if g.err != nil {
+		return "", g.err
+	}
+	for key, reply := range g.replies {
+		if strings.Contains(prompt, key) {
+			return reply, nil
+		}
+	}
+	return "", nil
+}
+
+// candidates builds search results in descending similarity order
+func candidates(contents ...string) []*types.SearchResult {
+	results := make([]*types.SearchResult, len(contents))
+	for i, content := range contents {
+		results[i] = &types.SearchResult{
+			Chunk:      &types.DocumentChunk{ID: fmt.Sprintf("chunk_%d", i), Content: content},
+			Document:   &types.Document{ID: "doc"},
+			Similarity: 1 - float64(i)*0.1,
+		}
+	}
+	return results
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		provider  string
+		expectNil bool
+		expectErr bool
+	}{
+		{"", true, false},
+		{"none", true, false},
+		{"http", false, false},
+		{"llm", false, false},
+		{"cohere", true, true},
+	}
+
+	for _, test := range tests {
+		reranker, err := New(test.provider, "http://test:8001", "model", &fakeGenerator{})
+		if (err != nil) != test.expectErr {
+			t.Errorf("New(%q): unexpected error state: %v", test.provider, err)
+		}
+		if (reranker == nil) != test.expectNil {
+			t.Errorf("New(%q): expected nil=%v, got %v", test.provider, test.expectNil, reranker)
+		}
+	}
+}
+
+func TestHTTPReranker(t *testing.T) {
+	t.Run("SuccessfulRequest", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/rerank" {
+				t.Errorf("Expected path '/rerank', got %s", r.URL.Path)
+			}
+			if r.Method != "POST" {
+				t.Errorf("Expected POST method, got %s", r.Method)
+			}
+
+			var req RerankRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Failed to decode request: %v", err)
+			}
+			if req.Query != "what is go" {
+				t.Errorf("Expected query 'what is go', got %s", req.Query)
+			}
+			if req.Model != "test-reranker" {
+				t.Errorf("Expected model 'test-reranker', got %s", req.Model)
+			}
+			if len(req.Documents) != 3 {
+				t.Fatalf("Expected 3 documents, got %d", len(req.Documents))
+			}
+
+			// Score in reverse order of similarity, results out of order
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"results": [
+				{"index": 2, "relevance_score": 0.9},
+				{"index": 0, "relevance_score": 0.1},
+				{"index": 1, "relevance_score": 0.5}
+			]}`))
+		}))
+		defer server.Close()
+
+		reranker := NewHTTPReranker(server.URL, "test-reranker")
+		results, err := reranker.Rerank("what is go", candidates("a", "b", "c"))
+		if err != nil {
+			t.Fatalf("Rerank failed: %v", err)
+		}
+
+		expected := []string{"c", "b", "a"}
+		for i, content := range expected {
+			if results[i].Chunk.Content != content {
+				t.Errorf("Position %d: expected %s, got %s", i, content, results[i].Chunk.Content)
+			}
+		}
+		if results[0].RerankScore != 0.9 {
+			t.Errorf("Expected rerank score 0.9, got %f", results[0].RerankScore)
+		}
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		reranker := NewHTTPReranker(server.URL, "")
+		if _, err := reranker.Rerank("q", candidates("a")); err == nil {
+			t.Error("Expected error for server error, got nil")
+		}
+	})
+
+	t.Run("CountMismatch", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results": [{"index": 0, "relevance_score": 0.9}]}`))
+		}))
+		defer server.Close()
+
+		reranker := NewHTTPReranker(server.URL, "")
+		if _, err := reranker.Rerank("q", candidates("a", "b")); err == nil {
+			t.Error("Expected error for count mismatch, got nil")
+		}
+	})
+
+	t.Run("IndexOutOfRange", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results": [{"index": 5, "relevance_score": 0.9}]}`))
+		}))
+		defer server.Close()
+
+		reranker := NewHTTPReranker(server.URL, "")
+		if _, err := reranker.Rerank("q", candidates("a")); err == nil {
+			t.Error("Expected error for out-of-range index, got nil")
+		}
+	})
+
+	t.Run("NoCandidates", func(t *testing.T) {
+		reranker := NewHTTPReranker("http://unused", "")
+		results, err := reranker.Rerank("q", nil)
+		if err != nil || len(results) != 0 {
+			t.Errorf("Expected empty results without a request, got %v, %v", results, err)
+		}
+	})
+}
+
+func TestLLMReranker(t *testing.T) {
+	t.Run("SortsByScore", func(t *testing.T) {
+		generator := &fakeGenerator{replies: map[string]string{
+			"alpha": "2",
+			"beta":  "Score: 9.5",
+			"gamma": "I would say 6/10",
+		}}
+
+		reranker := NewLLMReranker(generator)
+		results, err := reranker.Rerank("question", candidates("alpha", "beta", "gamma"))
+		if err != nil {
+			t.Fatalf("Rerank failed: %v", err)
+		}
+
+		expected := []string{"beta", "gamma", "alpha"}
+		for i, content := range expected {
+			if results[i].Chunk.Content != content {
+				t.Errorf("Position %d: expected %s, got %s", i, content, results[i].Chunk.Content)
+			}
+		}
+		if len(generator.prompts) != 3 {
+			t.Errorf("Expected 3 prompts, got %d", len(generator.prompts))
+		}
+		if !strings.Contains(generator.prompts[0], "Question: question") {
+			t.Error("Prompt should contain the question")
+		}
+	})
+
+	t.Run("TiesKeepRetrievalOrder", func(t *testing.T) {
+		generator := &fakeGenerator{replies: map[string]string{"": "5"}}
+
+		reranker := NewLLMReranker(generator)
+		results, err := reranker.Rerank("q", candidates("first", "second", "third"))
+		if err != nil {
+			t.Fatalf("Rerank failed: %v", err)
+		}
+		if results[0].Chunk.Content != "first" || results[2].Chunk.Content != "third" {
+			t.Errorf("Expected retrieval order on ties, got %s, %s, %s",
+				results[0].Chunk.Content, results[1].Chunk.Content, results[2].Chunk.Content)
+		}
+	})
+
+	t.Run("GeneratorError", func(t *testing.T) {
+		reranker := NewLLMReranker(&fakeGenerator{err: fmt.Errorf("boom")})
+		if _, err := reranker.Rerank("q", candidates("a")); err == nil {
+			t.Error("Expected error from generator, got nil")
+		}
+	})
+}
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		reply    string
+		expected float64
+	}{
+		{"7", 7},
+		{"Score: 8.5", 8.5},
+		{"42", 10},
+		{"not relevant", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		if got := parseScore(test.reply); got != test.expected {
+			t.Errorf("parseScore(%q): expected %f, got %f", test.reply, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package vector
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveVersion is the version of the export archive format
+const archiveVersion = 1
+
+// archiveManifest is the first file of an export archive
+type archiveManifest struct {
+	Version int `json:"version"`
+	CollectionInfo
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// archiveFiles are the store files an archive may contain, in archive order
+var archiveFiles = []string{"store.json", "documents.json", "chunks.json", "vectors.bin"}
+
+// Export writes a collection to w as a gzipped tar archive holding a
+// manifest and the store files. The archive can be imported on another
+// machine with Import.
+func (c *Collections) Export(name string, w io.Writer) (*CollectionInfo, error) {
+	db, err := c.Open(name, Options{})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	info, err := db.export(c.displayName(name), w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export collection %s: %w", c.displayName(name), err)
+	}
+	return info, nil
+}
+
+// export writes the store files while holding the lock, so the archive is
+// a consistent snapshot
+func (db *Database) export(name string, w io.Writer) (*CollectionInfo, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	info := db.info(name)
+	manifest, err := json.MarshalIndent(archiveManifest{Version: archiveVersion, CollectionInfo: info, ExportedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(manifest)), ModTime: time.Now()}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return nil, err
+	}
+	for _, file := range archiveFiles {
+		if err := addArchiveFile(tw, filepath.Join(db.storagePath, file)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// addArchiveFile copies a store file into the archive, skipping files the
+// store does not have
+func addArchiveFile(tw *tar.Writer, path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: filepath.Base(path), Mode: 0644, Size: stat.Size(), ModTime: stat.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// Import reads an archive written by Export into a new collection. An
+// empty name uses the collection name recorded in the archive. The store
+// is unpacked and loaded in a temporary directory first, so a corrupt
+// archive never leaves a partial collection behind.
+func (c *Collections) Import(r io.Reader, name string) (*CollectionInfo, error) {
+	if err := os.MkdirAll(c.root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(c.root, ".import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	manifest, err := unpackArchive(r, tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if name == "" {
+		name = manifest.Name
+	}
+	if name != DefaultCollection {
+		if err := ValidateCollectionName(name); err != nil {
+			return nil, err
+		}
+	}
+	if c.Exists(name) {
+		return nil, fmt.Errorf("collection %s already exists", name)
+	}
+
+	// Check that the store loads and matches the manifest before installing it
+	db := NewDatabaseWithOptions(tmp, Options{})
+	if err := db.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to load archived collection: %w", err)
+	}
+	info := db.Info(name)
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	if info.Documents != manifest.Documents || info.Chunks != manifest.Chunks {
+		return nil, fmt.Errorf("archive manifest lists %d documents and %d chunks, but the store has %d and %d",
+			manifest.Documents, manifest.Chunks, info.Documents, info.Chunks)
+	}
+
+	dir := c.Path(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create collection directory: %w", err)
+	}
+	for _, file := range archiveFiles {
+		err := os.Rename(filepath.Join(tmp, file), filepath.Join(dir, file))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to install collection %s: %w", name, err)
+		}
+	}
+	return &info, nil
+}
+
+// unpackArchive extracts the store files of an archive into dir and returns
+// its manifest. Entries other than the known store files are rejected.
+func unpackArchive(r io.Reader, dir string) (*archiveManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	allowed := make(map[string]bool, len(archiveFiles))
+	for _, file := range archiveFiles {
+		allowed[file] = true
+	}
+
+	var manifest *archiveManifest
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Name == "manifest.json" {
+			manifest = &archiveManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest: %w", err)
+			}
+			if manifest.Version != archiveVersion {
+				return nil, fmt.Errorf("unsupported archive version %d", manifest.Version)
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg || !allowed[header.Name] {
+			return nil, fmt.Errorf("unexpected entry %s", header.Name)
+		}
+
+		file, err := os.OpenFile(filepath.Join(dir, header.Name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(file, tr); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("missing manifest.json")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "store.json")); err != nil {
+		return nil, fmt.Errorf("missing store.json")
+	}
+	return manifest, nil
+}
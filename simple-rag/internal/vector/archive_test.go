@@ -0,0 +1,124 @@
+package vector
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestExportImport(t *testing.T) {
+	for _, quantization := range []string{QuantizationNone, QuantizationInt8, QuantizationPQ} {
+		t.Run(quantization, func(t *testing.T) {
+			source := NewCollections(t.TempDir())
+			if err := source.Create("docs", Options{Quantization: quantization, EmbeddingModel: "stub/test"}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+
+			vectors := syntheticVectors(rand.New(rand.NewSource(1)), 300, 16, 5)
+			db, err := source.Open("docs", Options{})
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			chunks := make([]*types.DocumentChunk, len(vectors))
+			for i, v := range vectors {
+				chunks[i] = &types.DocumentChunk{ID: fmt.Sprintf("c%d", i), DocumentID: "doc", Embedding: v}
+			}
+			if err := db.StoreDocumentWithChunks(&types.Document{ID: "doc"}, chunks); err != nil {
+				t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+			}
+			want, err := db.Search(vectors[0], 5, 0)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			db.Close()
+
+			var archive bytes.Buffer
+			exported, err := source.Export("docs", &archive)
+			if err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+			if exported.Chunks != len(vectors) {
+				t.Errorf("Expected %d exported chunks, got %d", len(vectors), exported.Chunks)
+			}
+
+			target := NewCollections(t.TempDir())
+			imported, err := target.Import(bytes.NewReader(archive.Bytes()), "")
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if imported.Name != "docs" || imported.EmbeddingModel != "stub/test" || imported.Dimensions != 16 || imported.Quantization != quantization {
+				t.Errorf("Unexpected imported collection: %+v", imported)
+			}
+
+			// The target machine's configuration may ask for another mode; the
+			// imported store keeps its own, next to collections using others
+			targetOptions := Options{Quantization: QuantizationNone, EmbeddingModel: "stub/test"}
+			if err := target.Create("local", targetOptions); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			copied, err := target.Open("docs", targetOptions)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer copied.Close()
+			if copied.Quantization() != quantization {
+				t.Errorf("Expected the archived %s mode, got %s", quantization, copied.Quantization())
+			}
+			got, err := copied.Search(vectors[0], 5, 0)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if recall(want, got) != 1 {
+				t.Error("Expected the imported collection to return the same results")
+			}
+
+			if _, err := target.Import(bytes.NewReader(archive.Bytes()), ""); err == nil {
+				t.Error("Expected error importing over an existing collection, got nil")
+			}
+			if _, err := target.Import(bytes.NewReader(archive.Bytes()), "copy"); err != nil {
+				t.Errorf("Import under a new name failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestImportRejectsUnsafeArchives(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{"PathTraversal", map[string]string{"manifest.json": `{"version":1,"name":"x"}`, "../evil": "x"}, "unexpected entry"},
+		{"NoManifest", map[string]string{"store.json": `{"quantization":"none"}`}, "missing manifest"},
+		{"FutureVersion", map[string]string{"manifest.json": `{"version":99,"name":"x"}`}, "unsupported archive version"},
+		{"CountMismatch", map[string]string{"manifest.json": `{"version":1,"name":"x","documents":3}`, "store.json": `{"quantization":"none"}`}, "manifest lists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var archive bytes.Buffer
+			gz := gzip.NewWriter(&archive)
+			tw := tar.NewWriter(gz)
+			for name, content := range test.files {
+				tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+				tw.Write([]byte(content))
+			}
+			tw.Close()
+			gz.Close()
+
+			collections := NewCollections(t.TempDir())
+			_, err := collections.Import(&archive, "")
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error containing %q, got %v", test.expected, err)
+			}
+			if collections.Exists("x") {
+				t.Error("Expected no collection after a failed import")
+			}
+		})
+	}
+}
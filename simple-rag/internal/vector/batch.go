@@ -0,0 +1,60 @@
+package vector
+
+import (
+	"fmt"
+	"sync"
+
+	"simple-rag/pkg/types"
+)
+
+// EmbedChunks embeds chunks in batches of batchSize using up to workers
+// concurrent requests. After the first failure no new batches are started
+// and that error is returned; chunks of unfinished batches keep no embedding.
+func EmbedChunks(provider EmbeddingProvider, chunks []*types.DocumentChunk, batchSize, workers int) error {
+	if batchSize <= 0 {
+		batchSize = len(chunks)
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	batches := make(chan []*types.DocumentChunk)
+	stop := make(chan struct{})
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if err := provider.ProcessChunks(batch); err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("failed to process embeddings for batch: %w", err)
+						close(stop)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < len(chunks); i += batchSize {
+		end := i + batchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		select {
+		case batches <- chunks[i:end]:
+		case <-stop:
+			break feed
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,95 @@
+package vector
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+// concurrentProvider tracks how many requests run at once
+type concurrentProvider struct {
+	StubEmbeddingClient
+	active  int32
+	peak    int32
+	failOn  string
+	mu      sync.Mutex
+	batches int
+}
+
+func (p *concurrentProvider) GetEmbeddings(texts []string) ([][]float64, error) {
+	active := atomic.AddInt32(&p.active, 1)
+	defer atomic.AddInt32(&p.active, -1)
+	for {
+		peak := atomic.LoadInt32(&p.peak)
+		if active <= peak || atomic.CompareAndSwapInt32(&p.peak, peak, active) {
+			break
+		}
+	}
+
+	p.mu.Lock()
+	p.batches++
+	p.mu.Unlock()
+
+	for _, text := range texts {
+		if text == p.failOn {
+			return nil, fmt.Errorf("boom")
+		}
+	}
+	return p.StubEmbeddingClient.GetEmbeddings(texts)
+}
+
+func (p *concurrentProvider) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(p, chunks)
+}
+
+func makeChunks(n int) []*types.DocumentChunk {
+	chunks := make([]*types.DocumentChunk, n)
+	for i := range chunks {
+		chunks[i] = &types.DocumentChunk{ID: fmt.Sprintf("c%d", i), Content: fmt.Sprintf("chunk %d", i)}
+	}
+	return chunks
+}
+
+func TestEmbedChunks(t *testing.T) {
+	t.Run("AllChunksEmbedded", func(t *testing.T) {
+		provider := &concurrentProvider{StubEmbeddingClient: *NewStubEmbeddingClient(8)}
+		chunks := makeChunks(25)
+
+		if err := EmbedChunks(provider, chunks, 4, 3); err != nil {
+			t.Fatalf("EmbedChunks failed: %v", err)
+		}
+		for _, chunk := range chunks {
+			if len(chunk.Embedding) != 8 {
+				t.Errorf("Chunk %s has no embedding", chunk.ID)
+			}
+		}
+		if provider.batches != 7 {
+			t.Errorf("Expected 7 batches, got %d", provider.batches)
+		}
+		if provider.peak > 3 {
+			t.Errorf("Expected at most 3 concurrent requests, got %d", provider.peak)
+		}
+	})
+
+	t.Run("StopsOnError", func(t *testing.T) {
+		provider := &concurrentProvider{StubEmbeddingClient: *NewStubEmbeddingClient(8), failOn: "chunk 0"}
+		chunks := makeChunks(100)
+
+		err := EmbedChunks(provider, chunks, 1, 1)
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if provider.batches >= 100 {
+			t.Errorf("Expected remaining batches to be skipped, got %d", provider.batches)
+		}
+	})
+
+	t.Run("NoChunks", func(t *testing.T) {
+		if err := EmbedChunks(NewStubEmbeddingClient(8), nil, 4, 2); err != nil {
+			t.Errorf("Expected nil error, got %v", err)
+		}
+	})
+}
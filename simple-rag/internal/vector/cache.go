@@ -0,0 +1,131 @@
+package vector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"simple-rag/pkg/types"
+)
+
+// EmbeddingCache stores embeddings on disk, one file per text, keyed by a
+// hash of the namespace and the text. The namespace should identify the
+// provider and model so vectors of different models never mix.
+type EmbeddingCache struct {
+	dir       string
+	namespace string
+}
+
+// NewEmbeddingCache creates a cache in dir
+func NewEmbeddingCache(dir, namespace string) (*EmbeddingCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+	return &EmbeddingCache{dir: dir, namespace: namespace}, nil
+}
+
+// Get returns the cached embedding of a text
+func (c *EmbeddingCache) Get(text string) ([]float64, bool) {
+	data, err := os.ReadFile(c.path(text))
+	if err != nil {
+		return nil, false
+	}
+
+	var embedding []float64
+	if err := json.Unmarshal(data, &embedding); err != nil || len(embedding) == 0 {
+		return nil, false
+	}
+	return embedding, true
+}
+
+// Put stores the embedding of a text
+func (c *EmbeddingCache) Put(text string, embedding []float64) error {
+	data, err := json.Marshal(embedding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal embedding: %w", err)
+	}
+
+	path := c.path(text)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// path returns the cache file of a text, sharded by the first key byte
+func (c *EmbeddingCache) path(text string) string {
+	sum := sha256.Sum256([]byte(c.namespace + "\x00" + text))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// CachedProvider answers from an EmbeddingCache and only sends cache misses
+// to the wrapped provider
+type CachedProvider struct {
+	provider EmbeddingProvider
+	cache    *EmbeddingCache
+}
+
+// NewCachedProvider wraps a provider with a cache
+func NewCachedProvider(provider EmbeddingProvider, cache *EmbeddingCache) *CachedProvider {
+	return &CachedProvider{provider: provider, cache: cache}
+}
+
+// GetEmbeddings gets embeddings for a list of texts
+func (p *CachedProvider) GetEmbeddings(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+
+	embeddings := make([][]float64, len(texts))
+	missing := make(map[string][]int)
+	var misses []string
+	for i, text := range texts {
+		if embedding, ok := p.cache.Get(text); ok {
+			embeddings[i] = embedding
+			continue
+		}
+		if _, seen := missing[text]; !seen {
+			misses = append(misses, text)
+		}
+		missing[text] = append(missing[text], i)
+	}
+
+	if len(misses) > 0 {
+		fetched, err := p.provider.GetEmbeddings(misses)
+		if err != nil {
+			return nil, err
+		}
+		if len(fetched) != len(misses) {
+			return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(misses), len(fetched))
+		}
+
+		for i, text := range misses {
+			for _, index := range missing[text] {
+				embeddings[index] = fetched[i]
+			}
+			// A failed cache write only costs a later re-embedding
+			p.cache.Put(text, fetched[i])
+		}
+	}
+
+	return embeddings, nil
+}
+
+// GetSingleEmbedding gets embedding for a single text
+func (p *CachedProvider) GetSingleEmbedding(text string) ([]float64, error) {
+	return singleEmbedding(p, text)
+}
+
+// ProcessChunks processes document chunks and adds embeddings
+func (p *CachedProvider) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(p, chunks)
+}
+
+// Health checks the wrapped provider
+func (p *CachedProvider) Health() error {
+	return p.provider.Health()
+}
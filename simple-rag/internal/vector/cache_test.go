@@ -0,0 +1,69 @@
+package vector
+
+import (
+	"os"
+	"testing"
+)
+
+// countingProvider records the texts it was asked to embed
+type countingProvider struct {
+	StubEmbeddingClient
+	requested []string
+}
+
+func (p *countingProvider) GetEmbeddings(texts []string) ([][]float64, error) {
+	p.requested = append(p.requested, texts...)
+	return p.StubEmbeddingClient.GetEmbeddings(texts)
+}
+
+func TestCachedProvider(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "cache_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cache, err := NewEmbeddingCache(tempDir, "stub|m")
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache failed: %v", err)
+	}
+	inner := &countingProvider{StubEmbeddingClient: *NewStubEmbeddingClient(8)}
+	provider := NewCachedProvider(inner, cache)
+
+	first, err := provider.GetEmbeddings([]string{"a", "b", "a"})
+	if err != nil {
+		t.Fatalf("GetEmbeddings failed: %v", err)
+	}
+	if len(inner.requested) != 2 {
+		t.Errorf("Expected duplicate texts requested once, got %v", inner.requested)
+	}
+	if cosineSimilarity(first[0], first[2]) < 1-1e-9 {
+		t.Error("Expected duplicates to share an embedding")
+	}
+
+	inner.requested = nil
+	second, err := provider.GetEmbeddings([]string{"b", "c"})
+	if err != nil {
+		t.Fatalf("GetEmbeddings failed: %v", err)
+	}
+	if len(inner.requested) != 1 || inner.requested[0] != "c" {
+		t.Errorf("Expected only the miss to be requested, got %v", inner.requested)
+	}
+	if cosineSimilarity(second[0], first[1]) < 1-1e-9 {
+		t.Error("Expected cached embedding for b")
+	}
+
+	t.Run("PersistsAcrossInstances", func(t *testing.T) {
+		reopened, _ := NewEmbeddingCache(tempDir, "stub|m")
+		if _, ok := reopened.Get("a"); !ok {
+			t.Error("Expected cached embedding on disk")
+		}
+	})
+
+	t.Run("NamespacesAreSeparate", func(t *testing.T) {
+		other, _ := NewEmbeddingCache(tempDir, "stub|other-model")
+		if _, ok := other.Get("a"); ok {
+			t.Error("Expected no hit for another model")
+		}
+	})
+}
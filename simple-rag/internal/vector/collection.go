@@ -0,0 +1,189 @@
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// DefaultCollection is the collection stored directly in the storage path,
+// where stores created before collections existed live
+const DefaultCollection = "default"
+
+// collectionsDir holds the named collections inside the storage path
+const collectionsDir = "collections"
+
+var collectionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Collections manages named vector stores under one storage path. Each
+// collection is a separate store with its own documents, chunks and
+// recorded embedding model.
+type Collections struct {
+	root string
+}
+
+// CollectionInfo describes a collection
+type CollectionInfo struct {
+	Name           string `json:"name"`
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	Dimensions     int    `json:"dimensions,omitempty"`
+	Quantization   string `json:"quantization"`
+	Documents      int    `json:"documents"`
+	Chunks         int    `json:"chunks"`
+}
+
+// NewCollections manages the collections under a storage path
+func NewCollections(root string) *Collections {
+	return &Collections{root: root}
+}
+
+// ValidateCollectionName checks that a name can be used as a directory name
+// on every platform
+func ValidateCollectionName(name string) error {
+	if !collectionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid collection name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Path returns the storage directory of a collection. An empty name is the
+// default collection.
+func (c *Collections) Path(name string) string {
+	if name == "" || name == DefaultCollection {
+		return c.root
+	}
+	return filepath.Join(c.root, collectionsDir, name)
+}
+
+// Exists reports whether a collection has been created
+func (c *Collections) Exists(name string) bool {
+	dir := c.Path(name)
+	for _, file := range []string{"store.json", "documents.json"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens an existing collection. The default collection is created on
+// first use; named collections must be created first. An existing
+// collection, including an imported one, keeps the quantization recorded in
+// its store whatever the options ask for.
+func (c *Collections) Open(name string, options Options) (*Database, error) {
+	if name != "" && name != DefaultCollection {
+		if err := ValidateCollectionName(name); err != nil {
+			return nil, err
+		}
+		if !c.Exists(name) {
+			return nil, fmt.Errorf("collection %s does not exist", name)
+		}
+	}
+
+	db := NewDatabaseWithOptions(c.Path(name), options)
+	if err := db.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to open collection %s: %w", c.displayName(name), err)
+	}
+	return db, nil
+}
+
+// Create creates an empty collection, recording the embedding model and
+// quantization from the options
+func (c *Collections) Create(name string, options Options) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if c.Exists(name) {
+		return fmt.Errorf("collection %s already exists", name)
+	}
+
+	db := NewDatabaseWithOptions(c.Path(name), options)
+	if err := db.Initialize(); err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", name, err)
+	}
+	return db.Close()
+}
+
+// List describes the default collection, if it has been created, and every
+// named collection, sorted by name
+func (c *Collections) List() ([]CollectionInfo, error) {
+	var names []string
+	if c.Exists(DefaultCollection) {
+		names = append(names, DefaultCollection)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(c.root, collectionsDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	var named []string
+	for _, entry := range entries {
+		if entry.IsDir() && c.Exists(entry.Name()) {
+			named = append(named, entry.Name())
+		}
+	}
+	sort.Strings(named)
+	names = append(names, named...)
+
+	infos := make([]CollectionInfo, 0, len(names))
+	for _, name := range names {
+		db, err := c.Open(name, Options{})
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, db.Info(name))
+		if err := db.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
+}
+
+// Drop deletes a named collection and all of its files
+func (c *Collections) Drop(name string) error {
+	if name == "" || name == DefaultCollection {
+		return fmt.Errorf("the default collection cannot be dropped")
+	}
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !c.Exists(name) {
+		return fmt.Errorf("collection %s does not exist", name)
+	}
+
+	if err := os.RemoveAll(c.Path(name)); err != nil {
+		return fmt.Errorf("failed to drop collection %s: %w", name, err)
+	}
+	return nil
+}
+
+// displayName returns the name shown for a collection
+func (c *Collections) displayName(name string) string {
+	if name == "" {
+		return DefaultCollection
+	}
+	return name
+}
+
+// Info describes the store as the named collection
+func (db *Database) Info(name string) CollectionInfo {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.info(name)
+}
+
+// info describes the store; the caller holds the lock
+func (db *Database) info(name string) CollectionInfo {
+	return CollectionInfo{
+		Name:           name,
+		EmbeddingModel: db.settings.EmbeddingModel,
+		Dimensions:     db.settings.Dimensions,
+		Quantization:   db.settings.Quantization,
+		Documents:      len(db.documents),
+		Chunks:         len(db.chunks),
+	}
+}
@@ -0,0 +1,193 @@
+package vector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestCollections(t *testing.T) {
+	root := t.TempDir()
+	collections := NewCollections(root)
+
+	t.Run("Create", func(t *testing.T) {
+		if err := collections.Create("team-a", Options{EmbeddingModel: "stub/a"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := collections.Create("team-b", Options{EmbeddingModel: "stub/b", Quantization: QuantizationInt8}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := collections.Create("team-a", Options{}); err == nil {
+			t.Error("Expected error creating an existing collection, got nil")
+		}
+		for _, name := range []string{"", "../escape", "a/b", ".hidden", "has space"} {
+			if err := collections.Create(name, Options{}); err == nil {
+				t.Errorf("Expected error for collection name %q, got nil", name)
+			}
+		}
+	})
+
+	t.Run("SeparateNamespaces", func(t *testing.T) {
+		db, err := collections.Open("team-a", Options{EmbeddingModel: "stub/a"})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer db.Close()
+
+		doc := &types.Document{ID: "doc1", FilePath: "a.md"}
+		chunks := []*types.DocumentChunk{{ID: "c1", DocumentID: "doc1", Embedding: []float64{1, 0, 0}}}
+		if err := db.StoreDocumentWithChunks(doc, chunks); err != nil {
+			t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+		}
+
+		other, err := collections.Open("team-b", Options{})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer other.Close()
+		if len(other.ListDocuments()) != 0 {
+			t.Error("Expected documents to stay in their own collection")
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		if _, err := collections.Open("missing", Options{}); err == nil {
+			t.Error("Expected error opening a missing collection, got nil")
+		}
+		if err := collections.Drop("missing"); err == nil {
+			t.Error("Expected error dropping a missing collection, got nil")
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		infos, err := collections.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(infos) != 2 || infos[0].Name != "team-a" || infos[1].Name != "team-b" {
+			t.Fatalf("Expected team-a and team-b, got %+v", infos)
+		}
+		if infos[0].EmbeddingModel != "stub/a" || infos[0].Dimensions != 3 || infos[0].Documents != 1 || infos[0].Chunks != 1 {
+			t.Errorf("Unexpected info for team-a: %+v", infos[0])
+		}
+		if infos[1].Quantization != QuantizationInt8 || infos[1].Dimensions != 0 {
+			t.Errorf("Unexpected info for team-b: %+v", infos[1])
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		db, err := collections.Open("", Options{})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		db.Close()
+
+		if _, err := os.Stat(filepath.Join(root, "store.json")); err != nil {
+			t.Errorf("Expected the default collection in the storage path: %v", err)
+		}
+		infos, err := collections.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(infos) != 3 || infos[0].Name != DefaultCollection {
+			t.Errorf("Expected the default collection first, got %+v", infos)
+		}
+		if err := collections.Drop(DefaultCollection); err == nil {
+			t.Error("Expected error dropping the default collection, got nil")
+		}
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		if err := collections.Drop("team-b"); err != nil {
+			t.Fatalf("Drop failed: %v", err)
+		}
+		if collections.Exists("team-b") {
+			t.Error("Expected team-b to be gone")
+		}
+		if !collections.Exists("team-a") {
+			t.Error("Expected team-a to remain")
+		}
+	})
+}
+
+func TestEmbeddingModelMismatch(t *testing.T) {
+	dir := t.TempDir()
+
+	db := NewDatabaseWithOptions(dir, Options{EmbeddingModel: "ollama/nomic-embed-text"})
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	db.Close()
+
+	for _, model := range []string{"", "ollama/nomic-embed-text"} {
+		db := NewDatabaseWithOptions(dir, Options{EmbeddingModel: model})
+		if err := db.Initialize(); err != nil {
+			t.Errorf("Expected store to open with model %q: %v", model, err)
+		}
+		db.Close()
+	}
+
+	db = NewDatabaseWithOptions(dir, Options{EmbeddingModel: "openai/text-embedding-3-small"})
+	err := db.Initialize()
+	if err == nil || !strings.Contains(err.Error(), "nomic-embed-text") {
+		t.Errorf("Expected embedding model mismatch error, got %v", err)
+	}
+}
+
+func TestMixedDimensions(t *testing.T) {
+	db := NewDatabase(t.TempDir())
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	t.Run("WithinDocument", func(t *testing.T) {
+		chunks := []*types.DocumentChunk{
+			{ID: "m1", DocumentID: "mixed", Embedding: []float64{1, 0, 0}},
+			{ID: "m2", DocumentID: "mixed", Embedding: []float64{1, 0}},
+		}
+		if err := db.StoreDocumentWithChunks(&types.Document{ID: "mixed"}, chunks); err == nil {
+			t.Error("Expected error storing mixed dimensions, got nil")
+		}
+		if db.Dimensions() != 0 {
+			t.Errorf("Expected no recorded dimensions, got %d", db.Dimensions())
+		}
+	})
+
+	chunks := []*types.DocumentChunk{{ID: "c1", DocumentID: "doc1", Embedding: []float64{1, 0, 0}}}
+	if err := db.StoreDocumentWithChunks(&types.Document{ID: "doc1"}, chunks); err != nil {
+		t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+	}
+
+	t.Run("AcrossDocuments", func(t *testing.T) {
+		chunks := []*types.DocumentChunk{{ID: "c2", DocumentID: "doc2", Embedding: []float64{1, 0}}}
+		if err := db.StoreDocumentWithChunks(&types.Document{ID: "doc2"}, chunks); err == nil {
+			t.Error("Expected error storing a different dimension, got nil")
+		}
+		if err := db.StoreChunk(chunks[0]); err == nil {
+			t.Error("Expected error storing a chunk of a different dimension, got nil")
+		}
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		if _, err := db.Search([]float64{1, 0}, 5, 0); err == nil {
+			t.Error("Expected error searching with a different dimension, got nil")
+		}
+	})
+
+	t.Run("Persisted", func(t *testing.T) {
+		// Dimensions stay recorded after the store is emptied and reopened
+		if err := db.DeleteDocument("doc1"); err != nil {
+			t.Fatalf("DeleteDocument failed: %v", err)
+		}
+		reopened := NewDatabase(db.storagePath)
+		if err := reopened.Initialize(); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		if reopened.Dimensions() != 3 {
+			t.Errorf("Expected 3 recorded dimensions, got %d", reopened.Dimensions())
+		}
+	})
+}
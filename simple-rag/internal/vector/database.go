@@ -2,6 +2,7 @@ package vector
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -12,18 +13,54 @@ import (
 	"simple-rag/pkg/types"
 )
 
-// Database represents a simple vector database using JSON storage
+// Database represents a simple vector database using JSON storage.
+// Quantized stores keep embeddings out of the JSON files, in a compact
+// vectors file managed by a quantizedIndex.
 type Database struct {
 	storagePath string
+	options     Options
 	mu          sync.RWMutex
 	chunks      map[string]*types.DocumentChunk
 	documents   map[string]*types.Document
+	index       *quantizedIndex
+	settings    storeSettings
+}
+
+// Options configures a database
+type Options struct {
+	// Quantization is "none", "int8" or "pq". It only applies when the store
+	// is created; an existing store always uses the mode it recorded.
+	Quantization string
+	// PQSubvectors is the number of product quantization subvectors
+	PQSubvectors int
+	// RescoreFactor times k candidates are rescored at full precision
+	RescoreFactor int
+	// EmbeddingModel names the model that embeds the store's chunks, e.g.
+	// "ollama/nomic-embed-text". It is recorded when the store is created
+	// and a store cannot be opened with a different model.
+	EmbeddingModel string
+}
+
+// storeSettings is persisted in store.json when a store is created.
+// Dimensions is recorded when the first embedding is stored.
+type storeSettings struct {
+	Quantization   string `json:"quantization"`
+	PQSubvectors   int    `json:"pq_subvectors,omitempty"`
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	Dimensions     int    `json:"dimensions,omitempty"`
 }
 
 // NewDatabase creates a new vector database
 func NewDatabase(storagePath string) *Database {
+	return NewDatabaseWithOptions(storagePath, Options{})
+}
+
+// NewDatabaseWithOptions creates a new vector database with quantization
+// options
+func NewDatabaseWithOptions(storagePath string, options Options) *Database {
 	return &Database{
 		storagePath: storagePath,
+		options:     options,
 		chunks:      make(map[string]*types.DocumentChunk),
 		documents:   make(map[string]*types.Document),
 	}
@@ -35,14 +72,172 @@ func (db *Database) Initialize() error {
 		return fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
+	switch db.options.Quantization {
+	case "", QuantizationNone, QuantizationInt8, QuantizationPQ:
+	default:
+		return fmt.Errorf("unknown quantization: %s", db.options.Quantization)
+	}
+
+	settings, err := db.loadSettings()
+	if err != nil {
+		return err
+	}
+	db.settings = settings
+	switch settings.Quantization {
+	case QuantizationNone:
+	case QuantizationInt8, QuantizationPQ:
+		db.index = newQuantizedIndex(filepath.Join(db.storagePath, "vectors.bin"), settings.Quantization, settings.PQSubvectors)
+	default:
+		return fmt.Errorf("unknown quantization in store settings: %s", settings.Quantization)
+	}
+
 	// Load existing data
 	if err := db.loadData(); err != nil {
 		return fmt.Errorf("failed to load existing data: %w", err)
 	}
 
+	// Stores created before dimensions were recorded take them from their data
+	if db.settings.Dimensions == 0 {
+		if err := db.recordDimensions(db.storedDimensions()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Quantization returns the quantization mode of the store
+func (db *Database) Quantization() string {
+	if db.index == nil {
+		return QuantizationNone
+	}
+	return db.index.kind
+}
+
+// EmbeddingModel returns the embedding model recorded for the store
+func (db *Database) EmbeddingModel() string {
+	return db.settings.EmbeddingModel
+}
+
+// Dimensions returns the embedding dimensions of the store, or zero before
+// the first embedding is stored
+func (db *Database) Dimensions() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.settings.Dimensions
+}
+
+// Close releases the vectors file of a quantized store
+func (db *Database) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.index == nil {
+		return nil
+	}
+	return db.index.close()
+}
+
+// loadSettings reads store.json, or creates it from the options for a new
+// store. Stores created before quantization existed use none, and the
+// quantization option is ignored for stores that already exist.
+func (db *Database) loadSettings() (storeSettings, error) {
+	requested := db.options.Quantization
+	settingsPath := filepath.Join(db.storagePath, "store.json")
+
+	var settings storeSettings
+	data, err := os.ReadFile(settingsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return settings, fmt.Errorf("failed to parse store settings: %w", err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		settings = storeSettings{Quantization: QuantizationNone, EmbeddingModel: db.options.EmbeddingModel}
+		if _, err := os.Stat(filepath.Join(db.storagePath, "documents.json")); errors.Is(err, os.ErrNotExist) && requested != "" {
+			settings.Quantization = requested
+			if requested == QuantizationPQ {
+				settings.PQSubvectors = db.options.PQSubvectors
+				if settings.PQSubvectors <= 0 {
+					settings.PQSubvectors = defaultPQSubvectors
+				}
+			}
+		}
+		if err := db.saveSettings(settings); err != nil {
+			return settings, err
+		}
+	default:
+		return settings, fmt.Errorf("failed to read store settings: %w", err)
+	}
+
+	// Stores created before the model was recorded adopt the first one used
+	if model := db.options.EmbeddingModel; model != "" && model != settings.EmbeddingModel {
+		if settings.EmbeddingModel != "" {
+			return settings, fmt.Errorf("store %s was built with embedding model %s, not %s; re-ingest into a new collection to change models",
+				db.storagePath, settings.EmbeddingModel, model)
+		}
+		settings.EmbeddingModel = model
+		if err := db.saveSettings(settings); err != nil {
+			return settings, err
+		}
+	}
+	return settings, nil
+}
+
+// saveSettings writes store.json
+func (db *Database) saveSettings(settings storeSettings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(db.storagePath, "store.json"), data); err != nil {
+		return fmt.Errorf("failed to write store settings: %w", err)
+	}
+	return nil
+}
+
+// checkDimensions rejects an embedding whose length differs from the
+// store's, so chunks from different models never end up side by side
+func (db *Database) checkDimensions(embedding []float64) error {
+	if dims := db.settings.Dimensions; dims > 0 && len(embedding) != dims {
+		return fmt.Errorf("embedding has %d dimensions, store has %d", len(embedding), dims)
+	}
+	if db.index != nil {
+		return db.index.checkDimensions(embedding)
+	}
 	return nil
 }
 
+// recordDimensions records the embedding dimensions of a store the first
+// time an embedding is stored
+func (db *Database) recordDimensions(dims int) error {
+	if dims == 0 || db.settings.Dimensions != 0 {
+		return nil
+	}
+	settings := db.settings
+	settings.Dimensions = dims
+	if err := db.saveSettings(settings); err != nil {
+		return err
+	}
+	db.settings = settings
+	return nil
+}
+
+// storedDimensions returns the length of the stored embeddings, or zero if
+// there are none
+func (db *Database) storedDimensions() int {
+	if db.index != nil {
+		return db.index.dims
+	}
+	for _, chunk := range db.chunks {
+		if len(chunk.Embedding) > 0 {
+			return len(chunk.Embedding)
+		}
+	}
+	return 0
+}
+
 // StoreDocument stores a document in the database
 func (db *Database) StoreDocument(doc *types.Document) error {
 	db.mu.Lock()
@@ -57,15 +252,138 @@ func (db *Database) StoreChunk(chunk *types.DocumentChunk) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	if chunk.Embedding != nil {
+		if err := db.checkDimensions(chunk.Embedding); err != nil {
+			return err
+		}
+	}
+	db.putChunk(chunk)
+	if err := db.saveChunks(); err != nil {
+		return err
+	}
+	return db.recordDimensions(len(chunk.Embedding))
+}
+
+// putChunk adds a chunk to the maps. In a quantized store the embedding
+// moves to the index and a copy of the chunk without it is kept.
+func (db *Database) putChunk(chunk *types.DocumentChunk) {
+	if db.index != nil && chunk.Embedding != nil {
+		db.index.add(chunk.ID, chunk.Embedding)
+		stored := *chunk
+		stored.Embedding = nil
+		chunk = &stored
+	}
 	db.chunks[chunk.ID] = chunk
-	return db.saveChunks()
+}
+
+// removeChunk removes a chunk and its embedding
+func (db *Database) removeChunk(id string) {
+	delete(db.chunks, id)
+	if db.index != nil {
+		db.index.remove(id)
+	}
+}
+
+// StoreDocumentWithChunks stores a document and its chunks in one step.
+// If persisting fails nothing is stored.
+func (db *Database) StoreDocumentWithChunks(doc *types.Document, chunks []*types.DocumentChunk) error {
+	return db.ReplaceDocument("", doc, chunks)
+}
+
+// ReplaceDocument removes the document oldID and its chunks, if any, and
+// stores doc with its chunks as a single transaction. If persisting fails
+// the previous state is restored, so readers never see a document without
+// its chunks or a half-replaced one.
+func (db *Database) ReplaceDocument(oldID string, doc *types.Document, chunks []*types.DocumentChunk) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	dims := db.settings.Dimensions
+	for _, chunk := range chunks {
+		if chunk.Embedding == nil {
+			continue
+		}
+		if err := db.checkDimensions(chunk.Embedding); err != nil {
+			return fmt.Errorf("chunk %s: %w", chunk.ID, err)
+		}
+		if dims == 0 {
+			dims = len(chunk.Embedding)
+		} else if len(chunk.Embedding) != dims {
+			return fmt.Errorf("chunk %s: embedding has %d dimensions, other chunks have %d", chunk.ID, len(chunk.Embedding), dims)
+		}
+	}
+
+	prevDocuments := make(map[string]*types.Document, len(db.documents))
+	for id, d := range db.documents {
+		prevDocuments[id] = d
+	}
+	prevChunks := make(map[string]*types.DocumentChunk, len(db.chunks))
+	for id, c := range db.chunks {
+		prevChunks[id] = c
+	}
+	var prevIndex indexSnapshot
+	if db.index != nil {
+		prevIndex = db.index.snapshot()
+	}
+
+	for _, id := range []string{oldID, doc.ID} {
+		if id == "" {
+			continue
+		}
+		delete(db.documents, id)
+		for chunkID, chunk := range db.chunks {
+			if chunk.DocumentID == id {
+				db.removeChunk(chunkID)
+			}
+		}
+	}
+
+	db.documents[doc.ID] = doc
+	for _, chunk := range chunks {
+		db.putChunk(chunk)
+	}
+
+	err := db.saveDocuments()
+	if err == nil {
+		err = db.saveChunks()
+	}
+	if err != nil {
+		db.documents = prevDocuments
+		db.chunks = prevChunks
+		if db.index != nil {
+			db.index.restore(prevIndex)
+		}
+		if restoreErr := db.saveDocuments(); restoreErr != nil {
+			return fmt.Errorf("failed to store document: %w (restoring previous state also failed: %v)", err, restoreErr)
+		}
+		if restoreErr := db.saveChunks(); restoreErr != nil {
+			return fmt.Errorf("failed to store document: %w (restoring previous state also failed: %v)", err, restoreErr)
+		}
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+
+	return db.recordDimensions(dims)
 }
 
 // Search performs similarity search and returns top k results
 func (db *Database) Search(queryEmbedding []float64, k int, threshold float64) ([]*types.SearchResult, error) {
+	return db.SearchWithFilter(queryEmbedding, k, threshold, nil)
+}
+
+// SearchWithFilter performs similarity search over the chunks matching the
+// filter and returns top k results. Chunks are filtered before scoring.
+func (db *Database) SearchWithFilter(queryEmbedding []float64, k int, threshold float64, filter *Filter) ([]*types.SearchResult, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
+	if dims := db.settings.Dimensions; dims > 0 && len(queryEmbedding) != dims {
+		return nil, fmt.Errorf("query embedding has %d dimensions, store has %d; was it embedded with a different model?", len(queryEmbedding), dims)
+	}
+
+	if db.index != nil {
+		return db.searchQuantized(queryEmbedding, k, threshold, filter)
+	}
+
 	var results []*types.SearchResult
 
 	for _, chunk := range db.chunks {
@@ -73,9 +391,13 @@ func (db *Database) Search(queryEmbedding []float64, k int, threshold float64) (
 			continue
 		}
 
+		document := db.documents[chunk.DocumentID]
+		if !filter.Match(document, chunk) {
+			continue
+		}
+
 		similarity := cosineSimilarity(queryEmbedding, chunk.Embedding)
 		if similarity >= threshold {
-			document := db.documents[chunk.DocumentID]
 			result := &types.SearchResult{
 				Chunk:      chunk,
 				Document:   document,
@@ -98,6 +420,65 @@ func (db *Database) Search(queryEmbedding []float64, k int, threshold float64) (
 	return results, nil
 }
 
+// searchQuantized ranks chunks by their approximate similarity to the query,
+// then rescores the top candidates with their full-precision vectors
+func (db *Database) searchQuantized(queryEmbedding []float64, k int, threshold float64, filter *Filter) ([]*types.SearchResult, error) {
+	score := db.index.scorer(queryEmbedding)
+
+	var candidates []*types.SearchResult
+	for id, entry := range db.index.entries {
+		chunk := db.chunks[id]
+		if chunk == nil {
+			continue
+		}
+		document := db.documents[chunk.DocumentID]
+		if !filter.Match(document, chunk) {
+			continue
+		}
+		candidates = append(candidates, &types.SearchResult{
+			Chunk:      chunk,
+			Document:   document,
+			Similarity: score(entry),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Similarity > candidates[j].Similarity
+	})
+	if n := k * db.rescoreFactor(); len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	var results []*types.SearchResult
+	for _, candidate := range candidates {
+		vector, err := db.index.vector(db.index.entries[candidate.Chunk.ID])
+		if err != nil {
+			return nil, err
+		}
+		candidate.Similarity = cosineSimilarity(queryEmbedding, toFloat64(vector))
+		if candidate.Similarity >= threshold {
+			results = append(results, candidate)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Similarity > results[j].Similarity
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results, nil
+}
+
+// rescoreFactor returns the configured rescore factor or the default
+func (db *Database) rescoreFactor() int {
+	if db.options.RescoreFactor > 0 {
+		return db.options.RescoreFactor
+	}
+	return defaultRescoreFactor
+}
+
 // GetDocument retrieves a document by ID
 func (db *Database) GetDocument(id string) (*types.Document, error) {
 	db.mu.RLock()
@@ -124,6 +505,33 @@ func (db *Database) GetChunk(id string) (*types.DocumentChunk, error) {
 	return chunk, nil
 }
 
+// FindDocumentByPath retrieves the document stored for a file path.
+// Paths are compared in absolute form, so a document ingested with a
+// relative path is found by its absolute path and vice versa.
+// It returns nil if no document was ingested from that path.
+func (db *Database) FindDocumentByPath(filePath string) *types.Document {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	target := absolutePath(filePath)
+	for _, doc := range db.documents {
+		if absolutePath(doc.FilePath) == target {
+			return doc
+		}
+	}
+
+	return nil
+}
+
+// absolutePath resolves a path against the working directory, falling back
+// to the cleaned path if that fails
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
 // ListDocuments returns all documents
 func (db *Database) ListDocuments() []*types.Document {
 	db.mu.RLock()
@@ -148,7 +556,7 @@ func (db *Database) DeleteDocument(docID string) error {
 	// Remove associated chunks
 	for chunkID, chunk := range db.chunks {
 		if chunk.DocumentID == docID {
-			delete(db.chunks, chunkID)
+			db.removeChunk(chunkID)
 		}
 	}
 
@@ -185,6 +593,10 @@ func (db *Database) loadData() error {
 		}
 	}
 
+	if db.index != nil {
+		return db.index.load()
+	}
+
 	return nil
 }
 
@@ -196,7 +608,7 @@ func (db *Database) saveDocuments() error {
 	}
 
 	docsPath := filepath.Join(db.storagePath, "documents.json")
-	return os.WriteFile(docsPath, data, 0644)
+	return writeFileAtomic(docsPath, data)
 }
 
 // saveChunks saves chunks to storage
@@ -207,7 +619,36 @@ func (db *Database) saveChunks() error {
 	}
 
 	chunksPath := filepath.Join(db.storagePath, "chunks.json")
-	return os.WriteFile(chunksPath, data, 0644)
+	if err := writeFileAtomic(chunksPath, data); err != nil {
+		return err
+	}
+
+	if db.index != nil {
+		return db.index.save()
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so a crash never leaves a truncated file behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
 // cosineSimilarity calculates cosine similarity between two vectors
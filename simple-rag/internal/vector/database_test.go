@@ -5,6 +5,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -287,6 +288,150 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestSearchWithFilter(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "vector_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db := NewDatabase(tempDir)
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	docs := []*types.Document{
+		{ID: "api", FilePath: "docs/api/auth.md", FileType: "md"},
+		{ID: "guide", FilePath: "docs/guide/intro.txt", FileType: "txt"},
+	}
+	for _, doc := range docs {
+		if err := db.StoreDocument(doc); err != nil {
+			t.Fatalf("StoreDocument failed: %v", err)
+		}
+	}
+	chunks := []*types.DocumentChunk{
+		{ID: "api_1", DocumentID: "api", Embedding: []float64{0.0, 1.0}},
+		{ID: "guide_1", DocumentID: "guide", Embedding: []float64{1.0, 0.0}},
+	}
+	for _, chunk := range chunks {
+		if err := db.StoreChunk(chunk); err != nil {
+			t.Fatalf("StoreChunk failed: %v", err)
+		}
+	}
+
+	filter, err := ParseFilter([]string{"path=docs/api/"})
+	if err != nil {
+		t.Fatalf("ParseFilter failed: %v", err)
+	}
+
+	// The guide chunk is the closest match but is filtered out before scoring
+	results, err := db.SearchWithFilter([]float64{1.0, 0.0}, 10, 0.0, filter)
+	if err != nil {
+		t.Fatalf("SearchWithFilter failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "api_1" {
+		t.Errorf("Expected only api_1, got %d results", len(results))
+	}
+
+	// A nil filter searches everything
+	results, err = db.SearchWithFilter([]float64{1.0, 0.0}, 10, 0.0, nil)
+	if err != nil {
+		t.Fatalf("SearchWithFilter failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results without filter, got %d", len(results))
+	}
+}
+
+func TestReplaceDocument(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "vector_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db := NewDatabase(tempDir)
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	oldDoc := &types.Document{ID: "old", FilePath: "a.txt"}
+	oldChunks := []*types.DocumentChunk{
+		{ID: "old_1", DocumentID: "old", Embedding: []float64{1.0, 0.0}},
+		{ID: "old_2", DocumentID: "old", Embedding: []float64{0.0, 1.0}},
+	}
+	if err := db.StoreDocumentWithChunks(oldDoc, oldChunks); err != nil {
+		t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+	}
+
+	newDoc := &types.Document{ID: "new", FilePath: "a.txt"}
+	newChunks := []*types.DocumentChunk{{ID: "new_1", DocumentID: "new", Embedding: []float64{1.0, 0.0}}}
+
+	t.Run("RollbackOnSaveFailure", func(t *testing.T) {
+		// A non-empty directory where chunks.json belongs makes saving fail
+		chunksPath := filepath.Join(tempDir, "chunks.json")
+		if err := os.Remove(chunksPath); err != nil {
+			t.Fatalf("Failed to remove chunks file: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Join(chunksPath, "blocker"), 0755); err != nil {
+			t.Fatalf("Failed to create blocker: %v", err)
+		}
+		defer func() {
+			os.RemoveAll(chunksPath)
+			db.mu.Lock()
+			db.saveChunks()
+			db.mu.Unlock()
+		}()
+
+		if err := db.ReplaceDocument("old", newDoc, newChunks); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if _, err := db.GetDocument("old"); err != nil {
+			t.Error("Expected old document to be restored")
+		}
+		if _, err := db.GetDocument("new"); err == nil {
+			t.Error("Expected new document to be rolled back")
+		}
+		if _, err := db.GetChunk("old_2"); err != nil {
+			t.Error("Expected old chunks to be restored")
+		}
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "documents.json"))
+		if err != nil {
+			t.Fatalf("Failed to read documents: %v", err)
+		}
+		if !strings.Contains(string(data), `"old"`) || strings.Contains(string(data), `"new"`) {
+			t.Errorf("Expected persisted documents to be restored, got %s", data)
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		if err := db.ReplaceDocument("old", newDoc, newChunks); err != nil {
+			t.Fatalf("ReplaceDocument failed: %v", err)
+		}
+		if _, err := db.GetDocument("old"); err == nil {
+			t.Error("Expected old document to be removed")
+		}
+		if _, err := db.GetChunk("old_2"); err == nil {
+			t.Error("Expected old chunks to be removed")
+		}
+		if _, err := db.GetChunk("new_1"); err != nil {
+			t.Error("Expected new chunk to be stored")
+		}
+
+		reloaded := NewDatabase(tempDir)
+		if err := reloaded.Initialize(); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		if len(reloaded.ListDocuments()) != 1 {
+			t.Errorf("Expected 1 persisted document, got %d", len(reloaded.ListDocuments()))
+		}
+		if _, err := reloaded.GetChunk("new_1"); err != nil {
+			t.Error("Expected new chunk to be persisted")
+		}
+	})
+}
+
 func TestListDocuments(t *testing.T) {
 	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "vector_test")
@@ -327,6 +472,61 @@ func TestListDocuments(t *testing.T) {
 	}
 }
 
+func TestFindDocumentByPath(t *testing.T) {
+	// Create temporary directory
+	tempDir, err := os.MkdirTemp("", "vector_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db := NewDatabase(tempDir)
+	err = db.Initialize()
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	doc := &types.Document{
+		ID:       "doc_path",
+		Title:    "guide.md",
+		FilePath: "docs/guide.md",
+	}
+	if err := db.StoreDocument(doc); err != nil {
+		t.Fatalf("StoreDocument failed: %v", err)
+	}
+
+	t.Run("ExactPath", func(t *testing.T) {
+		found := db.FindDocumentByPath("docs/guide.md")
+		if found == nil || found.ID != "doc_path" {
+			t.Errorf("Expected document doc_path, got %v", found)
+		}
+	})
+
+	t.Run("UncleanPath", func(t *testing.T) {
+		found := db.FindDocumentByPath("./docs/../docs/guide.md")
+		if found == nil || found.ID != "doc_path" {
+			t.Errorf("Expected document doc_path for unclean path, got %v", found)
+		}
+	})
+
+	t.Run("AbsolutePath", func(t *testing.T) {
+		absPath, err := filepath.Abs("docs/guide.md")
+		if err != nil {
+			t.Fatalf("Abs failed: %v", err)
+		}
+		found := db.FindDocumentByPath(absPath)
+		if found == nil || found.ID != "doc_path" {
+			t.Errorf("Expected document doc_path for absolute path, got %v", found)
+		}
+	})
+
+	t.Run("UnknownPath", func(t *testing.T) {
+		if found := db.FindDocumentByPath("docs/missing.md"); found != nil {
+			t.Errorf("Expected nil for unknown path, got %v", found)
+		}
+	})
+}
+
 func TestDeleteDocument(t *testing.T) {
 	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "vector_test")
@@ -10,6 +10,33 @@ import (
 	"simple-rag/pkg/types"
 )
 
+// EmbeddingProvider turns texts into embedding vectors.
+// Implementations speak one embedding service protocol each.
+type EmbeddingProvider interface {
+	GetEmbeddings(texts []string) ([][]float64, error)
+	GetSingleEmbedding(text string) ([]float64, error)
+	ProcessChunks(chunks []*types.DocumentChunk) error
+	Health() error
+}
+
+// NewEmbeddingProvider returns the client for a provider name:
+// "custom" (the default {texts, model} protocol), "openai", "ollama" or
+// "stub" (local hashed embeddings for tests and CI)
+func NewEmbeddingProvider(provider, baseURL, model, apiKey string) (EmbeddingProvider, error) {
+	switch provider {
+	case "", "custom":
+		return NewEmbeddingClient(baseURL, model), nil
+	case "openai":
+		return NewOpenAIEmbeddingClient(baseURL, model, apiKey), nil
+	case "ollama":
+		return NewOllamaEmbeddingClient(baseURL, model), nil
+	case "stub":
+		return NewStubEmbeddingClient(defaultStubDimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", provider)
+	}
+}
+
 // EmbeddingClient handles communication with embedding service
 type EmbeddingClient struct {
 	baseURL    string
@@ -62,7 +89,7 @@ func (c *EmbeddingClient) GetEmbeddings(texts []string) ([][]float64, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("embedding service returned status %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Parse response
@@ -81,7 +108,33 @@ func (c *EmbeddingClient) GetEmbeddings(texts []string) ([][]float64, error) {
 
 // GetSingleEmbedding gets embedding for a single text
 func (c *EmbeddingClient) GetSingleEmbedding(text string) ([]float64, error) {
-	embeddings, err := c.GetEmbeddings([]string{text})
+	return singleEmbedding(c, text)
+}
+
+// ProcessChunks processes document chunks and adds embeddings
+func (c *EmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(c, chunks)
+}
+
+// Health checks if the embedding service is available
+func (c *EmbeddingClient) Health() error {
+	url := fmt.Sprintf("%s/health", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("embedding service not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("embedding service health check failed: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// singleEmbedding gets the embedding for one text from a provider
+func singleEmbedding(p EmbeddingProvider, text string) ([]float64, error) {
+	embeddings, err := p.GetEmbeddings([]string{text})
 	if err != nil {
 		return nil, err
 	}
@@ -93,8 +146,8 @@ func (c *EmbeddingClient) GetSingleEmbedding(text string) ([]float64, error) {
 	return embeddings[0], nil
 }
 
-// ProcessChunks processes document chunks and adds embeddings
-func (c *EmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
+// processChunks embeds chunk contents with a provider and assigns the vectors
+func processChunks(p EmbeddingProvider, chunks []*types.DocumentChunk) error {
 	if len(chunks) == 0 {
 		return nil
 	}
@@ -106,7 +159,7 @@ func (c *EmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
 	}
 
 	// Get embeddings
-	embeddings, err := c.GetEmbeddings(texts)
+	embeddings, err := p.GetEmbeddings(texts)
 	if err != nil {
 		return fmt.Errorf("failed to get embeddings: %w", err)
 	}
@@ -118,19 +171,3 @@ func (c *EmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
 
 	return nil
 }
-
-// Health checks if the embedding service is available
-func (c *EmbeddingClient) Health() error {
-	url := fmt.Sprintf("%s/health", c.baseURL)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return fmt.Errorf("embedding service not reachable: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("embedding service health check failed: status %d", resp.StatusCode)
-	}
-
-	return nil
-}
\ No newline at end of file
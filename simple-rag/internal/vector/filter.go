@@ -0,0 +1,277 @@
+package vector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// Filter fields with dedicated handling. Any other field name is looked up
+// in the chunk metadata first and then in the document metadata.
+const (
+	FieldType    = "type"
+	FieldPath    = "path"
+	FieldTitle   = "title"
+	FieldID      = "id"
+	FieldCreated = "created"
+)
+
+// fieldAliases maps accepted spellings to the canonical field names
+var fieldAliases = map[string]string{
+	"file_type":   FieldType,
+	"file_path":   FieldPath,
+	"document_id": FieldID,
+	"created_at":  FieldCreated,
+}
+
+// dateLayouts are the accepted formats for created bounds
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// Filter restricts a search to chunks matching every condition
+type Filter struct {
+	Conditions []Condition
+}
+
+// Condition is a single filter term.
+// For created, From and To bound CreatedAt as [From, To); a zero bound is
+// open. For path, Values are prefixes of the file path. For every other
+// field the value must equal one of Values.
+type Condition struct {
+	Field  string
+	Values []string
+	From   time.Time
+	To     time.Time
+}
+
+// ParseFilter parses filter expressions such as "type=md", "type=md,txt",
+// "path=docs/api/", "created>=2024-01-01" or "created=2024-01-01..2024-06-30".
+// All expressions must match. An empty list returns a nil filter.
+func ParseFilter(exprs []string) (*Filter, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+
+	filter := &Filter{}
+	for _, expr := range exprs {
+		condition, err := ParseCondition(expr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Conditions = append(filter.Conditions, condition)
+	}
+
+	return filter, nil
+}
+
+// ParseCondition parses a single filter expression
+func ParseCondition(expr string) (Condition, error) {
+	field, op, value, ok := splitExpr(expr)
+	if !ok {
+		return Condition{}, fmt.Errorf("invalid filter %q: expected field=value", expr)
+	}
+
+	field = strings.ToLower(field)
+	if canonical, exists := fieldAliases[field]; exists {
+		field = canonical
+	}
+	condition := Condition{Field: field}
+
+	if field == FieldCreated {
+		if err := condition.setRange(op, value); err != nil {
+			return Condition{}, fmt.Errorf("invalid filter %q: %w", expr, err)
+		}
+		return condition, nil
+	}
+
+	if op != "=" {
+		return Condition{}, fmt.Errorf("invalid filter %q: %s only supports =", expr, field)
+	}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		switch field {
+		case FieldType:
+			v = strings.TrimPrefix(strings.ToLower(v), ".")
+		case FieldPath:
+			v = normalizePathPrefix(v)
+		}
+		condition.Values = append(condition.Values, v)
+	}
+	if len(condition.Values) == 0 {
+		return Condition{}, fmt.Errorf("invalid filter %q: empty value", expr)
+	}
+
+	return condition, nil
+}
+
+// Match reports whether a chunk and its document satisfy the filter.
+// A nil filter matches everything.
+func (f *Filter) Match(doc *types.Document, chunk *types.DocumentChunk) bool {
+	if f == nil {
+		return true
+	}
+	for _, condition := range f.Conditions {
+		if !condition.Match(doc, chunk) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether a chunk and its document satisfy the condition
+func (c Condition) Match(doc *types.Document, chunk *types.DocumentChunk) bool {
+	if doc == nil {
+		return false
+	}
+
+	switch c.Field {
+	case FieldCreated:
+		if !c.From.IsZero() && doc.CreatedAt.Before(c.From) {
+			return false
+		}
+		if !c.To.IsZero() && !doc.CreatedAt.Before(c.To) {
+			return false
+		}
+		return true
+	case FieldPath:
+		path := filepath.ToSlash(filepath.Clean(doc.FilePath))
+		for _, prefix := range c.Values {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value, ok := c.lookup(doc, chunk)
+	if !ok {
+		return false
+	}
+	for _, v := range c.Values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the value of the condition field
+func (c Condition) lookup(doc *types.Document, chunk *types.DocumentChunk) (string, bool) {
+	switch c.Field {
+	case FieldType:
+		return strings.TrimPrefix(strings.ToLower(doc.FileType), "."), true
+	case FieldTitle:
+		return doc.Title, true
+	case FieldID:
+		return doc.ID, true
+	}
+
+	if chunk != nil {
+		if value, ok := chunk.Metadata[c.Field]; ok {
+			return value, true
+		}
+	}
+	value, ok := doc.Metadata[c.Field]
+	return value, ok
+}
+
+// setRange sets the created bounds from a comparison or an a..b range
+func (c *Condition) setRange(op, value string) error {
+	if op == "=" {
+		from, to, isRange := strings.Cut(value, "..")
+		if !isRange {
+			// A single date matches that whole day
+			start, dateOnly, err := parseDate(value)
+			if err != nil {
+				return err
+			}
+			c.From = start
+			c.To = endOf(start, dateOnly)
+			return nil
+		}
+		if from != "" {
+			if err := c.setRange(">=", from); err != nil {
+				return err
+			}
+		}
+		if to != "" {
+			if err := c.setRange("<=", to); err != nil {
+				return err
+			}
+		}
+		if c.From.IsZero() && c.To.IsZero() {
+			return fmt.Errorf("empty date range")
+		}
+		return nil
+	}
+
+	t, dateOnly, err := parseDate(value)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case ">=":
+		c.From = t
+	case ">":
+		c.From = endOf(t, dateOnly)
+	case "<":
+		c.To = t
+	case "<=":
+		c.To = endOf(t, dateOnly)
+	}
+	return nil
+}
+
+// splitExpr splits "field<op>value" at the first comparison operator
+func splitExpr(expr string) (field, op, value string, ok bool) {
+	i := strings.IndexAny(expr, "=<>")
+	if i <= 0 {
+		return "", "", "", false
+	}
+
+	field = strings.TrimSpace(expr[:i])
+	op = expr[i : i+1]
+	rest := expr[i+1:]
+	if (op == "<" || op == ">") && strings.HasPrefix(rest, "=") {
+		op += "="
+		rest = rest[1:]
+	}
+	value = strings.TrimSpace(rest)
+
+	return field, op, value, field != "" && value != ""
+}
+
+// parseDate parses a timestamp in local time and reports whether it had no
+// time of day
+func parseDate(value string) (time.Time, bool, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t, layout == "2006-01-02", nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q: use YYYY-MM-DD or RFC 3339", value)
+}
+
+// endOf returns the exclusive end of a date or timestamp
+func endOf(t time.Time, dateOnly bool) time.Time {
+	if dateOnly {
+		return t.AddDate(0, 0, 1)
+	}
+	return t.Add(time.Nanosecond)
+}
+
+// normalizePathPrefix cleans a path prefix but keeps a trailing separator,
+// so "docs/api/" does not match "docs/apis"
+func normalizePathPrefix(prefix string) string {
+	slash := strings.HasSuffix(prefix, "/") || strings.HasSuffix(prefix, string(filepath.Separator))
+	cleaned := filepath.ToSlash(filepath.Clean(prefix))
+	if slash && !strings.HasSuffix(cleaned, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -0,0 +1,121 @@
+package vector
+
+import (
+	"testing"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expr   string
+		field  string
+		values []string
+	}{
+		{"type=md", FieldType, []string{"md"}},
+		{"type=.MD,txt", FieldType, []string{"md", "txt"}},
+		{"file_path=./docs/api/", FieldPath, []string{"docs/api/"}},
+		{"path=docs/api", FieldPath, []string{"docs/api"}},
+		{"heading = Install", "heading", []string{"Install"}},
+		{"document_id=doc_1", FieldID, []string{"doc_1"}},
+	}
+
+	for _, test := range tests {
+		condition, err := ParseCondition(test.expr)
+		if err != nil {
+			t.Errorf("ParseCondition(%q) failed: %v", test.expr, err)
+			continue
+		}
+		if condition.Field != test.field {
+			t.Errorf("ParseCondition(%q): expected field %s, got %s", test.expr, test.field, condition.Field)
+		}
+		if len(condition.Values) != len(test.values) {
+			t.Errorf("ParseCondition(%q): expected values %v, got %v", test.expr, test.values, condition.Values)
+			continue
+		}
+		for i, v := range test.values {
+			if condition.Values[i] != v {
+				t.Errorf("ParseCondition(%q): expected values %v, got %v", test.expr, test.values, condition.Values)
+			}
+		}
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := []string{"", "type", "=md", "type=", "type>=md", "created=yesterday", "created=..", "path=,"}
+		for _, expr := range invalid {
+			if _, err := ParseCondition(expr); err == nil {
+				t.Errorf("ParseCondition(%q): expected error, got nil", expr)
+			}
+		}
+	})
+}
+
+func TestFilterMatch(t *testing.T) {
+	day := func(s string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		return tm
+	}
+
+	doc := &types.Document{
+		ID:        "doc_1",
+		Title:     "auth.md",
+		FilePath:  "./docs/api/auth.md",
+		FileType:  "md",
+		Metadata:  map[string]string{"team": "platform"},
+		CreatedAt: day("2024-03-15 12:00"),
+	}
+	chunk := &types.DocumentChunk{
+		ID:       "chunk_1",
+		Metadata: map[string]string{"heading": "Tokens", "team": "security"},
+	}
+
+	tests := []struct {
+		exprs    []string
+		expected bool
+	}{
+		{nil, true},
+		{[]string{"type=md"}, true},
+		{[]string{"type=txt"}, false},
+		{[]string{"type=txt,md"}, true},
+		{[]string{"path=docs/api/"}, true},
+		{[]string{"path=docs/ap"}, true},
+		{[]string{"path=docs/ap/"}, false},
+		{[]string{"path=docs/guide/,docs/api/"}, true},
+		{[]string{"title=auth.md"}, true},
+		{[]string{"id=doc_2"}, false},
+		{[]string{"heading=Tokens"}, true},
+		{[]string{"team=security"}, true}, // chunk metadata takes precedence
+		{[]string{"team=platform"}, false},
+		{[]string{"missing=x"}, false},
+		{[]string{"created=2024-03-15"}, true},
+		{[]string{"created=2024-03-16"}, false},
+		{[]string{"created>=2024-03-01", "created<2024-04-01"}, true},
+		{[]string{"created<=2024-03-15"}, true},
+		{[]string{"created<2024-03-15"}, false},
+		{[]string{"created>2024-03-15"}, false},
+		{[]string{"created=2024-01-01..2024-03-14"}, false},
+		{[]string{"created=2024-03-01.."}, true},
+		{[]string{"created_at=..2024-12-31"}, true},
+		{[]string{"created>=2024-03-15T13:00:00"}, false},
+		{[]string{"type=md", "path=docs/guide/"}, false},
+	}
+
+	for _, test := range tests {
+		filter, err := ParseFilter(test.exprs)
+		if err != nil {
+			t.Errorf("ParseFilter(%v) failed: %v", test.exprs, err)
+			continue
+		}
+		if got := filter.Match(doc, chunk); got != test.expected {
+			t.Errorf("Filter %v: expected %v, got %v", test.exprs, test.expected, got)
+		}
+	}
+
+	t.Run("MissingDocument", func(t *testing.T) {
+		filter, _ := ParseFilter([]string{"type=md"})
+		if filter.Match(nil, chunk) {
+			t.Error("Expected no match without a document")
+		}
+	})
+}
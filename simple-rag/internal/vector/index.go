@@ -0,0 +1,507 @@
+package vector
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// vectorsFileMagic identifies a quantized vectors file
+const vectorsFileMagic = "RAGQ"
+
+// vectorsFileVersion is the version of the vectors file layout
+const vectorsFileVersion = 1
+
+// quantizedIndex keeps compressed embeddings in memory and full-precision
+// ones on disk, where they are read back only to rescore top candidates.
+//
+// The vectors file holds a header, the codebooks for product quantization,
+// and one record per chunk with its codes and float32 vector. It is rewritten
+// on every save and replaced atomically.
+type quantizedIndex struct {
+	path       string
+	kind       string
+	subvectors int
+	dims       int
+	quant      quantizer
+	// trainedOn is the number of vectors the codebooks were trained on
+	trainedOn int
+	entries   map[string]*indexEntry
+	file      *os.File
+}
+
+// indexEntry is the stored form of one chunk embedding. Entries are never
+// modified once created, so snapshots can share them.
+type indexEntry struct {
+	code quantizedVector
+	// encoded is false while product quantization codebooks are untrained
+	encoded bool
+	// offset locates the full vector in the vectors file when pending is nil
+	offset int64
+	// pending holds vectors not yet written to the vectors file
+	pending []float32
+}
+
+// indexSnapshot is the restorable state of an index
+type indexSnapshot struct {
+	dims      int
+	quant     quantizer
+	trainedOn int
+	entries   map[string]*indexEntry
+}
+
+// newQuantizedIndex creates an empty index stored at path
+func newQuantizedIndex(path, kind string, subvectors int) *quantizedIndex {
+	index := &quantizedIndex{
+		path:       path,
+		kind:       kind,
+		subvectors: subvectors,
+		entries:    make(map[string]*indexEntry),
+	}
+	if kind == QuantizationInt8 {
+		index.quant = int8Quantizer{}
+	}
+	return index
+}
+
+// checkDimensions reports an error if an embedding does not match the
+// dimensions of the vectors already stored
+func (x *quantizedIndex) checkDimensions(embedding []float64) error {
+	if len(x.entries) == 0 {
+		return nil
+	}
+	if len(embedding) != x.dims {
+		return fmt.Errorf("embedding has %d dimensions, store has %d", len(embedding), x.dims)
+	}
+	return nil
+}
+
+// add stores an embedding for a chunk, replacing any previous one
+func (x *quantizedIndex) add(id string, embedding []float64) {
+	if len(x.entries) == 0 {
+		// An empty store takes the dimensions of its first vector, and
+		// codebooks trained on earlier vectors no longer apply
+		x.dims = len(embedding)
+		if x.kind == QuantizationPQ {
+			x.quant = nil
+			x.trainedOn = 0
+		}
+	}
+	v := toFloat32(embedding)
+	entry := &indexEntry{pending: v}
+	if x.quant != nil {
+		entry.code = x.quant.encode(v)
+		entry.encoded = true
+	}
+	x.entries[id] = entry
+}
+
+// remove deletes the embedding of a chunk
+func (x *quantizedIndex) remove(id string) {
+	delete(x.entries, id)
+}
+
+// snapshot returns the current state for a later restore
+func (x *quantizedIndex) snapshot() indexSnapshot {
+	entries := make(map[string]*indexEntry, len(x.entries))
+	for id, entry := range x.entries {
+		entries[id] = entry
+	}
+	return indexSnapshot{dims: x.dims, quant: x.quant, trainedOn: x.trainedOn, entries: entries}
+}
+
+// restore resets the index to a snapshot
+func (x *quantizedIndex) restore(s indexSnapshot) {
+	x.dims = s.dims
+	x.quant = s.quant
+	x.trainedOn = s.trainedOn
+	x.entries = s.entries
+}
+
+// vector returns the full-precision vector of an entry
+func (x *quantizedIndex) vector(entry *indexEntry) ([]float32, error) {
+	if entry.pending != nil {
+		return entry.pending, nil
+	}
+	if x.file == nil {
+		return nil, fmt.Errorf("vectors file is not open")
+	}
+
+	buf := make([]byte, 4*x.dims)
+	if _, err := x.file.ReadAt(buf, entry.offset); err != nil {
+		return nil, fmt.Errorf("failed to read vector: %w", err)
+	}
+	v := make([]float32, x.dims)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
+
+// scorer returns a function giving the approximate cosine similarity of the
+// query and an entry. Entries without codes are scored exactly.
+func (x *quantizedIndex) scorer(query []float64) func(entry *indexEntry) float64 {
+	var queryNorm float64
+	for _, v := range query {
+		queryNorm += v * v
+	}
+	queryNorm = math.Sqrt(queryNorm)
+
+	var dot func(q quantizedVector) float64
+	if x.quant != nil && len(query) == x.dims {
+		dot = x.quant.dotProduct(query)
+	}
+
+	return func(entry *indexEntry) float64 {
+		if !entry.encoded || dot == nil {
+			if entry.pending == nil {
+				return 0
+			}
+			return cosineSimilarity(query, toFloat64(entry.pending))
+		}
+		if queryNorm == 0 || entry.code.norm == 0 {
+			return 0
+		}
+		return dot(entry.code) / (queryNorm * float64(entry.code.norm))
+	}
+}
+
+// load reads the index from its vectors file, keeping only the codes and
+// the offsets of the full vectors in memory
+func (x *quantizedIndex) load() error {
+	file, err := os.Open(x.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r := &countingReader{r: bufio.NewReader(file)}
+	if err := x.readFile(r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(x.path), err)
+	}
+
+	x.file = file
+	return nil
+}
+
+// readFile parses the header, codebooks and records of a vectors file
+func (x *quantizedIndex) readFile(r *countingReader) error {
+	magic := make([]byte, len(vectorsFileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if string(magic) != vectorsFileMagic {
+		return fmt.Errorf("not a vectors file")
+	}
+
+	version := r.uint32()
+	if r.err == nil && version != vectorsFileVersion {
+		return fmt.Errorf("unsupported vectors file version %d", version)
+	}
+	kind := r.string()
+	if r.err == nil && kind != x.kind {
+		return fmt.Errorf("vectors file uses %s quantization, store uses %s", kind, x.kind)
+	}
+	x.dims = int(r.uint32())
+	x.trainedOn = int(r.uint32())
+	count := int(r.uint32())
+
+	if kind == QuantizationPQ {
+		subvectors := int(r.uint32())
+		if subvectors > 0 {
+			pq := &pqQuantizer{bounds: make([]int, subvectors+1), codebooks: make([][][]float32, subvectors)}
+			for i := range pq.bounds {
+				pq.bounds[i] = int(r.uint32())
+			}
+			for i := range pq.codebooks {
+				centroids := int(r.uint32())
+				width := pq.bounds[i+1] - pq.bounds[i]
+				pq.codebooks[i] = make([][]float32, centroids)
+				for j := range pq.codebooks[i] {
+					pq.codebooks[i][j] = r.float32s(width)
+				}
+			}
+			x.quant = pq
+		}
+	}
+
+	for range count {
+		id := r.string()
+		entry := &indexEntry{encoded: true}
+		entry.code.norm = r.float32()
+		entry.code.min = r.float32()
+		entry.code.scale = r.float32()
+		entry.code.codes = r.bytes()
+		entry.offset = r.n
+		r.skip(4 * x.dims)
+		if r.err != nil {
+			return r.err
+		}
+		x.entries[id] = entry
+	}
+
+	return r.err
+}
+
+// save writes all vectors to a new vectors file and switches to it. Product
+// quantization codebooks are trained on first save and retrained whenever
+// the store has doubled since, which re-encodes every vector.
+func (x *quantizedIndex) save() error {
+	ids := make([]string, 0, len(x.entries))
+	for id := range x.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	quant := x.quant
+	trainedOn := x.trainedOn
+	retrain := x.kind == QuantizationPQ && len(ids) > 0 &&
+		(quant == nil || len(ids) >= 2*trainedOn)
+	if retrain {
+		sample, err := x.sample(ids)
+		if err != nil {
+			return err
+		}
+		quant = trainPQ(sample, x.dims, x.subvectors)
+		trainedOn = len(ids)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(x.path), filepath.Base(x.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := &countingWriter{w: bufio.NewWriter(tmp)}
+	w.write([]byte(vectorsFileMagic))
+	w.uint32(vectorsFileVersion)
+	w.string(x.kind)
+	w.uint32(uint32(x.dims))
+	w.uint32(uint32(trainedOn))
+	w.uint32(uint32(len(ids)))
+
+	if x.kind == QuantizationPQ {
+		pq, _ := quant.(*pqQuantizer)
+		if pq == nil {
+			w.uint32(0)
+		} else {
+			w.uint32(uint32(len(pq.codebooks)))
+			for _, b := range pq.bounds {
+				w.uint32(uint32(b))
+			}
+			for _, codebook := range pq.codebooks {
+				w.uint32(uint32(len(codebook)))
+				for _, centroid := range codebook {
+					w.float32s(centroid)
+				}
+			}
+		}
+	}
+
+	entries := make(map[string]*indexEntry, len(ids))
+	for _, id := range ids {
+		entry := x.entries[id]
+		v, err := x.vector(entry)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+
+		code := entry.code
+		if retrain || !entry.encoded {
+			code = quant.encode(v)
+		}
+
+		w.string(id)
+		w.float32(code.norm)
+		w.float32(code.min)
+		w.float32(code.scale)
+		w.bytes(code.codes)
+		entries[id] = &indexEntry{code: code, encoded: true, offset: w.n}
+		w.float32s(v)
+	}
+
+	if err := w.flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), x.path); err != nil {
+		return err
+	}
+
+	file, err := os.Open(x.path)
+	if err != nil {
+		return err
+	}
+	if x.file != nil {
+		x.file.Close()
+	}
+	x.file = file
+	x.quant = quant
+	x.trainedOn = trainedOn
+	x.entries = entries
+	return nil
+}
+
+// sample returns up to pqMaxTrainingVectors full vectors for training
+func (x *quantizedIndex) sample(ids []string) ([][]float32, error) {
+	step := 1
+	if len(ids) > pqMaxTrainingVectors {
+		step = len(ids) / pqMaxTrainingVectors
+	}
+
+	var vectors [][]float32
+	for i := 0; i < len(ids); i += step {
+		v, err := x.vector(x.entries[ids[i]])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+// close releases the vectors file
+func (x *quantizedIndex) close() error {
+	if x.file == nil {
+		return nil
+	}
+	err := x.file.Close()
+	x.file = nil
+	return err
+}
+
+// countingReader decodes little-endian values and tracks the offset.
+// After the first error all reads return zero values.
+type countingReader struct {
+	r   *bufio.Reader
+	n   int64
+	err error
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		r.err = err
+		return nil
+	}
+	return buf
+}
+
+func (r *countingReader) skip(n int) {
+	if r.err != nil {
+		return
+	}
+	discarded, err := r.r.Discard(n)
+	r.n += int64(discarded)
+	if err != nil {
+		r.err = err
+	}
+}
+
+func (r *countingReader) uint32() uint32 {
+	buf := r.read(4)
+	if buf == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(buf)
+}
+
+func (r *countingReader) float32() float32 {
+	return math.Float32frombits(r.uint32())
+}
+
+func (r *countingReader) float32s(n int) []float32 {
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = r.float32()
+	}
+	return v
+}
+
+func (r *countingReader) bytes() []byte {
+	buf := r.read(2)
+	if buf == nil {
+		return nil
+	}
+	return r.read(int(binary.LittleEndian.Uint16(buf)))
+}
+
+func (r *countingReader) string() string {
+	return string(r.bytes())
+}
+
+// countingWriter encodes little-endian values and tracks the offset.
+// After the first error all writes are skipped.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) uint32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	w.write(buf[:])
+}
+
+func (w *countingWriter) float32(v float32) {
+	w.uint32(math.Float32bits(v))
+}
+
+func (w *countingWriter) float32s(v []float32) {
+	for _, x := range v {
+		w.float32(x)
+	}
+}
+
+func (w *countingWriter) bytes(p []byte) {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], uint16(len(p)))
+	w.write(buf[:])
+	w.write(p)
+}
+
+func (w *countingWriter) string(s string) {
+	w.bytes([]byte(s))
+}
+
+func (w *countingWriter) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
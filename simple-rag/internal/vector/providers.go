@@ -0,0 +1,229 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// OpenAIEmbeddingClient speaks the OpenAI-compatible /v1/embeddings API
+// served by llama.cpp server, vLLM, LocalAI and OpenAI itself
+type OpenAIEmbeddingClient struct {
+	baseURL    string
+	model      string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbeddingClient creates a new OpenAI-compatible embedding client.
+// baseURL may be given with or without the trailing /v1.
+func NewOpenAIEmbeddingClient(baseURL, model, apiKey string) *OpenAIEmbeddingClient {
+	return &OpenAIEmbeddingClient{
+		baseURL: strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1"),
+		model:   model,
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// OpenAIEmbeddingRequest represents a request to /v1/embeddings
+type OpenAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OpenAIEmbeddingResponse represents a response from /v1/embeddings
+type OpenAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Model string `json:"model"`
+}
+
+// GetEmbeddings gets embeddings for a list of texts
+func (c *OpenAIEmbeddingClient) GetEmbeddings(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+
+	req := OpenAIEmbeddingRequest{
+		Model: c.model,
+		Input: texts,
+	}
+
+	var embResp OpenAIEmbeddingResponse
+	url := fmt.Sprintf("%s/v1/embeddings", c.baseURL)
+	if err := postJSON(c.httpClient, url, c.apiKey, req, &embResp); err != nil {
+		return nil, err
+	}
+
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d",
+			len(texts), len(embResp.Data))
+	}
+
+	// Results carry their input index and are not guaranteed to be in order
+	sort.Slice(embResp.Data, func(i, j int) bool {
+		return embResp.Data[i].Index < embResp.Data[j].Index
+	})
+	embeddings := make([][]float64, len(embResp.Data))
+	for i, item := range embResp.Data {
+		embeddings[i] = item.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// GetSingleEmbedding gets embedding for a single text
+func (c *OpenAIEmbeddingClient) GetSingleEmbedding(text string) ([]float64, error) {
+	return singleEmbedding(c, text)
+}
+
+// ProcessChunks processes document chunks and adds embeddings
+func (c *OpenAIEmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(c, chunks)
+}
+
+// Health checks if the embedding service is available
+func (c *OpenAIEmbeddingClient) Health() error {
+	return checkHealth(c.httpClient, fmt.Sprintf("%s/v1/models", c.baseURL), c.apiKey)
+}
+
+// OllamaEmbeddingClient speaks Ollama's /api/embed API
+type OllamaEmbeddingClient struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllamaEmbeddingClient creates a new Ollama embedding client
+func NewOllamaEmbeddingClient(baseURL, model string) *OllamaEmbeddingClient {
+	return &OllamaEmbeddingClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// OllamaEmbedRequest represents a request to /api/embed
+type OllamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OllamaEmbedResponse represents a response from /api/embed
+type OllamaEmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// GetEmbeddings gets embeddings for a list of texts
+func (c *OllamaEmbeddingClient) GetEmbeddings(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+
+	req := OllamaEmbedRequest{
+		Model: c.model,
+		Input: texts,
+	}
+
+	var embResp OllamaEmbedResponse
+	url := fmt.Sprintf("%s/api/embed", c.baseURL)
+	if err := postJSON(c.httpClient, url, "", req, &embResp); err != nil {
+		return nil, err
+	}
+
+	if len(embResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d",
+			len(texts), len(embResp.Embeddings))
+	}
+
+	return embResp.Embeddings, nil
+}
+
+// GetSingleEmbedding gets embedding for a single text
+func (c *OllamaEmbeddingClient) GetSingleEmbedding(text string) ([]float64, error) {
+	return singleEmbedding(c, text)
+}
+
+// ProcessChunks processes document chunks and adds embeddings
+func (c *OllamaEmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(c, chunks)
+}
+
+// Health checks if the embedding service is available
+func (c *OllamaEmbeddingClient) Health() error {
+	return checkHealth(c.httpClient, fmt.Sprintf("%s/api/tags", c.baseURL), "")
+}
+
+// postJSON sends a JSON request and decodes the JSON response
+func postJSON(httpClient *http.Client, url, apiKey string, req, resp interface{}) error {
+	// Serialize request
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make HTTP request
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: httpResp.StatusCode}
+	}
+
+	// Parse response
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// checkHealth issues a GET and expects 200 OK
+func checkHealth(httpClient *http.Client, url, apiKey string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("embedding service not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("embedding service health check failed: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package vector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+func TestNewEmbeddingProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		wantErr  bool
+	}{
+		{"", false},
+		{"custom", false},
+		{"openai", false},
+		{"ollama", false},
+		{"stub", false},
+		{"unknown", true},
+	}
+
+	for _, test := range tests {
+		provider, err := NewEmbeddingProvider(test.provider, "http://test", "m", "")
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("Provider %q: expected error, got nil", test.provider)
+			}
+			continue
+		}
+		if err != nil || provider == nil {
+			t.Errorf("Provider %q: unexpected error: %v", test.provider, err)
+		}
+	}
+}
+
+func TestOpenAIEmbeddingClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"data":[]}`))
+			return
+		case "/v1/embeddings":
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+
+		var req OpenAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "text-embedding" {
+			t.Errorf("Expected model 'text-embedding', got %s", req.Model)
+		}
+
+		// Return results out of order to check they are sorted by index
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	client := NewOpenAIEmbeddingClient(server.URL+"/v1/", "text-embedding", "key")
+	embeddings, err := client.GetEmbeddings([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("GetEmbeddings failed: %v", err)
+	}
+	if embeddings[0][0] != 1 || embeddings[1][1] != 1 {
+		t.Errorf("Expected embeddings ordered by index, got %v", embeddings)
+	}
+
+	if err := client.Health(); err != nil {
+		t.Errorf("Health failed: %v", err)
+	}
+
+	t.Run("CountMismatch", func(t *testing.T) {
+		if _, err := client.GetEmbeddings([]string{"a", "b", "c"}); err == nil {
+			t.Error("Expected count mismatch error, got nil")
+		}
+	})
+}
+
+func TestOllamaEmbeddingClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("Expected path '/api/embed', got %s", r.URL.Path)
+		}
+
+		var req OllamaEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		resp := OllamaEmbedResponse{Model: req.Model}
+		for range req.Input {
+			resp.Embeddings = append(resp.Embeddings, []float64{0.5, 0.5})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewOllamaEmbeddingClient(server.URL, "nomic-embed-text")
+	chunks := []*types.DocumentChunk{
+		{ID: "c1", Content: "first"},
+		{ID: "c2", Content: "second"},
+	}
+	if err := client.ProcessChunks(chunks); err != nil {
+		t.Fatalf("ProcessChunks failed: %v", err)
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) != 2 {
+			t.Errorf("Expected embedding on chunk %s, got %v", chunk.ID, chunk.Embedding)
+		}
+	}
+
+	embedding, err := client.GetSingleEmbedding("query")
+	if err != nil {
+		t.Fatalf("GetSingleEmbedding failed: %v", err)
+	}
+	if len(embedding) != 2 {
+		t.Errorf("Expected 2 dimensions, got %d", len(embedding))
+	}
+}
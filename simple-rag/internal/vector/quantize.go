@@ -0,0 +1,262 @@
+package vector
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Quantization modes of a store
+const (
+	QuantizationNone = "none"
+	QuantizationInt8 = "int8"
+	QuantizationPQ   = "pq"
+)
+
+const (
+	// defaultPQSubvectors is the number of product quantization subvectors
+	defaultPQSubvectors = 8
+	// pqCentroids is the codebook size per subvector, so each code is one byte
+	pqCentroids = 256
+	// pqIterations is the number of k-means iterations when training codebooks
+	pqIterations = 15
+	// pqMaxTrainingVectors caps the sample codebooks are trained on
+	pqMaxTrainingVectors = pqCentroids * 40
+	// defaultRescoreFactor times k candidates are rescored at full precision
+	defaultRescoreFactor = 10
+)
+
+// quantizedVector is the compressed form of an embedding. Min and Scale are
+// only used by scalar quantization. Norm is the norm of the reconstructed
+// vector, used to turn approximate dot products into cosine similarities.
+type quantizedVector struct {
+	codes []byte
+	min   float32
+	scale float32
+	norm  float32
+}
+
+// quantizer compresses vectors and scores queries against the codes
+type quantizer interface {
+	encode(v []float32) quantizedVector
+	// dotProduct prepares a query and returns its approximate dot product
+	// with a compressed vector
+	dotProduct(query []float64) func(q quantizedVector) float64
+}
+
+// int8Quantizer maps each component to one byte between the vector's
+// minimum and maximum
+type int8Quantizer struct{}
+
+func (int8Quantizer) encode(v []float32) quantizedVector {
+	if len(v) == 0 {
+		return quantizedVector{}
+	}
+
+	lo, hi := v[0], v[0]
+	for _, x := range v {
+		lo = min(lo, x)
+		hi = max(hi, x)
+	}
+	scale := (hi - lo) / 255
+
+	q := quantizedVector{codes: make([]byte, len(v)), min: lo, scale: scale}
+	var norm float64
+	for i, x := range v {
+		if scale > 0 {
+			q.codes[i] = byte(math.Round(float64((x - lo) / scale)))
+		}
+		r := float64(lo) + float64(scale)*float64(q.codes[i])
+		norm += r * r
+	}
+	q.norm = float32(math.Sqrt(norm))
+	return q
+}
+
+func (int8Quantizer) dotProduct(query []float64) func(q quantizedVector) float64 {
+	var sum float64
+	for _, x := range query {
+		sum += x
+	}
+	return func(q quantizedVector) float64 {
+		if len(q.codes) != len(query) {
+			return 0
+		}
+		var dot float64
+		for i, c := range q.codes {
+			dot += query[i] * float64(c)
+		}
+		return float64(q.min)*sum + float64(q.scale)*dot
+	}
+}
+
+// pqQuantizer splits vectors into subvectors and replaces each one with the
+// index of its nearest centroid in a per-subvector codebook
+type pqQuantizer struct {
+	// bounds[i] and bounds[i+1] delimit subvector i
+	bounds []int
+	// codebooks[i][j] is centroid j of subvector i
+	codebooks [][][]float32
+}
+
+// trainPQ learns codebooks with k-means on a sample of vectors. The number
+// of subvectors is capped at the dimensions and the number of centroids at
+// the sample size.
+func trainPQ(vectors [][]float32, dims, subvectors int) *pqQuantizer {
+	if subvectors <= 0 {
+		subvectors = defaultPQSubvectors
+	}
+	subvectors = min(subvectors, dims)
+
+	bounds := make([]int, subvectors+1)
+	for i := range bounds {
+		bounds[i] = i * dims / subvectors
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	if len(vectors) > pqMaxTrainingVectors {
+		rng.Shuffle(len(vectors), func(i, j int) { vectors[i], vectors[j] = vectors[j], vectors[i] })
+		vectors = vectors[:pqMaxTrainingVectors]
+	}
+
+	p := &pqQuantizer{bounds: bounds, codebooks: make([][][]float32, subvectors)}
+	for i := range subvectors {
+		sub := make([][]float32, len(vectors))
+		for j, v := range vectors {
+			sub[j] = v[bounds[i]:bounds[i+1]]
+		}
+		p.codebooks[i] = kmeans(sub, min(pqCentroids, len(sub)), rng)
+	}
+	return p
+}
+
+// kmeans clusters points into k centroids, starting from random points
+func kmeans(points [][]float32, k int, rng *rand.Rand) [][]float32 {
+	if k == 0 {
+		return nil
+	}
+	dims := len(points[0])
+
+	centroids := make([][]float32, k)
+	for i, j := range rng.Perm(len(points))[:k] {
+		centroids[i] = append([]float32(nil), points[j]...)
+	}
+
+	assignments := make([]int, len(points))
+	sums := make([][]float64, k)
+	for i := range sums {
+		sums[i] = make([]float64, dims)
+	}
+	counts := make([]int, k)
+
+	for range pqIterations {
+		changed := false
+		for i, p := range points {
+			nearest := nearestCentroid(centroids, p)
+			if nearest != assignments[i] {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+
+		for i := range sums {
+			clear(sums[i])
+			counts[i] = 0
+		}
+		for i, p := range points {
+			c := assignments[i]
+			counts[c]++
+			for d, x := range p {
+				sums[c][d] += float64(x)
+			}
+		}
+		for i := range centroids {
+			if counts[i] == 0 {
+				// Reseed empty clusters from a random point
+				copy(centroids[i], points[rng.Intn(len(points))])
+				continue
+			}
+			for d := range centroids[i] {
+				centroids[i][d] = float32(sums[i][d] / float64(counts[i]))
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+	return centroids
+}
+
+// nearestCentroid returns the index of the centroid closest to p
+func nearestCentroid(centroids [][]float32, p []float32) int {
+	best, bestDist := 0, math.Inf(1)
+	for i, c := range centroids {
+		var dist float64
+		for d, x := range p {
+			diff := float64(x - c[d])
+			dist += diff * diff
+		}
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+func (p *pqQuantizer) encode(v []float32) quantizedVector {
+	q := quantizedVector{codes: make([]byte, len(p.codebooks))}
+	var norm float64
+	for i, codebook := range p.codebooks {
+		c := nearestCentroid(codebook, v[p.bounds[i]:p.bounds[i+1]])
+		q.codes[i] = byte(c)
+		for _, x := range codebook[c] {
+			norm += float64(x) * float64(x)
+		}
+	}
+	q.norm = float32(math.Sqrt(norm))
+	return q
+}
+
+func (p *pqQuantizer) dotProduct(query []float64) func(q quantizedVector) float64 {
+	// Precompute the dot product of each query subvector with every centroid
+	tables := make([][]float64, len(p.codebooks))
+	for i, codebook := range p.codebooks {
+		sub := query[p.bounds[i]:p.bounds[i+1]]
+		tables[i] = make([]float64, len(codebook))
+		for j, centroid := range codebook {
+			var dot float64
+			for d, x := range centroid {
+				dot += sub[d] * float64(x)
+			}
+			tables[i][j] = dot
+		}
+	}
+
+	return func(q quantizedVector) float64 {
+		var dot float64
+		for i, c := range q.codes {
+			if int(c) < len(tables[i]) {
+				dot += tables[i][c]
+			}
+		}
+		return dot
+	}
+}
+
+// toFloat32 converts an embedding to single precision
+func toFloat32(v []float64) []float32 {
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(x)
+	}
+	return out
+}
+
+// toFloat64 converts a stored vector back to double precision
+func toFloat64(v []float32) []float64 {
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = float64(x)
+	}
+	return out
+}
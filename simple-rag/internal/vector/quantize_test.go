@@ -0,0 +1,302 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"simple-rag/pkg/types"
+)
+
+// syntheticVectors returns clustered random vectors, the shape real
+// embeddings of related documents tend to have
+func syntheticVectors(rng *rand.Rand, n, dims, clusters int) [][]float64 {
+	centers := make([][]float64, clusters)
+	for i := range centers {
+		centers[i] = make([]float64, dims)
+		for d := range centers[i] {
+			centers[i][d] = rng.NormFloat64()
+		}
+	}
+
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		center := centers[rng.Intn(clusters)]
+		vectors[i] = make([]float64, dims)
+		for d := range vectors[i] {
+			vectors[i][d] = center[d] + 0.6*rng.NormFloat64()
+		}
+	}
+	return vectors
+}
+
+// newSyntheticStore creates a store holding one document with a chunk per vector
+func newSyntheticStore(t *testing.T, options Options, vectors [][]float64) *Database {
+	t.Helper()
+
+	db := NewDatabaseWithOptions(t.TempDir(), options)
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	chunks := make([]*types.DocumentChunk, len(vectors))
+	for i, v := range vectors {
+		chunks[i] = &types.DocumentChunk{ID: fmt.Sprintf("c%d", i), DocumentID: "doc", Embedding: v}
+	}
+	if err := db.StoreDocumentWithChunks(&types.Document{ID: "doc"}, chunks); err != nil {
+		t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+	}
+	return db
+}
+
+// recall is the fraction of the expected chunk IDs found in the results
+func recall(expected, results []*types.SearchResult) float64 {
+	ids := make(map[string]bool)
+	for _, r := range results {
+		ids[r.Chunk.ID] = true
+	}
+	found := 0
+	for _, r := range expected {
+		if ids[r.Chunk.ID] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(expected))
+}
+
+func TestQuantizationRecall(t *testing.T) {
+	const (
+		n       = 2000
+		dims    = 64
+		k       = 10
+		queries = 50
+	)
+	rng := rand.New(rand.NewSource(42))
+	vectors := syntheticVectors(rng, n, dims, 20)
+	queryVectors := syntheticVectors(rng, queries, dims, 20)
+
+	exact := newSyntheticStore(t, Options{Quantization: QuantizationNone}, vectors)
+
+	tests := []struct {
+		name    string
+		options Options
+		// minimum average recall@k after rescoring
+		minRecall float64
+	}{
+		{"int8", Options{Quantization: QuantizationInt8}, 0.99},
+		{"pq", Options{Quantization: QuantizationPQ}, 0.85},
+		{"pq16", Options{Quantization: QuantizationPQ, PQSubvectors: 16}, 0.95},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newSyntheticStore(t, test.options, vectors)
+
+			var approximate, rescored float64
+			for _, query := range queryVectors {
+				expected, err := exact.Search(query, k, -1)
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+
+				results, err := db.Search(query, k, -1)
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+				rescored += recall(expected, results)
+
+				// Ranking by the codes alone, without rescoring
+				score := db.index.scorer(query)
+				var ranked []*types.SearchResult
+				for id, entry := range db.index.entries {
+					ranked = append(ranked, &types.SearchResult{Chunk: &types.DocumentChunk{ID: id}, Similarity: score(entry)})
+				}
+				sort.Slice(ranked, func(i, j int) bool { return ranked[i].Similarity > ranked[j].Similarity })
+				approximate += recall(expected, ranked[:k])
+			}
+			approximate /= queries
+			rescored /= queries
+
+			t.Logf("%s recall@%d: %.3f approximate, %.3f rescored (loss %.3f)",
+				test.name, k, approximate, rescored, 1-rescored)
+			if rescored < test.minRecall {
+				t.Errorf("Expected recall@%d >= %.2f, got %.3f", k, test.minRecall, rescored)
+			}
+			if rescored < approximate {
+				t.Errorf("Expected rescoring not to lower recall: %.3f < %.3f", rescored, approximate)
+			}
+		})
+	}
+}
+
+func TestQuantizedStorePersistence(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	vectors := syntheticVectors(rng, 300, 32, 5)
+	query := vectors[0]
+
+	for _, quantization := range []string{QuantizationInt8, QuantizationPQ} {
+		t.Run(quantization, func(t *testing.T) {
+			db := newSyntheticStore(t, Options{Quantization: quantization}, vectors)
+			before, err := db.Search(query, 5, 0)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(before) == 0 || before[0].Chunk.ID != "c0" {
+				t.Fatalf("Expected c0 first, got %v", before)
+			}
+			if before[0].Similarity < 0.999 {
+				t.Errorf("Expected full-precision similarity for c0, got %f", before[0].Similarity)
+			}
+
+			// Only codes stay in memory once saved
+			for id, entry := range db.index.entries {
+				if entry.pending != nil {
+					t.Fatalf("Expected no full-precision vector in memory for %s", id)
+				}
+			}
+
+			// Embeddings are kept out of chunks.json
+			data, err := os.ReadFile(filepath.Join(db.storagePath, "chunks.json"))
+			if err != nil {
+				t.Fatalf("Failed to read chunks: %v", err)
+			}
+			if !strings.Contains(string(data), `"embedding": null`) {
+				t.Error("Expected embeddings to be omitted from chunks.json")
+			}
+
+			db.Close()
+			reopened := NewDatabaseWithOptions(db.storagePath, Options{})
+			if err := reopened.Initialize(); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			defer reopened.Close()
+
+			if reopened.Quantization() != quantization {
+				t.Errorf("Expected %s store, got %s", quantization, reopened.Quantization())
+			}
+			after, err := reopened.Search(query, 5, 0)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(after) != len(before) {
+				t.Fatalf("Expected %d results after reload, got %d", len(before), len(after))
+			}
+			for i := range before {
+				if after[i].Chunk.ID != before[i].Chunk.ID {
+					t.Errorf("Result %d: expected %s, got %s", i, before[i].Chunk.ID, after[i].Chunk.ID)
+				}
+			}
+
+			// Deleting rewrites the vectors file without the document
+			if err := reopened.DeleteDocument("doc"); err != nil {
+				t.Fatalf("DeleteDocument failed: %v", err)
+			}
+			results, err := reopened.Search(query, 5, 0)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results) != 0 {
+				t.Errorf("Expected no results after delete, got %d", len(results))
+			}
+		})
+	}
+}
+
+func TestQuantizationFootprint(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := syntheticVectors(rng, 500, 128, 10)
+
+	size := func(db *Database) int64 {
+		var total int64
+		for _, name := range []string{"chunks.json", "vectors.bin"} {
+			if info, err := os.Stat(filepath.Join(db.storagePath, name)); err == nil {
+				total += info.Size()
+			}
+		}
+		return total
+	}
+
+	none := size(newSyntheticStore(t, Options{Quantization: QuantizationNone}, vectors))
+	for _, quantization := range []string{QuantizationInt8, QuantizationPQ} {
+		quantized := size(newSyntheticStore(t, Options{Quantization: quantization}, vectors))
+		t.Logf("%s: %d bytes, none: %d bytes", quantization, quantized, none)
+		if quantized*3 > none {
+			t.Errorf("%s: expected at least 3x smaller store than %d bytes, got %d", quantization, none, quantized)
+		}
+	}
+}
+
+func TestQuantizationChosenAtCreation(t *testing.T) {
+	tempDir := t.TempDir()
+
+	db := NewDatabaseWithOptions(tempDir, Options{Quantization: QuantizationInt8})
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	chunks := []*types.DocumentChunk{{ID: "a_1", DocumentID: "a", Embedding: []float64{1, 0, 0}}}
+	if err := db.StoreDocumentWithChunks(&types.Document{ID: "a"}, chunks); err != nil {
+		t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+	}
+	db.Close()
+
+	// The default configuration asks for none; an existing store keeps its mode
+	for _, requested := range []string{QuantizationNone, QuantizationPQ} {
+		t.Run("Open"+requested, func(t *testing.T) {
+			other := NewDatabaseWithOptions(tempDir, Options{Quantization: requested})
+			if err := other.Initialize(); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			defer other.Close()
+
+			if other.Quantization() != QuantizationInt8 {
+				t.Errorf("Expected the recorded int8 mode, got %s", other.Quantization())
+			}
+			results, err := other.Search([]float64{1, 0, 0}, 1, 0)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results) != 1 || results[0].Chunk.ID != "a_1" {
+				t.Errorf("Expected a_1, got %v", results)
+			}
+		})
+	}
+
+	t.Run("LegacyStore", func(t *testing.T) {
+		legacyDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(legacyDir, "documents.json"), []byte("{}"), 0644); err != nil {
+			t.Fatalf("Failed to write documents: %v", err)
+		}
+		legacy := NewDatabase(legacyDir)
+		if err := legacy.Initialize(); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		if legacy.Quantization() != QuantizationNone {
+			t.Errorf("Expected none for an existing store, got %s", legacy.Quantization())
+		}
+	})
+
+	t.Run("DimensionMismatch", func(t *testing.T) {
+		db := NewDatabaseWithOptions(tempDir, Options{})
+		if err := db.Initialize(); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		defer db.Close()
+
+		first := []*types.DocumentChunk{{ID: "a_1", DocumentID: "a", Embedding: []float64{1, 0, 0}}}
+		if err := db.StoreDocumentWithChunks(&types.Document{ID: "a"}, first); err != nil {
+			t.Fatalf("StoreDocumentWithChunks failed: %v", err)
+		}
+		second := []*types.DocumentChunk{{ID: "b_1", DocumentID: "b", Embedding: []float64{1, 0}}}
+		if err := db.StoreDocumentWithChunks(&types.Document{ID: "b"}, second); err == nil {
+			t.Error("Expected dimension mismatch error, got nil")
+		}
+		if _, err := db.GetDocument("b"); err == nil {
+			t.Error("Expected rejected document not to be stored")
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"simple-rag/pkg/types"
+)
+
+// StatusError is returned when the embedding service answers with a non-200 status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("embedding service returned status %d", e.StatusCode)
+}
+
+// IsRetryable reports whether an embedding error is likely transient:
+// network failures, 429 Too Many Requests and 5xx responses
+func IsRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryPolicy configures exponential backoff.
+// The wait before retry n is InitialBackoff*2^(n-1), capped at MaxBackoff,
+// with random jitter down to half of that so parallel workers spread out.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// backoff returns the wait before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half)+1))
+}
+
+// RetryingProvider retries transient GetEmbeddings failures of another provider
+type RetryingProvider struct {
+	provider EmbeddingProvider
+	policy   RetryPolicy
+	sleep    func(time.Duration)
+}
+
+// NewRetryingProvider wraps a provider with retries
+func NewRetryingProvider(provider EmbeddingProvider, policy RetryPolicy) *RetryingProvider {
+	return &RetryingProvider{
+		provider: provider,
+		policy:   policy,
+		sleep:    time.Sleep,
+	}
+}
+
+// GetEmbeddings gets embeddings, retrying transient errors with backoff
+func (p *RetryingProvider) GetEmbeddings(texts []string) ([][]float64, error) {
+	for retry := 0; ; retry++ {
+		embeddings, err := p.provider.GetEmbeddings(texts)
+		if err == nil {
+			return embeddings, nil
+		}
+		if retry >= p.policy.MaxRetries || !IsRetryable(err) {
+			if retry > 0 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", retry+1, err)
+			}
+			return nil, err
+		}
+		p.sleep(p.policy.backoff(retry + 1))
+	}
+}
+
+// GetSingleEmbedding gets embedding for a single text
+func (p *RetryingProvider) GetSingleEmbedding(text string) ([]float64, error) {
+	return singleEmbedding(p, text)
+}
+
+// ProcessChunks processes document chunks and adds embeddings
+func (p *RetryingProvider) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(p, chunks)
+}
+
+// Health checks the wrapped provider without retrying
+func (p *RetryingProvider) Health() error {
+	return p.provider.Health()
+}
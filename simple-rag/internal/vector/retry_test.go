@@ -0,0 +1,107 @@
+package vector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails a number of times before returning embeddings
+type flakyProvider struct {
+	StubEmbeddingClient
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *flakyProvider) GetEmbeddings(texts []string) ([][]float64, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		return nil, p.err
+	}
+	return p.StubEmbeddingClient.GetEmbeddings(texts)
+}
+
+func TestRetryingProvider(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 250 * time.Millisecond}
+
+	newRetrying := func(p EmbeddingProvider) (*RetryingProvider, *[]time.Duration) {
+		var waits []time.Duration
+		retrying := NewRetryingProvider(p, policy)
+		retrying.sleep = func(d time.Duration) { waits = append(waits, d) }
+		return retrying, &waits
+	}
+
+	t.Run("RecoversFromTransientErrors", func(t *testing.T) {
+		flaky := &flakyProvider{StubEmbeddingClient: *NewStubEmbeddingClient(8), failures: 3, err: &StatusError{StatusCode: 503}}
+		retrying, waits := newRetrying(flaky)
+
+		if _, err := retrying.GetEmbeddings([]string{"text"}); err != nil {
+			t.Fatalf("Expected success after retries, got %v", err)
+		}
+		if flaky.calls != 4 {
+			t.Errorf("Expected 4 calls, got %d", flaky.calls)
+		}
+
+		// Backoff doubles up to the cap, with jitter down to half
+		caps := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}
+		if len(*waits) != len(caps) {
+			t.Fatalf("Expected %d waits, got %v", len(caps), *waits)
+		}
+		for i, wait := range *waits {
+			if wait < caps[i]/2 || wait > caps[i] {
+				t.Errorf("Wait %d: expected between %v and %v, got %v", i, caps[i]/2, caps[i], wait)
+			}
+		}
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		flaky := &flakyProvider{failures: 10, err: &StatusError{StatusCode: 500}}
+		retrying, _ := newRetrying(flaky)
+
+		_, err := retrying.GetEmbeddings([]string{"text"})
+		if err == nil || !strings.Contains(err.Error(), "after 4 attempts") {
+			t.Errorf("Expected give-up error after 4 attempts, got %v", err)
+		}
+	})
+
+	t.Run("DoesNotRetryClientErrors", func(t *testing.T) {
+		flaky := &flakyProvider{failures: 10, err: &StatusError{StatusCode: 400}}
+		retrying, waits := newRetrying(flaky)
+
+		if _, err := retrying.GetEmbeddings([]string{"text"}); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		if flaky.calls != 1 || len(*waits) != 0 {
+			t.Errorf("Expected a single call without waiting, got %d calls", flaky.calls)
+		}
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	_, netErr := NewEmbeddingClient(server.URL, "m").GetEmbeddings([]string{"text"})
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"ServerError", &StatusError{StatusCode: 500}, true},
+		{"TooManyRequests", &StatusError{StatusCode: 429}, true},
+		{"Wrapped", fmt.Errorf("batch: %w", &StatusError{StatusCode: 502}), true},
+		{"BadRequest", &StatusError{StatusCode: 400}, false},
+		{"ConnectionRefused", netErr, true},
+		{"Other", fmt.Errorf("embedding count mismatch"), false},
+	}
+
+	for _, test := range tests {
+		if got := IsRetryable(test.err); got != test.expected {
+			t.Errorf("%s: expected %v, got %v (%v)", test.name, test.expected, got, test.err)
+		}
+	}
+}
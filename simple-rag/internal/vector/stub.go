@@ -0,0 +1,121 @@
+package vector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"simple-rag/pkg/types"
+)
+
+// defaultStubDimensions is the vector size of the stub embedding provider
+const defaultStubDimensions = 256
+
+// StubEmbeddingClient computes deterministic bag-of-words embeddings locally
+// by hashing tokens into a fixed number of dimensions. It needs no service,
+// so evaluations and tests can run in CI. Texts sharing words get similar
+// vectors, which keeps retrieval results meaningful.
+type StubEmbeddingClient struct {
+	dimensions int
+}
+
+// NewStubEmbeddingClient creates a stub embedding client
+func NewStubEmbeddingClient(dimensions int) *StubEmbeddingClient {
+	if dimensions <= 0 {
+		dimensions = defaultStubDimensions
+	}
+	return &StubEmbeddingClient{dimensions: dimensions}
+}
+
+// GetEmbeddings gets embeddings for a list of texts
+func (c *StubEmbeddingClient) GetEmbeddings(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i] = c.embed(text)
+	}
+	return embeddings, nil
+}
+
+// GetSingleEmbedding gets embedding for a single text
+func (c *StubEmbeddingClient) GetSingleEmbedding(text string) ([]float64, error) {
+	return singleEmbedding(c, text)
+}
+
+// ProcessChunks processes document chunks and adds embeddings
+func (c *StubEmbeddingClient) ProcessChunks(chunks []*types.DocumentChunk) error {
+	return processChunks(c, chunks)
+}
+
+// Health always succeeds
+func (c *StubEmbeddingClient) Health() error {
+	return nil
+}
+
+// embed hashes words, and CJK character bigrams, into a normalized vector
+func (c *StubEmbeddingClient) embed(text string) []float64 {
+	vector := make([]float64, c.dimensions)
+	for _, token := range stubTokens(text) {
+		h := fnv.New32a()
+		h.Write([]byte(token))
+		vector[h.Sum32()%uint32(c.dimensions)]++
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector
+}
+
+// stubTokens splits text into lowercase words; runs of CJK characters,
+// which have no spaces, become overlapping bigrams
+func stubTokens(text string) []string {
+	var tokens []string
+	var word, cjk []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			if len(word) > 0 {
+				tokens = append(tokens, string(word))
+				word = word[:0]
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,65 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStubEmbeddingClient(t *testing.T) {
+	client := NewStubEmbeddingClient(0)
+
+	embeddings, err := client.GetEmbeddings([]string{
+		"Access tokens expire after one hour",
+		"When do access tokens expire?",
+		"Install the CLI with go install",
+	})
+	if err != nil {
+		t.Fatalf("GetEmbeddings failed: %v", err)
+	}
+	if len(embeddings[0]) != defaultStubDimensions {
+		t.Fatalf("Expected %d dimensions, got %d", defaultStubDimensions, len(embeddings[0]))
+	}
+
+	var norm float64
+	for _, v := range embeddings[0] {
+		norm += v * v
+	}
+	if math.Abs(norm-1) > 1e-9 {
+		t.Errorf("Expected unit vector, got squared norm %f", norm)
+	}
+
+	related := cosineSimilarity(embeddings[0], embeddings[1])
+	unrelated := cosineSimilarity(embeddings[0], embeddings[2])
+	if related <= unrelated {
+		t.Errorf("Expected shared words to score higher: related %.3f, unrelated %.3f", related, unrelated)
+	}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		again, _ := client.GetSingleEmbedding("Access tokens expire after one hour")
+		if cosineSimilarity(again, embeddings[0]) < 1-1e-9 {
+			t.Error("Expected identical embeddings for identical text")
+		}
+	})
+
+	t.Run("Japanese", func(t *testing.T) {
+		a, _ := client.GetSingleEmbedding("Goは並行処理が得意です")
+		b, _ := client.GetSingleEmbedding("並行処理について")
+		c, _ := client.GetSingleEmbedding("機械学習の基礎")
+		if cosineSimilarity(a, b) <= cosineSimilarity(a, c) {
+			t.Error("Expected shared CJK bigrams to score higher")
+		}
+	})
+}
+
+func TestStubTokens(t *testing.T) {
+	tokens := stubTokens("Go言語は速い!")
+	expected := []string{"go", "言語", "語は", "は速", "速い"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, tokens)
+		}
+	}
+}
@@ -20,32 +20,106 @@ type Document struct {
 
 // DocumentChunk represents a chunk of a document with embedding
 type DocumentChunk struct {
-	ID         string    `json:"id"`
-	DocumentID string    `json:"document_id"`
-	ChunkIndex int       `json:"chunk_index"`
-	Content    string    `json:"content"`
-	StartPos   int       `json:"start_pos"`
-	EndPos     int       `json:"end_pos"`
-	Embedding  []float64 `json:"embedding"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         string            `json:"id"`
+	DocumentID string            `json:"document_id"`
+	ChunkIndex int               `json:"chunk_index"`
+	Content    string            `json:"content"`
+	StartPos   int               `json:"start_pos"`
+	EndPos     int               `json:"end_pos"`
+	Embedding  []float64         `json:"embedding"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
 }
 
-// SearchResult represents a search result with similarity score
+// SearchResult represents a search result with similarity score.
+// RerankScore is set when a rerank stage rescored the result.
 type SearchResult struct {
-	Chunk      *DocumentChunk `json:"chunk"`
-	Document   *Document      `json:"document"`
-	Similarity float64        `json:"similarity"`
+	Chunk       *DocumentChunk `json:"chunk"`
+	Document    *Document      `json:"document"`
+	Similarity  float64        `json:"similarity"`
+	RerankScore float64        `json:"rerank_score,omitempty"`
 }
 
-// RAGResponse represents the response from the RAG system
+// RAGResponse represents the response from the RAG system.
+// SearchQuery is the standalone query used for retrieval when it differs
+// from Query, e.g. after a follow-up question was rewritten.
+// Claims are the sentences of Answer that cite sources.
+// ProcessTime covers the whole query, from embedding to generation.
 type RAGResponse struct {
 	Query       string          `json:"query"`
+	SearchQuery string          `json:"search_query,omitempty"`
 	Answer      string          `json:"answer"`
 	Sources     []*SearchResult `json:"sources"`
+	Claims      []Claim         `json:"claims,omitempty"`
 	ProcessTime time.Duration   `json:"process_time"`
+	Trace       *QueryTrace     `json:"trace,omitempty"`
 	CreatedAt   time.Time       `json:"created_at"`
 }
 
+// QueryTrace records how long each stage of a query took and what it saw.
+// Candidates is the number of chunks vector search returned and Similarity
+// their score distribution; Results is the number passed to the LLM.
+// DroppedContexts counts results left out to fit the prompt budget.
+// Generation fields are zero when nothing was retrieved.
+type QueryTrace struct {
+	Query             string          `json:"query"`
+	SearchQuery       string          `json:"search_query,omitempty"`
+	CondenseLatency   time.Duration   `json:"condense_latency,omitempty"`
+	EmbeddingLatency  time.Duration   `json:"embedding_latency"`
+	SearchLatency     time.Duration   `json:"search_latency"`
+	RerankLatency     time.Duration   `json:"rerank_latency,omitempty"`
+	GenerationLatency time.Duration   `json:"generation_latency"`
+	TotalLatency      time.Duration   `json:"total_latency"`
+	Candidates        int             `json:"candidates"`
+	Results           int             `json:"results"`
+	Similarity        SimilarityStats `json:"similarity"`
+	PromptChars       int             `json:"prompt_chars"`
+	PromptTokens      int             `json:"prompt_tokens"`
+	DroppedContexts   int             `json:"dropped_contexts,omitempty"`
+	AnswerChars       int             `json:"answer_chars"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// SimilarityStats summarizes the similarity scores of search candidates
+type SimilarityStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+// Claim is a statement in an answer together with the sources it cites
+type Claim struct {
+	Text      string     `json:"text"`
+	Citations []Citation `json:"citations"`
+}
+
+// Citation is a bracketed source reference such as [2] in an answer.
+// Source is the 1-based index into RAGResponse.Sources. Retrieved is false
+// when the answer cites a source number that was not retrieved; the chunk
+// fields are empty in that case.
+type Citation struct {
+	Source     int    `json:"source"`
+	Retrieved  bool   `json:"retrieved"`
+	ChunkID    string `json:"chunk_id,omitempty"`
+	DocumentID string `json:"document_id,omitempty"`
+	StartPos   int    `json:"start_pos"`
+	EndPos     int    `json:"end_pos"`
+}
+
+// Chat roles used in conversation history
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatTurn represents one message in a conversation
+type ChatTurn struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // EmbeddingRequest represents a request to the embedding service
 type EmbeddingRequest struct {
 	Texts []string `json:"texts"`
@@ -142,9 +142,10 @@ func TestSearchResultSerialization(t *testing.T) {
 		Content:    "Test chunk content",
 	}
 	result := &SearchResult{
-		Chunk:      chunk,
-		Document:   doc,
-		Similarity: 0.95,
+		Chunk:       chunk,
+		Document:    doc,
+		Similarity:  0.95,
+		RerankScore: 7.5,
 	}
 
 	// Test JSON serialization
@@ -164,6 +165,9 @@ func TestSearchResultSerialization(t *testing.T) {
 	if deserializedResult.Similarity != result.Similarity {
 		t.Errorf("Expected similarity %f, got %f", result.Similarity, deserializedResult.Similarity)
 	}
+	if deserializedResult.RerankScore != result.RerankScore {
+		t.Errorf("Expected rerank score %f, got %f", result.RerankScore, deserializedResult.RerankScore)
+	}
 	if deserializedResult.Chunk == nil {
 		t.Fatal("Chunk should not be nil")
 	}
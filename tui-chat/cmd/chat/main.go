@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sakaeshinya/tui-chat/internal/app"
+	"github.com/sakaeshinya/tui-chat/internal/bot"
 	"github.com/sakaeshinya/tui-chat/internal/config"
 	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/e2e"
 	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/internal/storage"
 	"github.com/sakaeshinya/tui-chat/internal/ui"
 )
 
@@ -22,6 +31,10 @@ func main() {
 		address    = flag.String("a", "", "接続先アドレス（クライアントモード）またはリッスンアドレス（サーバーモード）")
 		showHelp   = flag.Bool("h", false, "ヘルプを表示")
 		configPath = flag.String("config", "", "設定ファイルのパス")
+		useTLS     = flag.Bool("tls", false, "TLSで通信する（設定ファイルの tls_enabled より優先）")
+		headless   = flag.Bool("headless", false, "画面を使わず、標準入力の行を送信して受信したメッセージをJSONの行で出力する")
+		bots       = flag.String("bot", "", "有効にするボット（カンマ区切り: "+strings.Join(bot.Names(), ",")+"）")
+		webhook    = flag.String("webhook", "", "Webhookを受け付けてチャットに中継するアドレス（例: 127.0.0.1:8090）")
 	)
 	flag.Parse()
 
@@ -32,11 +45,21 @@ func main() {
 	}
 
 	// 設定の読み込み
-	cfg, err := loadConfig(*configPath)
+	path, err := resolveConfigPath(*configPath)
+	if err != nil {
+		log.Fatalf("設定ファイルのパスを取得できません: %v", err)
+	}
+	cfg, err := config.LoadConfig(path)
 	if err != nil {
 		log.Fatalf("設定の読み込みに失敗しました: %v", err)
 	}
 
+	// 暗号化と認証の設定
+	security, err := buildSecurity(cfg, filepath.Dir(path), *useTLS)
+	if err != nil {
+		log.Fatalf("設定が不正です: %v", err)
+	}
+
 	// ユーザー名の設定
 	if *username == "" {
 		*username = cfg.Username
@@ -47,20 +70,52 @@ func main() {
 	if err != nil {
 		log.Fatalf("ユーザーの作成に失敗しました: %v", err)
 	}
+	user.ID, err = loadUserID(filepath.Join(filepath.Dir(path), "user_id"), user.ID)
+	if err != nil {
+		log.Fatalf("ユーザーIDの読み込みに失敗しました: %v", err)
+	}
+
+	// ダイレクトメッセージの暗号化に使う鍵ペアの読み込み（初回は生成する）
+	identity, err := e2e.LoadIdentity(filepath.Join(filepath.Dir(path), "identity.key"))
+	if err != nil {
+		log.Fatalf("鍵ペアの読み込みに失敗しました: %v", err)
+	}
 
 	// ネットワークコンポーネントの作成
-	var networkComponent network.NetworkPort
-	if *isServer {
-		networkComponent = network.NewTCPServer()
-	} else {
-		networkComponent = network.NewTCPClient()
+	// サーバーの起動や接続のたびに、モードに合ったものを作り直す
+	newNetwork := func(server bool) network.NetworkPort {
+		return newNetworkComponent(server, security)
 	}
 
 	// UIコントローラの作成（ハンドラは後で設定）
-	controller := ui.NewTUIController(nil, user)
+	var controller uiController
+	if *headless {
+		controller = ui.NewHeadlessController(nil, user, os.Stdin, os.Stdout)
+	} else {
+		controller = ui.NewTUIController(nil, user)
+	}
+
+	// ボットの作成
+	botHandlers, err := bot.Parse(*bots)
+	if err != nil {
+		log.Fatalf("設定が不正です: %v", err)
+	}
 
 	// サービスの作成
-	service := app.NewChatService(networkComponent, controller, user)
+	service := app.NewChatService(newNetwork(*isServer), controller, user)
+	service.SetNetworkFactory(newNetwork)
+	for _, handler := range botHandlers {
+		service.AddBot(handler)
+	}
+	downloadDir := cfg.DownloadDir
+	if downloadDir == "" {
+		downloadDir = filepath.Join(filepath.Dir(path), "downloads")
+	}
+	service.SetIdentity(identity, e2e.NewKnownKeys(filepath.Join(filepath.Dir(path), "known_keys.json")))
+	service.SetFileOptions(app.FileOptions{DownloadDir: downloadDir, MaxSize: cfg.MaxFileSize})
+	if cfg.History {
+		service.SetHistory(storage.NewFileHistory(filepath.Join(filepath.Dir(path), "history")), cfg.HistoryLimit)
+	}
 
 	// イベントハンドラの作成
 	handler := app.NewChatEventHandler(service, controller)
@@ -78,7 +133,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("サーバーの起動に失敗しました: %v", err)
 		}
-		controller.SetMode(ui.ModeServer)
+		setMode(controller, ui.ModeServer)
 	} else if *isClient && *address != "" {
 		// クライアントモードで起動
 		if *address == "" {
@@ -88,25 +143,140 @@ func main() {
 		if err != nil {
 			log.Fatalf("サーバーへの接続に失敗しました: %v", err)
 		}
-		controller.SetMode(ui.ModeClient)
+		setMode(controller, ui.ModeClient)
+	}
+
+	// Webhookの受け付け
+	var hook *bot.Webhook
+	if *webhook != "" {
+		hook = bot.NewWebhook(*webhook, service.SendMessage)
+		if err := hook.Start(); err != nil {
+			log.Fatalf("Webhookの受け付けを開始できません: %v", err)
+		}
+		defer hook.Close()
+		controller.DisplayNotice(fmt.Sprintf("Webhookを受け付けています: POST http://%s/message", hook.Addr()))
+	}
+
+	// ヘッドレスモードではシグナルを受けたら入力の読み込みをやめて終了する
+	ctx := context.Background()
+	if *headless {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			controller.Stop()
+		}()
 	}
 
 	// UIの起動
 	if err := controller.Start(); err != nil {
 		log.Fatalf("UIの起動に失敗しました: %v", err)
 	}
+
+	if *headless {
+		// ボットやWebhookを使う場合は、入力が終わってもシグナルか /quit を受けるまで動かし続ける
+		if len(botHandlers) > 0 || hook != nil {
+			select {
+			case <-ctx.Done():
+			case <-handler.Done():
+			}
+		}
+		if err := service.Flush(flushTimeout); err != nil {
+			controller.ShowError(err)
+		}
+	}
+	service.Disconnect()
 }
 
-// loadConfig は設定を読み込むヘルパー関数
-func loadConfig(path string) (config.Config, error) {
-	if path == "" {
-		var err error
-		path, err = config.GetConfigPath()
-		if err != nil {
-			return config.DefaultConfig, err
+// flushTimeout はヘッドレスモードの終了時に送信待ちのメッセージを送り終えるまで待つ時間
+const flushTimeout = 5 * time.Second
+
+// uiController はmainが扱うUIコントローラのインターフェース
+type uiController interface {
+	ui.UIPort
+	SetHandler(handler ui.UIEventHandler)
+}
+
+// setMode はUIコントローラがモードの表示に対応していればモードを設定するヘルパー関数
+func setMode(controller uiController, mode ui.UIMode) {
+	if tui, ok := controller.(*ui.TUIController); ok {
+		tui.SetMode(mode)
+	}
+}
+
+// newNetworkComponent はモードに合ったネットワークコンポーネントを作成するヘルパー関数
+func newNetworkComponent(server bool, security network.Security) network.NetworkPort {
+	if server {
+		component := network.NewTCPServer()
+		component.SetSecurity(security)
+		return component
+	}
+	component := network.NewTCPClient()
+	component.SetSecurity(security)
+	return component
+}
+
+// resolveConfigPath は設定ファイルのパスを決定するヘルパー関数
+func resolveConfigPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return config.GetConfigPath()
+}
+
+// loadUserID は設定ディレクトリに保存したユーザーIDを読み込むヘルパー関数
+// ファイルがなければgeneratedを保存して使う。相手は公開鍵をユーザーIDごとに記録するため、起動し直しても同じIDを名乗る
+func loadUserID(path string, generated domain.UserID) (domain.UserID, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return domain.UserID(id), nil
 		}
+	} else if !os.IsNotExist(err) {
+		return "", err
 	}
-	return config.LoadConfig(path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(generated.String()+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return generated, nil
+}
+
+// buildSecurity は設定から暗号化と認証の設定を作成するヘルパー関数
+// 証明書などのファイルは指定がなければ設定ディレクトリに置く
+// パスフレーズは環境変数 TUI_CHAT_PASSPHRASE でも指定できる
+func buildSecurity(cfg config.Config, configDir string, useTLS bool) (network.Security, error) {
+	verify, err := network.ParseVerifyMode(cfg.TLSVerify)
+	if err != nil {
+		return network.Security{}, err
+	}
+
+	security := network.Security{
+		TLS: network.TLSOptions{
+			Enabled:        cfg.TLSEnabled || useTLS,
+			CertFile:       cfg.TLSCertFile,
+			KeyFile:        cfg.TLSKeyFile,
+			Verify:         verify,
+			CAFile:         cfg.TLSCAFile,
+			KnownHostsFile: filepath.Join(configDir, "known_hosts"),
+		},
+		Passphrase: cfg.Passphrase,
+	}
+	if security.TLS.CertFile == "" {
+		security.TLS.CertFile = filepath.Join(configDir, "server.crt")
+	}
+	if security.TLS.KeyFile == "" {
+		security.TLS.KeyFile = filepath.Join(configDir, "server.key")
+	}
+	if passphrase := os.Getenv("TUI_CHAT_PASSPHRASE"); passphrase != "" {
+		security.Passphrase = passphrase
+	}
+
+	return security, nil
 }
 
 // printHelp はヘルプを表示する関数
@@ -122,9 +292,16 @@ func printHelp() {
 	fmt.Println("  -c            クライアントモードで起動")
 	fmt.Println("  -a <address>  接続先アドレス（クライアントモード）またはリッスンアドレス（サーバーモード）")
 	fmt.Println("  -config <path> 設定ファイルのパス")
+	fmt.Println("  -tls          TLSで通信する")
+	fmt.Println("  -headless     画面を使わず標準入出力で動作する（受信したメッセージはJSONの行で出力）")
+	fmt.Println("  -bot <names>  ボットを有効にする（カンマ区切り: " + strings.Join(bot.Names(), ",") + "）")
+	fmt.Println("  -webhook <address> Webhookを受け付けてチャットに中継する")
 	fmt.Println("  -h            ヘルプを表示")
 	fmt.Println("")
 	fmt.Println("例:")
 	fmt.Println("  サーバーモード: chat -s -a :8080")
 	fmt.Println("  クライアントモード: chat -c -a localhost:8080")
+	fmt.Println("  TLSとパスフレーズ: TUI_CHAT_PASSPHRASE=secret chat -s -tls -a :8080")
+	fmt.Println("  通知の投稿: echo \"ビルドが完了しました\" | chat -headless -c -a localhost:8080 -u ci")
+	fmt.Println("  ボット: chat -headless -c -a localhost:8080 -u bot -bot echo,time -webhook 127.0.0.1:8090")
 }
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownCommand は登録されていないコマンドが入力された場合のエラー
+var ErrUnknownCommand = errors.New("不明なコマンドです")
+
+// Command はスラッシュコマンドの定義
+type Command struct {
+	Name        string                  // 先頭の "/" を除いたコマンド名
+	Usage       string                  // 引数の説明（例: "<ルーム名>"）
+	Description string                  // /help で表示する説明
+	Run         func(args string) error // コマンド名に続く引数を受け取って実行する
+}
+
+// String はコマンドの使い方を返すメソッド
+func (c Command) String() string {
+	if c.Usage == "" {
+		return "/" + c.Name
+	}
+	return fmt.Sprintf("/%s %s", c.Name, c.Usage)
+}
+
+// CommandRegistry はスラッシュコマンドを名前で管理する構造体
+type CommandRegistry struct {
+	commands map[string]Command
+}
+
+// NewCommandRegistry は空のコマンド一覧を生成するファクトリ関数
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		commands: make(map[string]Command),
+	}
+}
+
+// Register はコマンドを登録するメソッド
+func (r *CommandRegistry) Register(cmd Command) error {
+	name := strings.ToLower(strings.TrimPrefix(cmd.Name, "/"))
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("コマンド名が不正です: %q", cmd.Name)
+	}
+	if cmd.Run == nil {
+		return fmt.Errorf("/%s の処理が設定されていません", name)
+	}
+	if _, ok := r.commands[name]; ok {
+		return fmt.Errorf("/%s は既に登録されています", name)
+	}
+
+	cmd.Name = name
+	r.commands[name] = cmd
+	return nil
+}
+
+// Execute は名前に対応するコマンドを実行するメソッド
+func (r *CommandRegistry) Execute(name, args string) error {
+	cmd, ok := r.commands[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("%w: /%s（/help で一覧を表示します）", ErrUnknownCommand, name)
+	}
+	return cmd.Run(strings.TrimSpace(args))
+}
+
+// Complete は入力途中のコマンド名に一致するコマンドを "/名前" の形で名前順に返すメソッド
+func (r *CommandRegistry) Complete(prefix string) []string {
+	prefix = strings.ToLower(strings.TrimPrefix(prefix, "/"))
+
+	candidates := []string{}
+	for name := range r.commands {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, "/"+name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// Commands は登録されているコマンドを名前順に返すメソッド
+func (r *CommandRegistry) Commands() []Command {
+	commands := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sakaeshinya/tui-chat/internal/ui"
+)
+
+// newTestRegistry は受け取った引数を記録するコマンドを登録した一覧を返すテスト用のヘルパー関数
+func newTestRegistry(t *testing.T, names ...string) (*CommandRegistry, map[string][]string) {
+	t.Helper()
+
+	registry := NewCommandRegistry()
+	calls := map[string][]string{}
+	for _, name := range names {
+		err := registry.Register(Command{Name: name, Run: func(args string) error {
+			calls[name] = append(calls[name], args)
+			return nil
+		}})
+		if err != nil {
+			t.Fatalf("Register(%q): %v", name, err)
+		}
+	}
+	return registry, calls
+}
+
+func TestCommandRegistryRegister(t *testing.T) {
+	run := func(string) error { return nil }
+	tests := []struct {
+		name    string
+		cmd     Command
+		wantErr string
+	}{
+		{"通常", Command{Name: "join", Run: run}, ""},
+		{"スラッシュ付き", Command{Name: "/rooms", Run: run}, ""},
+		{"大文字", Command{Name: "Help", Run: run}, ""},
+		{"重複", Command{Name: "JOIN", Run: run}, "既に登録されています"},
+		{"空の名前", Command{Name: "/", Run: run}, "コマンド名が不正です"},
+		{"空白を含む名前", Command{Name: "two words", Run: run}, "コマンド名が不正です"},
+		{"処理なし", Command{Name: "noop"}, "処理が設定されていません"},
+	}
+
+	registry := NewCommandRegistry()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := registry.Register(test.cmd)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("Register(%+v): %v", test.cmd, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Register(%+v) error = %v, want %q", test.cmd, err, test.wantErr)
+			}
+		})
+	}
+
+	var names []string
+	for _, cmd := range registry.Commands() {
+		names = append(names, cmd.Name)
+	}
+	if want := []string{"help", "join", "rooms"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Commands = %v, want %v", names, want)
+	}
+}
+
+func TestCommandRegistryExecute(t *testing.T) {
+	registry, calls := newTestRegistry(t, "join", "dm", "leave")
+
+	tests := []struct {
+		input    string
+		wantName string
+		wantArgs string
+	}{
+		{"/join general", "join", "general"},
+		{"  /JOIN   dev  ", "join", "dev"},
+		{"/dm bob  こんにちは 世界", "dm", "bob  こんにちは 世界"},
+		{"/leave", "leave", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			name, args, ok := ui.ParseCommand(test.input)
+			if !ok {
+				t.Fatalf("ParseCommand(%q) がコマンドと判定しませんでした", test.input)
+			}
+			before := len(calls[test.wantName])
+			if err := registry.Execute(name, args); err != nil {
+				t.Fatalf("Execute(%q, %q): %v", name, args, err)
+			}
+			got := calls[test.wantName]
+			if len(got) != before+1 || got[len(got)-1] != test.wantArgs {
+				t.Errorf("/%s に渡した引数 = %q, want %q", test.wantName, got, test.wantArgs)
+			}
+		})
+	}
+
+	// 前後の空白は取り除いて渡す
+	if err := registry.Execute("dm", "  bob hi \t"); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := calls["dm"][len(calls["dm"])-1]; got != "bob hi" {
+		t.Errorf("/dm に渡した引数 = %q, want %q", got, "bob hi")
+	}
+}
+
+func TestParseCommandRejectsMessages(t *testing.T) {
+	for _, input := range []string{"こんにちは", "/", "//join general", "", "  "} {
+		if name, _, ok := ui.ParseCommand(input); ok {
+			t.Errorf("ParseCommand(%q) = %q, メッセージをコマンドと判定しました", input, name)
+		}
+	}
+}
+
+func TestCommandRegistryUnknownCommand(t *testing.T) {
+	registry, calls := newTestRegistry(t, "join")
+
+	for _, name := range []string{"jion", "", "join2"} {
+		err := registry.Execute(name, "general")
+		if !errors.Is(err, ErrUnknownCommand) {
+			t.Errorf("Execute(%q) error = %v, want %v", name, err, ErrUnknownCommand)
+			continue
+		}
+		if !strings.Contains(err.Error(), "/"+name) || !strings.Contains(err.Error(), "/help") {
+			t.Errorf("Execute(%q) error = %q, コマンド名と /help の案内を含みません", name, err)
+		}
+	}
+	if len(calls["join"]) != 0 {
+		t.Errorf("不明なコマンドで /join を実行しました: %v", calls["join"])
+	}
+
+	// コマンドのエラーはそのまま返す
+	failing := errors.New("失敗しました")
+	if err := registry.Register(Command{Name: "fail", Run: func(string) error { return failing }}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := registry.Execute("fail", ""); !errors.Is(err, failing) {
+		t.Errorf("Execute error = %v, want %v", err, failing)
+	}
+}
+
+func TestCommandRegistryComplete(t *testing.T) {
+	registry, _ := newTestRegistry(t, "join", "leave", "list", "listen", "help")
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"/l", []string{"/leave", "/list", "/listen"}},
+		{"/list", []string{"/list", "/listen"}},
+		{"LI", []string{"/list", "/listen"}},
+		{"/j", []string{"/join"}},
+		{"/", []string{"/help", "/join", "/leave", "/list", "/listen"}},
+		{"/x", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.prefix, func(t *testing.T) {
+			if got := registry.Complete(test.prefix); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Complete(%q) = %v, want %v", test.prefix, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommandString(t *testing.T) {
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{Command{Name: "leave"}, "/leave"},
+		{Command{Name: "join", Usage: "<ルーム名>"}, "/join <ルーム名>"},
+	}
+	for _, test := range tests {
+		if got := test.cmd.String(); got != test.want {
+			t.Errorf("String() = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestNoArgs(t *testing.T) {
+	called := 0
+	run := noArgs("leave", func() error {
+		called++
+		return nil
+	})
+
+	if err := run(""); err != nil || called != 1 {
+		t.Errorf("run(\"\") = %v, called %d 回", err, called)
+	}
+	if err := run("extra"); err == nil || !strings.Contains(err.Error(), "使い方: /leave") || called != 1 {
+		t.Errorf("run(\"extra\") = %v, called %d 回", err, called)
+	}
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/e2e"
+	"github.com/sakaeshinya/tui-chat/internal/network"
+)
+
+// ダイレクトメッセージのエラー
+var (
+	ErrDirectUnsupported = errors.New("このネットワークはダイレクトメッセージに対応していません")
+	ErrNoIdentity        = errors.New("暗号化の鍵が設定されていません")
+)
+
+// SetIdentity はダイレクトメッセージの暗号化に使う鍵ペアと、メンバーの公開鍵の記録先を設定するメソッド
+// 公開鍵は次に送るユーザー情報からルームのメンバーに伝わる
+func (s *ChatService) SetIdentity(identity *e2e.Identity, knownKeys *e2e.KnownKeys) {
+	s.sessionMutex.Lock()
+	s.identity = identity
+	s.knownKeys = knownKeys
+	s.user.PublicKey = identity.PublicKey()
+	s.sessionMutex.Unlock()
+}
+
+// SendDirectMessage は「宛先の名前 本文」の形の引数から、同じルームのメンバーにダイレクトメッセージを送るメソッド
+// 名前に空白を含むメンバーにも送れるよう、在室中のメンバーの名前のうち最も長く一致するものを宛先にする
+// 本文は宛先の公開鍵で暗号化するため、中継するサーバーには読めない
+func (s *ChatService) SendDirectMessage(args string) error {
+	if !s.isRunning() || !s.isConnected() {
+		return errors.New("接続されていません")
+	}
+	direct, ok := s.currentNetwork().(network.DirectPort)
+	if !ok {
+		return ErrDirectUnsupported
+	}
+	if s.identity == nil {
+		return ErrNoIdentity
+	}
+
+	recipient, content, err := s.findRecipient(args)
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		return errors.New("使い方: /dm <名前> <本文>")
+	}
+	if len(recipient.PublicKey) == 0 {
+		return fmt.Errorf("%s はダイレクトメッセージの暗号化に対応していません", recipient.Name)
+	}
+	if err := s.checkKey(recipient); err != nil {
+		return err
+	}
+
+	user := s.currentUser()
+	msg, err := domain.NewMessage(content, user.ID)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sealed, err := s.identity.Seal(recipient.PublicKey, plaintext, directHeader(msg.ID, user.ID, recipient.ID))
+	if err != nil {
+		return err
+	}
+
+	if err := direct.SendDirectMessage(network.DirectMessage{ID: msg.ID, To: recipient.ID, Sealed: sealed}); err != nil {
+		return err
+	}
+	s.markSeen(msg.ID)
+	s.ui.DisplayDirectMessage(msg, user, recipient)
+	return nil
+}
+
+// receiveDirect は届いたダイレクトメッセージを復号して表示する内部メソッド
+// 送信者の公開鍵が記録と異なる場合は、なりすましの可能性があるため復号せずに破棄する
+func (s *ChatService) receiveDirect(direct network.DirectMessage) {
+	if s.identity == nil || !s.markSeen(direct.ID) {
+		return
+	}
+
+	sender := domain.User{ID: direct.From.ID, Name: direct.From.Name, IsActive: true, PublicKey: direct.From.PublicKey}
+	if err := s.checkKey(sender); err != nil {
+		s.ui.ShowError(fmt.Errorf("%s からのダイレクトメッセージを破棄しました: %w", sender.Name, err))
+		return
+	}
+
+	user := s.currentUser()
+	plaintext, err := s.identity.Open(sender.PublicKey, direct.Sealed, directHeader(direct.ID, sender.ID, user.ID))
+	var msg domain.Message
+	if err == nil {
+		err = json.Unmarshal(plaintext, &msg)
+	}
+	if err != nil || msg.ID != direct.ID || msg.Sender != sender.ID {
+		s.ui.ShowError(fmt.Errorf("%s からのダイレクトメッセージを復号できませんでした", sender.Name))
+		return
+	}
+
+	s.ui.DisplayDirectMessage(msg, sender, user)
+}
+
+// Verify は自分と相手の公開鍵のフィンガープリントを表示するメソッド
+// 名前を省略した場合は自分のフィンガープリントだけを表示する
+func (s *ChatService) Verify(name string) error {
+	if s.identity == nil {
+		return ErrNoIdentity
+	}
+
+	s.ui.DisplayNotice("あなたのフィンガープリント: " + e2e.Fingerprint(s.identity.PublicKey()))
+	if name == "" {
+		return nil
+	}
+
+	member, err := s.memberWithKey(name)
+	if err != nil {
+		return err
+	}
+	fingerprint := e2e.Fingerprint(member.PublicKey)
+	recorded, ok, err := s.knownKeys.Lookup(member.ID.String())
+	if err != nil {
+		return err
+	}
+
+	state := "記録済みの鍵と一致します"
+	switch {
+	case !ok:
+		state = "まだ記録していません"
+	case recorded.Fingerprint != fingerprint:
+		state = fmt.Sprintf("記録と異なります（記録: %s）。本人の鍵であれば /trust %s で記録を更新してください", recorded.Fingerprint, member.Name)
+	}
+	s.ui.DisplayNotice(fmt.Sprintf("%s のフィンガープリント: %s（%s）", member.Name, fingerprint, state))
+	s.ui.DisplayNotice("別の経路で相手とフィンガープリントを照合してください")
+	return nil
+}
+
+// Trust はメンバーの現在の公開鍵を正しいものとして記録するメソッド
+func (s *ChatService) Trust(name string) error {
+	if s.identity == nil {
+		return ErrNoIdentity
+	}
+
+	member, err := s.memberWithKey(name)
+	if err != nil {
+		return err
+	}
+	fingerprint := e2e.Fingerprint(member.PublicKey)
+	if err := s.knownKeys.Trust(member.ID.String(), member.Name, fingerprint); err != nil {
+		return err
+	}
+
+	s.keysMutex.Lock()
+	delete(s.keyWarnings, member.ID)
+	s.keysMutex.Unlock()
+
+	s.ui.DisplayNotice(fmt.Sprintf("%s の公開鍵を記録しました: %s", member.Name, fingerprint))
+	return nil
+}
+
+// checkKey はメンバーの公開鍵を記録と照合する内部メソッド
+// 初めてのメンバーは記録し、記録と異なる場合はエラーを返す
+func (s *ChatService) checkKey(member domain.User) error {
+	if s.knownKeys == nil || len(member.PublicKey) == 0 {
+		return nil
+	}
+	_, err := s.knownKeys.Check(member.ID.String(), member.Name, e2e.Fingerprint(member.PublicKey))
+	if errors.Is(err, e2e.ErrKeyChanged) {
+		return fmt.Errorf("%w。/verify %s で確認し、本人の鍵であれば /trust %s で記録を更新してください", err, member.Name, member.Name)
+	}
+	return err
+}
+
+// warnKeyChange はメンバーの公開鍵が記録と異なれば警告する内部メソッド
+// 同じ鍵について何度も警告しないよう、警告した鍵を覚えておく
+func (s *ChatService) warnKeyChange(member domain.User) {
+	if member.ID == s.currentUser().ID {
+		return
+	}
+	err := s.checkKey(member)
+	if err == nil {
+		return
+	}
+
+	fingerprint := e2e.Fingerprint(member.PublicKey)
+	s.keysMutex.Lock()
+	warned := s.keyWarnings[member.ID] == fingerprint
+	s.keyWarnings[member.ID] = fingerprint
+	s.keysMutex.Unlock()
+
+	if !warned {
+		s.ui.ShowError(err)
+	}
+}
+
+// findRecipient は引数の先頭に一致する在室中のメンバーと、続く本文を返す内部メソッド
+func (s *ChatService) findRecipient(args string) (domain.User, string, error) {
+	var recipient domain.User
+	content := ""
+	for _, member := range s.otherMembers() {
+		if len(member.Name) <= len(recipient.Name) {
+			continue
+		}
+		if args == member.Name || strings.HasPrefix(args, member.Name+" ") {
+			recipient = member
+			content = strings.TrimSpace(args[len(member.Name):])
+		}
+	}
+	if recipient.ID == "" {
+		return domain.User{}, "", errors.New("宛先のメンバーが見つかりません（同じルームのメンバーに送れます）")
+	}
+	return recipient, content, nil
+}
+
+// memberWithKey は名前が一致する、公開鍵を持つ在室中のメンバーを返す内部メソッド
+func (s *ChatService) memberWithKey(name string) (domain.User, error) {
+	for _, member := range s.otherMembers() {
+		if member.Name != name {
+			continue
+		}
+		if len(member.PublicKey) == 0 {
+			return domain.User{}, fmt.Errorf("%s はダイレクトメッセージの暗号化に対応していません", name)
+		}
+		return member, nil
+	}
+	return domain.User{}, fmt.Errorf("%s というメンバーは在室していません", name)
+}
+
+// otherMembers は自分以外の在室中のメンバーを返す内部メソッド
+func (s *ChatService) otherMembers() []domain.User {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+	if s.session == nil {
+		return nil
+	}
+
+	members := []domain.User{}
+	for _, user := range s.session.GetActiveUsers() {
+		if user.ID != s.user.ID {
+			members = append(members, user)
+		}
+	}
+	return members
+}
+
+// directHeader はダイレクトメッセージの暗号化で改ざんを検出する追加データを作るヘルパー関数
+// サーバーが宛先や送信者を差し替えると復号に失敗する
+func directHeader(id string, from, to domain.UserID) []byte {
+	return []byte(strings.Join([]string{id, from.String(), to.String()}, "\x00"))
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/e2e"
+	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/internal/ui"
+)
+
+// newTestIdentity は一時ディレクトリに鍵ペアを生成するテスト用のヘルパー関数
+func newTestIdentity(t *testing.T) *e2e.Identity {
+	t.Helper()
+
+	identity, err := e2e.LoadIdentity(filepath.Join(t.TempDir(), "identity.key"))
+	if err != nil {
+		t.Fatalf("LoadIdentity: %v", err)
+	}
+	return identity
+}
+
+// newDirectTestService は表示をJSONの行として記録するサービスを生成するテスト用のヘルパー関数
+func newDirectTestService(t *testing.T, user domain.User) (*ChatService, *bytes.Buffer) {
+	t.Helper()
+
+	output := &bytes.Buffer{}
+	controller := ui.NewHeadlessController(nil, user, strings.NewReader(""), output)
+	service := NewChatService(nil, controller, user)
+	service.SetIdentity(newTestIdentity(t), e2e.NewKnownKeys(filepath.Join(t.TempDir(), "known_keys.json")))
+	return service, output
+}
+
+// sealDirect は送信者の鍵で暗号化したダイレクトメッセージを作るテスト用のヘルパー関数
+func sealDirect(t *testing.T, identity *e2e.Identity, sender, recipient domain.User, content string) network.DirectMessage {
+	t.Helper()
+
+	msg, err := domain.NewMessage(content, sender.ID)
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	plaintext, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	sealed, err := identity.Seal(recipient.PublicKey, plaintext, directHeader(msg.ID, sender.ID, recipient.ID))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	return network.DirectMessage{
+		ID:     msg.ID,
+		To:     recipient.ID,
+		Sealed: sealed,
+		From:   network.UserInfo{ID: sender.ID, Name: sender.Name, PublicKey: identity.PublicKey()},
+	}
+}
+
+// headlessEvents は出力されたJSONの行を読み込むテスト用のヘルパー関数
+func headlessEvents(t *testing.T, output *bytes.Buffer, eventType string) []ui.HeadlessEvent {
+	t.Helper()
+
+	var events []ui.HeadlessEvent
+	decoder := json.NewDecoder(output)
+	for decoder.More() {
+		var event ui.HeadlessEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestReceiveDirect(t *testing.T) {
+	bob := domain.User{ID: "bob-id", Name: "bob"}
+	service, output := newDirectTestService(t, bob)
+	bob = service.currentUser()
+
+	alice := domain.User{ID: "alice-id", Name: "alice"}
+	service.receiveDirect(sealDirect(t, newTestIdentity(t), alice, bob, "こんにちは"))
+
+	events := headlessEvents(t, output, "direct")
+	if len(events) != 1 || events[0].Content != "こんにちは" {
+		t.Fatalf("表示されたダイレクトメッセージ = %+v", events)
+	}
+	if events[0].Sender.ID != alice.ID {
+		t.Errorf("Sender = %q, want %q", events[0].Sender.ID, alice.ID)
+	}
+}
+
+func TestReceiveDirectRejectsChangedKey(t *testing.T) {
+	bob := domain.User{ID: "bob-id", Name: "bob"}
+	service, output := newDirectTestService(t, bob)
+	bob = service.currentUser()
+
+	alice := domain.User{ID: "alice-id", Name: "alice"}
+	service.receiveDirect(sealDirect(t, newTestIdentity(t), alice, bob, "本人から"))
+	output.Reset()
+
+	// 同じユーザーIDで別の鍵を提示したメッセージは表示しない
+	service.receiveDirect(sealDirect(t, newTestIdentity(t), alice, bob, "なりすまし"))
+	if events := headlessEvents(t, bytes.NewBuffer(output.Bytes()), "direct"); len(events) != 0 {
+		t.Errorf("鍵が異なるメッセージが表示されました: %+v", events)
+	}
+	errs := headlessEvents(t, output, "error")
+	if len(errs) != 1 || !strings.Contains(errs[0].Text, e2e.ErrKeyChanged.Error()) {
+		t.Errorf("表示されたエラー = %+v", errs)
+	}
+}
+
+func TestReceiveDirectRejectsTamperedMessage(t *testing.T) {
+	bob := domain.User{ID: "bob-id", Name: "bob"}
+	service, output := newDirectTestService(t, bob)
+	bob = service.currentUser()
+
+	// サーバーが送信者を差し替えると追加データが一致せず復号できない
+	direct := sealDirect(t, newTestIdentity(t), domain.User{ID: "alice-id", Name: "alice"}, bob, "こんにちは")
+	direct.From.ID = "carol-id"
+	service.receiveDirect(direct)
+
+	if events := headlessEvents(t, bytes.NewBuffer(output.Bytes()), "direct"); len(events) != 0 {
+		t.Errorf("改ざんされたメッセージが表示されました: %+v", events)
+	}
+	if errs := headlessEvents(t, output, "error"); len(errs) != 1 {
+		t.Errorf("表示されたエラー = %+v", errs)
+	}
+}
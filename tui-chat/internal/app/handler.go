@@ -1,29 +1,252 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/sakaeshinya/tui-chat/internal/storage"
 	"github.com/sakaeshinya/tui-chat/internal/ui"
 )
 
 // ChatEventHandler はチャットイベントを処理するハンドラ
 type ChatEventHandler struct {
-	service *ChatService
-	ui      ui.UIPort
+	service  *ChatService
+	ui       ui.UIPort
+	commands *CommandRegistry
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 // NewChatEventHandler はチャットイベントハンドラを生成するファクトリ関数
 func NewChatEventHandler(service *ChatService, ui ui.UIPort) *ChatEventHandler {
-	return &ChatEventHandler{
-		service: service,
-		ui:      ui,
+	h := &ChatEventHandler{
+		service:  service,
+		ui:       ui,
+		commands: NewCommandRegistry(),
+		done:     make(chan struct{}),
+	}
+	h.registerBuiltinCommands()
+	return h
+}
+
+// Done は終了が求められると閉じられるチャネルを返すメソッド
+// 呼び出し側は送信待ちのメッセージの送信や切断などの後始末をしてから終了する
+func (h *ChatEventHandler) Done() <-chan struct{} {
+	return h.done
+}
+
+// RegisterCommand はスラッシュコマンドを追加するメソッド
+func (h *ChatEventHandler) RegisterCommand(cmd Command) error {
+	return h.commands.Register(cmd)
+}
+
+// registerBuiltinCommands は組み込みのスラッシュコマンドを登録する内部メソッド
+func (h *ChatEventHandler) registerBuiltinCommands() {
+	builtins := []Command{
+		{Name: "join", Usage: "<ルーム名>", Description: "ルームに参加します", Run: h.join},
+		{Name: "leave", Description: "ルームから退出してロビーに戻ります", Run: noArgs("leave", h.service.LeaveRoom)},
+		{Name: "rooms", Description: "ルームの一覧を表示します", Run: noArgs("rooms", h.service.ListRooms)},
+		{Name: "search", Usage: "<文字列>", Description: "参加中のルームの履歴を検索します", Run: h.search},
+		{Name: "export", Usage: "md|json [ファイル名]", Description: "参加中のルームの履歴を書き出します", Run: h.export},
+		{Name: "send", Usage: "<ファイルのパス>", Description: "ファイルをルームのメンバーに送信します", Run: h.send},
+		{Name: "nick", Usage: "<名前>", Description: "ユーザー名を変更します", Run: h.nick},
+		{Name: "me", Usage: "<動作>", Description: "動作を表すメッセージを送信します", Run: h.me},
+		{Name: "dm", Usage: "<名前> <本文>", Description: "暗号化したダイレクトメッセージを送信します", Run: h.dm},
+		{Name: "verify", Usage: "[名前]", Description: "公開鍵のフィンガープリントを表示します", Run: h.service.Verify},
+		{Name: "trust", Usage: "<名前>", Description: "メンバーの現在の公開鍵を記録します", Run: h.trust},
+		{Name: "connect", Usage: "<ホスト:ポート>", Description: "サーバーに接続します", Run: h.connect},
+		{Name: "listen", Usage: "<ポート>", Description: "サーバーを起動します", Run: h.listen},
+		{Name: "clear", Description: "チャット表示領域を空にします", Run: noArgs("clear", h.clear)},
+		{Name: "help", Description: "コマンドの一覧を表示します", Run: noArgs("help", h.help)},
+		{Name: "quit", Description: "アプリケーションを終了します", Run: noArgs("quit", h.quit)},
+	}
+	for _, cmd := range builtins {
+		if err := h.commands.Register(cmd); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// noArgs は引数を取らないコマンドの処理を作るヘルパー関数
+func noArgs(name string, run func() error) func(args string) error {
+	return func(args string) error {
+		if args != "" {
+			return fmt.Errorf("使い方: /%s", name)
+		}
+		return run()
 	}
 }
 
 // OnMessageSend はメッセージ送信イベントを処理するメソッド
 func (h *ChatEventHandler) OnMessageSend(content string) {
-	err := h.service.SendMessage(content)
+	if err := h.service.SendMessage(content); err != nil {
+		h.ui.ShowError(err)
+	}
+}
+
+// OnCommand はスラッシュコマンドを実行するメソッド
+func (h *ChatEventHandler) OnCommand(name, args string) {
+	if err := h.commands.Execute(name, args); err != nil {
+		h.ui.ShowError(err)
+	}
+}
+
+// CompleteCommand は入力途中のコマンド名の補完候補を返すメソッド
+func (h *ChatEventHandler) CompleteCommand(prefix string) []string {
+	return h.commands.Complete(prefix)
+}
+
+// join は /join <ルーム名> を処理するメソッド
+func (h *ChatEventHandler) join(args string) error {
+	if args == "" || strings.ContainsAny(args, " \t") {
+		return errors.New("使い方: /join <ルーム名>")
+	}
+	return h.service.JoinRoom(args)
+}
+
+// search は /search <文字列> を処理するメソッド
+func (h *ChatEventHandler) search(args string) error {
+	if args == "" {
+		return errors.New("使い方: /search <文字列>")
+	}
+	return h.service.Search(args)
+}
+
+// export は /export md|json [ファイル名] を処理するメソッド
+func (h *ChatEventHandler) export(args string) error {
+	fields := strings.Fields(args)
+	if len(fields) < 1 || len(fields) > 2 {
+		return errors.New("使い方: /export md|json [ファイル名]")
+	}
+
+	format, err := storage.ParseExportFormat(fields[0])
+	if err != nil {
+		return err
+	}
+	path := ""
+	if len(fields) == 2 {
+		path = fields[1]
+	}
+
+	path, err = h.service.Export(format, path)
+	if err != nil {
+		return err
+	}
+	h.ui.DisplayNotice(fmt.Sprintf("履歴を %s に書き出しました", path))
+	return nil
+}
+
+// send は /send <ファイルのパス> を処理するメソッド
+// パスに空白を含む場合も、引数全体を1つのパスとして扱う
+func (h *ChatEventHandler) send(args string) error {
+	if args == "" {
+		return errors.New("使い方: /send <ファイルのパス>")
+	}
+	return h.service.SendFile(expandHome(args))
+}
+
+// expandHome はパスの先頭の "~" をホームディレクトリに置き換えるヘルパー関数
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// nick は /nick <名前> を処理するメソッド
+func (h *ChatEventHandler) nick(args string) error {
+	if args == "" {
+		return errors.New("使い方: /nick <名前>")
+	}
+	return h.service.ChangeName(args)
+}
+
+// me は /me <動作> を処理するメソッド
+func (h *ChatEventHandler) me(args string) error {
+	if args == "" {
+		return errors.New("使い方: /me <動作>")
+	}
+	return h.service.SendAction(args)
+}
+
+// dm は /dm <名前> <本文> を処理するメソッド
+func (h *ChatEventHandler) dm(args string) error {
+	if args == "" {
+		return errors.New("使い方: /dm <名前> <本文>")
+	}
+	return h.service.SendDirectMessage(args)
+}
+
+// trust は /trust <名前> を処理するメソッド
+func (h *ChatEventHandler) trust(args string) error {
+	if args == "" {
+		return errors.New("使い方: /trust <名前>")
+	}
+	return h.service.Trust(args)
+}
+
+// connect は /connect <ホスト:ポート> を処理するメソッド
+// ポートを省略した場合は8080に接続する
+func (h *ChatEventHandler) connect(args string) error {
+	if args == "" || strings.ContainsAny(args, " \t") {
+		return errors.New("使い方: /connect <ホスト:ポート>")
+	}
+	if !strings.Contains(args, ":") {
+		args += ":8080"
+	}
+	h.OnConnect(args)
+	return nil
+}
+
+// listen は /listen <ポート> を処理するメソッド
+// "ホスト:ポート" の形でリッスンするアドレスも指定できる
+func (h *ChatEventHandler) listen(args string) error {
+	if args == "" || strings.ContainsAny(args, " \t") {
+		return errors.New("使い方: /listen <ポート>")
+	}
+	if !strings.Contains(args, ":") {
+		args = ":" + args
+	}
+	h.OnListen(args)
+	return nil
+}
+
+// clear はチャット表示領域を空にするメソッド
+func (h *ChatEventHandler) clear() error {
+	h.ui.ClearMessages()
+	return nil
+}
+
+// help はコマンドの一覧を表示するメソッド
+func (h *ChatEventHandler) help() error {
+	h.ui.DisplayNotice("コマンド一覧（\"//\" で始めると \"/\" で始まるメッセージを送信します）")
+	for _, cmd := range h.commands.Commands() {
+		h.ui.DisplayNotice(fmt.Sprintf("  %s : %s", cmd, cmd.Description))
+	}
+	return nil
+}
+
+// quit はアプリケーションを終了するメソッド
+func (h *ChatEventHandler) quit() error {
+	h.OnQuit()
+	return nil
+}
+
+// OnTyping は入力中イベントを処理するメソッド
+// コマンドの入力中や入力欄を空にしたときは通知しない
+func (h *ChatEventHandler) OnTyping(text string) {
+	if text == "" || strings.HasPrefix(text, "/") {
+		return
+	}
+	if err := h.service.NotifyTyping(); err != nil {
 		h.ui.ShowError(err)
 	}
 }
@@ -71,13 +294,12 @@ func (h *ChatEventHandler) OnDisconnect() {
 }
 
 // OnQuit は終了イベントを処理するメソッド
+// 切断は呼び出し側の後始末に任せ、終了を知らせてUIを停止する
 func (h *ChatEventHandler) OnQuit() {
-	// 切断処理
-	h.service.Disconnect()
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
 
 	// UIを停止
 	h.ui.Stop()
-
-	// アプリケーション終了
-	os.Exit(0)
 }
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/internal/ui"
+)
+
+// newHandlerTestHandler はクライアントとして起動したときのハンドラを生成するテスト用のヘルパー関数
+func newHandlerTestHandler(t *testing.T) (*ChatEventHandler, *ui.HeadlessController) {
+	t.Helper()
+
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	// 入力は終わらせず、終了するまで読み込みを続けさせる
+	input, writer := io.Pipe()
+	t.Cleanup(func() { writer.Close() })
+	controller := ui.NewHeadlessController(nil, user, input, io.Discard)
+	newNetwork := func(server bool) network.NetworkPort {
+		if server {
+			return network.NewTCPServer()
+		}
+		return network.NewTCPClient()
+	}
+	service := NewChatService(newNetwork(false), controller, user)
+	service.SetNetworkFactory(newNetwork)
+	t.Cleanup(func() { service.Disconnect() })
+
+	handler := NewChatEventHandler(service, controller)
+	controller.SetHandler(handler)
+	return handler, controller
+}
+
+func TestListenAfterStartingAsClient(t *testing.T) {
+	handler, _ := newHandlerTestHandler(t)
+
+	// クライアントとして起動していてもサーバーを起動できる
+	if err := handler.commands.Execute("listen", "127.0.0.1:0"); err != nil {
+		t.Fatalf("/listen: %v", err)
+	}
+	if !handler.service.isRunning() {
+		t.Fatal("サーバーが起動していません")
+	}
+	if _, ok := handler.service.currentNetwork().(*network.TCPServer); !ok {
+		t.Errorf("ネットワークコンポーネント = %T, want *network.TCPServer", handler.service.currentNetwork())
+	}
+
+	// 接続中は切断するまで別のサーバーに接続しない
+	if err := handler.service.JoinChat("127.0.0.1:1"); err == nil {
+		t.Error("接続中に別のサーバーに接続しました")
+	}
+}
+
+func TestQuitSignalsDone(t *testing.T) {
+	handler, controller := newHandlerTestHandler(t)
+
+	started := make(chan error, 1)
+	go func() { started <- controller.Start() }()
+
+	if err := handler.commands.Execute("quit", ""); err != nil {
+		t.Fatalf("/quit: %v", err)
+	}
+
+	select {
+	case <-handler.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("/quit で終了が知らされません")
+	}
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("/quit でUIが停止しません")
+	}
+}
@@ -1,25 +1,84 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/sakaeshinya/tui-chat/internal/bot"
 	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/e2e"
 	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/internal/storage"
 	"github.com/sakaeshinya/tui-chat/internal/ui"
 )
 
+const (
+	// typingInterval は入力中の通知を送る最短の間隔
+	typingInterval = 3 * time.Second
+
+	// typingTimeout は入力中の通知を受けてから表示を消すまでの時間
+	typingTimeout = 5 * time.Second
+
+	// reconnectMinDelay は接続が切れてから最初に再接続するまでの時間
+	reconnectMinDelay = time.Second
+
+	// reconnectMaxDelay は再接続の間隔の上限（失敗するたびに倍にする）
+	reconnectMaxDelay = 30 * time.Second
+
+	// seenLimit は重複を確認するために覚えておくメッセージIDの数
+	seenLimit = 1000
+)
+
+// 定義済みエラー
+var (
+	ErrRoomsUnsupported = errors.New("このネットワークはルームに対応していません")
+	ErrHistoryDisabled  = errors.New("履歴の保存が無効になっています")
+	ErrQueueFull        = errors.New("送信待ちのメッセージが多すぎます")
+)
+
 // ChatService はチャットアプリケーションのコアサービス
 type ChatService struct {
-	session      *domain.ChatSession
-	sessionMutex sync.RWMutex
-	messageQueue chan domain.Message
-	network      network.NetworkPort
-	ui           ui.UIPort
-	user         domain.User
-	stopChan     chan struct{}
-	running      bool
-	runningMutex sync.RWMutex
+	session        *domain.ChatSession
+	sessionMutex   sync.RWMutex
+	statusPrefix   string
+	address        string
+	history        storage.HistoryPort
+	historyLimit   int
+	messageQueue   chan domain.Message
+	pending        atomic.Int64
+	bots           []bot.Handler
+	network        network.NetworkPort
+	newNetwork     func(server bool) network.NetworkPort
+	ui             ui.UIPort
+	user           domain.User
+	typing         map[domain.UserID]time.Time
+	lastTyping     time.Time
+	typingMutex    sync.Mutex
+	seen           map[string]struct{}
+	seenOrder      []string
+	seenMutex      sync.Mutex
+	fileOptions    FileOptions
+	incoming       map[string]*incomingFile
+	outgoing       map[string]*outgoingFile
+	transfersMutex sync.Mutex
+	identity       *e2e.Identity
+	knownKeys      *e2e.KnownKeys
+	keyWarnings    map[domain.UserID]string
+	keysMutex      sync.Mutex
+	reconnect      bool
+	reconnected    chan struct{}
+	minDelay       time.Duration // 最初に再接続するまでの時間（テストでは短くする）
+	maxDelay       time.Duration // 再接続の間隔の上限
+	stopChan       chan struct{}
+	running        bool
+	connected      bool
+	runningMutex   sync.RWMutex // running・connected・network・reconnect・stopChanを保護する
 }
 
 // NewChatService はチャットサービスを生成するファクトリ関数
@@ -29,52 +88,189 @@ func NewChatService(network network.NetworkPort, ui ui.UIPort, user domain.User)
 		network:      network,
 		ui:           ui,
 		user:         user,
+		typing:       make(map[domain.UserID]time.Time),
+		seen:         make(map[string]struct{}),
+		incoming:     make(map[string]*incomingFile),
+		outgoing:     make(map[string]*outgoingFile),
+		keyWarnings:  make(map[domain.UserID]string),
+		reconnected:  make(chan struct{}, 1),
+		minDelay:     reconnectMinDelay,
+		maxDelay:     reconnectMaxDelay,
 		stopChan:     make(chan struct{}),
 	}
 }
 
+// SetNetworkFactory はサーバーの起動や接続のたびにネットワークコンポーネントを作る関数を設定するメソッド
+// 設定すると、起動時のモードによらずサーバーの起動とサーバーへの接続の両方ができる
+func (s *ChatService) SetNetworkFactory(factory func(server bool) network.NetworkPort) {
+	s.newNetwork = factory
+}
+
 // StartServer はサーバーモードでチャットを開始するメソッド
 func (s *ChatService) StartServer(address string) error {
-	// セッション初期化
-	s.sessionMutex.Lock()
-	s.session = new(domain.ChatSession)
-	*s.session = domain.NewChatSession(s.user)
-	s.sessionMutex.Unlock()
-
-	// サーバー起動
-	err := s.network.Listen(address)
+	component, err := s.prepareNetwork(true)
 	if err != nil {
 		return err
 	}
 
-	s.setRunning(true)
-	go s.processIncomingMessages()
-	go s.processOutgoingMessages()
+	// サーバー起動
+	if err := component.Listen(address); err != nil {
+		return err
+	}
 
-	s.ui.UpdateStatus(fmt.Sprintf("サーバーモード | アドレス: %s", address))
+	s.start(fmt.Sprintf("サーバーモード | アドレス: %s", address), address, false)
 	return nil
 }
 
 // JoinChat はクライアントモードでチャットに参加するメソッド
 func (s *ChatService) JoinChat(address string) error {
-	// クライアント接続
-	err := s.network.Connect(address)
+	component, err := s.prepareNetwork(false)
 	if err != nil {
 		return err
 	}
 
-	// セッション初期化（仮）
+	// クライアント接続
+	if err := component.Connect(address); err != nil {
+		return err
+	}
+
+	// 接続が切れたらサーバーに再接続する
+	s.start(fmt.Sprintf("クライアントモード | 接続先: %s", address), address, true)
+	return nil
+}
+
+// prepareNetwork はサーバーの起動か接続に使うネットワークコンポーネントを用意する内部メソッド
+// ファクトリが設定されていれば、モードに合ったコンポーネントを新しく作る
+func (s *ChatService) prepareNetwork(server bool) (network.NetworkPort, error) {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	if s.running {
+		return nil, errors.New("既に接続しています。切断してから実行してください")
+	}
+	if s.newNetwork != nil {
+		s.network = s.newNetwork(server)
+	}
+	return s.network, nil
+}
+
+// currentNetwork は使用中のネットワークコンポーネントを返す内部メソッド
+func (s *ChatService) currentNetwork() network.NetworkPort {
+	s.runningMutex.RLock()
+	defer s.runningMutex.RUnlock()
+	return s.network
+}
+
+// SetHistory は履歴の保存先と、接続時に表示する履歴の件数を設定するメソッド
+// 設定しなければ履歴は保存しない
+func (s *ChatService) SetHistory(history storage.HistoryPort, limit int) {
+	s.history = history
+	s.historyLimit = limit
+}
+
+// start はセッションを初期化してメッセージ処理を開始する内部メソッド
+// reconnectがtrueなら接続が切れたときに再接続する
+func (s *ChatService) start(statusPrefix, address string, reconnect bool) {
+	room := domain.DefaultRoom
+	if rooms, ok := s.currentNetwork().(network.RoomPort); ok {
+		room = rooms.CurrentRoom()
+	}
+
 	s.sessionMutex.Lock()
 	s.session = new(domain.ChatSession)
-	*s.session = domain.NewChatSession(s.user)
+	*s.session = domain.NewRoomSession(room, s.user)
+	s.statusPrefix = statusPrefix
+	s.address = address
 	s.sessionMutex.Unlock()
 
-	s.setRunning(true)
-	go s.processIncomingMessages()
-	go s.processOutgoingMessages()
+	s.showHistory(room)
 
-	s.ui.UpdateStatus(fmt.Sprintf("クライアントモード | 接続先: %s", address))
-	return nil
+	// ルームの参加者に自分の参加を知らせる
+	// サーバーはユーザー情報を登録していないクライアントのメッセージを配信しないため、送信を始める前に名乗る
+	if presence, ok := s.currentNetwork().(network.PresencePort); ok {
+		if err := presence.SendUserInfo(s.currentUser()); err != nil {
+			s.ui.ShowError(err)
+		}
+	}
+
+	// 切断後に再び開始できるよう、開始のたびに停止用のチャネルを作る
+	stop := make(chan struct{})
+	s.runningMutex.Lock()
+	s.stopChan = stop
+	s.reconnect = reconnect
+	s.running = true
+	s.connected = true
+	s.runningMutex.Unlock()
+
+	go s.processIncomingMessages(stop)
+	go s.processOutgoingMessages(stop)
+	if events, ok := s.currentNetwork().(network.EventPort); ok {
+		go s.processEvents(events.Events(), stop)
+	}
+
+	s.updateMembers()
+	s.updateStatus()
+}
+
+// NotifyTyping は入力中であることをルームの参加者に知らせるメソッド
+// 通知はtypingIntervalに1回までに間引く
+func (s *ChatService) NotifyTyping() error {
+	if !s.isRunning() || !s.isConnected() {
+		return nil
+	}
+	presence, ok := s.currentNetwork().(network.PresencePort)
+	if !ok {
+		return nil
+	}
+
+	s.typingMutex.Lock()
+	if time.Since(s.lastTyping) < typingInterval {
+		s.typingMutex.Unlock()
+		return nil
+	}
+	s.lastTyping = time.Now()
+	s.typingMutex.Unlock()
+
+	return presence.SendTyping()
+}
+
+// JoinRoom はルームに参加するメソッド
+func (s *ChatService) JoinRoom(room string) error {
+	rooms, err := s.rooms()
+	if err != nil {
+		return err
+	}
+	return rooms.JoinRoom(room)
+}
+
+// LeaveRoom はルームから退出してロビーに戻るメソッド
+func (s *ChatService) LeaveRoom() error {
+	rooms, err := s.rooms()
+	if err != nil {
+		return err
+	}
+	return rooms.LeaveRoom()
+}
+
+// ListRooms はルーム一覧を要求するメソッド
+func (s *ChatService) ListRooms() error {
+	rooms, err := s.rooms()
+	if err != nil {
+		return err
+	}
+	return rooms.ListRooms()
+}
+
+// rooms はルーム操作に使うネットワークコンポーネントを返す内部メソッド
+func (s *ChatService) rooms() (network.RoomPort, error) {
+	if !s.isRunning() {
+		return nil, errors.New("接続されていません")
+	}
+	rooms, ok := s.currentNetwork().(network.RoomPort)
+	if !ok {
+		return nil, ErrRoomsUnsupported
+	}
+	return rooms, nil
 }
 
 // SendMessage はメッセージを送信するメソッド
@@ -83,21 +279,113 @@ func (s *ChatService) SendMessage(content string) error {
 	if err != nil {
 		return err
 	}
+	return s.enqueue(msg)
+}
+
+// SendAction は /me の動作を表すメッセージを送信するメソッド
+func (s *ChatService) SendAction(content string) error {
+	msg, err := domain.NewMessage(content, s.user.ID)
+	if err != nil {
+		return err
+	}
+	msg.Action = true
+	return s.enqueue(msg)
+}
+
+// ChangeName はユーザー名を変更してルームの参加者に知らせるメソッド
+// 接続していない場合は次の接続から新しい名前を使う
+func (s *ChatService) ChangeName(name string) error {
+	s.sessionMutex.Lock()
+	previous := s.user.Name
+	if err := s.user.Rename(name); err != nil {
+		s.sessionMutex.Unlock()
+		return err
+	}
+	user := s.user
+	if s.session != nil {
+		s.session.ActivateUser(user)
+	}
+	s.sessionMutex.Unlock()
+
+	s.ui.DisplayNotice(fmt.Sprintf("名前を %s から %s に変更しました", previous, name))
+	if !s.isRunning() {
+		return nil
+	}
+
+	s.updateMembers()
+	if presence, ok := s.currentNetwork().(network.PresencePort); ok && s.isConnected() {
+		return presence.SendUserInfo(user)
+	}
+	return nil
+}
+
+// AddBot は受信したメッセージに反応するボットを追加するメソッド
+// ボットは受信のたびに受信処理のゴルーチンから呼ばれる
+func (s *ChatService) AddBot(handler bot.Handler) {
+	s.bots = append(s.bots, handler)
+}
+
+// runBots は受信したメッセージをボットに渡し、返信があれば送信する内部メソッド
+func (s *ChatService) runBots(msg domain.Message, sender domain.User) {
+	for _, handler := range s.bots {
+		reply, ok := handler(msg, sender)
+		if !ok {
+			continue
+		}
+		if err := s.SendMessage(reply); err != nil {
+			s.ui.ShowError(err)
+		}
+	}
+}
+
+// Flush は送信待ちのメッセージを送り終えるまで待つメソッド
+// timeoutまでに送り終えなかった場合はエラーを返す
+func (s *ChatService) Flush(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for s.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d件のメッセージを送信できませんでした", s.pending.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// enqueue はメッセージを送信待ちのキューに入れる内部メソッド
+func (s *ChatService) enqueue(msg domain.Message) error {
+	// 接続するまでの間は送信待ちのキューに貯めておく
+	s.pending.Add(1)
+	select {
+	case s.messageQueue <- msg:
+	default:
+		s.pending.Add(-1)
+		return ErrQueueFull
+	}
 
-	s.messageQueue <- msg
+	switch {
+	case !s.isRunning():
+		s.ui.DisplayNotice("接続していません。サーバーを起動するか接続したら送信します")
+	case !s.isConnected():
+		s.ui.DisplayNotice("接続が切れています。再接続したら送信します")
+	}
 	return nil
 }
 
 // Disconnect は接続を切断するメソッド
 func (s *ChatService) Disconnect() error {
-	if !s.isRunning() {
+	// 停止用のチャネルを2度閉じないよう、確認と停止をまとめて行う
+	s.runningMutex.Lock()
+	if !s.running {
+		s.runningMutex.Unlock()
 		return nil
 	}
-
 	close(s.stopChan)
-	s.setRunning(false)
+	s.running = false
+	s.runningMutex.Unlock()
+
+	s.abortTransfers()
 
-	err := s.network.Close()
+	err := s.currentNetwork().Close()
 	if err != nil {
 		return err
 	}
@@ -107,22 +395,51 @@ func (s *ChatService) Disconnect() error {
 }
 
 // processIncomingMessages は受信メッセージを処理するメソッド
-func (s *ChatService) processIncomingMessages() {
-	defer s.setRunning(false)
-
+// クライアントモードでは接続が切れると再接続を試みる
+func (s *ChatService) processIncomingMessages(stop chan struct{}) {
 	for {
 		select {
-		case <-s.stopChan:
+		case <-stop:
 			return
 		default:
-			msg, err := s.network.ReceiveMessage()
+			msg, err := s.currentNetwork().ReceiveMessage()
 			if err != nil {
-				s.ui.ShowError(err)
-				return
+				select {
+				case <-stop:
+					// 切断による終了
+					return
+				default:
+				}
+				if !s.shouldReconnect() {
+					s.ui.ShowError(err)
+					s.setRunning(false)
+					return
+				}
+				if !s.reconnectLoop(stop, err) {
+					return
+				}
+				continue
+			}
+
+			// 再送などで同じメッセージが2度届いた場合は表示しない
+			if !s.markSeen(msg.ID) {
+				continue
 			}
 
 			s.sessionMutex.Lock()
+			// ルーム移動前に送られたメッセージは表示しない
+			if msg.Room != "" && msg.Room != s.session.Room {
+				s.sessionMutex.Unlock()
+				continue
+			}
+			// 在室状況を通知しない相手からのメッセージもあるため、初めての送信者はセッションに追加する
+			sender, ok := s.session.GetUserByID(msg.Sender)
+			if !ok {
+				sender = domain.User{ID: msg.Sender, Name: msg.Sender.String(), IsActive: true}
+				s.session.AddUser(sender)
+			}
 			err = s.session.AddMessage(msg)
+			room := s.session.Room
 			s.sessionMutex.Unlock()
 
 			if err != nil {
@@ -130,36 +447,510 @@ func (s *ChatService) processIncomingMessages() {
 				continue
 			}
 
-			s.ui.DisplayMessage(msg)
+			s.record(room, msg, sender)
+
+			// メッセージが届いたら入力中の表示を消す
+			s.stopTyping(msg.Sender)
+			s.ui.DisplayMessage(msg, sender)
+			s.runBots(msg, sender)
 		}
 	}
 }
 
-// processOutgoingMessages は送信メッセージを処理するメソッド
-func (s *ChatService) processOutgoingMessages() {
+// processEvents はネットワークコンポーネントからのイベントを処理するメソッド
+func (s *ChatService) processEvents(events <-chan network.Event, stop chan struct{}) {
 	for {
 		select {
-		case <-s.stopChan:
+		case <-stop:
 			return
-		case msg := <-s.messageQueue:
-			err := s.network.SendMessage(msg)
-			if err != nil {
-				s.ui.ShowError(err)
-				continue
+		case event := <-events:
+			switch event.Type {
+			case network.EventRoomChanged:
+				// 再接続して同じルームに参加し直した場合はセッションをそのまま使う
+				s.sessionMutex.Lock()
+				if event.Room == s.session.Room {
+					s.sessionMutex.Unlock()
+					s.updateStatus()
+					continue
+				}
+				// ルームが変わったらセッションを作り直す
+				*s.session = domain.NewRoomSession(event.Room, s.user)
+				s.sessionMutex.Unlock()
+
+				s.clearTyping()
+				s.ui.DisplayNotice(fmt.Sprintf("ルーム #%s に参加しました", event.Room))
+				s.showHistory(event.Room)
+				s.updateMembers()
+				s.updateStatus()
+			case network.EventMembers:
+				s.sessionMutex.Lock()
+				if event.Room == s.session.Room {
+					s.syncMembers(event.Members)
+				}
+				s.sessionMutex.Unlock()
+				for _, member := range event.Members {
+					s.warnKeyChange(memberUser(member))
+				}
+				s.updateMembers()
+			case network.EventMemberJoined:
+				s.memberJoined(event)
+			case network.EventMemberLeft:
+				s.memberLeft(event)
+			case network.EventTyping:
+				s.startTyping(event.User.ID)
+			case network.EventRoomList:
+				names := make([]string, 0, len(event.Rooms))
+				for _, room := range event.Rooms {
+					names = append(names, fmt.Sprintf("#%s (%d人)", room.Name, room.Members))
+				}
+				s.ui.DisplayNotice("ルーム一覧: " + strings.Join(names, ", "))
+			case network.EventNotice:
+				s.ui.DisplayNotice(event.Text)
+			case network.EventFileOffer:
+				s.receiveOffer(event.Offer)
+			case network.EventFileAccept:
+				s.fileAccepted(event.Accept)
+			case network.EventFileChunk:
+				s.receiveChunk(event.Chunk)
+			case network.EventFileComplete:
+				s.completeFile(event.Complete)
+			case network.EventDirectMessage:
+				s.receiveDirect(event.Direct)
 			}
+		}
+	}
+}
 
-			s.sessionMutex.Lock()
-			err = s.session.AddMessage(msg)
-			s.sessionMutex.Unlock()
+// syncMembers はメンバー一覧をセッションに反映する内部メソッド
+// 切断中に退出したメンバーもいるため、一覧にいないメンバーは退出状態にする
+// 呼び出し側でsessionMutexをロックしておく
+func (s *ChatService) syncMembers(members []network.UserInfo) {
+	present := make(map[domain.UserID]bool, len(members))
+	for _, member := range members {
+		s.session.ActivateUser(memberUser(member))
+		present[member.ID] = true
+	}
+	for _, user := range s.session.GetActiveUsers() {
+		if user.ID != s.user.ID && !present[user.ID] {
+			s.session.DeactivateUser(user.ID)
+		}
+	}
+}
 
-			if err != nil {
-				s.ui.ShowError(err)
+// memberJoined はメンバーの参加をセッションに反映して知らせる内部メソッド
+// 在室中のメンバーの参加の通知は名前の変更として扱う
+func (s *ChatService) memberJoined(event network.Event) {
+	s.sessionMutex.Lock()
+	if event.Room != s.session.Room {
+		s.sessionMutex.Unlock()
+		return
+	}
+	previous, known := s.session.GetUserByID(event.User.ID)
+	member := memberUser(event.User)
+	s.session.ActivateUser(member)
+	s.sessionMutex.Unlock()
+
+	switch {
+	case !known || !previous.IsActive:
+		s.ui.DisplayNotice(fmt.Sprintf("%s が参加しました", event.User.Name))
+	case previous.Name != event.User.Name:
+		s.ui.DisplayNotice(fmt.Sprintf("%s は %s に名前を変更しました", previous.Name, event.User.Name))
+	}
+	s.warnKeyChange(member)
+	s.updateMembers()
+}
+
+// memberUser はネットワークから届いたユーザー情報をセッションのユーザーに変換するヘルパー関数
+func memberUser(info network.UserInfo) domain.User {
+	return domain.User{ID: info.ID, Name: info.Name, PublicKey: info.PublicKey}
+}
+
+// memberLeft はメンバーの退出をセッションに反映して知らせる内部メソッド
+func (s *ChatService) memberLeft(event network.Event) {
+	s.sessionMutex.Lock()
+	if event.Room != s.session.Room {
+		s.sessionMutex.Unlock()
+		return
+	}
+	err := s.session.DeactivateUser(event.User.ID)
+	s.sessionMutex.Unlock()
+
+	if err == nil {
+		s.ui.DisplayNotice(fmt.Sprintf("%s が退出しました", event.User.Name))
+	}
+	s.stopTyping(event.User.ID)
+	s.updateMembers()
+}
+
+// updateMembers は在室中のメンバーをUIに表示する内部メソッド
+func (s *ChatService) updateMembers() {
+	s.sessionMutex.RLock()
+	users := s.session.GetActiveUsers()
+	s.sessionMutex.RUnlock()
+
+	s.ui.UpdateMembers(users)
+}
+
+// startTyping はメンバーの入力中の表示を始める内部メソッド
+// typingTimeoutの間に次の通知がなければ表示を消す
+func (s *ChatService) startTyping(userID domain.UserID) {
+	if userID == s.user.ID {
+		return
+	}
+
+	s.typingMutex.Lock()
+	s.typing[userID] = time.Now().Add(typingTimeout)
+	s.typingMutex.Unlock()
+
+	time.AfterFunc(typingTimeout, s.showTyping)
+	s.showTyping()
+}
+
+// stopTyping はメンバーの入力中の表示を消す内部メソッド
+func (s *ChatService) stopTyping(userID domain.UserID) {
+	s.typingMutex.Lock()
+	_, ok := s.typing[userID]
+	delete(s.typing, userID)
+	s.typingMutex.Unlock()
+
+	if ok {
+		s.showTyping()
+	}
+}
+
+// clearTyping は全員の入力中の表示を消す内部メソッド
+func (s *ChatService) clearTyping() {
+	s.typingMutex.Lock()
+	s.typing = make(map[domain.UserID]time.Time)
+	s.typingMutex.Unlock()
+
+	s.showTyping()
+}
+
+// showTyping は入力中のメンバーの名前をUIに表示する内部メソッド
+func (s *ChatService) showTyping() {
+	now := time.Now()
+
+	s.typingMutex.Lock()
+	var ids []domain.UserID
+	for id, until := range s.typing {
+		if now.After(until) {
+			delete(s.typing, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	s.typingMutex.Unlock()
+
+	names := make([]string, 0, len(ids))
+	s.sessionMutex.RLock()
+	for _, id := range ids {
+		if user, ok := s.session.GetUserByID(id); ok {
+			names = append(names, user.Name)
+		}
+	}
+	s.sessionMutex.RUnlock()
+
+	sort.Strings(names)
+	s.ui.ShowTyping(names)
+}
+
+// Search は参加中のルームの履歴から本文に文字列を含むメッセージを探して表示するメソッド
+func (s *ChatService) Search(query string) error {
+	if s.history == nil {
+		return ErrHistoryDisabled
+	}
+	key, err := s.historyKey()
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.history.Search(key, query)
+	if err != nil {
+		return err
+	}
+
+	s.ui.DisplayNotice(fmt.Sprintf("「%s」の検索結果: %d件", query, len(entries)))
+	for _, entry := range entries {
+		s.ui.DisplaySearchResult(entry.Message, entry.Sender(), query)
+	}
+	return nil
+}
+
+// Export は参加中のルームの履歴をファイルに書き出すメソッド
+// pathが空ならカレントディレクトリにルーム名と日時から名前を付けて保存し、保存先を返す
+func (s *ChatService) Export(format storage.ExportFormat, path string) (string, error) {
+	if s.history == nil {
+		return "", ErrHistoryDisabled
+	}
+	key, err := s.historyKey()
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := s.history.Load(key, 0)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		path = fmt.Sprintf("tui-chat-%s-%s.%s", key.Room, time.Now().Format("20060102-150405"), format)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("エクスポート先のファイルを作成できません: %w", err)
+	}
+	defer file.Close()
+
+	if err := storage.Export(file, format, key.Room, entries); err != nil {
+		return "", fmt.Errorf("エクスポートに失敗しました: %w", err)
+	}
+	return path, nil
+}
+
+// historyKey は参加中のルームの履歴のキーを返す内部メソッド
+func (s *ChatService) historyKey() (storage.HistoryKey, error) {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	if s.session == nil {
+		return storage.HistoryKey{}, errors.New("接続されていません")
+	}
+	return storage.HistoryKey{Address: s.address, Room: s.session.Room}, nil
+}
+
+// record はメッセージを履歴に保存する内部メソッド
+func (s *ChatService) record(room string, msg domain.Message, sender domain.User) {
+	if s.history == nil {
+		return
+	}
+
+	key := storage.HistoryKey{Address: s.address, Room: room}
+	if err := s.history.Append(key, storage.Entry{Message: msg, SenderName: sender.Name}); err != nil {
+		s.ui.ShowError(err)
+	}
+}
+
+// showHistory はルームの最近の履歴を表示する内部メソッド
+func (s *ChatService) showHistory(room string) {
+	if s.history == nil {
+		return
+	}
+
+	entries, err := s.history.Load(storage.HistoryKey{Address: s.address, Room: room}, s.historyLimit)
+	if err != nil {
+		s.ui.ShowError(err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	s.ui.DisplayNotice(fmt.Sprintf("以前の履歴（%d件）", len(entries)))
+	for _, entry := range entries {
+		s.ui.DisplayMessage(entry.Message, entry.Sender())
+	}
+	s.ui.DisplayNotice("ここから新しいメッセージ")
+}
+
+// updateStatus はモード・アドレス・ルームをステータスに表示する内部メソッド
+func (s *ChatService) updateStatus() {
+	s.sessionMutex.RLock()
+	status := fmt.Sprintf("%s | ルーム: #%s", s.statusPrefix, s.session.Room)
+	s.sessionMutex.RUnlock()
+
+	s.ui.UpdateStatus(status)
+}
+
+// statusLine はモードとアドレスに続けて状態を表示する文字列を返す内部メソッド
+func (s *ChatService) statusLine(state string) string {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+	return fmt.Sprintf("%s | %s", s.statusPrefix, state)
+}
+
+// processOutgoingMessages は送信メッセージを処理するメソッド
+// 接続が切れている間は送信を止め、再接続したら送信できなかったメッセージから順に送る
+func (s *ChatService) processOutgoingMessages(stop chan struct{}) {
+	var retry *domain.Message
+	for {
+		if !s.isConnected() {
+			select {
+			case <-stop:
+				return
+			case <-s.reconnected:
+			}
+			continue
+		}
+
+		var msg domain.Message
+		if retry != nil {
+			msg, retry = *retry, nil
+		} else {
+			select {
+			case <-stop:
+				return
+			case msg = <-s.messageQueue:
+			}
+		}
+
+		err := s.currentNetwork().SendMessage(msg)
+		if err != nil {
+			if s.shouldReconnect() && !s.networkConnected() {
+				// 受信側が切断に気付いて再接続するまで待ち、同じメッセージを送り直す
+				retry = &msg
+				select {
+				case <-stop:
+					return
+				case <-s.reconnected:
+				case <-time.After(s.minDelay):
+				}
 				continue
 			}
+			s.pending.Add(-1)
+			s.ui.ShowError(err)
+			continue
+		}
+		s.pending.Add(-1)
+		s.markSeen(msg.ID)
+
+		s.sessionMutex.Lock()
+		err = s.session.AddMessage(msg)
+		room := s.session.Room
+		s.sessionMutex.Unlock()
+
+		if err != nil {
+			s.ui.ShowError(err)
+			continue
+		}
+
+		user := s.currentUser()
+		s.record(room, msg, user)
+
+		// 送信したら次の入力ですぐに入力中を通知できるようにする
+		s.typingMutex.Lock()
+		s.lastTyping = time.Time{}
+		s.typingMutex.Unlock()
+
+		s.ui.DisplayMessage(msg, user)
+	}
+}
+
+// reconnectLoop は間隔を倍にしながらサーバーへの再接続を繰り返す内部メソッド
+// 再接続できればtrue、切断操作で中断した場合はfalseを返す
+func (s *ChatService) reconnectLoop(stop chan struct{}, cause error) bool {
+	s.setConnected(false)
+	s.clearTyping()
+	s.abortTransfers()
+	s.ui.DisplayNotice(fmt.Sprintf("接続が切れました: %v", cause))
+
+	delay := s.minDelay
+	for attempt := 1; ; attempt++ {
+		if !s.countdown(stop, delay, attempt) {
+			return false
+		}
+
+		s.ui.UpdateStatus(s.statusLine(fmt.Sprintf("再接続中…（%d回目）", attempt)))
+		err := s.currentNetwork().Connect(s.address)
+
+		select {
+		case <-stop:
+			// 接続中に切断操作が行われた
+			if err == nil {
+				s.currentNetwork().Close()
+			}
+			return false
+		default:
+		}
 
-			s.ui.DisplayMessage(msg)
+		if err == nil {
+			break
 		}
+		s.ui.DisplayNotice(fmt.Sprintf("再接続に失敗しました: %v", err))
+		delay = min(delay*2, s.maxDelay)
+	}
+
+	// 再接続後のサーバーにとっては新しい参加者なので、送信を再開する前に改めて名乗る
+	if presence, ok := s.currentNetwork().(network.PresencePort); ok {
+		if err := presence.SendUserInfo(s.currentUser()); err != nil {
+			s.ui.ShowError(err)
+		}
+	}
+
+	s.setConnected(true)
+	select {
+	case s.reconnected <- struct{}{}:
+	default:
 	}
+	s.ui.DisplayNotice("再接続しました")
+	s.updateStatus()
+	return true
+}
+
+// countdown は再接続までの残り時間をステータスに表示しながら待つ内部メソッド
+// 切断操作で中断した場合はfalseを返す
+func (s *ChatService) countdown(stop chan struct{}, delay time.Duration, attempt int) bool {
+	step := min(delay, time.Second)
+	ticker := time.NewTicker(step)
+	defer ticker.Stop()
+
+	for remaining := delay; remaining > 0; remaining -= step {
+		s.ui.UpdateStatus(s.statusLine(fmt.Sprintf("切断中: %d秒後に再接続します（%d回目）", int(remaining.Round(time.Second)/time.Second), attempt)))
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// networkConnected はネットワークコンポーネントが接続中かを返す内部メソッド
+// 接続状態を返さないコンポーネントは常に接続中とみなす
+func (s *ChatService) networkConnected() bool {
+	status, ok := s.currentNetwork().(network.StatusPort)
+	return !ok || status.GetStatus() == network.StatusConnected
+}
+
+// markSeen はメッセージIDを記録し、初めて見るIDならtrueを返す内部メソッド
+// 記録するIDはseenLimit件までで、古いものから忘れる
+func (s *ChatService) markSeen(id string) bool {
+	s.seenMutex.Lock()
+	defer s.seenMutex.Unlock()
+
+	if _, ok := s.seen[id]; ok {
+		return false
+	}
+	s.seen[id] = struct{}{}
+	s.seenOrder = append(s.seenOrder, id)
+	if len(s.seenOrder) > seenLimit {
+		delete(s.seen, s.seenOrder[0])
+		s.seenOrder = s.seenOrder[1:]
+	}
+	return true
+}
+
+// currentUser は自分のユーザーを返す内部メソッド
+// 名前は /nick で変わるため、sessionMutexで保護する
+func (s *ChatService) currentUser() domain.User {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+	return s.user
+}
+
+// setConnected は接続状態を設定する内部メソッド
+func (s *ChatService) setConnected(connected bool) {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+	s.connected = connected
+}
+
+// isConnected は接続状態を取得する内部メソッド
+// 再接続を待っている間はfalseを返す
+func (s *ChatService) isConnected() bool {
+	s.runningMutex.RLock()
+	defer s.runningMutex.RUnlock()
+	return s.connected
 }
 
 // setRunning は実行状態を設定する内部メソッド
@@ -169,6 +960,14 @@ func (s *ChatService) setRunning(running bool) {
 	s.running = running
 }
 
+// shouldReconnect は接続が切れたときに再接続するかを返す内部メソッド
+// クライアントとして接続した場合だけtrueを返す
+func (s *ChatService) shouldReconnect() bool {
+	s.runningMutex.RLock()
+	defer s.runningMutex.RUnlock()
+	return s.reconnect
+}
+
 // isRunning は実行状態を取得する内部メソッド
 func (s *ChatService) isRunning() bool {
 	s.runningMutex.RLock()
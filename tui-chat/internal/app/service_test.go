@@ -0,0 +1,327 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/internal/ui"
+)
+
+func TestSendMessageBeforeConnecting(t *testing.T) {
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	output := &bytes.Buffer{}
+	service := NewChatService(network.NewTCPClient(), ui.NewHeadlessController(nil, user, strings.NewReader(""), output), user)
+
+	// 接続する前のメッセージはキューに入れ、そのことを知らせる
+	if err := service.SendMessage("こんにちは"); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if notices := headlessEvents(t, output, "notice"); len(notices) != 1 || !strings.Contains(notices[0].Text, "接続していません") {
+		t.Errorf("表示されたお知らせ = %+v", notices)
+	}
+	if got := service.pending.Load(); got != 1 {
+		t.Errorf("送信待ちのメッセージ = %d, want 1", got)
+	}
+}
+
+// fakeReconnectNetwork は接続の切断と再接続を再現するテスト用のネットワークコンポーネント
+type fakeReconnectNetwork struct {
+	network.NetworkPort
+	mutex      sync.Mutex
+	connected  bool
+	down       chan struct{}
+	failures   int // 失敗させる残りの接続回数
+	attempts   []time.Time
+	sent       []domain.Message
+	deliveries chan domain.Message
+}
+
+func newFakeReconnectNetwork() *fakeReconnectNetwork {
+	return &fakeReconnectNetwork{down: make(chan struct{}), deliveries: make(chan domain.Message, 10)}
+}
+
+func (f *fakeReconnectNetwork) Connect(address string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.attempts = append(f.attempts, time.Now())
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("connection refused")
+	}
+	f.connected = true
+	f.down = make(chan struct{})
+	return nil
+}
+
+func (f *fakeReconnectNetwork) SendMessage(msg domain.Message) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.connected {
+		return errors.New("接続されていません")
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func (f *fakeReconnectNetwork) ReceiveMessage() (domain.Message, error) {
+	f.mutex.Lock()
+	down := f.down
+	f.mutex.Unlock()
+
+	select {
+	case msg := <-f.deliveries:
+		return msg, nil
+	case <-down:
+		return domain.Message{}, errors.New("connection reset")
+	}
+}
+
+func (f *fakeReconnectNetwork) Close() error {
+	f.drop(0)
+	return nil
+}
+
+func (f *fakeReconnectNetwork) GetStatus() network.ConnectionStatus {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.connected {
+		return network.StatusConnected
+	}
+	return network.StatusDisconnected
+}
+
+// drop は接続を切り、続くfailures回の接続を失敗させるメソッド
+func (f *fakeReconnectNetwork) drop(failures int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.connected {
+		f.connected = false
+		close(f.down)
+	}
+	f.failures = failures
+}
+
+// snapshot は接続の試行時刻と送信したメッセージの写しを返すメソッド
+func (f *fakeReconnectNetwork) snapshot() ([]time.Time, []domain.Message) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]time.Time(nil), f.attempts...), append([]domain.Message(nil), f.sent...)
+}
+
+// waitFor は条件を満たすまで待つテスト用のヘルパー関数
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s を待ちきれませんでした", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newReconnectTestService は偽のネットワークに接続したサービスを生成するテスト用のヘルパー関数
+func newReconnectTestService(t *testing.T, fake *fakeReconnectNetwork) *ChatService {
+	t.Helper()
+
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	service := NewChatService(fake, ui.NewHeadlessController(nil, user, strings.NewReader(""), io.Discard), user)
+	service.minDelay = 10 * time.Millisecond
+	service.maxDelay = 30 * time.Millisecond
+	if err := service.JoinChat("127.0.0.1:8080"); err != nil {
+		t.Fatalf("JoinChat: %v", err)
+	}
+	t.Cleanup(func() { service.Disconnect() })
+	return service
+}
+
+// sessionMessageIDs はセッションに表示したメッセージのIDを返すテスト用のヘルパー関数
+func sessionMessageIDs(service *ChatService) []string {
+	service.sessionMutex.RLock()
+	defer service.sessionMutex.RUnlock()
+
+	ids := []string{}
+	for _, msg := range service.session.Messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestReconnectBacksOff(t *testing.T) {
+	fake := newFakeReconnectNetwork()
+	service := newReconnectTestService(t, fake)
+
+	// 3回失敗してから再接続する
+	fake.drop(3)
+	waitFor(t, "再接続", func() bool {
+		attempts, _ := fake.snapshot()
+		return len(attempts) == 5 && service.isConnected()
+	})
+
+	// 最初の接続に続く再接続の間隔は倍になり、上限で止まる
+	attempts, _ := fake.snapshot()
+	for i, want := range []time.Duration{20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond} {
+		if gap := attempts[i+2].Sub(attempts[i+1]); gap < want {
+			t.Errorf("%d回目の再接続までの間隔 = %v, want %v 以上", i+2, gap, want)
+		}
+	}
+}
+
+func TestQueuedMessagesResentAfterReconnect(t *testing.T) {
+	fake := newFakeReconnectNetwork()
+	service := newReconnectTestService(t, fake)
+
+	fake.drop(1)
+	waitFor(t, "切断の検出", func() bool { return !service.isConnected() })
+
+	// 切断中に送ったメッセージは再接続してから順に送る
+	for _, content := range []string{"one", "two"} {
+		if err := service.SendMessage(content); err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+	}
+	if err := service.Flush(5 * time.Second); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	attempts, sent := fake.snapshot()
+	if len(attempts) != 3 {
+		t.Errorf("接続の試行 = %d 回, want 3", len(attempts))
+	}
+	if len(sent) != 2 || sent[0].Content != "one" || sent[1].Content != "two" {
+		t.Errorf("送信したメッセージ = %+v, want one, two", sent)
+	}
+	if got := sessionMessageIDs(service); len(got) != 2 {
+		t.Errorf("表示したメッセージ = %v, want 2件", got)
+	}
+}
+
+func TestDuplicateMessagesSuppressed(t *testing.T) {
+	fake := newFakeReconnectNetwork()
+	service := newReconnectTestService(t, fake)
+
+	if err := service.SendMessage("mine"); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if err := service.Flush(5 * time.Second); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	_, sent := fake.snapshot()
+
+	// 再送で同じメッセージが2度届いても、自分の送信が戻ってきても1度だけ表示する
+	msg := domain.Message{ID: "bob-msg", Content: "hi", Sender: "bob-id", Room: domain.DefaultRoom, Timestamp: time.Now()}
+	fake.deliveries <- msg
+	fake.deliveries <- msg
+	fake.deliveries <- sent[0]
+	last := domain.Message{ID: "bob-last", Content: "bye", Sender: "bob-id", Room: domain.DefaultRoom, Timestamp: time.Now()}
+	fake.deliveries <- last
+
+	waitFor(t, "最後のメッセージ", func() bool {
+		ids := sessionMessageIDs(service)
+		return len(ids) > 0 && ids[len(ids)-1] == last.ID
+	})
+	if got, want := sessionMessageIDs(service), []string{sent[0].ID, msg.ID, last.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("表示したメッセージ = %v, want %v", got, want)
+	}
+}
+
+func TestMarkSeenForgetsOldest(t *testing.T) {
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	service := NewChatService(nil, ui.NewHeadlessController(nil, user, strings.NewReader(""), io.Discard), user)
+
+	if !service.markSeen("first") || service.markSeen("first") {
+		t.Fatal("同じIDを2度目も初めてと判定しました")
+	}
+	for i := 0; i < seenLimit; i++ {
+		service.markSeen(fmt.Sprintf("id-%d", i))
+	}
+	if !service.markSeen("first") {
+		t.Error("seenLimit件を超えた古いIDを忘れていません")
+	}
+	if service.markSeen(fmt.Sprintf("id-%d", seenLimit-1)) {
+		t.Error("新しいIDを忘れました")
+	}
+}
+
+// fakePresenceNetwork は入力中の通知の回数を数えるテスト用のネットワークコンポーネント
+type fakePresenceNetwork struct {
+	network.NetworkPort
+	mutex   sync.Mutex
+	typings int
+}
+
+func (f *fakePresenceNetwork) SendUserInfo(user domain.User) error { return nil }
+
+func (f *fakePresenceNetwork) SendTyping() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.typings++
+	return nil
+}
+
+func TestNotifyTypingThrottle(t *testing.T) {
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	presence := &fakePresenceNetwork{}
+	service := NewChatService(presence, ui.NewHeadlessController(nil, user, strings.NewReader(""), io.Discard), user)
+
+	// 接続する前は通知しない
+	if err := service.NotifyTyping(); err != nil {
+		t.Fatalf("NotifyTyping: %v", err)
+	}
+	if presence.typings != 0 {
+		t.Fatalf("接続前に %d 回通知しました", presence.typings)
+	}
+
+	service.setRunning(true)
+	service.setConnected(true)
+	tests := []struct {
+		name    string
+		elapsed time.Duration // 前回の通知からの経過時間（0なら変更しない）
+		want    int
+	}{
+		{"最初の入力", 0, 1},
+		{"続けて入力", 0, 1},
+		{"間隔の直前", typingInterval - time.Second, 1},
+		{"間隔の経過後", typingInterval, 2},
+		{"経過後に続けて入力", 0, 2},
+	}
+	for _, test := range tests {
+		if test.elapsed > 0 {
+			service.typingMutex.Lock()
+			service.lastTyping = time.Now().Add(-test.elapsed)
+			service.typingMutex.Unlock()
+		}
+		if err := service.NotifyTyping(); err != nil {
+			t.Fatalf("%s: NotifyTyping: %v", test.name, err)
+		}
+		if presence.typings != test.want {
+			t.Errorf("%s: 通知の回数 = %d, want %d", test.name, presence.typings, test.want)
+		}
+	}
+
+	// 再接続を待っている間は通知しない
+	service.setConnected(false)
+	service.typingMutex.Lock()
+	service.lastTyping = time.Time{}
+	service.typingMutex.Unlock()
+	if err := service.NotifyTyping(); err != nil {
+		t.Fatalf("NotifyTyping: %v", err)
+	}
+	if presence.typings != 2 {
+		t.Errorf("切断中に通知しました: %d 回", presence.typings)
+	}
+}
@@ -0,0 +1,457 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/pkg/utils"
+)
+
+const (
+	// fileAcceptTimeout はファイル送信を申し出てから誰も承諾しなければ取り下げるまでの時間
+	fileAcceptTimeout = 30 * time.Second
+
+	// fileAcceptWindow は最初の承諾が届いてから送信を始めるまでの時間
+	// 他のメンバーの承諾も待ち、送信の途中から受け取るメンバーが出ないようにする
+	fileAcceptWindow = time.Second
+
+	// progressInterval は転送の進み具合の表示を更新する最短の間隔
+	progressInterval = 100 * time.Millisecond
+)
+
+// ErrFilesUnsupported はネットワークがファイル転送に対応していない場合のエラー
+var ErrFilesUnsupported = errors.New("このネットワークはファイル転送に対応していません")
+
+// FileOptions はファイル転送の設定
+type FileOptions struct {
+	DownloadDir string // 受信したファイルの保存先（空なら受信しない）
+	MaxSize     int64  // 送受信できるファイルの最大バイト数
+}
+
+// outgoingFile は送信を申し出たファイル
+type outgoingFile struct {
+	offer   network.FileOffer
+	path    string
+	started bool
+	cancel  chan struct{} // 接続が切れて送信を中止するときに閉じる
+}
+
+// incomingFile は受信中のファイル
+// 受信中はイベントと再接続の両方のゴルーチンから触れるため、transfersMutexを持って扱う
+type incomingFile struct {
+	offer        network.FileOffer
+	name         string
+	file         *os.File
+	hash         hash.Hash
+	received     int64
+	lastProgress time.Time
+}
+
+// SetFileOptions はファイル転送の設定を行うメソッド
+func (s *ChatService) SetFileOptions(options FileOptions) {
+	s.fileOptions = options
+}
+
+// SendFile はファイルを参加中のルームのメンバーに送るメソッド
+// 送信を申し出て、承諾したメンバーに断片に分けて送る。転送はチャットと並行して行う
+func (s *ChatService) SendFile(path string) error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("ファイルを開けません: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("通常のファイルではありません: %s", path)
+	}
+	if info.Size() > s.fileOptions.MaxSize {
+		return fmt.Errorf("ファイルが大きすぎます（%s、上限は %s）",
+			utils.FormatSize(info.Size()), utils.FormatSize(s.fileOptions.MaxSize))
+	}
+
+	// チェックサムの計算には時間がかかることがあるので、入力を待たせない
+	go s.offerFile(files, path, info.Size())
+	return nil
+}
+
+// offerFile はファイルのチェックサムを計算して送信を申し出る内部メソッド
+func (s *ChatService) offerFile(files network.FilePort, path string, size int64) {
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		s.ui.ShowError(err)
+		return
+	}
+
+	out := &outgoingFile{
+		offer: network.FileOffer{
+			ID:       uuid.New().String(),
+			Name:     filepath.Base(path),
+			Size:     size,
+			Checksum: checksum,
+		},
+		path:   path,
+		cancel: make(chan struct{}),
+	}
+
+	s.transfersMutex.Lock()
+	s.outgoing[out.offer.ID] = out
+	s.transfersMutex.Unlock()
+
+	if err := files.SendFileOffer(out.offer); err != nil {
+		s.forgetOutgoing(out.offer.ID)
+		s.ui.ShowError(err)
+		return
+	}
+	s.ui.DisplayNotice(fmt.Sprintf("%s（%s）の送信を申し出ました", out.offer.Name, utils.FormatSize(size)))
+
+	time.AfterFunc(fileAcceptTimeout, func() {
+		s.transfersMutex.Lock()
+		_, pending := s.outgoing[out.offer.ID]
+		pending = pending && !out.started
+		if pending {
+			delete(s.outgoing, out.offer.ID)
+		}
+		s.transfersMutex.Unlock()
+
+		if pending {
+			s.ui.DisplayNotice(fmt.Sprintf("%s を受け取るメンバーがいなかったため、送信を取り下げました", out.offer.Name))
+		}
+	})
+}
+
+// fileAccepted は申し出への応答を処理する内部メソッド
+// 最初に承諾が届いてからfileAcceptWindowの後に送信を始める
+func (s *ChatService) fileAccepted(accept network.FileAccept) {
+	s.transfersMutex.Lock()
+	out, ok := s.outgoing[accept.ID]
+	start := ok && accept.Accepted && !out.started
+	if start {
+		out.started = true
+	}
+	s.transfersMutex.Unlock()
+
+	if !ok {
+		return
+	}
+	if !accept.Accepted {
+		s.ui.DisplayNotice(fmt.Sprintf("%s は %s を受け取りませんでした: %s", accept.From.Name, out.offer.Name, accept.Reason))
+		return
+	}
+
+	s.ui.DisplayNotice(fmt.Sprintf("%s が %s の受け取りを承諾しました", accept.From.Name, out.offer.Name))
+	if start {
+		time.AfterFunc(fileAcceptWindow, func() { s.streamFile(out) })
+	}
+}
+
+// streamFile はファイルを断片に分けて送る内部メソッド
+func (s *ChatService) streamFile(out *outgoingFile) {
+	defer s.forgetOutgoing(out.offer.ID)
+	defer s.ui.HideProgress(out.offer.ID)
+
+	files, err := s.files()
+	if err != nil {
+		s.ui.ShowError(err)
+		return
+	}
+
+	file, err := os.Open(out.path)
+	if err != nil {
+		s.ui.ShowError(fmt.Errorf("ファイルを開けません: %w", err))
+		return
+	}
+	defer file.Close()
+
+	label := "送信 " + out.offer.Name
+	var offset int64
+	var lastProgress time.Time
+	for {
+		select {
+		case <-out.cancel:
+			s.ui.ShowError(fmt.Errorf("%s の送信を中止しました: 接続が切れました", out.offer.Name))
+			return
+		default:
+		}
+
+		// 断片は送信キューに入ったまま後で符号化されることがあるので、毎回新しく確保する
+		data := make([]byte, network.FileChunkSize)
+		n, err := io.ReadFull(file, data)
+		if n > 0 {
+			if err := files.SendFileChunk(network.FileChunk{ID: out.offer.ID, Offset: offset, Data: data[:n]}); err != nil {
+				s.ui.ShowError(fmt.Errorf("%s の送信を中止しました: %w", out.offer.Name, err))
+				return
+			}
+			offset += int64(n)
+			if time.Since(lastProgress) >= progressInterval {
+				s.ui.ShowProgress(out.offer.ID, label, offset, out.offer.Size)
+				lastProgress = time.Now()
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			s.ui.ShowError(fmt.Errorf("%s の読み込みに失敗しました: %w", out.offer.Name, err))
+			return
+		}
+	}
+
+	if offset != out.offer.Size {
+		s.ui.ShowError(fmt.Errorf("%s は送信中に変更されたため、受信側で破棄されます", out.offer.Name))
+	}
+	if err := files.SendFileComplete(network.FileComplete{ID: out.offer.ID}); err != nil {
+		s.ui.ShowError(err)
+		return
+	}
+	s.ui.DisplayNotice(fmt.Sprintf("%s を送信しました", out.offer.Name))
+}
+
+// receiveOffer はファイル送信の申し出に応答する内部メソッド
+// 保存先が設定されていて上限以下のファイルは自動的に受け取る
+func (s *ChatService) receiveOffer(offer network.FileOffer) {
+	files, err := s.files()
+	if err != nil {
+		return
+	}
+
+	name := sanitizeFileName(offer.Name)
+	var reason string
+	switch {
+	case s.fileOptions.DownloadDir == "":
+		reason = "ファイルの受信が無効です"
+	case offer.Size > s.fileOptions.MaxSize:
+		reason = fmt.Sprintf("サイズの上限（%s）を超えています", utils.FormatSize(s.fileOptions.MaxSize))
+	case name == "":
+		reason = "ファイル名が不正です"
+	}
+
+	var file *os.File
+	if reason == "" {
+		file, err = createPartFile(s.fileOptions.DownloadDir)
+		if err != nil {
+			s.ui.ShowError(err)
+			reason = "ファイルを保存できません"
+		}
+	}
+
+	size := utils.FormatSize(offer.Size)
+	if reason != "" {
+		files.SendFileAccept(network.FileAccept{ID: offer.ID, Accepted: false, Reason: reason})
+		s.ui.DisplayNotice(fmt.Sprintf("%s から %s（%s）が届きましたが、受け取りませんでした: %s", offer.From.Name, offer.Name, size, reason))
+		return
+	}
+
+	s.transfersMutex.Lock()
+	s.incoming[offer.ID] = &incomingFile{offer: offer, name: name, file: file, hash: sha256.New()}
+	s.transfersMutex.Unlock()
+
+	if err := files.SendFileAccept(network.FileAccept{ID: offer.ID, Accepted: true}); err != nil {
+		s.abortIncoming(offer.ID, err.Error())
+		return
+	}
+	s.ui.DisplayNotice(fmt.Sprintf("%s から %s（%s）を受信しています", offer.From.Name, name, size))
+	s.ui.ShowProgress(offer.ID, "受信 "+name, 0, offer.Size)
+}
+
+// receiveChunk は受信中のファイルに断片を書き込む内部メソッド
+func (s *ChatService) receiveChunk(chunk network.FileChunk) {
+	s.transfersMutex.Lock()
+	in, ok := s.incoming[chunk.ID]
+	if !ok {
+		s.transfersMutex.Unlock()
+		return
+	}
+
+	var reason string
+	if chunk.Offset != in.received || in.received+int64(len(chunk.Data)) > in.offer.Size {
+		reason = "断片の順序または大きさが不正です"
+	} else if _, err := in.file.Write(chunk.Data); err != nil {
+		reason = err.Error()
+	} else {
+		in.hash.Write(chunk.Data)
+		in.received += int64(len(chunk.Data))
+	}
+
+	label, received, size := "受信 "+in.name, in.received, in.offer.Size
+	progress := reason == "" && time.Since(in.lastProgress) >= progressInterval
+	if progress {
+		in.lastProgress = time.Now()
+	}
+	s.transfersMutex.Unlock()
+
+	if reason != "" {
+		s.abortIncoming(chunk.ID, reason)
+		return
+	}
+	if progress {
+		s.ui.ShowProgress(chunk.ID, label, received, size)
+	}
+}
+
+// completeFile はチェックサムを確かめて受信したファイルを保存先に置く内部メソッド
+func (s *ChatService) completeFile(complete network.FileComplete) {
+	s.transfersMutex.Lock()
+	in, ok := s.incoming[complete.ID]
+	delete(s.incoming, complete.ID)
+	s.transfersMutex.Unlock()
+	if !ok {
+		return
+	}
+	s.ui.HideProgress(complete.ID)
+
+	part := in.file.Name()
+	if err := in.file.Close(); err != nil {
+		os.Remove(part)
+		s.ui.ShowError(err)
+		return
+	}
+
+	if in.received != in.offer.Size {
+		os.Remove(part)
+		s.ui.ShowError(fmt.Errorf("%s の受信が途中で途切れたため破棄しました", in.name))
+		return
+	}
+	checksum := hex.EncodeToString(in.hash.Sum(nil))
+	if checksum != in.offer.Checksum {
+		os.Remove(part)
+		s.ui.ShowError(fmt.Errorf("%s のチェックサムが一致しないため破棄しました", in.name))
+		return
+	}
+
+	path, err := renameUnique(part, s.fileOptions.DownloadDir, in.name)
+	if err != nil {
+		os.Remove(part)
+		s.ui.ShowError(err)
+		return
+	}
+	s.ui.DisplayNotice(fmt.Sprintf("%s から %s を受信しました: %s", in.offer.From.Name, in.name, path))
+}
+
+// abortIncoming は受信を中止して書きかけのファイルを消す内部メソッド
+func (s *ChatService) abortIncoming(id, reason string) {
+	s.transfersMutex.Lock()
+	in, ok := s.incoming[id]
+	delete(s.incoming, id)
+	s.transfersMutex.Unlock()
+	if !ok {
+		return
+	}
+
+	s.ui.HideProgress(id)
+	in.file.Close()
+	os.Remove(in.file.Name())
+	s.ui.ShowError(fmt.Errorf("%s の受信を中止しました: %s", in.name, reason))
+}
+
+// abortTransfers は接続が切れたときに全ての転送を中止する内部メソッド
+// 送信中のファイルは、再接続した後の接続に続きを送らないよう止める
+func (s *ChatService) abortTransfers() {
+	s.transfersMutex.Lock()
+	ids := make([]string, 0, len(s.incoming))
+	for id := range s.incoming {
+		ids = append(ids, id)
+	}
+	for _, out := range s.outgoing {
+		close(out.cancel)
+	}
+	s.outgoing = make(map[string]*outgoingFile)
+	s.transfersMutex.Unlock()
+
+	for _, id := range ids {
+		s.abortIncoming(id, "接続が切れました")
+	}
+}
+
+// forgetOutgoing は送信の記録を消す内部メソッド
+func (s *ChatService) forgetOutgoing(id string) {
+	s.transfersMutex.Lock()
+	delete(s.outgoing, id)
+	s.transfersMutex.Unlock()
+}
+
+// files はファイル転送に使うネットワークコンポーネントを返す内部メソッド
+func (s *ChatService) files() (network.FilePort, error) {
+	if !s.isRunning() {
+		return nil, errors.New("接続されていません")
+	}
+	files, ok := s.currentNetwork().(network.FilePort)
+	if !ok {
+		return nil, ErrFilesUnsupported
+	}
+	return files, nil
+}
+
+// fileChecksum はファイル全体のSHA-256を16進数で返す関数
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("ファイルを開けません: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("ファイルの読み込みに失敗しました: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sanitizeFileName は相手が送ってきたファイル名から、保存先の外を指す部分を取り除く関数
+// 使えない名前なら空文字列を返す
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || strings.HasPrefix(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// createPartFile は保存先に受信中のファイルを作る関数
+func createPartFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("保存先を作成できません: %w", err)
+	}
+	file, err := os.CreateTemp(dir, ".tui-chat-*.part")
+	if err != nil {
+		return nil, fmt.Errorf("ファイルを保存できません: %w", err)
+	}
+	return file, nil
+}
+
+// renameUnique は受信したファイルを保存先に置く関数
+// 同じ名前のファイルがあれば「名前 (1).拡張子」のように番号を付け、上書きしない
+func renameUnique(part, dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; i < 1000; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		path := filepath.Join(dir, candidate)
+
+		// 既存のファイルを上書きしないよう、リンクを作ってから元の名前を消す
+		if err := os.Link(part, path); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				continue
+			}
+			return "", fmt.Errorf("ファイルを保存できません: %w", err)
+		}
+		os.Remove(part)
+		return path, nil
+	}
+	return "", fmt.Errorf("%s と同じ名前のファイルが多すぎます", name)
+}
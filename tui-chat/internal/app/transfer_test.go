@@ -0,0 +1,122 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/internal/network"
+	"github.com/sakaeshinya/tui-chat/internal/ui"
+)
+
+// fakeFileNetwork はファイル転送で送ったものを記録するテスト用のネットワークコンポーネント
+type fakeFileNetwork struct {
+	network.NetworkPort
+	mutex     sync.Mutex
+	chunks    []network.FileChunk
+	completed bool
+	onChunk   func()
+}
+
+func (f *fakeFileNetwork) SendFileOffer(offer network.FileOffer) error    { return nil }
+func (f *fakeFileNetwork) SendFileAccept(accept network.FileAccept) error { return nil }
+
+func (f *fakeFileNetwork) SendFileChunk(chunk network.FileChunk) error {
+	f.mutex.Lock()
+	f.chunks = append(f.chunks, chunk)
+	f.mutex.Unlock()
+	if f.onChunk != nil {
+		f.onChunk()
+	}
+	return nil
+}
+
+func (f *fakeFileNetwork) SendFileComplete(complete network.FileComplete) error {
+	f.mutex.Lock()
+	f.completed = true
+	f.mutex.Unlock()
+	return nil
+}
+
+// newTransferTestService はファイル転送を試すための実行中のサービスを生成するテスト用のヘルパー関数
+func newTransferTestService(t *testing.T, files *fakeFileNetwork) (*ChatService, *bytes.Buffer) {
+	t.Helper()
+
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	output := &bytes.Buffer{}
+	service := NewChatService(files, ui.NewHeadlessController(nil, user, strings.NewReader(""), output), user)
+	service.SetFileOptions(FileOptions{DownloadDir: t.TempDir(), MaxSize: 1 << 20})
+	service.setRunning(true)
+	return service, output
+}
+
+func TestAbortTransfersStopsOutgoing(t *testing.T) {
+	files := &fakeFileNetwork{}
+	service, _ := newTransferTestService(t, files)
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	data := bytes.Repeat([]byte{'x'}, 3*network.FileChunkSize)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := &outgoingFile{
+		offer:   network.FileOffer{ID: "file-id", Name: "data.bin", Size: int64(len(data))},
+		path:    path,
+		started: true,
+		cancel:  make(chan struct{}),
+	}
+	service.outgoing[out.offer.ID] = out
+
+	// 最初の断片を送ったところで接続が切れる
+	files.onChunk = func() {
+		files.onChunk = nil
+		service.abortTransfers()
+	}
+	service.streamFile(out)
+
+	if len(files.chunks) != 1 {
+		t.Errorf("中止後も断片を送りました: %d 個", len(files.chunks))
+	}
+	if files.completed {
+		t.Error("中止した送信の完了を知らせました")
+	}
+}
+
+func TestReceiveChunkDuringAbort(t *testing.T) {
+	files := &fakeFileNetwork{}
+	service, _ := newTransferTestService(t, files)
+
+	data := []byte("hello")
+	for i := 0; i < 20; i++ {
+		offer := network.FileOffer{ID: "file-id", Name: "hello.txt", Size: int64(100 * len(data))}
+		service.receiveOffer(offer)
+
+		// 受信中の断片の書き込みと、再接続による中止が同時に起きても競合しない
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				service.receiveChunk(network.FileChunk{ID: offer.ID, Offset: int64(j * len(data)), Data: data})
+			}
+		}()
+		service.abortTransfers()
+		wg.Wait()
+
+		if len(service.incoming) != 0 {
+			t.Fatalf("中止した受信が残っています")
+		}
+	}
+
+	entries, err := os.ReadDir(service.fileOptions.DownloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("書きかけのファイルが残っています: %v", entries)
+	}
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// Handler は受信したメッセージに反応するボットの処理
+// 返信する場合は返信の本文とtrueを返す。受信のたびに呼ばれるため、時間のかかる処理は避ける
+type Handler func(msg domain.Message, sender domain.User) (reply string, ok bool)
+
+// builtins は名前で選べる組み込みのボット
+var builtins = map[string]func() Handler{
+	"echo": Echo,
+	"time": Time,
+}
+
+// Echo は "!echo <本文>" に本文をそのまま返すボットを生成するファクトリ関数
+// ボット同士で返信し合わないよう、"!echo" で始まるメッセージにだけ反応する
+func Echo() Handler {
+	return func(msg domain.Message, sender domain.User) (string, bool) {
+		text, ok := strings.CutPrefix(msg.Content, "!echo ")
+		text = strings.TrimSpace(text)
+		if !ok || text == "" {
+			return "", false
+		}
+		return text, true
+	}
+}
+
+// Time は "!time" に現在の日時を返すボットを生成するファクトリ関数
+func Time() Handler {
+	return func(msg domain.Message, sender domain.User) (string, bool) {
+		if strings.TrimSpace(msg.Content) != "!time" {
+			return "", false
+		}
+		return time.Now().Format("2006-01-02 15:04:05 MST"), true
+	}
+}
+
+// Parse はカンマ区切りの名前から組み込みのボットを生成する関数
+func Parse(names string) ([]Handler, error) {
+	handlers := []Handler{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		factory, ok := builtins[name]
+		if !ok {
+			return nil, fmt.Errorf("不明なボットです: %s（%s から選んでください）", name, strings.Join(Names(), ", "))
+		}
+		handlers = append(handlers, factory())
+	}
+	return handlers, nil
+}
+
+// Names は組み込みのボットの名前を名前順に返す関数
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// reply はメッセージに対するボットの返信を返すテスト用のヘルパー関数
+func reply(handler Handler, content string) (string, bool) {
+	msg := domain.Message{ID: "msg-id", Content: content, Sender: "alice-id"}
+	return handler(msg, domain.User{ID: "alice-id", Name: "alice"})
+}
+
+func TestEcho(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		wantOK  bool
+	}{
+		{"!echo こんにちは", "こんにちは", true},
+		{"!echo   前後の空白  ", "前後の空白", true},
+		{"!echo 複数行\n2行目", "複数行\n2行目", true},
+		{"!echo", "", false},
+		{"!echo    ", "", false},
+		{"!echoes hi", "", false},
+		{"こんにちは !echo hi", "", false},
+		// ボットの返信には反応しない
+		{"こんにちは", "", false},
+	}
+	handler := Echo()
+	for _, test := range tests {
+		t.Run(test.content, func(t *testing.T) {
+			got, ok := reply(handler, test.content)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("Echo(%q) = (%q, %v), want (%q, %v)", test.content, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestTime(t *testing.T) {
+	handler := Time()
+
+	before := time.Now().Truncate(time.Second)
+	got, ok := reply(handler, "  !time  ")
+	after := time.Now()
+	if !ok {
+		t.Fatal("!time に返信しませんでした")
+	}
+	parsed, err := time.ParseInLocation("2006-01-02 15:04:05 MST", got, time.Local)
+	if err != nil {
+		t.Fatalf("返信 %q を日時として解析できません: %v", got, err)
+	}
+	if parsed.Before(before) || parsed.After(after) {
+		t.Errorf("返信の日時 = %v, want %v から %v の間", parsed, before, after)
+	}
+
+	for _, content := range []string{"!time please", "!timer", "time", ""} {
+		if got, ok := reply(handler, content); ok {
+			t.Errorf("Time(%q) = %q, 返信しないはずです", content, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		names   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"echo", 1, false},
+		{" Echo , TIME ", 2, false},
+		{"echo,,time,", 2, false},
+		{"echo,weather", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.names, func(t *testing.T) {
+			handlers, err := Parse(test.names)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", test.names, err, test.wantErr)
+			}
+			if err != nil {
+				// 選べる名前を案内する
+				if !strings.Contains(err.Error(), "weather") || !strings.Contains(err.Error(), "echo, time") {
+					t.Errorf("Parse(%q) error = %q", test.names, err)
+				}
+				return
+			}
+			if len(handlers) != test.want {
+				t.Errorf("Parse(%q) = %d 個, want %d", test.names, len(handlers), test.want)
+			}
+		})
+	}
+
+	handlers, err := Parse("time,echo")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, ok := reply(handlers[1], "!echo hi"); !ok || got != "hi" {
+		t.Errorf("2番目のボットの返信 = (%q, %v), want echo の返信", got, ok)
+	}
+}
+
+func TestNames(t *testing.T) {
+	if got, want := Names(), []string{"echo", "time"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxWebhookBody はWebhookで受け付ける本文の最大バイト数
+const maxWebhookBody = 64 * 1024
+
+// errBodyTooLarge は本文がmaxWebhookBodyを超えた場合のエラー
+var errBodyTooLarge = errors.New("本文が大きすぎます")
+
+// Webhook はローカルのHTTPエンドポイントで受けた通知をチャットに中継する構造体
+// POST /message の本文をメッセージとして送る。JSONなら "content" を、それ以外は本文全体を使う
+type Webhook struct {
+	address  string
+	send     func(content string) error
+	server   *http.Server
+	listener net.Listener
+}
+
+// webhookRequest はJSONで送られたWebhookの本文
+type webhookRequest struct {
+	Content string `json:"content"`
+}
+
+// NewWebhook はリッスンするアドレスと、メッセージの送り方を指定してWebhookを生成するファクトリ関数
+func NewWebhook(address string, send func(content string) error) *Webhook {
+	return &Webhook{address: address, send: send}
+}
+
+// Start はHTTPエンドポイントを開いて通知の受け付けを始めるメソッド
+func (w *Webhook) Start() error {
+	listener, err := net.Listen("tcp", w.address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/message", w.handleMessage)
+	w.listener = listener
+	w.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go w.server.Serve(listener)
+	return nil
+}
+
+// Addr はリッスンしているアドレスを返すメソッド
+func (w *Webhook) Addr() string {
+	if w.listener == nil {
+		return w.address
+	}
+	return w.listener.Addr().String()
+}
+
+// Close はHTTPエンドポイントを閉じるメソッド
+func (w *Webhook) Close() error {
+	if w.server == nil {
+		return nil
+	}
+	return w.server.Close()
+}
+
+// handleMessage は POST /message を処理するメソッド
+func (w *Webhook) handleMessage(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "POSTで送ってください", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, err := readContent(http.MaxBytesReader(rw, r.Body, maxWebhookBody), r.Header.Get("Content-Type"))
+	if errors.Is(err, errBodyTooLarge) {
+		http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := w.send(content); err != nil {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// readContent はWebhookの本文からメッセージの内容を取り出す関数
+func readContent(body io.Reader, contentType string) (string, error) {
+	data, err := io.ReadAll(body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return "", errBodyTooLarge
+	}
+	if err != nil {
+		return "", errors.New("本文を読み込めませんでした")
+	}
+
+	content := string(data)
+	if strings.HasPrefix(contentType, "application/json") {
+		var req webhookRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return "", errors.New("JSONの形式が不正です")
+		}
+		content = req.Content
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", errors.New("メッセージが空です")
+	}
+	return content, nil
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandleMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		sendErr     error
+		wantStatus  int
+		wantContent string
+	}{
+		{"テキスト", http.MethodPost, "text/plain", "  デプロイが完了しました\n", nil, http.StatusNoContent, "デプロイが完了しました"},
+		{"Content-Typeなし", http.MethodPost, "", "本文全体", nil, http.StatusNoContent, "本文全体"},
+		{"JSON", http.MethodPost, "application/json", `{"content":"ビルド成功"}`, nil, http.StatusNoContent, "ビルド成功"},
+		{"文字コード付きのJSON", http.MethodPost, "application/json; charset=utf-8", `{"content":"テスト"}`, nil, http.StatusNoContent, "テスト"},
+		{"JSONでないテキストのJSON", http.MethodPost, "text/plain", `{"content":"そのまま"}`, nil, http.StatusNoContent, `{"content":"そのまま"}`},
+		{"不正なJSON", http.MethodPost, "application/json", `{"content":`, nil, http.StatusBadRequest, ""},
+		{"contentのないJSON", http.MethodPost, "application/json", `{"text":"x"}`, nil, http.StatusBadRequest, ""},
+		{"空の本文", http.MethodPost, "text/plain", "  \n", nil, http.StatusBadRequest, ""},
+		{"上限ちょうど", http.MethodPost, "text/plain", strings.Repeat("a", maxWebhookBody), nil, http.StatusNoContent, strings.Repeat("a", maxWebhookBody)},
+		{"上限超え", http.MethodPost, "text/plain", strings.Repeat("a", maxWebhookBody+1), nil, http.StatusRequestEntityTooLarge, ""},
+		{"送信の失敗", http.MethodPost, "text/plain", "届かない", errors.New("接続されていません"), http.StatusServiceUnavailable, "届かない"},
+		{"GET", http.MethodGet, "", "", nil, http.StatusMethodNotAllowed, ""},
+		{"PUT", http.MethodPut, "text/plain", "x", nil, http.StatusMethodNotAllowed, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent []string
+			webhook := NewWebhook("127.0.0.1:0", func(content string) error {
+				sent = append(sent, content)
+				return test.sendErr
+			})
+
+			req := httptest.NewRequest(test.method, "/message", strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			rec := httptest.NewRecorder()
+			webhook.handleMessage(rec, req)
+
+			if rec.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d（%s）", rec.Code, test.wantStatus, rec.Body.String())
+			}
+			if test.wantStatus == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("Allow = %q, want POST", rec.Header().Get("Allow"))
+			}
+			if test.wantContent == "" {
+				if len(sent) != 0 {
+					t.Errorf("送信したメッセージ = %q, 送信しないはずです", sent)
+				}
+				return
+			}
+			if len(sent) != 1 || sent[0] != test.wantContent {
+				t.Errorf("送信したメッセージ = %.40q, want %.40q", sent, test.wantContent)
+			}
+		})
+	}
+}
+
+func TestWebhookStart(t *testing.T) {
+	sent := make(chan string, 1)
+	webhook := NewWebhook("127.0.0.1:0", func(content string) error {
+		sent <- content
+		return nil
+	})
+	if err := webhook.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer webhook.Close()
+
+	base := "http://" + webhook.Addr()
+	resp, err := http.Post(base+"/message", "application/json", strings.NewReader(`{"content":"こんにちは"}`))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if got := <-sent; got != "こんにちは" {
+		t.Errorf("送信したメッセージ = %q, want %q", got, "こんにちは")
+	}
+
+	// /message 以外のパスは受け付けない
+	resp, err = http.Post(base+"/other", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	if err := webhook.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := http.Post(base+"/message", "text/plain", strings.NewReader("x")); err == nil {
+		t.Error("Close の後も受け付けました")
+	}
+}
@@ -12,6 +12,16 @@ type Config struct {
 	DefaultPort  string `json:"default_port"`
 	DefaultHost  string `json:"default_host"`
 	ColorEnabled bool   `json:"color_enabled"`
+	TLSEnabled   bool   `json:"tls_enabled"`
+	TLSCertFile  string `json:"tls_cert_file"` // 空なら設定ディレクトリの server.crt
+	TLSKeyFile   string `json:"tls_key_file"`  // 空なら設定ディレクトリの server.key
+	TLSVerify    string `json:"tls_verify"`    // tofu または ca
+	TLSCAFile    string `json:"tls_ca_file"`
+	Passphrase   string `json:"passphrase"`
+	History      bool   `json:"history"`       // 設定ディレクトリの history に会話を保存する
+	HistoryLimit int    `json:"history_limit"` // 接続時に表示する履歴の件数
+	DownloadDir  string `json:"download_dir"`  // 空なら設定ディレクトリの downloads
+	MaxFileSize  int64  `json:"max_file_size"` // 送受信できるファイルの最大バイト数
 }
 
 // DefaultConfig はデフォルト設定
@@ -20,6 +30,10 @@ var DefaultConfig = Config{
 	DefaultPort:  "8080",
 	DefaultHost:  "localhost",
 	ColorEnabled: true,
+	TLSVerify:    "tofu",
+	History:      true,
+	HistoryLimit: 200,
+	MaxFileSize:  100 << 20,
 }
 
 // LoadConfig は設定をファイルから読み込むメソッド
@@ -9,34 +9,48 @@ import (
 
 // 定義済みエラー
 var (
-	ErrSessionFull      = errors.New("チャットセッションは最大2人までです")
+	ErrSessionFull      = errors.New("チャットセッションは満員です")
 	ErrUserNotInSession = errors.New("ユーザーはセッションに参加していません")
 	ErrDuplicateUser    = errors.New("ユーザーは既にセッションに参加しています")
 )
 
+// OneToOneMaxUsers は1対1のチャットセッションの最大人数
+const OneToOneMaxUsers = 2
+
 // ChatSession はチャットセッションを表す集約ルート
 type ChatSession struct {
 	ID        string
+	Room      string // ルーム名（1対1のセッションでは空）
 	Users     []User
 	Messages  []Message
+	MaxUsers  int // 最大人数（0は無制限）
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
-// NewChatSession はチャットセッションを生成するファクトリ関数
+// NewChatSession は1対1のチャットセッションを生成するファクトリ関数
 func NewChatSession(initialUser User) ChatSession {
 	return ChatSession{
 		ID:        uuid.New().String(),
 		Users:     []User{initialUser},
 		Messages:  []Message{},
+		MaxUsers:  OneToOneMaxUsers,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 }
 
+// NewRoomSession は人数制限のないルームのチャットセッションを生成するファクトリ関数
+func NewRoomSession(room string, initialUser User) ChatSession {
+	session := NewChatSession(initialUser)
+	session.Room = room
+	session.MaxUsers = 0
+	return session
+}
+
 // AddUser はチャットセッションにユーザーを追加するメソッド
 func (c *ChatSession) AddUser(user User) error {
-	if len(c.Users) >= 2 {
+	if c.IsFull() {
 		return ErrSessionFull
 	}
 
@@ -65,6 +79,47 @@ func (c *ChatSession) RemoveUser(userID UserID) error {
 	return ErrUserNotInSession
 }
 
+// ActivateUser はユーザーを在室状態にするメソッド
+// 未参加のユーザーは追加し、参加済みのユーザーは名前と公開鍵を更新する
+func (c *ChatSession) ActivateUser(user User) error {
+	for i := range c.Users {
+		if c.Users[i].ID == user.ID {
+			c.Users[i].Name = user.Name
+			c.Users[i].PublicKey = user.PublicKey
+			c.Users[i].Activate()
+			c.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+
+	user.Activate()
+	return c.AddUser(user)
+}
+
+// DeactivateUser はユーザーを退出状態にするメソッド
+// 過去のメッセージの送信者を表示できるよう、セッションからは削除しない
+func (c *ChatSession) DeactivateUser(userID UserID) error {
+	for i := range c.Users {
+		if c.Users[i].ID == userID {
+			c.Users[i].Deactivate()
+			c.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrUserNotInSession
+}
+
+// GetActiveUsers は在室中のユーザーを取得するメソッド
+func (c *ChatSession) GetActiveUsers() []User {
+	users := []User{}
+	for _, user := range c.Users {
+		if user.IsActive {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
 // AddMessage はチャットセッションにメッセージを追加するメソッド
 func (c *ChatSession) AddMessage(msg Message) error {
 	// 送信者がセッションに参加しているか確認
@@ -102,7 +157,7 @@ func (c *ChatSession) GetUsers() []User {
 
 // IsFull はチャットセッションが満員かどうかを判定するメソッド
 func (c *ChatSession) IsFull() bool {
-	return len(c.Users) >= 2
+	return c.MaxUsers > 0 && len(c.Users) >= c.MaxUsers
 }
 
 // GetUserByID はIDでユーザーを検索するメソッド
@@ -9,10 +9,12 @@ import (
 
 // Message はチャットメッセージを表す値オブジェクト
 type Message struct {
-	ID        string    // メッセージの一意識別子
-	Content   string    // メッセージ内容
-	Sender    UserID    // 送信者ID
-	Timestamp time.Time // 送信時刻
+	ID        string    `json:"id"`               // メッセージの一意識別子
+	Content   string    `json:"content"`          // メッセージ内容
+	Sender    UserID    `json:"sender"`           // 送信者ID
+	Room      string    `json:"room,omitempty"`   // 送信先のルーム名（ハブが設定する）
+	Action    bool      `json:"action,omitempty"` // /me で送った動作の表現
+	Timestamp time.Time `json:"timestamp"`        // 送信時刻
 }
 
 // NewMessage はメッセージオブジェクトを生成するファクトリ関数
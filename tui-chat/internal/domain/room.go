@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// DefaultRoom は接続直後に参加するルーム
+const DefaultRoom = "lobby"
+
+// ErrInvalidRoomName はルーム名が不正な場合のエラー
+var ErrInvalidRoomName = errors.New("ルーム名は英小文字・数字・-・_ の32文字以内で指定してください")
+
+var roomNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// NormalizeRoomName はルーム名を正規化して検証する関数
+// 先頭の # は取り除き、大文字は小文字に変換する
+func NormalizeRoomName(name string) (string, error) {
+	room := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if !roomNamePattern.MatchString(room) {
+		return "", ErrInvalidRoomName
+	}
+	return room, nil
+}
@@ -11,9 +11,10 @@ type UserID string
 
 // User はチャットユーザーを表すエンティティ
 type User struct {
-	ID       UserID
-	Name     string
-	IsActive bool
+	ID        UserID
+	Name      string
+	IsActive  bool
+	PublicKey []byte // ダイレクトメッセージの暗号化に使う公開鍵（なければ暗号化に対応していない）
 }
 
 // NewUser はユーザーオブジェクトを生成するファクトリ関数
@@ -33,6 +34,15 @@ func (id UserID) String() string {
 	return string(id)
 }
 
+// Rename はユーザー名を変更するメソッド
+func (u *User) Rename(name string) error {
+	if name == "" {
+		return errors.New("ユーザー名が空です")
+	}
+	u.Name = name
+	return nil
+}
+
 // Deactivate はユーザーを非アクティブ状態にするメソッド
 func (u *User) Deactivate() {
 	u.IsActive = false
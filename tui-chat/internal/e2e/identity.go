@@ -0,0 +1,159 @@
+package e2e
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// keyInfo は共有鍵の導出に使う用途の文字列
+const keyInfo = "tui-chat direct message v1"
+
+// 暗号化のエラー
+var (
+	ErrInvalidPublicKey = errors.New("公開鍵が不正です")
+	ErrDecrypt          = errors.New("メッセージを復号できません")
+)
+
+// Identity はダイレクトメッセージの暗号化に使うX25519の鍵ペア
+type Identity struct {
+	private *ecdh.PrivateKey
+}
+
+// LoadIdentity は鍵ペアをファイルから読み込むファクトリ関数
+// ファイルが存在しない場合は鍵ペアを生成して保存する
+func LoadIdentity(path string) (*Identity, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return generateIdentity(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("鍵ファイルの形式が不正です: %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("鍵ファイルの読み込みに失敗しました: %w", err)
+	}
+	private, ok := key.(*ecdh.PrivateKey)
+	if !ok || private.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("X25519の鍵ではありません: %s", path)
+	}
+	return &Identity{private: private}, nil
+}
+
+// generateIdentity は鍵ペアを生成してPEM形式で保存する関数
+func generateIdentity(path string) (*Identity, error) {
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	return &Identity{private: private}, nil
+}
+
+// PublicKey は相手に渡す公開鍵を返すメソッド
+func (i *Identity) PublicKey() []byte {
+	return i.private.PublicKey().Bytes()
+}
+
+// Seal は相手の公開鍵との共有鍵でメッセージを暗号化するメソッド
+// 戻り値はノンスに暗号文を続けたもの。additionalは暗号化せずに改ざんだけを検出するデータ
+func (i *Identity) Seal(peer, plaintext, additional []byte) ([]byte, error) {
+	aead, err := i.aead(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// Open はSealで暗号化されたメッセージを復号するメソッド
+func (i *Identity) Open(peer, sealed, additional []byte) ([]byte, error) {
+	aead, err := i.aead(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// aead は相手の公開鍵とのECDHで得た秘密からAES-256-GCMを作る内部メソッド
+// 双方が同じ鍵を導出できるよう、両者の公開鍵を並べ替えてHKDFの用途に含める
+func (i *Identity) aead(peer []byte) (cipher.AEAD, error) {
+	peerKey, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	secret, err := i.private.ECDH(peerKey)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	own := i.PublicKey()
+	info := []byte(keyInfo)
+	if bytes.Compare(own, peer) < 0 {
+		info = append(append(info, own...), peer...)
+	} else {
+		info = append(append(info, peer...), own...)
+	}
+	key, err := hkdf.Key(sha256.New, secret, nil, string(info), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Fingerprint は公開鍵のSHA-256を4桁ずつ区切った16進数で返す関数
+// 相手と読み上げて照合しやすいよう空白で区切る
+func Fingerprint(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	hex := fmt.Sprintf("%X", sum)
+	groups := make([]string, 0, len(hex)/4)
+	for i := 0; i < len(hex); i += 4 {
+		groups = append(groups, hex[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
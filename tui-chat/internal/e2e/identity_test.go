@@ -0,0 +1,100 @@
+package e2e
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newTestIdentity は一時ディレクトリに鍵ペアを生成するテスト用のヘルパー関数
+func newTestIdentity(t *testing.T, name string) *Identity {
+	t.Helper()
+
+	identity, err := LoadIdentity(filepath.Join(t.TempDir(), name+".key"))
+	if err != nil {
+		t.Fatalf("LoadIdentity: %v", err)
+	}
+	return identity
+}
+
+func TestSealOpen(t *testing.T) {
+	alice := newTestIdentity(t, "alice")
+	bob := newTestIdentity(t, "bob")
+	plaintext := []byte("こんにちは")
+	additional := []byte("header")
+
+	sealed, err := alice.Seal(bob.PublicKey(), plaintext, additional)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Error("暗号文に平文が含まれています")
+	}
+
+	got, err := bob.Open(alice.PublicKey(), sealed, additional)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Open = %q, want %q", got, plaintext)
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	alice := newTestIdentity(t, "alice")
+	bob := newTestIdentity(t, "bob")
+	mallory := newTestIdentity(t, "mallory")
+	additional := []byte("header")
+
+	sealed, err := alice.Seal(bob.PublicKey(), []byte("こんにちは"), additional)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	flipped := bytes.Clone(sealed)
+	flipped[len(flipped)-1] ^= 0x01
+
+	tests := []struct {
+		name       string
+		opener     *Identity
+		peer       []byte
+		sealed     []byte
+		additional []byte
+	}{
+		{"暗号文の改ざん", bob, alice.PublicKey(), flipped, additional},
+		{"追加データの改ざん", bob, alice.PublicKey(), sealed, []byte("other")},
+		{"短すぎる暗号文", bob, alice.PublicKey(), sealed[:4], additional},
+		{"送信者の鍵のすり替え", bob, mallory.PublicKey(), sealed, additional},
+		{"宛先以外による復号", mallory, alice.PublicKey(), sealed, additional},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.opener.Open(tt.peer, tt.sealed, tt.additional); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("Open error = %v, want %v", err, ErrDecrypt)
+			}
+		})
+	}
+}
+
+func TestSealRejectsInvalidPublicKey(t *testing.T) {
+	alice := newTestIdentity(t, "alice")
+	if _, err := alice.Seal([]byte("short"), []byte("こんにちは"), nil); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Errorf("Seal error = %v, want %v", err, ErrInvalidPublicKey)
+	}
+}
+
+func TestLoadIdentityReusesKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.key")
+	first, err := LoadIdentity(path)
+	if err != nil {
+		t.Fatalf("LoadIdentity: %v", err)
+	}
+	second, err := LoadIdentity(path)
+	if err != nil {
+		t.Fatalf("LoadIdentity: %v", err)
+	}
+	if !bytes.Equal(first.PublicKey(), second.PublicKey()) {
+		t.Error("保存した鍵ペアが読み込まれていません")
+	}
+}
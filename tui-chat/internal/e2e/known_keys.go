@@ -0,0 +1,117 @@
+package e2e
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrKeyChanged は記録済みのメンバーが異なる公開鍵を提示した場合のエラー
+var ErrKeyChanged = errors.New("公開鍵が以前と異なります")
+
+// KnownKeys はTOFUで記録したメンバーの公開鍵のフィンガープリントを管理する構造体
+// 名前は誰でも名乗れて変更もできるため、記録はユーザーIDごとに行う
+// ファイルはユーザーIDから記録へのJSONオブジェクトにする
+type KnownKeys struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// KnownKey はメンバーごとの公開鍵の記録
+type KnownKey struct {
+	Name        string `json:"name"` // 最後に確認したときの名前（表示用）
+	Fingerprint string `json:"fingerprint"`
+}
+
+// NewKnownKeys はフィンガープリントの保存先を指定してKnownKeysを生成するファクトリ関数
+func NewKnownKeys(path string) *KnownKeys {
+	return &KnownKeys{path: path}
+}
+
+// Check はメンバーのフィンガープリントを確認するメソッド
+// 未記録のユーザーIDは記録してfirstにtrueを返し、記録と異なる場合はErrKeyChangedを返す
+func (k *KnownKeys) Check(id, name, fingerprint string) (first bool, err error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	known, err := k.load()
+	if err != nil {
+		return false, err
+	}
+
+	if recorded, ok := known[id]; ok {
+		if recorded.Fingerprint != fingerprint {
+			return false, fmt.Errorf("%w: %s の記録は %s ですが %s が提示されました", ErrKeyChanged, name, recorded.Fingerprint, fingerprint)
+		}
+		if recorded.Name == name {
+			return false, nil
+		}
+	} else {
+		first = true
+	}
+
+	known[id] = KnownKey{Name: name, Fingerprint: fingerprint}
+	if err := k.save(known); err != nil {
+		return false, fmt.Errorf("フィンガープリントの記録に失敗しました: %w", err)
+	}
+	return first, nil
+}
+
+// Lookup はユーザーIDの記録を返すメソッド
+func (k *KnownKeys) Lookup(id string) (KnownKey, bool, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	known, err := k.load()
+	if err != nil {
+		return KnownKey{}, false, err
+	}
+	recorded, ok := known[id]
+	return recorded, ok, nil
+}
+
+// Trust はユーザーIDの記録を新しいフィンガープリントで置き換えるメソッド
+func (k *KnownKeys) Trust(id, name, fingerprint string) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	known, err := k.load()
+	if err != nil {
+		return err
+	}
+	known[id] = KnownKey{Name: name, Fingerprint: fingerprint}
+	return k.save(known)
+}
+
+// load は記録済みのフィンガープリントを読み込む内部メソッド
+func (k *KnownKeys) load() (map[string]KnownKey, error) {
+	known := make(map[string]KnownKey)
+
+	data, err := os.ReadFile(k.path)
+	if os.IsNotExist(err) {
+		return known, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &known); err != nil {
+		return nil, fmt.Errorf("%s の形式が不正です: %w", k.path, err)
+	}
+	return known, nil
+}
+
+// save はフィンガープリントの記録をファイルに書き込む内部メソッド
+func (k *KnownKeys) save(known map[string]KnownKey) error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(known, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(k.path, append(data, '\n'), 0600)
+}
@@ -0,0 +1,78 @@
+package e2e
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestKnownKeysCheck(t *testing.T) {
+	known := NewKnownKeys(filepath.Join(t.TempDir(), "known_keys.json"))
+
+	first, err := known.Check("alice-id", "alice", "fp-1")
+	if err != nil || !first {
+		t.Fatalf("初回の Check = (%v, %v), want (true, nil)", first, err)
+	}
+	first, err = known.Check("alice-id", "alice", "fp-1")
+	if err != nil || first {
+		t.Fatalf("同じ鍵の Check = (%v, %v), want (false, nil)", first, err)
+	}
+
+	// 異なる鍵は記録を更新せずに拒否する
+	if _, err := known.Check("alice-id", "alice", "fp-2"); !errors.Is(err, ErrKeyChanged) {
+		t.Fatalf("異なる鍵の Check error = %v, want %v", err, ErrKeyChanged)
+	}
+	if recorded, _, _ := known.Lookup("alice-id"); recorded.Fingerprint != "fp-1" {
+		t.Errorf("記録 = %q, want %q", recorded.Fingerprint, "fp-1")
+	}
+
+	// Trustで記録を置き換えると新しい鍵を受け入れる
+	if err := known.Trust("alice-id", "alice", "fp-2"); err != nil {
+		t.Fatalf("Trust: %v", err)
+	}
+	if _, err := known.Check("alice-id", "alice", "fp-2"); err != nil {
+		t.Errorf("Trust後の Check error = %v", err)
+	}
+	if _, err := known.Check("alice-id", "alice", "fp-1"); !errors.Is(err, ErrKeyChanged) {
+		t.Errorf("Trust前の鍵の Check error = %v, want %v", err, ErrKeyChanged)
+	}
+}
+
+func TestKnownKeysKeyedByUserID(t *testing.T) {
+	known := NewKnownKeys(filepath.Join(t.TempDir(), "known_keys.json"))
+	if _, err := known.Check("alice-id", "alice", "fp-alice"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	// 名前を変えても同じユーザーIDの記録と照合する
+	if _, err := known.Check("alice-id", "alice2", "fp-mallory"); !errors.Is(err, ErrKeyChanged) {
+		t.Errorf("名前を変えた Check error = %v, want %v", err, ErrKeyChanged)
+	}
+	if _, err := known.Check("alice-id", "alice2", "fp-alice"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if recorded, _, _ := known.Lookup("alice-id"); recorded.Name != "alice2" {
+		t.Errorf("記録の名前 = %q, want %q", recorded.Name, "alice2")
+	}
+
+	// 同じ名前の別のユーザーは別に記録する
+	first, err := known.Check("bob-id", "alice", "fp-bob")
+	if err != nil || !first {
+		t.Errorf("同じ名前の別ユーザーの Check = (%v, %v), want (true, nil)", first, err)
+	}
+}
+
+func TestKnownKeysPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_keys.json")
+	if _, err := NewKnownKeys(path).Check("alice-id", "alice", "fp-1"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	recorded, ok, err := NewKnownKeys(path).Lookup("alice-id")
+	if err != nil || !ok {
+		t.Fatalf("Lookup = (%v, %v), want (true, nil)", ok, err)
+	}
+	if recorded != (KnownKey{Name: "alice", Fingerprint: "fp-1"}) {
+		t.Errorf("Lookup = %+v", recorded)
+	}
+}
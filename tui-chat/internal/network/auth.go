@@ -0,0 +1,86 @@
+package network
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+)
+
+// handshakeTimeout は接続直後の認証にかける時間の上限
+const handshakeTimeout = 10 * time.Second
+
+// authNonceSize は認証チャレンジの乱数のバイト数
+const authNonceSize = 32
+
+// authExporterLabel はTLSセッションから認証用の鍵素材を導出する際のラベル
+const authExporterLabel = "EXPORTER-tui-chat-passphrase"
+
+// 認証のエラー
+var (
+	ErrPassphraseRequired = errors.New("サーバーがパスフレーズを要求しています。設定ファイルの passphrase を指定してください")
+	ErrAuthFailed         = errors.New("パスフレーズが一致しません")
+)
+
+// Security は接続の暗号化と認証の設定
+type Security struct {
+	TLS        TLSOptions
+	Passphrase string // 空ならパスフレーズによる認証を行わない
+}
+
+// AuthChallenge は接続直後にサーバーが送信する認証チャレンジ
+type AuthChallenge struct {
+	Version  int    `json:"version"`
+	Nonce    []byte `json:"nonce"`
+	Required bool   `json:"required"` // パスフレーズが必要かどうか
+}
+
+// AuthResponse は認証チャレンジに対するクライアントの応答
+type AuthResponse struct {
+	Version      int      `json:"version"`
+	Capabilities []string `json:"capabilities"`    // クライアントが対応している機能
+	Proof        []byte   `json:"proof,omitempty"` // パスフレーズを鍵としたHMAC（パスフレーズがなければ空）
+}
+
+// ConnectionAck は認証結果と合意した機能を伝える接続確認
+type ConnectionAck struct {
+	Accepted     bool     `json:"accepted"`
+	Reason       string   `json:"reason,omitempty"`
+	Version      int      `json:"version"`
+	Capabilities []string `json:"capabilities"` // 双方が対応している機能
+}
+
+// authProof はパスフレーズを知っていることの証明を計算する関数
+// パスフレーズそのものは送らず、チャレンジの乱数とTLSセッション固有の鍵素材に対するHMACを送る
+// TLSセッションに結び付けることで、中間者が証明を別の接続に転用できないようにする
+func authProof(conn net.Conn, passphrase string, nonce []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, nil
+	}
+
+	mac := hmac.New(sha256.New, []byte(passphrase))
+	mac.Write(nonce)
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		binding, err := state.ExportKeyingMaterial(authExporterLabel, nil, sha256.Size)
+		if err != nil {
+			return nil, err
+		}
+		mac.Write(binding)
+	}
+
+	return mac.Sum(nil), nil
+}
+
+// newAuthNonce は認証チャレンジの乱数を生成する関数
+func newAuthNonce() ([]byte, error) {
+	nonce := make([]byte, authNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
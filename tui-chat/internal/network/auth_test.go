@@ -0,0 +1,147 @@
+package network
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// tlsTestPipe はメモリ上でTLSのハンドシェイクを済ませた接続の組を返すテスト用のヘルパー関数
+func tlsTestPipe(t *testing.T, serverConfig *tls.Config) (client, server *tls.Conn) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	client = tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS13})
+	server = tls.Server(serverConn, serverConfig)
+	// TLSのCloseは相手が読むまで待つため、下の接続を直接閉じる
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Handshake() }()
+	if err := client.Handshake(); err != nil {
+		t.Fatalf("client Handshake: %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("server Handshake: %v", err)
+	}
+	return client, server
+}
+
+func TestAuthProof(t *testing.T) {
+	nonce := []byte("0123456789abcdef0123456789abcdef")
+
+	if proof, err := authProof(nil, "", nonce); err != nil || proof != nil {
+		t.Errorf("パスフレーズなしの authProof = (%x, %v), want (nil, nil)", proof, err)
+	}
+
+	proof, err := authProof(nil, "secret", nonce)
+	if err != nil {
+		t.Fatalf("authProof: %v", err)
+	}
+	again, _ := authProof(nil, "secret", nonce)
+	if !bytes.Equal(proof, again) {
+		t.Error("同じ入力から異なる証明が計算されました")
+	}
+	if other, _ := authProof(nil, "wrong", nonce); bytes.Equal(proof, other) {
+		t.Error("異なるパスフレーズから同じ証明が計算されました")
+	}
+	if other, _ := authProof(nil, "secret", []byte("another nonce")); bytes.Equal(proof, other) {
+		t.Error("異なるチャレンジから同じ証明が計算されました")
+	}
+}
+
+func TestAuthProofBindsTLSSession(t *testing.T) {
+	dir := t.TempDir()
+	serverConfig, _, err := serverTLSConfig(TLSOptions{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+	if err != nil {
+		t.Fatalf("serverTLSConfig: %v", err)
+	}
+	nonce := []byte("0123456789abcdef0123456789abcdef")
+
+	// 同じセッションの両端では同じ証明になる
+	client, server := tlsTestPipe(t, serverConfig)
+	clientProof, err := authProof(client, "secret", nonce)
+	if err != nil {
+		t.Fatalf("authProof: %v", err)
+	}
+	serverProof, err := authProof(server, "secret", nonce)
+	if err != nil {
+		t.Fatalf("authProof: %v", err)
+	}
+	if !bytes.Equal(clientProof, serverProof) {
+		t.Error("同じセッションの両端で証明が一致しません")
+	}
+
+	// 別のセッションには転用できない
+	other, _ := tlsTestPipe(t, serverConfig)
+	otherProof, err := authProof(other, "secret", nonce)
+	if err != nil {
+		t.Fatalf("authProof: %v", err)
+	}
+	if bytes.Equal(clientProof, otherProof) {
+		t.Error("別のセッションで同じ証明が計算されました")
+	}
+	plainProof, _ := authProof(nil, "secret", nonce)
+	if bytes.Equal(clientProof, plainProof) {
+		t.Error("TLSセッションの鍵素材が証明に含まれていません")
+	}
+}
+
+func TestPassphraseAuthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		accepted   bool
+	}{
+		{"正しいパスフレーズ", "secret", true},
+		{"誤ったパスフレーズ", "wrong", false},
+		{"パスフレーズなし", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, address := startTestServer(t, Security{Passphrase: "secret"})
+
+			client := NewTCPClient()
+			client.SetSecurity(Security{Passphrase: tt.passphrase})
+			err := client.Connect(address)
+			t.Cleanup(func() { client.Close() })
+
+			if accepted := err == nil; accepted != tt.accepted {
+				t.Errorf("Connect error = %v, 接続できること = %v", err, tt.accepted)
+			}
+		})
+	}
+}
+
+func TestPassphraseAuthenticationOverTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverTLS := TLSOptions{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	}
+	_, address := startTestServer(t, Security{TLS: serverTLS, Passphrase: "secret"})
+
+	for _, passphrase := range []string{"secret", "wrong"} {
+		client := NewTCPClient()
+		client.SetSecurity(Security{
+			TLS:        TLSOptions{Enabled: true, Verify: VerifyTOFU, KnownHostsFile: filepath.Join(dir, "known_hosts")},
+			Passphrase: passphrase,
+		})
+		err := client.Connect(address)
+		client.Close()
+
+		if accepted := err == nil; accepted != (passphrase == "secret") {
+			t.Errorf("パスフレーズ %q: Connect error = %v", passphrase, err)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package network
 
 import (
-	"encoding/gob"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/sakaeshinya/tui-chat/internal/domain"
 )
@@ -12,11 +14,15 @@ import (
 // TCPClient はTCPベースのクライアント実装
 type TCPClient struct {
 	conn         net.Conn
-	encoder      *gob.Encoder
-	decoder      *gob.Decoder
+	security     Security
+	codec        *Codec
+	capabilities []string
+	room         string
+	roomMutex    sync.RWMutex
 	status       ConnectionStatus
 	statusMutex  sync.RWMutex
 	messageChan  chan domain.Message
+	eventChan    chan Event
 	errorChan    chan error
 	stopChan     chan struct{}
 	running      bool
@@ -26,53 +32,165 @@ type TCPClient struct {
 // NewTCPClient はTCPクライアントを生成するファクトリ関数
 func NewTCPClient() *TCPClient {
 	return &TCPClient{
+		room:        domain.DefaultRoom,
 		status:      StatusDisconnected,
 		messageChan: make(chan domain.Message, 100),
+		eventChan:   make(chan Event, 100),
 		errorChan:   make(chan error, 10),
 		stopChan:    make(chan struct{}),
 	}
 }
 
+// SetSecurity は暗号化と認証の設定を行うメソッド
+// Connectより前に呼び出す
+func (c *TCPClient) SetSecurity(security Security) {
+	c.security = security
+}
+
+// ErrServerClosed はサーバーが接続を終了した場合のエラー
+var ErrServerClosed = errors.New("サーバーが接続を終了しました")
+
 // Connect はサーバーに接続するメソッド
+// 接続が切れた後に再び呼び出すと、切断前に参加していたルームに参加し直す
 func (c *TCPClient) Connect(address string) error {
+	if c.isRunning() {
+		return errors.New("既に接続しています")
+	}
 	c.setStatus(StatusConnecting)
 
 	var err error
-	c.conn, err = net.Dial("tcp", address)
+	c.conn, err = c.dial(address)
 	if err != nil {
 		c.setStatus(StatusError)
 		return err
 	}
 
-	c.encoder = gob.NewEncoder(c.conn)
-	c.decoder = gob.NewDecoder(c.conn)
+	c.codec = NewCodec(c.conn)
+
+	if err := c.handshake(); err != nil {
+		c.conn.Close()
+		c.setStatus(StatusError)
+		return err
+	}
 	c.setStatus(StatusConnected)
 
+	// 接続直後はロビーにいるので、切断前のルームがあれば参加し直す
+	c.roomMutex.Lock()
+	previous := c.room
+	c.room = domain.DefaultRoom
+	c.roomMutex.Unlock()
+
+	c.stopChan = make(chan struct{})
 	c.setRunning(true)
-	go c.receiveLoop()
+	go c.receiveLoop(c.codec, c.stopChan)
 
+	if previous != domain.DefaultRoom && c.HasCapability(CapabilityRooms) {
+		if err := c.JoinRoom(previous); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// dial は設定に応じて平文またはTLSでサーバーに接続する内部メソッド
+func (c *TCPClient) dial(address string) (net.Conn, error) {
+	if !c.security.TLS.Enabled {
+		return net.Dial("tcp", address)
+	}
+
+	config, err := clientTLSConfig(c.security.TLS, address, func(text string) {
+		c.eventChan <- Event{Type: EventNotice, Text: text}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: handshakeTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, config)
+	if err != nil {
+		return nil, fmt.Errorf("TLS接続に失敗しました: %w", err)
+	}
+	return conn, nil
+}
+
+// handshake はサーバーの認証チャレンジに応答し、接続確認を待つ内部メソッド
+// 応答ではプロトコルバージョンと対応機能を伝え、接続確認で合意した機能を受け取る
+func (c *TCPClient) handshake() error {
+	c.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	netMsg, err := c.codec.Decode()
+	if err != nil {
+		return fmt.Errorf("サーバーとのハンドシェイクに失敗しました: %w", err)
+	}
+	challenge, ok := netMsg.Payload.(AuthChallenge)
+	if netMsg.Type != TypeAuthChallenge || !ok {
+		return errors.New("サーバーの認証チャレンジが不正です")
+	}
+	if challenge.Required && c.security.Passphrase == "" {
+		return ErrPassphraseRequired
+	}
+
+	proof, err := authProof(c.conn, c.security.Passphrase, challenge.Nonce)
+	if err != nil {
+		return err
+	}
+	response := AuthResponse{
+		Version:      ProtocolVersion,
+		Capabilities: SupportedCapabilities,
+		Proof:        proof,
+	}
+	if err := c.codec.Encode(NetworkMessage{Type: TypeAuthResponse, Payload: response}); err != nil {
+		return err
+	}
+
+	netMsg, err = c.codec.Decode()
+	if err != nil {
+		return fmt.Errorf("サーバーとのハンドシェイクに失敗しました: %w", err)
+	}
+	ack, ok := netMsg.Payload.(ConnectionAck)
+	if netMsg.Type != TypeConnectionAck || !ok {
+		return errors.New("サーバーの接続確認が不正です")
+	}
+	if !ack.Accepted {
+		return fmt.Errorf("サーバーに接続を拒否されました: %s", ack.Reason)
+	}
+
+	c.capabilities = ack.Capabilities
+	return nil
+}
+
+// HasCapability はサーバーと合意した機能に指定した機能が含まれるかを判定するメソッド
+func (c *TCPClient) HasCapability(capability string) bool {
+	return hasCapability(c.capabilities, capability)
+}
+
 // receiveLoop はメッセージ受信ループ
-func (c *TCPClient) receiveLoop() {
+// 終了するとReceiveMessageに終了の理由をエラーとして返す
+func (c *TCPClient) receiveLoop(codec *Codec, stop chan struct{}) {
+	var err error
 	defer func() {
 		if c.conn != nil {
 			c.conn.Close()
 		}
 		c.setStatus(StatusDisconnected)
 		c.setRunning(false)
+		c.errorChan <- err
 	}()
 
 	for {
 		select {
-		case <-c.stopChan:
+		case <-stop:
+			err = net.ErrClosed
 			return
 		default:
 			var netMsg NetworkMessage
-			err := c.decoder.Decode(&netMsg)
+			netMsg, err = codec.Decode()
+			if errors.Is(err, ErrUnknownMessageType) {
+				// 新しいバージョンのサーバーが送る未知のメッセージは読み飛ばす
+				continue
+			}
 			if err != nil {
-				c.errorChan <- err
 				return
 			}
 
@@ -81,7 +199,55 @@ func (c *TCPClient) receiveLoop() {
 				if msg, ok := netMsg.Payload.(domain.Message); ok {
 					c.messageChan <- msg
 				}
+			case TypeJoinRoom:
+				if req, ok := netMsg.Payload.(RoomRequest); ok {
+					c.roomMutex.Lock()
+					c.room = req.Room
+					c.roomMutex.Unlock()
+					c.eventChan <- Event{Type: EventRoomChanged, Room: req.Room}
+				}
+			case TypeRoomList:
+				if list, ok := netMsg.Payload.(RoomList); ok {
+					c.eventChan <- Event{Type: EventRoomList, Rooms: list.Rooms}
+				}
+			case TypeMemberList:
+				if list, ok := netMsg.Payload.(MemberList); ok {
+					c.eventChan <- Event{Type: EventMembers, Room: list.Room, Members: list.Members}
+				}
+			case TypeMemberJoined:
+				if member, ok := netMsg.Payload.(MemberEvent); ok {
+					c.eventChan <- Event{Type: EventMemberJoined, Room: member.Room, User: member.User}
+				}
+			case TypeMemberLeft:
+				if member, ok := netMsg.Payload.(MemberEvent); ok {
+					c.eventChan <- Event{Type: EventMemberLeft, Room: member.Room, User: member.User}
+				}
+			case TypeTyping:
+				if typing, ok := netMsg.Payload.(Typing); ok {
+					c.eventChan <- Event{Type: EventTyping, Room: c.CurrentRoom(), User: typing.User}
+				}
+			case TypeFileOffer:
+				if offer, ok := netMsg.Payload.(FileOffer); ok {
+					c.eventChan <- Event{Type: EventFileOffer, Room: c.CurrentRoom(), Offer: offer}
+				}
+			case TypeFileAccept:
+				if accept, ok := netMsg.Payload.(FileAccept); ok {
+					c.eventChan <- Event{Type: EventFileAccept, Accept: accept}
+				}
+			case TypeFileChunk:
+				if chunk, ok := netMsg.Payload.(FileChunk); ok {
+					c.eventChan <- Event{Type: EventFileChunk, Chunk: chunk}
+				}
+			case TypeFileComplete:
+				if complete, ok := netMsg.Payload.(FileComplete); ok {
+					c.eventChan <- Event{Type: EventFileComplete, Complete: complete}
+				}
+			case TypeDirectMessage:
+				if direct, ok := netMsg.Payload.(DirectMessage); ok {
+					c.eventChan <- Event{Type: EventDirectMessage, Direct: direct}
+				}
 			case TypeDisconnect:
+				err = ErrServerClosed
 				return
 			}
 		}
@@ -99,7 +265,7 @@ func (c *TCPClient) SendMessage(msg domain.Message) error {
 		Payload: msg,
 	}
 
-	return c.encoder.Encode(netMsg)
+	return c.send(netMsg)
 }
 
 // ReceiveMessage はメッセージを受信するメソッド
@@ -124,9 +290,10 @@ func (c *TCPClient) Close() error {
 		netMsg := NetworkMessage{
 			Type: TypeDisconnect,
 		}
-		c.encoder.Encode(netMsg)
+		c.send(netMsg)
 
 		close(c.stopChan)
+		c.setRunning(false)
 	}
 
 	if c.conn != nil {
@@ -149,8 +316,9 @@ func (c *TCPClient) SendUserInfo(user domain.User) error {
 	}
 
 	userInfo := UserInfo{
-		ID:   user.ID,
-		Name: user.Name,
+		ID:        user.ID,
+		Name:      user.Name,
+		PublicKey: user.PublicKey,
 	}
 
 	netMsg := NetworkMessage{
@@ -158,7 +326,80 @@ func (c *TCPClient) SendUserInfo(user domain.User) error {
 		Payload: userInfo,
 	}
 
-	return c.encoder.Encode(netMsg)
+	return c.send(netMsg)
+}
+
+// SendTyping は入力中であることをサーバーに知らせるメソッド
+// サーバーが在室状況の通知に対応していない場合は何もしない
+func (c *TCPClient) SendTyping() error {
+	if c.getStatus() != StatusConnected {
+		return errors.New("クライアントが接続されていません")
+	}
+	if !c.HasCapability(CapabilityPresence) {
+		return nil
+	}
+
+	return c.send(NetworkMessage{Type: TypeTyping})
+}
+
+// Events はイベントを受信するチャネルを返すメソッド
+func (c *TCPClient) Events() <-chan Event {
+	return c.eventChan
+}
+
+// JoinRoom はルームへの参加をサーバーに要求するメソッド
+// 参加はサーバーの確認を受けてからEventRoomChangedで通知される
+func (c *TCPClient) JoinRoom(room string) error {
+	room, err := domain.NormalizeRoomName(room)
+	if err != nil {
+		return err
+	}
+	if err := c.requireRooms(); err != nil {
+		return err
+	}
+
+	return c.send(NetworkMessage{Type: TypeJoinRoom, Payload: RoomRequest{Room: room}})
+}
+
+// LeaveRoom はルームからの退出をサーバーに要求するメソッド
+func (c *TCPClient) LeaveRoom() error {
+	if err := c.requireRooms(); err != nil {
+		return err
+	}
+
+	return c.send(NetworkMessage{Type: TypeLeaveRoom})
+}
+
+// ListRooms はルーム一覧をサーバーに要求するメソッド
+func (c *TCPClient) ListRooms() error {
+	if err := c.requireRooms(); err != nil {
+		return err
+	}
+
+	return c.send(NetworkMessage{Type: TypeListRooms})
+}
+
+// CurrentRoom は参加中のルーム名を返すメソッド
+func (c *TCPClient) CurrentRoom() string {
+	c.roomMutex.RLock()
+	defer c.roomMutex.RUnlock()
+	return c.room
+}
+
+// requireRooms は接続中で、サーバーがルーム機能に対応していることを確認する内部メソッド
+func (c *TCPClient) requireRooms() error {
+	if c.getStatus() != StatusConnected {
+		return errors.New("クライアントが接続されていません")
+	}
+	if !c.HasCapability(CapabilityRooms) {
+		return errors.New("サーバーがルーム機能に対応していません")
+	}
+	return nil
+}
+
+// send はメッセージをエンコードして送信する内部メソッド
+func (c *TCPClient) send(netMsg NetworkMessage) error {
+	return c.codec.Encode(netMsg)
 }
 
 // setStatus は接続状態を設定する内部メソッド
@@ -0,0 +1,199 @@
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// ProtocolVersion は通信プロトコルのバージョン
+// 互換性のない変更を加えた場合に上げる
+const ProtocolVersion = 1
+
+// MaxFrameSize は1フレームのJSONの最大バイト数
+const MaxFrameSize = 1 << 20
+
+// フレームのエラー
+var (
+	ErrFrameTooLarge      = errors.New("フレームが大きすぎます")
+	ErrUnsupportedVersion = errors.New("対応していないプロトコルバージョンです")
+	ErrUnknownMessageType = errors.New("不明なメッセージの種類です")
+)
+
+// frame は通信路上のフレーム
+// 4バイトのビッグエンディアンの長さに続けて、このJSONオブジェクトを送る
+type frame struct {
+	Version int             `json:"v"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// messageTypeNames はメッセージの種類の通信路上の名前
+var messageTypeNames = map[MessageType]string{
+	TypeChatMessage:   "chat",
+	TypeUserInfo:      "user_info",
+	TypeConnectionAck: "connection_ack",
+	TypeDisconnect:    "disconnect",
+	TypeJoinRoom:      "join_room",
+	TypeLeaveRoom:     "leave_room",
+	TypeListRooms:     "list_rooms",
+	TypeRoomList:      "room_list",
+	TypeAuthChallenge: "auth_challenge",
+	TypeAuthResponse:  "auth_response",
+	TypeMemberList:    "member_list",
+	TypeMemberJoined:  "member_joined",
+	TypeMemberLeft:    "member_left",
+	TypeTyping:        "typing",
+	TypeFileOffer:     "file_offer",
+	TypeFileAccept:    "file_accept",
+	TypeFileChunk:     "file_chunk",
+	TypeFileComplete:  "file_complete",
+	TypeDirectMessage: "direct_message",
+}
+
+// messageTypesByName は通信路上の名前からメッセージの種類を引く表
+var messageTypesByName = func() map[string]MessageType {
+	types := make(map[string]MessageType, len(messageTypeNames))
+	for messageType, name := range messageTypeNames {
+		types[name] = messageType
+	}
+	return types
+}()
+
+// payloadDecoders はメッセージの種類ごとのペイロードの復元方法
+// ここにない種類はペイロードを持たない
+var payloadDecoders = map[MessageType]func(json.RawMessage) (interface{}, error){
+	TypeChatMessage:   decodePayload[domain.Message],
+	TypeUserInfo:      decodePayload[UserInfo],
+	TypeConnectionAck: decodePayload[ConnectionAck],
+	TypeJoinRoom:      decodePayload[RoomRequest],
+	TypeRoomList:      decodePayload[RoomList],
+	TypeAuthChallenge: decodePayload[AuthChallenge],
+	TypeAuthResponse:  decodePayload[AuthResponse],
+	TypeMemberList:    decodePayload[MemberList],
+	TypeMemberJoined:  decodePayload[MemberEvent],
+	TypeMemberLeft:    decodePayload[MemberEvent],
+	TypeTyping:        decodePayload[Typing],
+	TypeFileOffer:     decodePayload[FileOffer],
+	TypeFileAccept:    decodePayload[FileAccept],
+	TypeFileChunk:     decodePayload[FileChunk],
+	TypeFileComplete:  decodePayload[FileComplete],
+	TypeDirectMessage: decodePayload[DirectMessage],
+}
+
+// decodePayload はペイロードのJSONを指定した型の値に復元する関数
+func decodePayload[T any](raw json.RawMessage) (interface{}, error) {
+	var payload T
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// String はMessageTypeの通信路上の名前を返すメソッド
+func (t MessageType) String() string {
+	if name, ok := messageTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// Codec はNetworkMessageを長さ付きのJSONフレームとして読み書きする構造体
+// クライアントとサーバーで共有し、プロトコルの食い違いを防ぐ
+// Encodeは複数のゴルーチンから呼び出せるが、Decodeは1つのゴルーチンから呼び出す
+type Codec struct {
+	reader     *bufio.Reader
+	writer     io.Writer
+	writeMutex sync.Mutex
+}
+
+// NewCodec は接続を読み書きするCodecを生成するファクトリ関数
+func NewCodec(conn io.ReadWriter) *Codec {
+	return &Codec{
+		reader: bufio.NewReader(conn),
+		writer: conn,
+	}
+}
+
+// Encode はメッセージを1フレームとして書き込むメソッド
+func (c *Codec) Encode(msg NetworkMessage) error {
+	name, ok := messageTypeNames[msg.Type]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownMessageType, int(msg.Type))
+	}
+
+	f := frame{Version: ProtocolVersion, Type: name}
+	if msg.Payload != nil {
+		payload, err := json.Marshal(msg.Payload)
+		if err != nil {
+			return err
+		}
+		f.Payload = payload
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	// 長さとJSONを1回で書き込み、他のゴルーチンの書き込みと混ざらないようにする
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_, err = c.writer.Write(buf)
+	return err
+}
+
+// Decode は1フレームを読み込んでメッセージに復元するメソッド
+// 不明な種類のフレームはErrUnknownMessageTypeを返すので、呼び出し側は読み飛ばして続行できる
+func (c *Codec) Decode() (NetworkMessage, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return NetworkMessage{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return NetworkMessage{}, ErrFrameTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return NetworkMessage{}, err
+	}
+
+	var f frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		return NetworkMessage{}, fmt.Errorf("フレームを解析できません: %w", err)
+	}
+	if f.Version != ProtocolVersion {
+		return NetworkMessage{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, f.Version)
+	}
+
+	messageType, ok := messageTypesByName[f.Type]
+	if !ok {
+		return NetworkMessage{}, fmt.Errorf("%w: %s", ErrUnknownMessageType, f.Type)
+	}
+
+	msg := NetworkMessage{Type: messageType}
+	if decode, ok := payloadDecoders[messageType]; ok && len(f.Payload) > 0 {
+		payload, err := decode(f.Payload)
+		if err != nil {
+			return NetworkMessage{}, fmt.Errorf("%s のペイロードを解析できません: %w", f.Type, err)
+		}
+		msg.Payload = payload
+	}
+	return msg, nil
+}
@@ -0,0 +1,160 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// writeTestFrame は長さを付けたJSONをそのまま書き込むテスト用のヘルパー関数
+func writeTestFrame(buf *bytes.Buffer, data string) {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(data)))
+	buf.Write(header[:])
+	buf.WriteString(data)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	alice := UserInfo{ID: "alice-id", Name: "alice", PublicKey: []byte{1, 2, 3}}
+	tests := []NetworkMessage{
+		{Type: TypeChatMessage, Payload: domain.Message{
+			ID: "msg-id", Content: "こんにちは", Sender: alice.ID, Room: "general", Action: true,
+			Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		}},
+		{Type: TypeUserInfo, Payload: alice},
+		{Type: TypeConnectionAck, Payload: ConnectionAck{Accepted: true, Version: ProtocolVersion, Capabilities: []string{CapabilityRooms}}},
+		{Type: TypeDisconnect},
+		{Type: TypeJoinRoom, Payload: RoomRequest{Room: "general"}},
+		{Type: TypeLeaveRoom},
+		{Type: TypeListRooms},
+		{Type: TypeRoomList, Payload: RoomList{Rooms: []RoomInfo{{Name: "general", Members: 2}}}},
+		{Type: TypeAuthChallenge, Payload: AuthChallenge{Version: ProtocolVersion, Nonce: []byte("nonce"), Required: true}},
+		{Type: TypeAuthResponse, Payload: AuthResponse{Version: ProtocolVersion, Capabilities: []string{CapabilityFiles}, Proof: []byte("proof")}},
+		{Type: TypeMemberList, Payload: MemberList{Room: "general", Members: []UserInfo{alice}}},
+		{Type: TypeMemberJoined, Payload: MemberEvent{Room: "general", User: alice}},
+		{Type: TypeMemberLeft, Payload: MemberEvent{Room: "general", User: alice}},
+		{Type: TypeTyping, Payload: Typing{User: alice}},
+		{Type: TypeFileOffer, Payload: FileOffer{ID: "file-id", Name: "a.txt", Size: 3, Checksum: "abc", From: alice}},
+		{Type: TypeFileAccept, Payload: FileAccept{ID: "file-id", Reason: "容量不足", From: alice}},
+		{Type: TypeFileChunk, Payload: FileChunk{ID: "file-id", Offset: 3, Data: []byte{0, 1, 2}}},
+		{Type: TypeFileComplete, Payload: FileComplete{ID: "file-id"}},
+		{Type: TypeDirectMessage, Payload: DirectMessage{ID: "dm-id", To: "bob-id", Sealed: []byte("sealed"), From: alice}},
+	}
+
+	// 種類を追加したときにテストの追加を忘れないようにする
+	if len(tests) != len(messageTypeNames) {
+		t.Fatalf("テストの種類の数 = %d, want %d", len(tests), len(messageTypeNames))
+	}
+
+	var buf bytes.Buffer
+	codec := NewCodec(&buf)
+	for _, want := range tests {
+		t.Run(want.Type.String(), func(t *testing.T) {
+			if err := codec.Encode(want); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got, err := codec.Decode()
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestCodecRejectsLargeFrame(t *testing.T) {
+	var buf bytes.Buffer
+	codec := NewCodec(&buf)
+
+	large := domain.Message{ID: "msg-id", Content: strings.Repeat("a", MaxFrameSize)}
+	if err := codec.Encode(NetworkMessage{Type: TypeChatMessage, Payload: large}); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Encode error = %v, want %v", err, ErrFrameTooLarge)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("大きすぎるフレームを %d バイト書き込みました", buf.Len())
+	}
+
+	// 本体を読む前に長さだけで拒否する
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], MaxFrameSize+1)
+	buf.Write(header[:])
+	if _, err := codec.Decode(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Decode error = %v, want %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestCodecRejectsVersionMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestFrame(&buf, `{"v":2,"type":"chat","payload":{}}`)
+
+	if _, err := NewCodec(&buf).Decode(); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Decode error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestCodecSkipsUnknownType(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestFrame(&buf, `{"v":1,"type":"future_feature","payload":{"x":1}}`)
+	writeTestFrame(&buf, `{"v":1,"type":"join_room","payload":{"room":"general"}}`)
+	codec := NewCodec(&buf)
+
+	if _, err := codec.Decode(); !errors.Is(err, ErrUnknownMessageType) {
+		t.Fatalf("Decode error = %v, want %v", err, ErrUnknownMessageType)
+	}
+
+	// 不明な種類のフレームを読み飛ばした後も続きを読める
+	msg, err := codec.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := (NetworkMessage{Type: TypeJoinRoom, Payload: RoomRequest{Room: "general"}}); !reflect.DeepEqual(msg, want) {
+		t.Errorf("Decode = %#v, want %#v", msg, want)
+	}
+
+	if err := codec.Encode(NetworkMessage{Type: MessageType(-1)}); !errors.Is(err, ErrUnknownMessageType) {
+		t.Errorf("Encode error = %v, want %v", err, ErrUnknownMessageType)
+	}
+}
+
+func TestCodecTruncatedFrame(t *testing.T) {
+	var full bytes.Buffer
+	if err := NewCodec(&full).Encode(NetworkMessage{Type: TypeJoinRoom, Payload: RoomRequest{Room: "general"}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	data := full.Bytes()
+
+	tests := []struct {
+		name string
+		size int
+		want error
+	}{
+		{"空", 0, io.EOF},
+		{"長さの途中", 2, io.ErrUnexpectedEOF},
+		{"本体の途中", len(data) - 1, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCodec(bytes.NewBuffer(data[:tt.size])).Decode(); !errors.Is(err, tt.want) {
+				t.Errorf("Decode error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodecRejectsMalformedPayload(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestFrame(&buf, `{"v":1,"type":"join_room","payload":{"room":1}}`)
+
+	if _, err := NewCodec(&buf).Decode(); err == nil {
+		t.Error("ペイロードの型が不正なフレームを受け入れました")
+	}
+}
@@ -0,0 +1,74 @@
+package network
+
+import (
+	"errors"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// DirectPort はダイレクトメッセージに対応したネットワークコンポーネントのインターフェース
+// 届いたダイレクトメッセージはEventPortのイベントとして通知される
+type DirectPort interface {
+	// SendDirectMessage は宛先のメンバーにだけダイレクトメッセージを届けるメソッド
+	SendDirectMessage(direct DirectMessage) error
+}
+
+// DirectMessage はダイレクトメッセージに使用する構造体（送信時はサーバーが送信者を設定する）
+// 本文は送信者と宛先の鍵で暗号化されており、中継するサーバーには読めない
+type DirectMessage struct {
+	ID     string        `json:"id"`
+	To     domain.UserID `json:"to"`
+	Sealed []byte        `json:"sealed"` // ノンスに続く暗号文
+	From   UserInfo      `json:"from"`
+}
+
+// routeDirect はダイレクトメッセージを宛先のメンバーにだけ届けるメソッド
+// 宛先はルームを問わずに探す。fromがnilならサーバーのユーザーからのものとして扱う
+func (s *TCPServer) routeDirect(from *peer, direct DirectMessage) {
+	s.peersMutex.RLock()
+	direct.From = s.hostUser
+	if from != nil {
+		direct.From = from.user
+	}
+	toHost := s.hostUser.ID != "" && direct.To == s.hostUser.ID
+	var target *peer
+	for p := range s.peers {
+		if p != from && p.user.ID == direct.To && hasCapability(p.capabilities, CapabilityDirect) {
+			target = p
+			break
+		}
+	}
+	s.peersMutex.RUnlock()
+
+	// ユーザー情報を登録していない送信者からのものは中継しない
+	if direct.ID == "" || direct.From.ID == "" {
+		return
+	}
+
+	switch {
+	case toHost && from != nil:
+		s.emit(Event{Type: EventDirectMessage, Direct: direct})
+	case target != nil:
+		s.send(target, NetworkMessage{Type: TypeDirectMessage, Payload: direct})
+	}
+}
+
+// SendDirectMessage はサーバーのユーザーからのダイレクトメッセージを宛先に届けるメソッド
+func (s *TCPServer) SendDirectMessage(direct DirectMessage) error {
+	if s.getStatus() != StatusConnected {
+		return errors.New("サーバーが起動していません")
+	}
+	s.routeDirect(nil, direct)
+	return nil
+}
+
+// SendDirectMessage はダイレクトメッセージをサーバーに送るメソッド
+func (c *TCPClient) SendDirectMessage(direct DirectMessage) error {
+	if c.getStatus() != StatusConnected {
+		return errors.New("クライアントが接続されていません")
+	}
+	if !c.HasCapability(CapabilityDirect) {
+		return errors.New("サーバーがダイレクトメッセージに対応していません")
+	}
+	return c.send(NetworkMessage{Type: TypeDirectMessage, Payload: direct})
+}
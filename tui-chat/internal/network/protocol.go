@@ -22,10 +22,178 @@ type NetworkPort interface {
 	Close() error
 }
 
+// RoomPort はルームをサポートするネットワークコンポーネントのインターフェース
+// 結果はEventPortのイベントとして通知される
+type RoomPort interface {
+	// JoinRoom はルームに参加するメソッド（参加中のルームからは退出する）
+	JoinRoom(room string) error
+
+	// LeaveRoom はルームから退出してロビーに戻るメソッド
+	LeaveRoom() error
+
+	// ListRooms はルーム一覧を要求するメソッド
+	ListRooms() error
+
+	// CurrentRoom は参加中のルーム名を返すメソッド
+	CurrentRoom() string
+}
+
+// PresencePort は参加者の在室状況を扱うネットワークコンポーネントのインターフェース
+// 在室状況の変化はEventPortのイベントとして通知される
+type PresencePort interface {
+	// SendUserInfo は自分のユーザー情報を登録し、ルームの参加者に知らせるメソッド
+	SendUserInfo(user domain.User) error
+
+	// SendTyping は入力中であることをルームの参加者に知らせるメソッド
+	SendTyping() error
+}
+
+// EventPort はチャットメッセージ以外の通知を受け取るインターフェース
+type EventPort interface {
+	// Events はイベントを受信するチャネルを返すメソッド
+	Events() <-chan Event
+}
+
+// StatusPort は接続状態を返すネットワークコンポーネントのインターフェース
+type StatusPort interface {
+	// GetStatus は現在の接続状態を取得するメソッド
+	GetStatus() ConnectionStatus
+}
+
+// EventType はイベントの種類を表す型
+type EventType int
+
+const (
+	// EventRoomChanged は参加中のルームが変わったことを表す
+	EventRoomChanged EventType = iota
+
+	// EventRoomList はルーム一覧の応答を表す
+	EventRoomList
+
+	// EventNotice は利用者に伝えるお知らせを表す
+	EventNotice
+
+	// EventMembers は参加中のルームのメンバー一覧を表す
+	EventMembers
+
+	// EventMemberJoined はルームにメンバーが参加したことを表す
+	EventMemberJoined
+
+	// EventMemberLeft はルームからメンバーが退出したことを表す
+	EventMemberLeft
+
+	// EventTyping はメンバーが入力中であることを表す
+	EventTyping
+
+	// EventFileOffer はメンバーからファイル送信の申し出が届いたことを表す
+	EventFileOffer
+
+	// EventFileAccept は送信を申し出たファイルへの応答が届いたことを表す
+	EventFileAccept
+
+	// EventFileChunk は受信中のファイルの断片が届いたことを表す
+	EventFileChunk
+
+	// EventFileComplete は受信中のファイルの送信が完了したことを表す
+	EventFileComplete
+
+	// EventDirectMessage はメンバーからダイレクトメッセージが届いたことを表す
+	EventDirectMessage
+)
+
+// Event はネットワークコンポーネントからの通知
+type Event struct {
+	Type     EventType
+	Room     string
+	Rooms    []RoomInfo
+	Text     string
+	User     UserInfo
+	Members  []UserInfo
+	Offer    FileOffer
+	Accept   FileAccept
+	Chunk    FileChunk
+	Complete FileComplete
+	Direct   DirectMessage
+}
+
+// RoomInfo はルームの情報
+type RoomInfo struct {
+	Name    string `json:"name"`
+	Members int    `json:"members"`
+}
+
+// RoomRequest はルームへの参加要求と、その確認に使用する構造体
+type RoomRequest struct {
+	Room string `json:"room"`
+}
+
+// RoomList はルーム一覧の応答に使用する構造体
+type RoomList struct {
+	Rooms []RoomInfo `json:"rooms"`
+}
+
 // UserInfo はユーザー情報の交換に使用する構造体
 type UserInfo struct {
-	ID   domain.UserID
-	Name string
+	ID        domain.UserID `json:"id"`
+	Name      string        `json:"name"`
+	PublicKey []byte        `json:"public_key,omitempty"` // ダイレクトメッセージの暗号化に使うX25519の公開鍵
+}
+
+// MemberList はルームのメンバー一覧の通知に使用する構造体
+type MemberList struct {
+	Room    string     `json:"room"`
+	Members []UserInfo `json:"members"`
+}
+
+// MemberEvent はメンバーの参加・退出の通知に使用する構造体
+type MemberEvent struct {
+	Room string   `json:"room"`
+	User UserInfo `json:"user"`
+}
+
+// Typing は入力中の通知に使用する構造体（送信時はサーバーが送信者を設定する）
+type Typing struct {
+	User UserInfo `json:"user"`
+}
+
+// 機能名
+const (
+	// CapabilityRooms はルーム機能を表す
+	CapabilityRooms = "rooms"
+
+	// CapabilityPresence はメンバーの在室状況と入力中の通知を表す
+	CapabilityPresence = "presence"
+
+	// CapabilityFiles はファイル転送を表す
+	CapabilityFiles = "files"
+
+	// CapabilityDirect はメンバー同士の暗号化されたダイレクトメッセージを表す
+	CapabilityDirect = "direct"
+)
+
+// SupportedCapabilities はこの実装が対応している機能の一覧
+// 接続時に相手と共通する機能だけを使用する
+var SupportedCapabilities = []string{CapabilityRooms, CapabilityPresence, CapabilityFiles, CapabilityDirect}
+
+// negotiateCapabilities は相手が提示した機能のうち、こちらも対応しているものを返す関数
+func negotiateCapabilities(offered []string) []string {
+	agreed := []string{}
+	for _, capability := range offered {
+		if hasCapability(SupportedCapabilities, capability) && !hasCapability(agreed, capability) {
+			agreed = append(agreed, capability)
+		}
+	}
+	return agreed
+}
+
+// hasCapability は機能の一覧に指定した機能が含まれるかを判定する関数
+func hasCapability(capabilities []string, capability string) bool {
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
 }
 
 // ConnectionStatus は接続状態を表す型
@@ -71,14 +239,60 @@ const (
 	// TypeUserInfo はユーザー情報メッセージ
 	TypeUserInfo
 
-	// TypeConnectionAck は接続確認メッセージ
+	// TypeConnectionAck は接続確認メッセージ（ペイロードは認証結果と合意した機能のConnectionAck）
 	TypeConnectionAck
 
 	// TypeDisconnect は切断メッセージ
 	TypeDisconnect
+
+	// TypeJoinRoom はルーム参加要求（サーバーからは参加の確認）
+	TypeJoinRoom
+
+	// TypeLeaveRoom はルーム退出要求
+	TypeLeaveRoom
+
+	// TypeListRooms はルーム一覧の要求
+	TypeListRooms
+
+	// TypeRoomList はルーム一覧の応答
+	TypeRoomList
+
+	// TypeAuthChallenge は接続直後にサーバーが送るハンドシェイクの開始と認証チャレンジ
+	TypeAuthChallenge
+
+	// TypeAuthResponse は認証チャレンジに対する応答（クライアントのバージョンと機能を含む）
+	TypeAuthResponse
+
+	// TypeMemberList はルームのメンバー一覧
+	TypeMemberList
+
+	// TypeMemberJoined はルームへのメンバーの参加
+	TypeMemberJoined
+
+	// TypeMemberLeft はルームからのメンバーの退出
+	TypeMemberLeft
+
+	// TypeTyping は入力中の通知
+	TypeTyping
+
+	// TypeFileOffer はファイル送信の申し出
+	TypeFileOffer
+
+	// TypeFileAccept はファイル送信の申し出への応答
+	TypeFileAccept
+
+	// TypeFileChunk はファイルの断片
+	TypeFileChunk
+
+	// TypeFileComplete はファイル送信の完了
+	TypeFileComplete
+
+	// TypeDirectMessage は暗号化されたダイレクトメッセージ
+	TypeDirectMessage
 )
 
 // NetworkMessage はネットワーク経由で送受信するメッセージの構造体
+// 通信路上の表現はCodecが決める
 type NetworkMessage struct {
 	Type    MessageType
 	Payload interface{}
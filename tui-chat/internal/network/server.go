@@ -1,122 +1,527 @@
 package network
 
 import (
-	"encoding/gob"
+	"crypto/hmac"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/sakaeshinya/tui-chat/internal/domain"
 )
 
-// TCPServer はTCPベースのサーバー実装
+// peerSendBuffer は接続ごとの送信待ちメッセージ数の上限
+// これを超えて溜まる遅いクライアントは切断し、他の参加者を待たせない
+const peerSendBuffer = 64
+
+// TCPServer は複数のクライアントを収容するTCPベースのハブ実装
+// クライアントはルームに参加し、チャットメッセージは同じルームの全員に配信される
+// サーバー自身のユーザーもいずれかのルームに参加する
 type TCPServer struct {
 	listener     net.Listener
-	conn         net.Conn
-	encoder      *gob.Encoder
-	decoder      *gob.Decoder
+	security     Security
+	peers        map[*peer]struct{}
+	transfers    map[string]*transfer
+	room         string
+	hostUser     UserInfo
+	peersMutex   sync.RWMutex
 	status       ConnectionStatus
 	statusMutex  sync.RWMutex
 	messageChan  chan domain.Message
+	eventChan    chan Event
 	errorChan    chan error
 	stopChan     chan struct{}
 	running      bool
 	runningMutex sync.RWMutex
 }
 
+// peer はハブに接続しているクライアント
+type peer struct {
+	conn         net.Conn
+	codec        *Codec
+	capabilities []string
+	room         string
+	user         UserInfo
+	sendChan     chan NetworkMessage
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
 // NewTCPServer はTCPサーバーを生成するファクトリ関数
 func NewTCPServer() *TCPServer {
 	return &TCPServer{
+		peers:       make(map[*peer]struct{}),
+		transfers:   make(map[string]*transfer),
+		room:        domain.DefaultRoom,
 		status:      StatusDisconnected,
 		messageChan: make(chan domain.Message, 100),
+		eventChan:   make(chan Event, 100),
 		errorChan:   make(chan error, 10),
 		stopChan:    make(chan struct{}),
 	}
 }
 
+// SetSecurity は暗号化と認証の設定を行うメソッド
+// Listenより前に呼び出す
+func (s *TCPServer) SetSecurity(security Security) {
+	s.security = security
+}
+
 // Listen はTCPサーバーを起動するメソッド
 func (s *TCPServer) Listen(address string) error {
 	s.setStatus(StatusConnecting)
 
 	var err error
-	s.listener, err = net.Listen("tcp", address)
+	if s.security.TLS.Enabled {
+		var config *tls.Config
+		var fingerprint string
+		config, fingerprint, err = serverTLSConfig(s.security.TLS)
+		if err != nil {
+			s.setStatus(StatusError)
+			return err
+		}
+		s.listener, err = tls.Listen("tcp", address, config)
+		if err == nil {
+			// 自己署名証明書の場合、クライアント側で照合できるようフィンガープリントを伝える
+			s.eventChan <- Event{Type: EventNotice, Text: fmt.Sprintf("TLSを有効にしました。証明書のフィンガープリント: %s", fingerprint)}
+		}
+	} else {
+		s.listener, err = net.Listen("tcp", address)
+	}
 	if err != nil {
 		s.setStatus(StatusError)
 		return err
 	}
 
+	s.setStatus(StatusConnected)
 	s.setRunning(true)
-	go s.acceptConnection()
+	go s.acceptConnections()
 
 	return nil
 }
 
-// acceptConnection はクライアント接続を受け付けるゴルーチン
-func (s *TCPServer) acceptConnection() {
+// acceptConnections はクライアント接続を受け付け続けるゴルーチン
+func (s *TCPServer) acceptConnections() {
 	defer s.setRunning(false)
 
-	// クライアント接続を待機
-	conn, err := s.listener.Accept()
-	if err != nil {
-		s.errorChan <- err
-		s.setStatus(StatusError)
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.stopChan:
+			default:
+				s.errorChan <- err
+				s.setStatus(StatusError)
+			}
+			return
+		}
+
+		go s.admit(conn)
+	}
+}
+
+// admit は接続したクライアントを認証し、成功すればハブに加えるメソッド
+func (s *TCPServer) admit(conn net.Conn) {
+	p := &peer{
+		conn:     conn,
+		codec:    NewCodec(conn),
+		room:     domain.DefaultRoom,
+		sendChan: make(chan NetworkMessage, peerSendBuffer),
+		done:     make(chan struct{}),
+	}
+
+	if err := s.authenticate(p); err != nil {
+		conn.Close()
 		return
 	}
 
-	s.conn = conn
-	s.encoder = gob.NewEncoder(s.conn)
-	s.decoder = gob.NewDecoder(s.conn)
-	s.setStatus(StatusConnected)
+	// 認証中にサーバーが終了した場合は受け入れない
+	select {
+	case <-s.stopChan:
+		conn.Close()
+		return
+	default:
+	}
 
-	// 受信ループを開始
-	go s.receiveLoop()
+	s.peersMutex.Lock()
+	s.peers[p] = struct{}{}
+	s.peersMutex.Unlock()
+
+	go s.writeLoop(p)
+	go s.receiveLoop(p)
 }
 
-// receiveLoop はメッセージ受信ループ
-func (s *TCPServer) receiveLoop() {
-	defer func() {
-		if s.conn != nil {
-			s.conn.Close()
+// authenticate は認証チャレンジを送り、応答を検証して結果を接続確認で返すメソッド
+// プロトコルバージョンが異なるクライアントは拒否し、双方が対応している機能を接続確認で伝える
+// パスフレーズが設定されていない場合は証明の内容にかかわらず接続を受け入れる
+func (s *TCPServer) authenticate(p *peer) error {
+	p.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer p.conn.SetDeadline(time.Time{})
+
+	nonce, err := newAuthNonce()
+	if err != nil {
+		return err
+	}
+
+	challenge := AuthChallenge{Version: ProtocolVersion, Nonce: nonce, Required: s.security.Passphrase != ""}
+	if err := p.codec.Encode(NetworkMessage{Type: TypeAuthChallenge, Payload: challenge}); err != nil {
+		return err
+	}
+
+	netMsg, err := p.codec.Decode()
+	if errors.Is(err, ErrUnsupportedVersion) {
+		// 拒否の理由を伝えてから切断する
+		reason := fmt.Sprintf("%s（サーバーは %d）", err.Error(), ProtocolVersion)
+		p.codec.Encode(NetworkMessage{Type: TypeConnectionAck, Payload: ConnectionAck{Accepted: false, Reason: reason, Version: ProtocolVersion}})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	response, ok := netMsg.Payload.(AuthResponse)
+	if netMsg.Type != TypeAuthResponse || !ok {
+		return errors.New("認証チャレンジへの応答が不正です")
+	}
+
+	p.capabilities = negotiateCapabilities(response.Capabilities)
+	ack := ConnectionAck{Accepted: true, Version: ProtocolVersion, Capabilities: p.capabilities}
+	if response.Version != ProtocolVersion {
+		ack = ConnectionAck{Accepted: false, Reason: fmt.Sprintf("プロトコルバージョン %d には対応していません（サーバーは %d）", response.Version, ProtocolVersion), Version: ProtocolVersion}
+	} else if challenge.Required {
+		expected, err := authProof(p.conn, s.security.Passphrase, nonce)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(response.Proof, expected) {
+			ack = ConnectionAck{Accepted: false, Reason: ErrAuthFailed.Error(), Version: ProtocolVersion}
 		}
-		s.setStatus(StatusDisconnected)
-	}()
+	}
+
+	if err := p.codec.Encode(NetworkMessage{Type: TypeConnectionAck, Payload: ack}); err != nil {
+		return err
+	}
+	if !ack.Accepted {
+		return errors.New(ack.Reason)
+	}
+	return nil
+}
+
+// receiveLoop はクライアントごとのメッセージ受信ループ
+func (s *TCPServer) receiveLoop(p *peer) {
+	defer s.removePeer(p)
 
 	for {
-		select {
-		case <-s.stopChan:
+		netMsg, err := p.codec.Decode()
+		if errors.Is(err, ErrUnknownMessageType) {
+			continue
+		}
+		if err != nil {
 			return
-		default:
-			var netMsg NetworkMessage
-			err := s.decoder.Decode(&netMsg)
-			if err != nil {
-				s.errorChan <- err
-				return
-			}
+		}
+
+		switch netMsg.Type {
+		case TypeUserInfo:
+			if info, ok := netMsg.Payload.(UserInfo); ok && info.ID != "" {
+				s.peersMutex.Lock()
+				p.user = info
+				room := p.room
+				s.peersMutex.Unlock()
 
-			switch netMsg.Type {
-			case TypeChatMessage:
-				if msg, ok := netMsg.Payload.(domain.Message); ok {
-					s.messageChan <- msg
+				// 名前の変更も参加の通知で伝わる
+				s.announce(TypeMemberJoined, room, info, p)
+				s.sendMembers(p)
+			}
+		case TypeTyping:
+			s.relayTyping(p)
+		case TypeChatMessage:
+			if msg, ok := netMsg.Payload.(domain.Message); ok {
+				s.relay(p, msg)
+			}
+		case TypeJoinRoom:
+			if req, ok := netMsg.Payload.(RoomRequest); ok {
+				room, err := domain.NormalizeRoomName(req.Room)
+				if err != nil {
+					room = s.peerRoom(p)
 				}
-			case TypeDisconnect:
+				s.movePeer(p, room)
+			}
+		case TypeLeaveRoom:
+			s.movePeer(p, domain.DefaultRoom)
+		case TypeListRooms:
+			s.send(p, NetworkMessage{Type: TypeRoomList, Payload: RoomList{Rooms: s.rooms()}})
+		case TypeFileOffer:
+			if offer, ok := netMsg.Payload.(FileOffer); ok {
+				s.relayFileOffer(p, offer)
+			}
+		case TypeFileAccept:
+			if accept, ok := netMsg.Payload.(FileAccept); ok {
+				s.routeFileAccept(p, accept)
+			}
+		case TypeFileChunk:
+			if chunk, ok := netMsg.Payload.(FileChunk); ok {
+				s.relayFile(p, chunk.ID, netMsg, Event{Type: EventFileChunk, Chunk: chunk})
+			}
+		case TypeFileComplete:
+			if complete, ok := netMsg.Payload.(FileComplete); ok {
+				s.relayFile(p, complete.ID, netMsg, Event{Type: EventFileComplete, Complete: complete})
+			}
+		case TypeDirectMessage:
+			if direct, ok := netMsg.Payload.(DirectMessage); ok {
+				s.routeDirect(p, direct)
+			}
+		case TypeDisconnect:
+			return
+		}
+	}
+}
+
+// writeLoop はクライアントごとのメッセージ送信ループ
+func (s *TCPServer) writeLoop(p *peer) {
+	for {
+		select {
+		case <-p.done:
+			return
+		case netMsg := <-p.sendChan:
+			if err := p.codec.Encode(netMsg); err != nil {
+				s.removePeer(p)
 				return
 			}
 		}
 	}
 }
 
-// SendMessage はメッセージを送信するメソッド
+// send はクライアントにメッセージを送信キュー経由で送るメソッド
+// キューが溢れたクライアントは切断する
+func (s *TCPServer) send(p *peer, netMsg NetworkMessage) {
+	select {
+	case <-p.done:
+	case p.sendChan <- netMsg:
+	default:
+		s.removePeer(p)
+	}
+}
+
+// relay はクライアントから受信したメッセージを同じルームに配信するメソッド
+// 送信者はクライアントが登録したユーザーに置き換え、ユーザー情報を登録していないクライアントのメッセージは配信しない
+func (s *TCPServer) relay(from *peer, msg domain.Message) {
+	s.peersMutex.RLock()
+	if from.user.ID == "" {
+		s.peersMutex.RUnlock()
+		return
+	}
+	msg.Sender = from.user.ID
+	msg.Room = from.room
+	hostInRoom := s.room == msg.Room
+	targets := s.peersInRoom(msg.Room, from)
+	s.peersMutex.RUnlock()
+
+	netMsg := NetworkMessage{Type: TypeChatMessage, Payload: msg}
+	for _, p := range targets {
+		s.send(p, netMsg)
+	}
+
+	if hostInRoom {
+		select {
+		case s.messageChan <- msg:
+		case <-s.stopChan:
+		}
+	}
+}
+
+// relayTyping はクライアントの入力中の通知を同じルームに配信するメソッド
+// ユーザー情報を登録していないクライアントの通知は配信しない
+func (s *TCPServer) relayTyping(from *peer) {
+	s.peersMutex.RLock()
+	user := from.user
+	room := from.room
+	hostInRoom := s.room == room
+	targets := s.presencePeersInRoom(room, from)
+	s.peersMutex.RUnlock()
+
+	if user.ID == "" {
+		return
+	}
+
+	netMsg := NetworkMessage{Type: TypeTyping, Payload: Typing{User: user}}
+	for _, p := range targets {
+		s.send(p, netMsg)
+	}
+	if hostInRoom {
+		s.emit(Event{Type: EventTyping, Room: room, User: user})
+	}
+}
+
+// movePeer はクライアントを別のルームに移動し、参加を確認するメソッド
+// 移動元と移動先のルームには退出と参加を知らせ、移動したクライアントにはメンバー一覧を送る
+func (s *TCPServer) movePeer(p *peer, room string) {
+	s.peersMutex.Lock()
+	previous := p.room
+	p.room = room
+	user := p.user
+	s.peersMutex.Unlock()
+
+	s.send(p, NetworkMessage{Type: TypeJoinRoom, Payload: RoomRequest{Room: room}})
+
+	if user.ID != "" && previous != room {
+		s.announce(TypeMemberLeft, previous, user, p)
+		s.announce(TypeMemberJoined, room, user, p)
+	}
+	s.sendMembers(p)
+}
+
+// announce はメンバーの参加・退出をルームの他の参加者に知らせるメソッド
+// サーバーのユーザーが同じルームにいればイベントとして通知する
+func (s *TCPServer) announce(messageType MessageType, room string, user UserInfo, except *peer) {
+	s.peersMutex.RLock()
+	targets := s.presencePeersInRoom(room, except)
+	hostInRoom := s.room == room && user.ID != s.hostUser.ID
+	s.peersMutex.RUnlock()
+
+	netMsg := NetworkMessage{Type: messageType, Payload: MemberEvent{Room: room, User: user}}
+	for _, p := range targets {
+		s.send(p, netMsg)
+	}
+
+	if hostInRoom {
+		eventType := EventMemberJoined
+		if messageType == TypeMemberLeft {
+			eventType = EventMemberLeft
+		}
+		s.emit(Event{Type: eventType, Room: room, User: user})
+	}
+}
+
+// sendMembers はクライアントに参加中のルームのメンバー一覧を送るメソッド
+func (s *TCPServer) sendMembers(p *peer) {
+	if !hasCapability(p.capabilities, CapabilityPresence) {
+		return
+	}
+
+	s.peersMutex.RLock()
+	list := MemberList{Room: p.room, Members: s.members(p.room)}
+	s.peersMutex.RUnlock()
+
+	s.send(p, NetworkMessage{Type: TypeMemberList, Payload: list})
+}
+
+// members はルームのメンバー一覧を名前順で返す内部メソッド
+// ユーザー情報を登録していないクライアントは含めない
+// 呼び出し側でpeersMutexを保持すること
+func (s *TCPServer) members(room string) []UserInfo {
+	members := []UserInfo{}
+	if s.room == room && s.hostUser.ID != "" {
+		members = append(members, s.hostUser)
+	}
+	for p := range s.peers {
+		if p.room == room && p.user.ID != "" {
+			members = append(members, p.user)
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// presencePeersInRoom はルームの参加者のうち、在室状況の通知に対応しているクライアントを返す内部メソッド
+// 呼び出し側でpeersMutexを保持すること
+func (s *TCPServer) presencePeersInRoom(room string, except *peer) []*peer {
+	var targets []*peer
+	for _, p := range s.peersInRoom(room, except) {
+		if hasCapability(p.capabilities, CapabilityPresence) {
+			targets = append(targets, p)
+		}
+	}
+	return targets
+}
+
+// emit はサーバーのユーザーにイベントを通知する内部メソッド
+func (s *TCPServer) emit(event Event) {
+	select {
+	case s.eventChan <- event:
+	case <-s.stopChan:
+	}
+}
+
+// peerRoom はクライアントが参加中のルーム名を返すメソッド
+func (s *TCPServer) peerRoom(p *peer) string {
+	s.peersMutex.RLock()
+	defer s.peersMutex.RUnlock()
+	return p.room
+}
+
+// peersInRoom はルームに参加しているクライアントを返す内部メソッド
+// 呼び出し側でpeersMutexを保持すること
+func (s *TCPServer) peersInRoom(room string, except *peer) []*peer {
+	var targets []*peer
+	for p := range s.peers {
+		if p != except && p.room == room {
+			targets = append(targets, p)
+		}
+	}
+	return targets
+}
+
+// rooms は参加者のいるルームの一覧を返すメソッド
+// ロビーは参加者がいなくても常に含まれる
+func (s *TCPServer) rooms() []RoomInfo {
+	s.peersMutex.RLock()
+	counts := map[string]int{domain.DefaultRoom: 0, s.room: 1}
+	for p := range s.peers {
+		counts[p.room]++
+	}
+	s.peersMutex.RUnlock()
+
+	rooms := make([]RoomInfo, 0, len(counts))
+	for name, members := range counts {
+		rooms = append(rooms, RoomInfo{Name: name, Members: members})
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Name < rooms[j].Name
+	})
+	return rooms
+}
+
+// removePeer はクライアントを切断してハブから取り除くメソッド
+func (s *TCPServer) removePeer(p *peer) {
+	p.closeOnce.Do(func() {
+		s.peersMutex.Lock()
+		delete(s.peers, p)
+		abandoned := s.forgetTransfers(p)
+		user := p.user
+		room := p.room
+		s.peersMutex.Unlock()
+
+		close(p.done)
+		p.conn.Close()
+
+		s.abandonTransfers(abandoned)
+		if user.ID != "" {
+			s.announce(TypeMemberLeft, room, user, nil)
+		}
+	})
+}
+
+// SendMessage はサーバーのユーザーが参加中のルームにメッセージを配信するメソッド
 func (s *TCPServer) SendMessage(msg domain.Message) error {
 	if s.getStatus() != StatusConnected {
-		return errors.New("サーバーが接続されていません")
+		return errors.New("サーバーが起動していません")
 	}
 
-	netMsg := NetworkMessage{
-		Type:    TypeChatMessage,
-		Payload: msg,
-	}
+	s.peersMutex.RLock()
+	msg.Room = s.room
+	targets := s.peersInRoom(msg.Room, nil)
+	s.peersMutex.RUnlock()
 
-	return s.encoder.Encode(netMsg)
+	netMsg := NetworkMessage{Type: TypeChatMessage, Payload: msg}
+	for _, p := range targets {
+		s.send(p, netMsg)
+	}
+	return nil
 }
 
 // ReceiveMessage はメッセージを受信するメソッド
@@ -129,25 +534,122 @@ func (s *TCPServer) ReceiveMessage() (domain.Message, error) {
 	}
 }
 
+// Events はイベントを受信するチャネルを返すメソッド
+func (s *TCPServer) Events() <-chan Event {
+	return s.eventChan
+}
+
+// JoinRoom はサーバーのユーザーをルームに参加させるメソッド
+func (s *TCPServer) JoinRoom(room string) error {
+	room, err := domain.NormalizeRoomName(room)
+	if err != nil {
+		return err
+	}
+
+	s.peersMutex.Lock()
+	previous := s.room
+	s.room = room
+	host := s.hostUser
+	s.peersMutex.Unlock()
+
+	s.emit(Event{Type: EventRoomChanged, Room: room})
+
+	if host.ID != "" && previous != room {
+		s.announce(TypeMemberLeft, previous, host, nil)
+		s.announce(TypeMemberJoined, room, host, nil)
+	}
+	s.emitMembers()
+	return nil
+}
+
+// SendUserInfo はサーバーのユーザー情報を登録し、同じルームの参加者に知らせるメソッド
+func (s *TCPServer) SendUserInfo(user domain.User) error {
+	info := UserInfo{ID: user.ID, Name: user.Name, PublicKey: user.PublicKey}
+
+	s.peersMutex.Lock()
+	s.hostUser = info
+	room := s.room
+	s.peersMutex.Unlock()
+
+	s.announce(TypeMemberJoined, room, info, nil)
+	s.emitMembers()
+	return nil
+}
+
+// SendTyping はサーバーのユーザーが入力中であることを同じルームの参加者に知らせるメソッド
+func (s *TCPServer) SendTyping() error {
+	s.peersMutex.RLock()
+	host := s.hostUser
+	targets := s.presencePeersInRoom(s.room, nil)
+	s.peersMutex.RUnlock()
+
+	if host.ID == "" {
+		return nil
+	}
+
+	netMsg := NetworkMessage{Type: TypeTyping, Payload: Typing{User: host}}
+	for _, p := range targets {
+		s.send(p, netMsg)
+	}
+	return nil
+}
+
+// emitMembers はサーバーのユーザーが参加中のルームのメンバー一覧をイベントとして通知するメソッド
+func (s *TCPServer) emitMembers() {
+	s.peersMutex.RLock()
+	room := s.room
+	members := s.members(room)
+	s.peersMutex.RUnlock()
+
+	s.emit(Event{Type: EventMembers, Room: room, Members: members})
+}
+
+// LeaveRoom はサーバーのユーザーをロビーに戻すメソッド
+func (s *TCPServer) LeaveRoom() error {
+	return s.JoinRoom(domain.DefaultRoom)
+}
+
+// ListRooms はルーム一覧をイベントとして通知するメソッド
+func (s *TCPServer) ListRooms() error {
+	s.emit(Event{Type: EventRoomList, Rooms: s.rooms()})
+	return nil
+}
+
+// CurrentRoom はサーバーのユーザーが参加中のルーム名を返すメソッド
+func (s *TCPServer) CurrentRoom() string {
+	s.peersMutex.RLock()
+	defer s.peersMutex.RUnlock()
+	return s.room
+}
+
 // Connect はサーバーモードでは使用しないメソッド
 func (s *TCPServer) Connect(address string) error {
 	return errors.New("サーバーモードではConnectメソッドは使用できません")
 }
 
-// Close はサーバーを終了するメソッド
+// Close はサーバーを終了し、全てのクライアントを切断するメソッド
 func (s *TCPServer) Close() error {
 	if s.isRunning() {
 		close(s.stopChan)
 	}
 
-	if s.conn != nil {
-		s.conn.Close()
-	}
-
 	if s.listener != nil {
 		s.listener.Close()
 	}
 
+	s.peersMutex.RLock()
+	peers := make([]*peer, 0, len(s.peers))
+	for p := range s.peers {
+		peers = append(peers, p)
+	}
+	s.peersMutex.RUnlock()
+
+	for _, p := range peers {
+		// 切断メッセージは届かなくても構わない
+		p.codec.Encode(NetworkMessage{Type: TypeDisconnect})
+		s.removePeer(p)
+	}
+
 	s.setStatus(StatusDisconnected)
 	return nil
 }
@@ -184,11 +686,3 @@ func (s *TCPServer) isRunning() bool {
 	defer s.runningMutex.RUnlock()
 	return s.running
 }
-
-func init() {
-	// gob登録
-	gob.Register(domain.Message{})
-	gob.Register(domain.UserID(""))
-	gob.Register(UserInfo{})
-	gob.Register(NetworkMessage{})
-}
@@ -0,0 +1,401 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// startTestServer はループバックの空いているポートでサーバーを起動するテスト用のヘルパー関数
+func startTestServer(t *testing.T, security Security) (*TCPServer, string) {
+	t.Helper()
+
+	server := NewTCPServer()
+	server.SetSecurity(security)
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, server.listener.Addr().String()
+}
+
+// connectTestClient はサーバーに接続したクライアントを返すテスト用のヘルパー関数
+func connectTestClient(t *testing.T, address string, security Security) *TCPClient {
+	t.Helper()
+
+	client := NewTCPClient()
+	client.SetSecurity(security)
+	if err := client.Connect(address); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// receiveTestMessage はサーバーのユーザー宛てのメッセージを待つテスト用のヘルパー関数
+func receiveTestMessage(t *testing.T, server *TCPServer) domain.Message {
+	t.Helper()
+
+	select {
+	case msg := <-server.messageChan:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("メッセージが届きません")
+		return domain.Message{}
+	}
+}
+
+func TestRelayOverridesSender(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	alice := connectTestClient(t, address, Security{})
+
+	aliceUser := domain.User{ID: "alice-id", Name: "alice"}
+	if err := alice.SendUserInfo(aliceUser); err != nil {
+		t.Fatalf("SendUserInfo: %v", err)
+	}
+
+	// 他人になりすましたメッセージも、登録したユーザーからのものとして配信される
+	msg, _ := domain.NewMessage("こんにちは", "bob-id")
+	if err := alice.SendMessage(msg); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	got := receiveTestMessage(t, server)
+	if got.Sender != aliceUser.ID {
+		t.Errorf("Sender = %q, want %q", got.Sender, aliceUser.ID)
+	}
+	if got.Room != domain.DefaultRoom {
+		t.Errorf("Room = %q, want %q", got.Room, domain.DefaultRoom)
+	}
+}
+
+func TestRelayDropsUnregisteredPeer(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	anonymous := connectTestClient(t, address, Security{})
+	alice := connectTestClient(t, address, Security{})
+
+	// ユーザー情報を登録していないクライアントのメッセージは配信しない
+	dropped, _ := domain.NewMessage("名乗らずに送信", "anonymous-id")
+	if err := anonymous.SendMessage(dropped); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	// 先に送ったメッセージが処理されるのを待つ
+	time.Sleep(100 * time.Millisecond)
+
+	if err := alice.SendUserInfo(domain.User{ID: "alice-id", Name: "alice"}); err != nil {
+		t.Fatalf("SendUserInfo: %v", err)
+	}
+	delivered, _ := domain.NewMessage("名乗ってから送信", "alice-id")
+	if err := alice.SendMessage(delivered); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	if got := receiveTestMessage(t, server); got.ID != delivered.ID {
+		t.Errorf("届いたメッセージ = %q, want %q", got.Content, delivered.Content)
+	}
+}
+
+// receiveTestEvent は指定した種類のイベントを待つテスト用のヘルパー関数
+// 途中の他の種類のイベントは読み飛ばす
+func receiveTestEvent(t *testing.T, events <-chan Event, eventType EventType) Event {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("イベント %d が届きません", eventType)
+			return Event{}
+		}
+	}
+}
+
+// registerTestClient はユーザー情報を登録し、ハブに登録されるまで待つテスト用のヘルパー関数
+func registerTestClient(t *testing.T, client *TCPClient, user domain.User) {
+	t.Helper()
+
+	if err := client.SendUserInfo(user); err != nil {
+		t.Fatalf("SendUserInfo: %v", err)
+	}
+	// メンバー一覧はハブに登録してから届く
+	receiveTestEvent(t, client.Events(), EventMembers)
+}
+
+func TestMemberEventsReachRoomMembers(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	alice := connectTestClient(t, address, Security{})
+	bob := connectTestClient(t, address, Security{})
+
+	registerTestClient(t, alice, domain.User{ID: "alice-id", Name: "alice"})
+	receiveTestEvent(t, server.Events(), EventMemberJoined)
+
+	// 参加は同じルームの他の参加者とサーバーのユーザーに届く
+	registerTestClient(t, bob, domain.User{ID: "bob-id", Name: "bob"})
+	for name, events := range map[string]<-chan Event{"alice": alice.Events(), "サーバー": server.Events()} {
+		event := receiveTestEvent(t, events, EventMemberJoined)
+		if event.User.ID != "bob-id" || event.Room != domain.DefaultRoom {
+			t.Errorf("%s への参加の通知 = %+v, want bob-id in %s", name, event, domain.DefaultRoom)
+		}
+	}
+
+	// 退出は切断したクライアント以外に届く
+	bob.Close()
+	for name, events := range map[string]<-chan Event{"alice": alice.Events(), "サーバー": server.Events()} {
+		event := receiveTestEvent(t, events, EventMemberLeft)
+		if event.User.ID != "bob-id" || event.Room != domain.DefaultRoom {
+			t.Errorf("%s への退出の通知 = %+v, want bob-id in %s", name, event, domain.DefaultRoom)
+		}
+	}
+}
+
+func TestMemberEventsStayInRoom(t *testing.T) {
+	_, address := startTestServer(t, Security{})
+	alice := connectTestClient(t, address, Security{})
+	bob := connectTestClient(t, address, Security{})
+	carol := connectTestClient(t, address, Security{})
+
+	registerTestClient(t, alice, domain.User{ID: "alice-id", Name: "alice"})
+	registerTestClient(t, carol, domain.User{ID: "carol-id", Name: "carol"})
+	if err := carol.JoinRoom("dev"); err != nil {
+		t.Fatalf("JoinRoom: %v", err)
+	}
+	receiveTestEvent(t, carol.Events(), EventRoomChanged)
+	receiveTestEvent(t, alice.Events(), EventMemberJoined)
+	if event := receiveTestEvent(t, alice.Events(), EventMemberLeft); event.User.ID != "carol-id" {
+		t.Fatalf("alice への退出の通知 = %+v, want carol-id", event)
+	}
+
+	// 別のルームの参加者には参加を知らせないので、carolに最初に届くのはdevへの参加になる
+	registerTestClient(t, bob, domain.User{ID: "bob-id", Name: "bob"})
+	if event := receiveTestEvent(t, alice.Events(), EventMemberJoined); event.User.ID != "bob-id" {
+		t.Errorf("alice への参加の通知 = %+v, want bob-id", event)
+	}
+
+	// ルームを移ると移動元には退出、移動先には参加が届く
+	if err := bob.JoinRoom("dev"); err != nil {
+		t.Fatalf("JoinRoom: %v", err)
+	}
+	if event := receiveTestEvent(t, alice.Events(), EventMemberLeft); event.User.ID != "bob-id" || event.Room != domain.DefaultRoom {
+		t.Errorf("alice への退出の通知 = %+v, want bob-id in %s", event, domain.DefaultRoom)
+	}
+	event := receiveTestEvent(t, carol.Events(), EventMemberJoined)
+	if event.User.ID != "bob-id" || event.Room != "dev" {
+		t.Errorf("carol への参加の通知 = %+v, want bob-id in dev", event)
+	}
+}
+
+// receiveClientMessage はクライアントに届くメッセージを待つテスト用のヘルパー関数
+func receiveClientMessage(t *testing.T, client *TCPClient) domain.Message {
+	t.Helper()
+
+	select {
+	case msg := <-client.messageChan:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("メッセージが届きません")
+		return domain.Message{}
+	}
+}
+
+// joinTestRoom はクライアントをルームに移し、サーバーの確認を待つテスト用のヘルパー関数
+func joinTestRoom(t *testing.T, client *TCPClient, room string) {
+	t.Helper()
+
+	if err := client.JoinRoom(room); err != nil {
+		t.Fatalf("JoinRoom: %v", err)
+	}
+	if event := receiveTestEvent(t, client.Events(), EventRoomChanged); event.Room != room {
+		t.Fatalf("移動先のルーム = %q, want %q", event.Room, room)
+	}
+}
+
+// sendTestMessage はクライアントからメッセージを送るテスト用のヘルパー関数
+func sendTestMessage(t *testing.T, client *TCPClient, content string) domain.Message {
+	t.Helper()
+
+	msg, _ := domain.NewMessage(content, "")
+	if err := client.SendMessage(msg); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	return msg
+}
+
+func TestRoomsIsolateMessages(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	alice := connectTestClient(t, address, Security{})
+	bob := connectTestClient(t, address, Security{})
+	carol := connectTestClient(t, address, Security{})
+	registerTestClient(t, alice, domain.User{ID: "alice-id", Name: "alice"})
+	registerTestClient(t, bob, domain.User{ID: "bob-id", Name: "bob"})
+	registerTestClient(t, carol, domain.User{ID: "carol-id", Name: "carol"})
+	joinTestRoom(t, bob, "dev")
+	joinTestRoom(t, carol, "dev")
+
+	// devのメッセージはdevの参加者にだけ届く
+	inDev := sendTestMessage(t, carol, "devだけ")
+	if got := receiveClientMessage(t, bob); got.ID != inDev.ID || got.Room != "dev" {
+		t.Errorf("bob に届いたメッセージ = %+v, want %q in dev", got, inDev.Content)
+	}
+
+	// carolの送信は順に処理されるため、ロビーの参加者に最初に届くのはロビーに戻ってからのメッセージになる
+	if err := carol.LeaveRoom(); err != nil {
+		t.Fatalf("LeaveRoom: %v", err)
+	}
+	inLobby := sendTestMessage(t, carol, "ロビーへ")
+	if got := receiveClientMessage(t, alice); got.ID != inLobby.ID || got.Room != domain.DefaultRoom {
+		t.Errorf("alice に届いたメッセージ = %+v, want %q in %s", got, inLobby.Content, domain.DefaultRoom)
+	}
+	if got := receiveTestMessage(t, server); got.ID != inLobby.ID {
+		t.Errorf("サーバーに届いたメッセージ = %q, want %q", got.Content, inLobby.Content)
+	}
+
+	// ロビーに戻ったbobには戻る前のロビーのメッセージは届かない
+	joinTestRoom(t, bob, domain.DefaultRoom)
+	afterLeave := sendTestMessage(t, alice, "おかえり")
+	if got := receiveClientMessage(t, bob); got.ID != afterLeave.ID {
+		t.Errorf("bob に届いたメッセージ = %q, want %q", got.Content, afterLeave.Content)
+	}
+}
+
+func TestHostJoinRoom(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	alice := connectTestClient(t, address, Security{})
+	bob := connectTestClient(t, address, Security{})
+	registerTestClient(t, alice, domain.User{ID: "alice-id", Name: "alice"})
+	registerTestClient(t, bob, domain.User{ID: "bob-id", Name: "bob"})
+
+	if err := server.JoinRoom("dev"); err != nil {
+		t.Fatalf("JoinRoom: %v", err)
+	}
+	if server.CurrentRoom() != "dev" {
+		t.Errorf("CurrentRoom = %q, want dev", server.CurrentRoom())
+	}
+	joinTestRoom(t, alice, "dev")
+
+	// サーバーのユーザーはdevのメッセージを受け取り、devに送る
+	inDev := sendTestMessage(t, alice, "devへ")
+	if got := receiveTestMessage(t, server); got.ID != inDev.ID {
+		t.Errorf("サーバーに届いたメッセージ = %q, want %q", got.Content, inDev.Content)
+	}
+	fromHost, _ := domain.NewMessage("サーバーから", "host-id")
+	if err := server.SendMessage(fromHost); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if got := receiveClientMessage(t, alice); got.ID != fromHost.ID || got.Room != "dev" {
+		t.Errorf("alice に届いたメッセージ = %+v, want %q in dev", got, fromHost.Content)
+	}
+
+	// ロビーに戻ると、先に送られたdevのメッセージではなくロビーのメッセージが届く
+	if err := server.LeaveRoom(); err != nil {
+		t.Fatalf("LeaveRoom: %v", err)
+	}
+	sendTestMessage(t, alice, "devに残る")
+	inLobby := sendTestMessage(t, bob, "ロビーへ")
+	if got := receiveTestMessage(t, server); got.ID != inLobby.ID {
+		t.Errorf("サーバーに届いたメッセージ = %q, want %q", got.Content, inLobby.Content)
+	}
+}
+
+func TestMessagesFanOutInRoom(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	clients := map[string]*TCPClient{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		clients[name] = connectTestClient(t, address, Security{})
+		registerTestClient(t, clients[name], domain.User{ID: domain.UserID(name + "-id"), Name: name})
+	}
+
+	msg := sendTestMessage(t, clients["alice"], "みなさんへ")
+	for _, name := range []string{"bob", "carol", "dave"} {
+		got := receiveClientMessage(t, clients[name])
+		if got.ID != msg.ID || got.Sender != "alice-id" {
+			t.Errorf("%s に届いたメッセージ = %+v, want %q from alice-id", name, got, msg.Content)
+		}
+	}
+	if got := receiveTestMessage(t, server); got.ID != msg.ID {
+		t.Errorf("サーバーに届いたメッセージ = %q, want %q", got.Content, msg.Content)
+	}
+
+	// 送信者自身には送り返さない
+	fromHost, _ := domain.NewMessage("サーバーから", "host-id")
+	if err := server.SendMessage(fromHost); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if got := receiveClientMessage(t, clients["alice"]); got.ID != fromHost.ID {
+		t.Errorf("alice に届いたメッセージ = %q, want %q", got.Content, fromHost.Content)
+	}
+}
+
+func TestListRooms(t *testing.T) {
+	server, address := startTestServer(t, Security{})
+	alice := connectTestClient(t, address, Security{})
+	bob := connectTestClient(t, address, Security{})
+	carol := connectTestClient(t, address, Security{})
+	joinTestRoom(t, bob, "dev")
+	joinTestRoom(t, carol, "dev")
+	if err := server.JoinRoom("ops"); err != nil {
+		t.Fatalf("JoinRoom: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		list func() error
+		want []RoomInfo
+	}{
+		{"クライアント", alice.ListRooms, []RoomInfo{{"dev", 2}, {domain.DefaultRoom, 1}, {"ops", 1}}},
+		{"サーバー", server.ListRooms, []RoomInfo{{"dev", 2}, {domain.DefaultRoom, 1}, {"ops", 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.list(); err != nil {
+				t.Fatalf("ListRooms: %v", err)
+			}
+			events := alice.Events()
+			if test.name == "サーバー" {
+				events = server.Events()
+			}
+			if got := receiveTestEvent(t, events, EventRoomList).Rooms; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Rooms = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// 誰もいなくなったルームは一覧から消え、ロビーは空でも残る
+	joinTestRoom(t, bob, domain.DefaultRoom)
+	joinTestRoom(t, carol, domain.DefaultRoom)
+	alice.Close()
+	if err := server.LeaveRoom(); err != nil {
+		t.Fatalf("LeaveRoom: %v", err)
+	}
+	if err := server.JoinRoom("ops"); err != nil {
+		t.Fatalf("JoinRoom: %v", err)
+	}
+	waitForPeers(t, server, 2)
+	if got, want := server.rooms(), []RoomInfo{{domain.DefaultRoom, 2}, {"ops", 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rooms = %v, want %v", got, want)
+	}
+}
+
+// waitForPeers はハブに接続しているクライアントが指定した数になるまで待つテスト用のヘルパー関数
+func waitForPeers(t *testing.T, server *TCPServer, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		server.peersMutex.RLock()
+		n := len(server.peers)
+		server.peersMutex.RUnlock()
+		if n == count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("接続しているクライアント = %d, want %d", n, count)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
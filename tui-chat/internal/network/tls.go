@@ -0,0 +1,259 @@
+package network
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VerifyMode はクライアントがサーバー証明書を検証する方法を表す型
+type VerifyMode string
+
+const (
+	// VerifyTOFU は初回接続時の証明書を記録し、以降は一致を確認するモード
+	// 自己署名証明書のサーバーに接続する場合に使用する
+	VerifyTOFU VerifyMode = "tofu"
+
+	// VerifyCA は認証局による通常の証明書検証を行うモード
+	VerifyCA VerifyMode = "ca"
+)
+
+// ErrFingerprintMismatch は記録済みのフィンガープリントと異なる証明書が提示された場合のエラー
+var ErrFingerprintMismatch = errors.New("サーバー証明書のフィンガープリントが記録と一致しません")
+
+// TLSOptions はTLS接続の設定
+type TLSOptions struct {
+	Enabled        bool
+	CertFile       string     // サーバー証明書のパス（存在しなければ自己署名証明書を生成する）
+	KeyFile        string     // サーバー秘密鍵のパス
+	Verify         VerifyMode // クライアントの証明書検証方法
+	CAFile         string     // VerifyCAで信頼する認証局の証明書（空ならシステムの認証局）
+	KnownHostsFile string     // VerifyTOFUで記録したフィンガープリントの保存先
+}
+
+// ParseVerifyMode は設定値から証明書の検証方法を取得する関数
+func ParseVerifyMode(value string) (VerifyMode, error) {
+	switch VerifyMode(value) {
+	case "", VerifyTOFU:
+		return VerifyTOFU, nil
+	case VerifyCA:
+		return VerifyCA, nil
+	default:
+		return "", fmt.Errorf("不明な証明書の検証方法です: %s（tofu または ca を指定してください）", value)
+	}
+}
+
+// Fingerprint はDER形式の証明書のSHA-256フィンガープリントを返す関数
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// serverTLSConfig はサーバーのTLS設定を生成する関数
+// 証明書ファイルが存在しない場合は自己署名証明書を生成して保存する
+func serverTLSConfig(opts TLSOptions) (*tls.Config, string, error) {
+	if _, err := os.Stat(opts.CertFile); os.IsNotExist(err) {
+		if err := generateSelfSignedCert(opts.CertFile, opts.KeyFile); err != nil {
+			return nil, "", fmt.Errorf("自己署名証明書の生成に失敗しました: %w", err)
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("証明書の読み込みに失敗しました: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}
+	return config, Fingerprint(cert.Certificate[0]), nil
+}
+
+// generateSelfSignedCert は自己署名証明書と秘密鍵を生成してPEM形式で保存する関数
+func generateSelfSignedCert(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "tui-chat"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// clientTLSConfig はクライアントのTLS設定を生成する関数
+// VerifyTOFUでは初めて接続したサーバーのフィンガープリントを記録し、notifyで通知する
+func clientTLSConfig(opts TLSOptions, address string, notify func(string)) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS13,
+	}
+
+	switch opts.Verify {
+	case VerifyCA:
+		if opts.CAFile != "" {
+			data, err := os.ReadFile(opts.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("認証局の証明書の読み込みに失敗しました: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("認証局の証明書が見つかりません: %s", opts.CAFile)
+			}
+			config.RootCAs = pool
+		}
+	default:
+		knownHosts := NewKnownHosts(opts.KnownHostsFile)
+		// 証明書チェーンの代わりにフィンガープリントで検証する
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("サーバーが証明書を提示しませんでした")
+			}
+			fingerprint := Fingerprint(rawCerts[0])
+			first, err := knownHosts.Check(address, fingerprint)
+			if err != nil {
+				return err
+			}
+			if first {
+				notify(fmt.Sprintf("初めて接続するサーバーの証明書を記録しました: %s", fingerprint))
+			}
+			return nil
+		}
+	}
+
+	return config, nil
+}
+
+// KnownHosts はTOFUで記録したサーバーのフィンガープリントを管理する構造体
+// ファイルは「アドレス フィンガープリント」の行で構成される
+type KnownHosts struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewKnownHosts はフィンガープリントの保存先を指定してKnownHostsを生成するファクトリ関数
+func NewKnownHosts(path string) *KnownHosts {
+	return &KnownHosts{path: path}
+}
+
+// Check はアドレスのフィンガープリントを確認するメソッド
+// 未記録のアドレスは記録してfirstにtrueを返し、記録と異なる場合はエラーを返す
+func (k *KnownHosts) Check(address, fingerprint string) (first bool, err error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	known, err := k.load()
+	if err != nil {
+		return false, err
+	}
+
+	if recorded, ok := known[address]; ok {
+		if recorded != fingerprint {
+			return false, fmt.Errorf("%w: %s は %s として記録されていますが %s が提示されました（正当な変更であれば %s から該当する行を削除してください）",
+				ErrFingerprintMismatch, address, recorded, fingerprint, k.path)
+		}
+		return false, nil
+	}
+
+	if err := k.append(address, fingerprint); err != nil {
+		return false, fmt.Errorf("フィンガープリントの記録に失敗しました: %w", err)
+	}
+	return true, nil
+}
+
+// load は記録済みのフィンガープリントを読み込む内部メソッド
+func (k *KnownHosts) load() (map[string]string, error) {
+	known := make(map[string]string)
+
+	file, err := os.Open(k.path)
+	if os.IsNotExist(err) {
+		return known, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			known[fields[0]] = fields[1]
+		}
+	}
+	return known, scanner.Err()
+}
+
+// append はフィンガープリントをファイルに追記する内部メソッド
+func (k *KnownHosts) append(address, fingerprint string) error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(k.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s %s\n", address, fingerprint)
+	return err
+}
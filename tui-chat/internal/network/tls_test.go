@@ -0,0 +1,66 @@
+package network
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKnownHostsCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	known := NewKnownHosts(path)
+
+	first, err := known.Check("example.com:8080", "fp-1")
+	if err != nil || !first {
+		t.Fatalf("初回の Check = (%v, %v), want (true, nil)", first, err)
+	}
+	first, err = known.Check("example.com:8080", "fp-1")
+	if err != nil || first {
+		t.Fatalf("同じ証明書の Check = (%v, %v), want (false, nil)", first, err)
+	}
+
+	// 記録と異なる証明書は拒否し、記録も書き換えない
+	if _, err := known.Check("example.com:8080", "fp-2"); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("異なる証明書の Check error = %v, want %v", err, ErrFingerprintMismatch)
+	}
+	if _, err := NewKnownHosts(path).Check("example.com:8080", "fp-1"); err != nil {
+		t.Errorf("記録が書き換えられました: %v", err)
+	}
+
+	// アドレスごとに記録する
+	if first, err := known.Check("example.com:9090", "fp-2"); err != nil || !first {
+		t.Errorf("別のアドレスの Check = (%v, %v), want (true, nil)", first, err)
+	}
+}
+
+func TestTOFURejectsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	_, address := startTestServer(t, Security{TLS: TLSOptions{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	}})
+	clientSecurity := Security{TLS: TLSOptions{
+		Enabled:        true,
+		Verify:         VerifyTOFU,
+		KnownHostsFile: filepath.Join(dir, "known_hosts"),
+	}}
+
+	// 初回は証明書を記録して接続する
+	client := connectTestClient(t, address, clientSecurity)
+	client.Close()
+
+	// 別の証明書が記録されたアドレスには接続しない
+	if err := os.WriteFile(clientSecurity.TLS.KnownHostsFile, []byte(address+" "+strings.Repeat("00", 32)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	other := NewTCPClient()
+	other.SetSecurity(clientSecurity)
+	err := other.Connect(address)
+	other.Close()
+	if !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("Connect error = %v, want %v", err, ErrFingerprintMismatch)
+	}
+}
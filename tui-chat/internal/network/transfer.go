@@ -0,0 +1,270 @@
+package network
+
+import (
+	"errors"
+	"time"
+)
+
+// FileChunkSize は1つの断片に入れるファイルのバイト数
+// Base64で膨らんでもMaxFrameSizeに十分収まり、チャットのメッセージを長く待たせない大きさにする
+const FileChunkSize = 64 * 1024
+
+// FilePort はファイル転送に対応したネットワークコンポーネントのインターフェース
+// 申し出への応答や届いた断片はEventPortのイベントとして通知される
+type FilePort interface {
+	// SendFileOffer は参加中のルームにファイル送信を申し出るメソッド
+	SendFileOffer(offer FileOffer) error
+
+	// SendFileAccept は届いた申し出に応答するメソッド
+	SendFileAccept(accept FileAccept) error
+
+	// SendFileChunk は受け取りを承諾したメンバーにファイルの断片を送るメソッド
+	SendFileChunk(chunk FileChunk) error
+
+	// SendFileComplete はファイルを送り終えたことを知らせるメソッド
+	SendFileComplete(complete FileComplete) error
+}
+
+// FileOffer はファイル送信の申し出に使用する構造体（送信時はサーバーが送信者を設定する）
+type FileOffer struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Size     int64    `json:"size"`
+	Checksum string   `json:"checksum"` // ファイル全体のSHA-256（16進数）
+	From     UserInfo `json:"from"`
+}
+
+// FileAccept は申し出への応答に使用する構造体（送信時はサーバーが応答者を設定する）
+type FileAccept struct {
+	ID       string   `json:"id"`
+	Accepted bool     `json:"accepted"`
+	Reason   string   `json:"reason,omitempty"` // 拒否した理由
+	From     UserInfo `json:"from"`
+}
+
+// FileChunk はファイルの断片に使用する構造体
+type FileChunk struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset"`
+	Data   []byte `json:"data"`
+}
+
+// FileComplete はファイル送信の完了に使用する構造体
+type FileComplete struct {
+	ID string `json:"id"`
+}
+
+// transfer はハブが中継しているファイル転送
+// 断片は受け取りを承諾したメンバーにだけ送る
+type transfer struct {
+	from      *peer // nilならサーバーのユーザーが送信者
+	receivers map[*peer]struct{}
+	host      bool // サーバーのユーザーが受け取りを承諾したか
+}
+
+// relayFileOffer はファイル送信の申し出を同じルームのファイル転送に対応したメンバーに配信するメソッド
+// fromがnilならサーバーのユーザーからの申し出として扱う
+func (s *TCPServer) relayFileOffer(from *peer, offer FileOffer) {
+	s.peersMutex.Lock()
+	room := s.room
+	offer.From = s.hostUser
+	if from != nil {
+		room = from.room
+		offer.From = from.user
+	}
+	// ユーザー情報を登録していない送信者や、IDが重複する申し出は中継しない
+	if _, ok := s.transfers[offer.ID]; ok || offer.ID == "" || offer.From.ID == "" {
+		s.peersMutex.Unlock()
+		return
+	}
+	s.transfers[offer.ID] = &transfer{from: from, receivers: make(map[*peer]struct{})}
+	hostInRoom := from != nil && s.room == room
+	targets := s.filePeersInRoom(room, from)
+	s.peersMutex.Unlock()
+
+	netMsg := NetworkMessage{Type: TypeFileOffer, Payload: offer}
+	for _, p := range targets {
+		s.send(p, netMsg)
+	}
+	if hostInRoom {
+		s.emit(Event{Type: EventFileOffer, Room: room, Offer: offer})
+	}
+}
+
+// routeFileAccept は申し出への応答を送信者に届け、承諾したメンバーを記録するメソッド
+// fromがnilならサーバーのユーザーからの応答として扱う
+func (s *TCPServer) routeFileAccept(from *peer, accept FileAccept) {
+	s.peersMutex.Lock()
+	t, ok := s.transfers[accept.ID]
+	if !ok || t.from == from {
+		s.peersMutex.Unlock()
+		return
+	}
+	if from == nil {
+		accept.From = s.hostUser
+		t.host = t.host || accept.Accepted
+	} else {
+		accept.From = from.user
+		if accept.Accepted {
+			t.receivers[from] = struct{}{}
+		}
+	}
+	sender := t.from
+	s.peersMutex.Unlock()
+
+	if sender == nil {
+		s.emit(Event{Type: EventFileAccept, Accept: accept})
+		return
+	}
+	s.send(sender, NetworkMessage{Type: TypeFileAccept, Payload: accept})
+}
+
+// relayFile はファイルの断片または完了の通知を、受け取りを承諾したメンバーに中継するメソッド
+// 送信者以外からのものは中継しない。完了を中継したら転送の記録を消す
+func (s *TCPServer) relayFile(from *peer, id string, netMsg NetworkMessage, event Event) {
+	s.peersMutex.Lock()
+	t, ok := s.transfers[id]
+	if !ok || t.from != from {
+		s.peersMutex.Unlock()
+		return
+	}
+	targets := make([]*peer, 0, len(t.receivers))
+	for p := range t.receivers {
+		targets = append(targets, p)
+	}
+	host := t.host
+	if netMsg.Type == TypeFileComplete {
+		delete(s.transfers, id)
+	}
+	s.peersMutex.Unlock()
+
+	for _, p := range targets {
+		s.sendWait(p, netMsg)
+	}
+	if host {
+		s.emit(event)
+	}
+}
+
+// sendWait はキューに空きができるまで待ってクライアントにメッセージを送るメソッド
+// 断片を落とさないよう、送信者の受信を止めて速度を合わせる。待ちきれないクライアントは切断する
+func (s *TCPServer) sendWait(p *peer, netMsg NetworkMessage) {
+	timer := time.NewTimer(handshakeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-p.done:
+	case p.sendChan <- netMsg:
+	case <-timer.C:
+		s.removePeer(p)
+	}
+}
+
+// forgetTransfers は切断したクライアントをファイル転送の記録から取り除く内部メソッド
+// 切断したクライアントが送信中だった転送は記録から消して返す
+// 呼び出し側でpeersMutexを保持すること
+func (s *TCPServer) forgetTransfers(p *peer) map[string]*transfer {
+	abandoned := make(map[string]*transfer)
+	for id, t := range s.transfers {
+		if t.from == p {
+			delete(s.transfers, id)
+			abandoned[id] = t
+			continue
+		}
+		delete(t.receivers, p)
+	}
+	return abandoned
+}
+
+// abandonTransfers は送信者が切断した転送の完了を受信者に知らせるメソッド
+// 受信者は受け取った大きさが足りないことから、途中で途切れたと判断して破棄する
+func (s *TCPServer) abandonTransfers(transfers map[string]*transfer) {
+	for id, t := range transfers {
+		complete := FileComplete{ID: id}
+		for p := range t.receivers {
+			s.send(p, NetworkMessage{Type: TypeFileComplete, Payload: complete})
+		}
+		if t.host {
+			s.emit(Event{Type: EventFileComplete, Complete: complete})
+		}
+	}
+}
+
+// filePeersInRoom はルームの参加者のうち、ファイル転送に対応しているクライアントを返す内部メソッド
+// 呼び出し側でpeersMutexを保持すること
+func (s *TCPServer) filePeersInRoom(room string, except *peer) []*peer {
+	var targets []*peer
+	for _, p := range s.peersInRoom(room, except) {
+		if hasCapability(p.capabilities, CapabilityFiles) {
+			targets = append(targets, p)
+		}
+	}
+	return targets
+}
+
+// SendFileOffer はサーバーのユーザーが参加中のルームにファイル送信を申し出るメソッド
+func (s *TCPServer) SendFileOffer(offer FileOffer) error {
+	s.relayFileOffer(nil, offer)
+	return nil
+}
+
+// SendFileAccept はサーバーのユーザーが申し出に応答するメソッド
+func (s *TCPServer) SendFileAccept(accept FileAccept) error {
+	s.routeFileAccept(nil, accept)
+	return nil
+}
+
+// SendFileChunk はサーバーのユーザーが送るファイルの断片を中継するメソッド
+func (s *TCPServer) SendFileChunk(chunk FileChunk) error {
+	s.relayFile(nil, chunk.ID, NetworkMessage{Type: TypeFileChunk, Payload: chunk}, Event{})
+	return nil
+}
+
+// SendFileComplete はサーバーのユーザーがファイルを送り終えたことを知らせるメソッド
+func (s *TCPServer) SendFileComplete(complete FileComplete) error {
+	s.relayFile(nil, complete.ID, NetworkMessage{Type: TypeFileComplete, Payload: complete}, Event{})
+	return nil
+}
+
+// SendFileOffer は参加中のルームにファイル送信を申し出るメソッド
+func (c *TCPClient) SendFileOffer(offer FileOffer) error {
+	if err := c.requireFiles(); err != nil {
+		return err
+	}
+	return c.send(NetworkMessage{Type: TypeFileOffer, Payload: offer})
+}
+
+// SendFileAccept は届いた申し出に応答するメソッド
+func (c *TCPClient) SendFileAccept(accept FileAccept) error {
+	if err := c.requireFiles(); err != nil {
+		return err
+	}
+	return c.send(NetworkMessage{Type: TypeFileAccept, Payload: accept})
+}
+
+// SendFileChunk はファイルの断片を送るメソッド
+func (c *TCPClient) SendFileChunk(chunk FileChunk) error {
+	if err := c.requireFiles(); err != nil {
+		return err
+	}
+	return c.send(NetworkMessage{Type: TypeFileChunk, Payload: chunk})
+}
+
+// SendFileComplete はファイルを送り終えたことを知らせるメソッド
+func (c *TCPClient) SendFileComplete(complete FileComplete) error {
+	if err := c.requireFiles(); err != nil {
+		return err
+	}
+	return c.send(NetworkMessage{Type: TypeFileComplete, Payload: complete})
+}
+
+// requireFiles は接続中で、サーバーがファイル転送に対応していることを確認する内部メソッド
+func (c *TCPClient) requireFiles() error {
+	if c.getStatus() != StatusConnected {
+		return errors.New("クライアントが接続されていません")
+	}
+	if !c.HasCapability(CapabilityFiles) {
+		return errors.New("サーバーがファイル転送に対応していません")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// HistoryPort はチャット履歴の保存先のインターフェース
+type HistoryPort interface {
+	// Append は履歴にメッセージを追加するメソッド
+	Append(key HistoryKey, entry Entry) error
+
+	// Load は新しい順にlimit件までの履歴を古い順で返すメソッド（limitが0なら全件）
+	Load(key HistoryKey, limit int) ([]Entry, error)
+
+	// Search は本文に文字列を含む履歴を古い順で返すメソッド（大文字と小文字は区別しない）
+	Search(key HistoryKey, query string) ([]Entry, error)
+}
+
+// HistoryKey は履歴を区別するキー
+// 同じ名前のルームでも接続先が異なれば別の履歴になる
+type HistoryKey struct {
+	Address string // 接続先またはリッスンしたアドレス
+	Room    string
+}
+
+// Entry は履歴に保存する1件のメッセージ
+type Entry struct {
+	domain.Message
+	SenderName string `json:"sender_name"` // 保存時点の送信者の名前
+}
+
+// Sender は履歴の送信者をユーザーとして返すメソッド
+func (e Entry) Sender() domain.User {
+	return domain.User{ID: e.Message.Sender, Name: e.SenderName}
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// FileHistory はルームごとのJSON Linesファイルに履歴を保存する実装
+// ファイルは <dir>/<アドレス>/<ルーム名>.jsonl に置く
+// アドレスとルーム名の英数字と . _ - 以外のバイトは %XX に置き換えるため、異なるキーが同じファイルになることはない
+type FileHistory struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// NewFileHistory は履歴の保存先ディレクトリを指定してFileHistoryを生成するファクトリ関数
+func NewFileHistory(dir string) *FileHistory {
+	return &FileHistory{dir: dir}
+}
+
+// Append は履歴ファイルにメッセージを追記するメソッド
+func (h *FileHistory) Append(key HistoryKey, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	path, err := h.path(key)
+	if err != nil {
+		return fmt.Errorf("履歴の保存に失敗しました: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("履歴の保存に失敗しました: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("履歴の保存に失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("履歴の保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// Load は新しい順にlimit件までの履歴を古い順で返すメソッド
+func (h *FileHistory) Load(key HistoryKey, limit int) ([]Entry, error) {
+	entries, err := h.readAll(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// Search は本文に文字列を含む履歴を古い順で返すメソッド
+func (h *FileHistory) Search(key HistoryKey, query string) ([]Entry, error) {
+	entries, err := h.readAll(key)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	matches := []Entry{}
+	for _, entry := range entries {
+		if pattern.MatchString(entry.Content) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// readAll は履歴ファイルを全て読み込む内部メソッド
+// 書き込み途中で壊れた行は読み飛ばす
+func (h *FileHistory) readAll(key HistoryKey) ([]Entry, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	path, err := h.path(key)
+	if err != nil {
+		return nil, fmt.Errorf("履歴の読み込みに失敗しました: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("履歴の読み込みに失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("履歴の読み込みに失敗しました: %w", err)
+	}
+	return entries, nil
+}
+
+// path は履歴ファイルのパスを返す内部メソッド
+func (h *FileHistory) path(key HistoryKey) (string, error) {
+	address, err := escapePathComponent(key.Address)
+	if err != nil {
+		return "", err
+	}
+	room, err := escapePathComponent(key.Room)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(h.dir, address, room+".jsonl"), nil
+}
+
+// escapePathComponent はキーをディレクトリ外を指さないファイル名に変換するヘルパー関数
+// % も置き換えるので変換後の名前から元のキーが一意に決まる
+func escapePathComponent(value string) (string, error) {
+	if value == "." || value == ".." {
+		return "", fmt.Errorf("履歴のキーに使えない名前です: %q", value)
+	}
+	return unsafePathChars.ReplaceAllStringFunc(value, func(match string) string {
+		var escaped strings.Builder
+		for i := 0; i < len(match); i++ {
+			fmt.Fprintf(&escaped, "%%%02X", match[i])
+		}
+		return escaped.String()
+	}), nil
+}
+
+// ExportFormat は履歴のエクスポート形式を表す型
+type ExportFormat string
+
+const (
+	// FormatMarkdown はMarkdown形式
+	FormatMarkdown ExportFormat = "md"
+
+	// FormatJSON はJSON形式
+	FormatJSON ExportFormat = "json"
+)
+
+// ParseExportFormat はエクスポート形式を解析する関数
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch ExportFormat(strings.ToLower(value)) {
+	case FormatMarkdown, "markdown":
+		return FormatMarkdown, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("不明なエクスポート形式です: %s（md または json を指定してください）", value)
+	}
+}
+
+// Export は履歴を指定した形式で書き出す関数
+func Export(w io.Writer, format ExportFormat, room string, entries []Entry) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case FormatMarkdown:
+		return exportMarkdown(w, room, entries)
+	default:
+		return fmt.Errorf("不明なエクスポート形式です: %s", format)
+	}
+}
+
+// exportMarkdown は履歴をMarkdownの箇条書きとして書き出す関数
+func exportMarkdown(w io.Writer, room string, entries []Entry) error {
+	if _, err := fmt.Fprintf(w, "# #%s\n\n", room); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		// 複数行のメッセージは箇条書きの中で改行する
+		content := strings.ReplaceAll(entry.Content, "\n", "  \n  ")
+		format := "- %s **%s**: %s\n"
+		if entry.Action {
+			// /me の動作は斜体で表す
+			format = "- %s _%s %s_\n"
+		}
+		if _, err := fmt.Fprintf(w, format,
+			entry.Timestamp.Format("2006-01-02 15:04:05"), entry.SenderName, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// testEntry は履歴のエントリを作るテスト用のヘルパー関数
+func testEntry(id, content string) Entry {
+	return Entry{
+		Message: domain.Message{
+			ID: id, Content: content, Sender: "alice-id", Room: "general",
+			Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		SenderName: "alice",
+	}
+}
+
+// entryIDs はエントリのIDを並べるテスト用のヘルパー関数
+func entryIDs(entries []Entry) []string {
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func TestFileHistoryAppendAndLoad(t *testing.T) {
+	history := NewFileHistory(t.TempDir())
+	key := HistoryKey{Address: "127.0.0.1:8080", Room: "general"}
+	for i := 1; i <= 5; i++ {
+		if err := history.Append(key, testEntry(fmt.Sprintf("m%d", i), "メッセージ")); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{"全件", 0, []string{"m1", "m2", "m3", "m4", "m5"}},
+		{"新しい順に制限", 2, []string{"m4", "m5"}},
+		{"件数より大きい制限", 10, []string{"m1", "m2", "m3", "m4", "m5"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := history.Load(key, test.limit)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if got := entryIDs(entries); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Load(%d) = %v, want %v", test.limit, got, test.want)
+			}
+		})
+	}
+
+	entries, err := history.Load(key, 1)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if sender := entries[0].Sender(); sender.ID != "alice-id" || sender.Name != "alice" {
+		t.Errorf("Sender = %+v, want alice", sender)
+	}
+
+	entries, err = history.Load(HistoryKey{Address: "127.0.0.1:8080", Room: "random"}, 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("履歴のないルームから %d 件読み込みました", len(entries))
+	}
+}
+
+func TestFileHistorySearch(t *testing.T) {
+	history := NewFileHistory(t.TempDir())
+	key := HistoryKey{Address: "localhost:8080", Room: "general"}
+	for _, entry := range []Entry{
+		testEntry("m1", "Hello world"),
+		testEntry("m2", "こんにちは"),
+		testEntry("m3", "hello again"),
+		testEntry("m4", "a.b"),
+	} {
+		if err := history.Append(key, entry); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"HELLO", []string{"m1", "m3"}},
+		{"こんにち", []string{"m2"}},
+		{".", []string{"m4"}},
+		{"missing", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			entries, err := history.Search(key, test.query)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if got := entryIDs(entries); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFileHistorySkipsCorruptLines(t *testing.T) {
+	history := NewFileHistory(t.TempDir())
+	key := HistoryKey{Address: "localhost:8080", Room: "general"}
+	if err := history.Append(key, testEntry("m1", "first")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// 書き込み途中で終了した行を再現する
+	path, err := history.path(key)
+	if err != nil {
+		t.Fatalf("path: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	file.WriteString(`{"id":"broken","content":"tru` + "\n")
+	file.Close()
+
+	if err := history.Append(key, testEntry("m2", "second")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	entries, err := history.Load(key, 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, want := entryIDs(entries), []string{"m1", "m2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Load = %v, want %v", got, want)
+	}
+}
+
+func TestFileHistoryPath(t *testing.T) {
+	dir := t.TempDir()
+	history := NewFileHistory(dir)
+
+	t.Run("ディレクトリの外を指さない", func(t *testing.T) {
+		for _, key := range []HistoryKey{
+			{Address: "..", Room: "general"},
+			{Address: ".", Room: "general"},
+			{Address: "localhost:8080", Room: ".."},
+		} {
+			if _, err := history.path(key); err == nil {
+				t.Errorf("path(%+v) がエラーになりませんでした", key)
+			}
+			if err := history.Append(key, testEntry("m1", "x")); err == nil {
+				t.Errorf("Append(%+v) がエラーになりませんでした", key)
+			}
+		}
+
+		path, err := history.path(HistoryKey{Address: "../../etc", Room: "a/../../b"})
+		if err != nil {
+			t.Fatalf("path: %v", err)
+		}
+		if filepath.Dir(filepath.Dir(path)) != dir {
+			t.Errorf("path = %q, want %q の直下", path, dir)
+		}
+	})
+
+	t.Run("異なるキーは異なるファイル", func(t *testing.T) {
+		keys := []HistoryKey{
+			{Address: "a:b", Room: "general"},
+			{Address: "a_b", Room: "general"},
+			{Address: "a%3Ab", Room: "general"},
+			{Address: "a:b", Room: "ゼネラル"},
+			{Address: "a:b", Room: "____"},
+		}
+		seen := map[string]HistoryKey{}
+		for _, key := range keys {
+			path, err := history.path(key)
+			if err != nil {
+				t.Fatalf("path(%+v): %v", key, err)
+			}
+			if other, ok := seen[path]; ok {
+				t.Errorf("%+v と %+v が同じファイル %q になりました", key, other, path)
+			}
+			seen[path] = key
+		}
+	})
+
+	t.Run("読みやすい名前はそのまま", func(t *testing.T) {
+		path, err := history.path(HistoryKey{Address: "chat.example.com", Room: "general-2_b"})
+		if err != nil {
+			t.Fatalf("path: %v", err)
+		}
+		if want := filepath.Join(dir, "chat.example.com", "general-2_b.jsonl"); path != want {
+			t.Errorf("path = %q, want %q", path, want)
+		}
+	})
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ExportFormat
+		wantErr bool
+	}{
+		{"md", FormatMarkdown, false},
+		{"Markdown", FormatMarkdown, false},
+		{"JSON", FormatJSON, false},
+		{"csv", "", true},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseExportFormat(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseExportFormat(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseExportFormat(%q) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	action := testEntry("m2", "waves")
+	action.Action = true
+	entries := []Entry{testEntry("m1", "line1\nline2"), action}
+
+	t.Run("Markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, FormatMarkdown, "general", entries); err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		want := "# #general\n\n" +
+			"- 2025-01-02 03:04:05 **alice**: line1  \n  line2\n" +
+			"- 2025-01-02 03:04:05 _alice waves_\n"
+		if buf.String() != want {
+			t.Errorf("Export = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, FormatJSON, "general", entries); err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		var decoded []Entry
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, entries) {
+			t.Errorf("Export = %+v, want %+v", decoded, entries)
+		}
+	})
+
+	t.Run("不明な形式", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Export(&buf, "csv", "general", entries)
+		if err == nil || !strings.Contains(err.Error(), "csv") {
+			t.Errorf("Export error = %v, want 不明なエクスポート形式", err)
+		}
+	})
+}
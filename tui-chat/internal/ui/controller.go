@@ -2,26 +2,34 @@ package ui
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"github.com/sakaeshinya/tui-chat/internal/domain"
+	"github.com/sakaeshinya/tui-chat/pkg/utils"
 )
 
 // TUIController はTUIコントローラ
 type TUIController struct {
-	app        *tview.Application
-	chatView   *tview.TextView
-	inputField *tview.InputField
-	statusBar  *tview.TextView
-	rootFlex   *tview.Flex
-	handler    UIEventHandler
-	user       domain.User
-	mode       UIMode
-	modeMutex  sync.RWMutex
-	stopChan   chan struct{}
+	app           *tview.Application
+	chatView      *tview.TextView
+	memberView    *tview.TextView
+	typingView    *tview.TextView
+	progressView  *tview.TextView
+	progress      map[string]string
+	progressOrder []string
+	progressMutex sync.Mutex
+	inputField    *tview.InputField
+	statusBar     *tview.TextView
+	rootFlex      *tview.Flex
+	handler       UIEventHandler
+	user          domain.User
+	mode          UIMode
+	modeMutex     sync.RWMutex
+	stopChan      chan struct{}
 }
 
 // NewTUIController はTUIコントローラを生成するファクトリ関数
@@ -31,6 +39,7 @@ func NewTUIController(handler UIEventHandler, user domain.User) *TUIController {
 		handler:  handler,
 		user:     user,
 		mode:     ModeNormal,
+		progress: make(map[string]string),
 		stopChan: make(chan struct{}),
 	}
 
@@ -50,6 +59,28 @@ func (c *TUIController) setupUI() {
 	c.chatView.SetScrollable(true)
 	c.chatView.SetWordWrap(true)
 
+	// メンバー一覧
+	c.memberView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetChangedFunc(func() {
+			c.app.Draw()
+		})
+	c.memberView.SetBorder(true).SetTitle("メンバー")
+
+	// 入力中の表示
+	c.typingView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetChangedFunc(func() {
+			c.app.Draw()
+		})
+
+	// ファイル転送の進み具合
+	c.progressView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetChangedFunc(func() {
+			c.app.Draw()
+		})
+
 	// 入力領域
 	c.inputField = tview.NewInputField().
 		SetLabel("> ").
@@ -58,11 +89,22 @@ func (c *TUIController) setupUI() {
 			if key == tcell.KeyEnter {
 				text := c.inputField.GetText()
 				if text != "" {
-					c.handler.OnMessageSend(text)
 					c.inputField.SetText("")
+					c.submit(text)
 				}
 			}
 		})
+	c.inputField.SetChangedFunc(func(text string) {
+		c.handler.OnTyping(text)
+	})
+	c.inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			// コマンド名を補完する
+			c.completeCommand()
+			return nil
+		}
+		return event
+	})
 	c.inputField.SetBorder(true).SetTitle("メッセージ入力")
 
 	// ステータスバー
@@ -75,13 +117,19 @@ func (c *TUIController) setupUI() {
 	helpText := tview.NewTextView().
 		SetDynamicColors(true).
 		SetTextAlign(tview.AlignCenter)
-	helpText.SetText("[yellow]Ctrl+S[white]: サーバー開始 | [yellow]Ctrl+C[white]: クライアント接続 | [yellow]Ctrl+D[white]: 切断 | [yellow]Ctrl+Q[white]: 終了")
+	helpText.SetText("[yellow]Ctrl+S[white]: サーバー開始 | [yellow]Ctrl+C[white]: クライアント接続 | [yellow]Ctrl+D[white]: 切断 | [yellow]PgUp/PgDn[white]: スクロール | [yellow]Ctrl+Q[white]: 終了")
 	helpText.SetBorder(false)
 
 	// レイアウト設定
+	body := tview.NewFlex().
+		AddItem(c.chatView, 0, 1, false).
+		AddItem(c.memberView, 24, 0, false)
+
 	c.rootFlex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(c.chatView, 0, 1, false).
+		AddItem(body, 0, 1, false).
+		AddItem(c.progressView, 1, 0, false).
+		AddItem(c.typingView, 1, 0, false).
 		AddItem(c.inputField, 3, 0, true).
 		AddItem(c.statusBar, 3, 0, false).
 		AddItem(helpText, 1, 0, false)
@@ -105,6 +153,14 @@ func (c *TUIController) setupUI() {
 			// 終了
 			c.handler.OnQuit()
 			return nil
+		case tcell.KeyPgUp:
+			// 履歴を遡る
+			c.scrollChat(-1)
+			return nil
+		case tcell.KeyPgDn:
+			// 新しい方へ戻る
+			c.scrollChat(1)
+			return nil
 		}
 		return event
 	})
@@ -162,12 +218,203 @@ func (c *TUIController) showClientDialog() {
 	c.app.SetRoot(form, true)
 }
 
-// DisplayMessage はメッセージを表示するメソッド
-func (c *TUIController) DisplayMessage(msg domain.Message) {
+// submit は入力された行をコマンドまたはメッセージとしてハンドラに渡すメソッド
+func (c *TUIController) submit(text string) {
+	dispatchInput(c.handler, text)
+}
+
+// completeCommand は入力途中のコマンド名を補完するメソッド
+// 候補が1つなら補完し、複数なら共通する部分まで補完して候補を表示する
+func (c *TUIController) completeCommand() {
+	handler, ok := c.handler.(CommandHandler)
+	text := c.inputField.GetText()
+	if !ok || !strings.HasPrefix(text, "/") || strings.Contains(text, " ") {
+		return
+	}
+
+	candidates := handler.CompleteCommand(text)
+	switch len(candidates) {
+	case 0:
+		return
+	case 1:
+		c.inputField.SetText(candidates[0] + " ")
+		return
+	}
+
+	prefix := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(text) {
+		c.inputField.SetText(prefix)
+		return
+	}
+	c.DisplayNotice("候補: " + strings.Join(candidates, " "))
+}
+
+// DisplayMessage はメッセージを送信者の名前とともに表示するメソッド
+// /me で送られた動作は「* 名前 内容」の形で表示する
+func (c *TUIController) DisplayMessage(msg domain.Message, sender domain.User) {
+	if msg.Action {
+		c.chatView.Write([]byte(fmt.Sprintf("[%s] [fuchsia]* %s %s[-]\n",
+			msg.FormattedTime(),
+			tview.Escape(sender.Name),
+			tview.Escape(msg.Content))))
+		return
+	}
+
 	c.chatView.Write([]byte(fmt.Sprintf("[%s] %s: %s\n",
 		msg.FormattedTime(),
-		msg.Sender,
-		msg.Content)))
+		tview.Escape(sender.Name),
+		tview.Escape(msg.Content))))
+}
+
+// DisplayDirectMessage はダイレクトメッセージを「(DM) 送信者 → 宛先: 内容」の形で表示するメソッド
+func (c *TUIController) DisplayDirectMessage(msg domain.Message, sender, recipient domain.User) {
+	c.chatView.Write([]byte(fmt.Sprintf("[%s] [teal](DM) %s → %s:[-] %s\n",
+		msg.FormattedTime(),
+		tview.Escape(sender.Name),
+		tview.Escape(recipient.Name),
+		tview.Escape(msg.Content))))
+}
+
+// ClearMessages はチャット表示領域を空にするメソッド
+func (c *TUIController) ClearMessages() {
+	c.chatView.Clear()
+}
+
+// DisplaySearchResult は検索に一致したメッセージを、一致した部分を強調して表示するメソッド
+func (c *TUIController) DisplaySearchResult(msg domain.Message, sender domain.User, query string) {
+	c.chatView.Write([]byte(fmt.Sprintf("[gray]%s[-] %s: %s\n",
+		msg.Timestamp.Format("2006-01-02 15:04:05"),
+		tview.Escape(sender.Name),
+		highlight(msg.Content, query))))
+}
+
+// highlight は文字列中の一致した部分を強調する関数（大文字と小文字は区別しない）
+func highlight(text, query string) string {
+	if query == "" {
+		return tview.Escape(text)
+	}
+
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	var result strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(text, -1) {
+		result.WriteString(tview.Escape(text[last:match[0]]))
+		result.WriteString("[black:yellow]")
+		result.WriteString(tview.Escape(text[match[0]:match[1]]))
+		result.WriteString("[-:-]")
+		last = match[1]
+	}
+	result.WriteString(tview.Escape(text[last:]))
+	return result.String()
+}
+
+// scrollChat はチャット表示領域を1画面分スクロールする内部メソッド
+// 末尾まで戻ったら新しいメッセージへの追従を再開する
+func (c *TUIController) scrollChat(direction int) {
+	_, _, _, height := c.chatView.GetInnerRect()
+	if height < 1 {
+		height = 1
+	}
+	row, _ := c.chatView.GetScrollOffset()
+
+	row += direction * height
+	if row < 0 {
+		row = 0
+	}
+	if row+height >= c.chatView.GetWrappedLineCount() {
+		c.chatView.ScrollToEnd()
+		return
+	}
+	c.chatView.ScrollTo(row, 0)
+}
+
+// UpdateMembers はメンバー一覧を更新するメソッド
+func (c *TUIController) UpdateMembers(users []domain.User) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "[yellow]%d人[-]\n", len(users))
+	for _, user := range users {
+		if user.ID == c.user.ID {
+			fmt.Fprintf(&text, "[green]%s (自分)[-]\n", tview.Escape(user.Name))
+			continue
+		}
+		fmt.Fprintf(&text, "%s\n", tview.Escape(user.Name))
+	}
+
+	c.memberView.SetText(text.String())
+}
+
+// ShowTyping は入力中のメンバーの名前を表示するメソッド
+func (c *TUIController) ShowTyping(names []string) {
+	if len(names) == 0 {
+		c.typingView.SetText("")
+		return
+	}
+	c.typingView.SetText(fmt.Sprintf("[gray]%s が入力中…[-]", tview.Escape(strings.Join(names, "、"))))
+}
+
+// ShowProgress はファイル転送の進み具合をプログレスバーで表示するメソッド
+// 複数の転送は始まった順に並べて表示する
+func (c *TUIController) ShowProgress(id, label string, done, total int64) {
+	const width = 20
+	filled := width
+	percent := 100
+	if total > 0 {
+		filled = int(done * width / total)
+		percent = int(done * 100 / total)
+	}
+
+	text := fmt.Sprintf("%s [green]%s[gray]%s[-] %d%% (%s/%s)",
+		tview.Escape(label),
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled),
+		percent, utils.FormatSize(done), utils.FormatSize(total))
+
+	c.progressMutex.Lock()
+	if _, ok := c.progress[id]; !ok {
+		c.progressOrder = append(c.progressOrder, id)
+	}
+	c.progress[id] = text
+	c.progressMutex.Unlock()
+
+	c.renderProgress()
+}
+
+// HideProgress はファイル転送の進み具合の表示を消すメソッド
+func (c *TUIController) HideProgress(id string) {
+	c.progressMutex.Lock()
+	if _, ok := c.progress[id]; ok {
+		delete(c.progress, id)
+		for i, existing := range c.progressOrder {
+			if existing == id {
+				c.progressOrder = append(c.progressOrder[:i], c.progressOrder[i+1:]...)
+				break
+			}
+		}
+	}
+	c.progressMutex.Unlock()
+
+	c.renderProgress()
+}
+
+// renderProgress は進行中の転送を1行にまとめて表示する内部メソッド
+func (c *TUIController) renderProgress() {
+	c.progressMutex.Lock()
+	lines := make([]string, 0, len(c.progressOrder))
+	for _, id := range c.progressOrder {
+		lines = append(lines, c.progress[id])
+	}
+	c.progressMutex.Unlock()
+
+	c.progressView.SetText(strings.Join(lines, " | "))
+}
+
+// DisplayNotice はシステムからのお知らせを表示するメソッド
+func (c *TUIController) DisplayNotice(text string) {
+	c.chatView.Write([]byte(fmt.Sprintf("[yellow]* %s[-]\n", tview.Escape(text))))
 }
 
 // GetInput は入力を取得するメソッド
@@ -192,6 +439,7 @@ func (c *TUIController) Start() error {
 	// 初期メッセージ
 	c.chatView.Write([]byte("[yellow]TUIチャットアプリケーションへようこそ！[-]\n"))
 	c.chatView.Write([]byte("[yellow]Ctrl+S[white]でサーバーを起動するか、[yellow]Ctrl+C[white]でサーバーに接続してください。\n"))
+	c.chatView.Write([]byte("[yellow]/help[white]でコマンドの一覧を表示します。コマンド名は[yellow]Tab[white]で補完できます。\n"))
 	c.UpdateStatus(fmt.Sprintf("ユーザー: %s | モード: %s", c.user.Name, c.getMode().String()))
 
 	return c.app.Run()
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// maxInputLine は標準入力から読み込む1行の最大バイト数
+const maxInputLine = 1 << 20
+
+// HeadlessController は端末の画面を使わずに標準入出力で動作するコントローラ
+// 入力の1行をメッセージ（"/" で始まればコマンド）として送り、表示する内容は1行1つのJSONで出力する
+// スクリプトからの通知の投稿やボットに使う
+type HeadlessController struct {
+	input       io.Reader
+	output      *json.Encoder
+	outputMutex sync.Mutex
+	handler     UIEventHandler
+	user        domain.User
+	lastInput   string
+	inputMutex  sync.Mutex
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+// HeadlessUser は出力するJSONのユーザー
+type HeadlessUser struct {
+	ID   domain.UserID `json:"id"`
+	Name string        `json:"name"`
+}
+
+// HeadlessEvent は出力するJSONの1行
+// Typeによって使う項目が変わり、使わない項目は省略する
+//
+//   - message: ID, Room, Sender, Content, Action, Timestamp
+//   - direct: ID, Sender, Recipient, Content, Timestamp
+//   - search_result: ID, Sender, Content, Timestamp, Query
+//   - notice / status / error: Text
+//   - members: Members
+//   - typing: Names（空なら入力中のメンバーはいない）
+//   - progress: ID, Text（ファイル名などの説明）, Done, Total
+//   - progress_end: ID
+//   - clear: なし
+type HeadlessEvent struct {
+	Type      string         `json:"type"`
+	ID        string         `json:"id,omitempty"`
+	Room      string         `json:"room,omitempty"`
+	Sender    *HeadlessUser  `json:"sender,omitempty"`
+	Recipient *HeadlessUser  `json:"recipient,omitempty"`
+	Content   string         `json:"content,omitempty"`
+	Action    bool           `json:"action,omitempty"`
+	Timestamp *time.Time     `json:"timestamp,omitempty"`
+	Query     string         `json:"query,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Members   []HeadlessUser `json:"members,omitempty"`
+	Names     []string       `json:"names,omitempty"`
+	Done      int64          `json:"done,omitempty"`
+	Total     int64          `json:"total,omitempty"`
+}
+
+// NewHeadlessController は入力と出力を指定してヘッドレスコントローラを生成するファクトリ関数
+func NewHeadlessController(handler UIEventHandler, user domain.User, input io.Reader, output io.Writer) *HeadlessController {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	return &HeadlessController{
+		input:    input,
+		output:   encoder,
+		handler:  handler,
+		user:     user,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// SetHandler はイベントハンドラを設定するメソッド
+func (c *HeadlessController) SetHandler(handler UIEventHandler) {
+	c.handler = handler
+}
+
+// Start は入力を1行ずつハンドラに渡すメソッド
+// 入力が終わるか、Stopが呼ばれると戻る
+func (c *HeadlessController) Start() error {
+	c.UpdateStatus(fmt.Sprintf("ユーザー: %s", c.user.Name))
+
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(c.input)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxInputLine)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-c.stopChan:
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-errs:
+					return err
+				default:
+					return nil
+				}
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			c.inputMutex.Lock()
+			c.lastInput = line
+			c.inputMutex.Unlock()
+			dispatchInput(c.handler, line)
+		}
+	}
+}
+
+// Stop は入力の読み込みをやめてStartから戻るメソッド
+func (c *HeadlessController) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+	})
+}
+
+// GetInput は最後に読み込んだ入力を返すメソッド
+func (c *HeadlessController) GetInput() (string, error) {
+	c.inputMutex.Lock()
+	defer c.inputMutex.Unlock()
+	return c.lastInput, nil
+}
+
+// DisplayMessage はメッセージを出力するメソッド
+func (c *HeadlessController) DisplayMessage(msg domain.Message, sender domain.User) {
+	c.emit(HeadlessEvent{
+		Type:      "message",
+		ID:        msg.ID,
+		Room:      msg.Room,
+		Sender:    headlessUser(sender),
+		Content:   msg.Content,
+		Action:    msg.Action,
+		Timestamp: &msg.Timestamp,
+	})
+}
+
+// DisplayDirectMessage は復号したダイレクトメッセージを出力するメソッド
+func (c *HeadlessController) DisplayDirectMessage(msg domain.Message, sender, recipient domain.User) {
+	c.emit(HeadlessEvent{
+		Type:      "direct",
+		ID:        msg.ID,
+		Sender:    headlessUser(sender),
+		Recipient: headlessUser(recipient),
+		Content:   msg.Content,
+		Timestamp: &msg.Timestamp,
+	})
+}
+
+// DisplaySearchResult は検索に一致したメッセージを出力するメソッド
+func (c *HeadlessController) DisplaySearchResult(msg domain.Message, sender domain.User, query string) {
+	c.emit(HeadlessEvent{
+		Type:      "search_result",
+		ID:        msg.ID,
+		Sender:    headlessUser(sender),
+		Content:   msg.Content,
+		Timestamp: &msg.Timestamp,
+		Query:     query,
+	})
+}
+
+// DisplayNotice はシステムからのお知らせを出力するメソッド
+func (c *HeadlessController) DisplayNotice(text string) {
+	c.emit(HeadlessEvent{Type: "notice", Text: text})
+}
+
+// ClearMessages は表示を空にする操作を出力するメソッド
+func (c *HeadlessController) ClearMessages() {
+	c.emit(HeadlessEvent{Type: "clear"})
+}
+
+// ShowProgress はファイル転送の進み具合を出力するメソッド
+func (c *HeadlessController) ShowProgress(id, label string, done, total int64) {
+	c.emit(HeadlessEvent{Type: "progress", ID: id, Text: label, Done: done, Total: total})
+}
+
+// HideProgress はファイル転送の進み具合の表示が終わったことを出力するメソッド
+func (c *HeadlessController) HideProgress(id string) {
+	c.emit(HeadlessEvent{Type: "progress_end", ID: id})
+}
+
+// UpdateMembers はメンバー一覧を出力するメソッド
+func (c *HeadlessController) UpdateMembers(users []domain.User) {
+	members := make([]HeadlessUser, 0, len(users))
+	for _, user := range users {
+		members = append(members, *headlessUser(user))
+	}
+	c.emit(HeadlessEvent{Type: "members", Members: members})
+}
+
+// ShowTyping は入力中のメンバーの名前を出力するメソッド
+func (c *HeadlessController) ShowTyping(names []string) {
+	c.emit(HeadlessEvent{Type: "typing", Names: names})
+}
+
+// UpdateStatus はステータスを出力するメソッド
+func (c *HeadlessController) UpdateStatus(status string) {
+	c.emit(HeadlessEvent{Type: "status", Text: status})
+}
+
+// ShowError はエラーを出力するメソッド
+func (c *HeadlessController) ShowError(err error) {
+	c.emit(HeadlessEvent{Type: "error", Text: err.Error()})
+}
+
+// emit はイベントをJSONの1行として出力する内部メソッド
+// 複数のゴルーチンから呼ばれても行が混ざらないようにする
+func (c *HeadlessController) emit(event HeadlessEvent) {
+	c.outputMutex.Lock()
+	defer c.outputMutex.Unlock()
+	if err := c.output.Encode(event); err != nil {
+		// 出力先が閉じられた場合は読み込みもやめる
+		c.Stop()
+	}
+}
+
+// headlessUser はユーザーを出力用の形に変換するヘルパー関数
+func headlessUser(user domain.User) *HeadlessUser {
+	return &HeadlessUser{ID: user.ID, Name: user.Name}
+}
@@ -0,0 +1,242 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sakaeshinya/tui-chat/internal/domain"
+)
+
+// recordingHandler は受け取った入力を記録するテスト用のイベントハンドラ
+type recordingHandler struct {
+	mutex    sync.Mutex
+	messages []string
+	commands []string
+}
+
+func (h *recordingHandler) OnMessageSend(content string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.messages = append(h.messages, content)
+}
+
+func (h *recordingHandler) OnTyping(text string)     {}
+func (h *recordingHandler) OnConnect(address string) {}
+func (h *recordingHandler) OnListen(address string)  {}
+func (h *recordingHandler) OnDisconnect()            {}
+func (h *recordingHandler) OnQuit()                  {}
+
+// commandHandler はスラッシュコマンドも記録するテスト用のイベントハンドラ
+type commandHandler struct {
+	recordingHandler
+}
+
+func (h *commandHandler) OnCommand(name, args string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.commands = append(h.commands, name+"|"+args)
+}
+
+func (h *commandHandler) CompleteCommand(prefix string) []string { return nil }
+
+// decodeEvents は出力されたJSONの行を読み込むテスト用のヘルパー関数
+func decodeEvents(t *testing.T, output []byte) []HeadlessEvent {
+	t.Helper()
+
+	var events []HeadlessEvent
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var event HeadlessEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("出力が1行1つのJSONではありません: %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// failingWriter は書き込みに失敗する出力先
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("closed pipe") }
+
+func TestHeadlessDispatchesInput(t *testing.T) {
+	input := "hello\n\n   \n  /join dev  \n//literal\n/me waves\n/\n"
+	user := domain.User{ID: "alice-id", Name: "alice"}
+
+	t.Run("コマンドに対応するハンドラ", func(t *testing.T) {
+		handler := &commandHandler{}
+		var output bytes.Buffer
+		controller := NewHeadlessController(handler, user, strings.NewReader(input), &output)
+		if err := controller.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+
+		if want := []string{"hello", "/literal", "/"}; !reflect.DeepEqual(handler.messages, want) {
+			t.Errorf("メッセージ = %q, want %q", handler.messages, want)
+		}
+		if want := []string{"join|dev", "me|waves"}; !reflect.DeepEqual(handler.commands, want) {
+			t.Errorf("コマンド = %q, want %q", handler.commands, want)
+		}
+		if got, _ := controller.GetInput(); got != "/" {
+			t.Errorf("GetInput = %q, want %q", got, "/")
+		}
+
+		events := decodeEvents(t, output.Bytes())
+		if len(events) != 1 || events[0].Type != "status" || !strings.Contains(events[0].Text, "alice") {
+			t.Errorf("出力 = %+v, want ユーザー名を含むstatus", events)
+		}
+	})
+
+	t.Run("コマンドに対応しないハンドラ", func(t *testing.T) {
+		handler := &recordingHandler{}
+		controller := NewHeadlessController(handler, user, strings.NewReader(input), io.Discard)
+		if err := controller.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+
+		// コマンドもメッセージとして送る
+		if want := []string{"hello", "/join dev", "/literal", "/me waves", "/"}; !reflect.DeepEqual(handler.messages, want) {
+			t.Errorf("メッセージ = %q, want %q", handler.messages, want)
+		}
+	})
+}
+
+func TestHeadlessOutput(t *testing.T) {
+	var output bytes.Buffer
+	alice := domain.User{ID: "alice-id", Name: "alice"}
+	bob := domain.User{ID: "bob-id", Name: "bob"}
+	controller := NewHeadlessController(&recordingHandler{}, alice, strings.NewReader(""), &output)
+
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := domain.Message{ID: "msg-id", Content: "<b>こんにちは</b>", Sender: bob.ID, Room: "general", Action: true, Timestamp: timestamp}
+	controller.DisplayMessage(msg, bob)
+	controller.DisplayDirectMessage(msg, bob, alice)
+	controller.DisplaySearchResult(msg, bob, "こんにち")
+	controller.DisplayNotice("お知らせ")
+	controller.ClearMessages()
+	controller.ShowProgress("file-id", "data.bin", 10, 100)
+	controller.HideProgress("file-id")
+	controller.UpdateMembers([]domain.User{alice, bob})
+	controller.ShowTyping([]string{"bob"})
+	controller.ShowTyping(nil)
+	controller.UpdateStatus("接続中")
+	controller.ShowError(errors.New("失敗しました"))
+
+	// HTMLの文字はエスケープしない
+	if !strings.Contains(output.String(), "<b>こんにちは</b>") {
+		t.Errorf("本文がエスケープされました: %s", output.String())
+	}
+
+	bobJSON := &HeadlessUser{ID: bob.ID, Name: bob.Name}
+	aliceJSON := &HeadlessUser{ID: alice.ID, Name: alice.Name}
+	want := []HeadlessEvent{
+		{Type: "message", ID: "msg-id", Room: "general", Sender: bobJSON, Content: msg.Content, Action: true, Timestamp: &timestamp},
+		{Type: "direct", ID: "msg-id", Sender: bobJSON, Recipient: aliceJSON, Content: msg.Content, Timestamp: &timestamp},
+		{Type: "search_result", ID: "msg-id", Sender: bobJSON, Content: msg.Content, Timestamp: &timestamp, Query: "こんにち"},
+		{Type: "notice", Text: "お知らせ"},
+		{Type: "clear"},
+		{Type: "progress", ID: "file-id", Text: "data.bin", Done: 10, Total: 100},
+		{Type: "progress_end", ID: "file-id"},
+		{Type: "members", Members: []HeadlessUser{*aliceJSON, *bobJSON}},
+		{Type: "typing", Names: []string{"bob"}},
+		{Type: "typing"},
+		{Type: "status", Text: "接続中"},
+		{Type: "error", Text: "失敗しました"},
+	}
+	got := decodeEvents(t, output.Bytes())
+	if len(got) != len(want) {
+		t.Fatalf("出力 = %d 行, want %d 行", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("%d 行目 = %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestHeadlessConcurrentOutput(t *testing.T) {
+	var output bytes.Buffer
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	controller := NewHeadlessController(&recordingHandler{}, user, strings.NewReader(""), &output)
+
+	// 複数のゴルーチンから出力しても行が混ざらない
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				controller.DisplayNotice(strings.Repeat("x", 1000))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(decodeEvents(t, output.Bytes())); got != 200 {
+		t.Errorf("出力 = %d 行, want 200", got)
+	}
+}
+
+func TestHeadlessStop(t *testing.T) {
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	input, writer := io.Pipe()
+	defer writer.Close()
+	handler := &recordingHandler{}
+	controller := NewHeadlessController(handler, user, input, io.Discard)
+
+	done := make(chan error, 1)
+	go func() { done <- controller.Start() }()
+
+	writer.Write([]byte("first\n"))
+
+	// 入力が続いていてもStopで戻る
+	controller.Stop()
+	controller.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop の後も Start が戻りません")
+	}
+}
+
+func TestHeadlessStopsWhenOutputFails(t *testing.T) {
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	input, writer := io.Pipe()
+	defer writer.Close()
+	controller := NewHeadlessController(&recordingHandler{}, user, input, failingWriter{})
+
+	// 出力先が閉じられたら読み込みもやめる
+	done := make(chan error, 1)
+	go func() { done <- controller.Start() }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("出力に失敗しても Start が戻りません")
+	}
+}
+
+func TestHeadlessRejectsLongLine(t *testing.T) {
+	user := domain.User{ID: "alice-id", Name: "alice"}
+	handler := &recordingHandler{}
+	input := "short\n" + strings.Repeat("x", maxInputLine+1) + "\n"
+	controller := NewHeadlessController(handler, user, strings.NewReader(input), io.Discard)
+
+	if err := controller.Start(); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("Start error = %v, want %v", err, bufio.ErrTooLong)
+	}
+	if want := []string{"short"}; !reflect.DeepEqual(handler.messages, want) {
+		t.Errorf("メッセージ = %q, want %q", handler.messages, want)
+	}
+}
@@ -1,17 +1,43 @@
 package ui
 
 import (
+	"strings"
+
 	"github.com/sakaeshinya/tui-chat/internal/domain"
 )
 
 // UIPort はユーザーインターフェースのインターフェース
 type UIPort interface {
-	// DisplayMessage はメッセージを表示するメソッド
-	DisplayMessage(msg domain.Message)
+	// DisplayMessage はメッセージを送信者の名前とともに表示するメソッド
+	DisplayMessage(msg domain.Message, sender domain.User)
 
 	// GetInput は入力を取得するメソッド
 	GetInput() (string, error)
 
+	// DisplayDirectMessage は復号したダイレクトメッセージを送信者と宛先の名前とともに表示するメソッド
+	DisplayDirectMessage(msg domain.Message, sender, recipient domain.User)
+
+	// DisplayNotice はシステムからのお知らせを表示するメソッド
+	DisplayNotice(text string)
+
+	// DisplaySearchResult は検索に一致したメッセージを、一致した部分を強調して表示するメソッド
+	DisplaySearchResult(msg domain.Message, sender domain.User, query string)
+
+	// ClearMessages はメッセージ表示領域を空にするメソッド
+	ClearMessages()
+
+	// ShowProgress はファイル転送の進み具合を表示するメソッド（idごとに1つ表示する）
+	ShowProgress(id, label string, done, total int64)
+
+	// HideProgress はファイル転送の進み具合の表示を消すメソッド
+	HideProgress(id string)
+
+	// UpdateMembers は参加中のルームのメンバー一覧を更新するメソッド
+	UpdateMembers(users []domain.User)
+
+	// ShowTyping は入力中のメンバーの名前を表示するメソッド（空なら表示を消す）
+	ShowTyping(names []string)
+
 	// UpdateStatus はステータスを更新するメソッド
 	UpdateStatus(status string)
 
@@ -30,6 +56,9 @@ type UIEventHandler interface {
 	// OnMessageSend はメッセージ送信イベントを処理するメソッド
 	OnMessageSend(content string)
 
+	// OnTyping は入力欄の内容が変わったイベントを処理するメソッド
+	OnTyping(text string)
+
 	// OnConnect は接続イベントを処理するメソッド
 	OnConnect(address string)
 
@@ -43,6 +72,44 @@ type UIEventHandler interface {
 	OnQuit()
 }
 
+// CommandHandler はスラッシュコマンドに対応するハンドラのインターフェース
+// UIEventHandlerがこれを実装していれば、UIはコマンドをOnMessageSendより先に渡し、補完にも使う
+type CommandHandler interface {
+	// OnCommand はコマンドを実行するメソッド（nameは先頭の "/" を除いたコマンド名）
+	OnCommand(name, args string)
+
+	// CompleteCommand は入力途中のコマンド名に一致するコマンドを "/名前" の形で返すメソッド
+	CompleteCommand(prefix string) []string
+}
+
+// ParseCommand は入力がスラッシュコマンドならコマンド名と引数を返す関数
+// "//" で始まる入力はコマンドではなく、"/" で始まるメッセージとして扱う
+func ParseCommand(text string) (name, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") || strings.HasPrefix(text, "//") || len(text) == 1 {
+		return "", "", false
+	}
+
+	name, args, _ = strings.Cut(text[1:], " ")
+	return name, strings.TrimSpace(args), true
+}
+
+// dispatchInput は入力された行をコマンドまたはメッセージとしてハンドラに渡す関数
+func dispatchInput(handler UIEventHandler, text string) {
+	if name, args, ok := ParseCommand(text); ok {
+		if commands, ok := handler.(CommandHandler); ok {
+			commands.OnCommand(name, args)
+			return
+		}
+	}
+
+	// "//" で始まる入力は先頭の "/" を1つ取り除いて送る
+	if strings.HasPrefix(text, "//") {
+		text = text[1:]
+	}
+	handler.OnMessageSend(text)
+}
+
 // UIMode はUIのモードを表す型
 type UIMode int
 